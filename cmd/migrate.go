@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/libopenstorage/operator/drivers/storage/portworx"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+func migrateCommand() cli.Command {
+	return cli.Command{
+		Name: "migrate",
+		Usage: "Print a StorageCluster equivalent to an existing Portworx DaemonSet. Once the " +
+			"StorageCluster is applied, the operator replaces the pods of the DaemonSet one node " +
+			"at a time and deletes the DaemonSet.",
+		Action: migrate,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  flagFile + ",f",
+				Usage: "File containing the Portworx DaemonSet spec. Use /dev/stdin to read from stdin.",
+			},
+		},
+	}
+}
+
+func migrate(c *cli.Context) {
+	log.SetLevel(log.WarnLevel)
+
+	filePath := c.String(flagFile)
+	if len(filePath) == 0 {
+		log.Fatalf("%s option is required", flagFile)
+	}
+	ds := &appsv1.DaemonSet{}
+	if err := k8sutil.ParseObjectFromFile(filePath, scheme.Scheme, ds); err != nil {
+		log.Fatalf("Error reading DaemonSet from %v: %v", filePath, err)
+	}
+
+	cluster, err := portworx.StorageClusterFromDaemonSet(ds)
+	if err != nil {
+		log.Fatalf("Error migrating DaemonSet %v/%v: %v", ds.Namespace, ds.Name, err)
+	}
+	out, err := yaml.Marshal(cluster)
+	if err != nil {
+		log.Fatalf("Error encoding StorageCluster %v: %v", cluster.Name, err)
+	}
+	fmt.Printf("%s", out)
+}
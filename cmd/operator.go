@@ -12,12 +12,14 @@ import (
 	_ "github.com/libopenstorage/operator/drivers/storage/portworx"
 	"github.com/libopenstorage/operator/pkg/apis"
 	"github.com/libopenstorage/operator/pkg/controller/storagecluster"
+	"github.com/libopenstorage/operator/pkg/controller/storagenode"
 	_ "github.com/libopenstorage/operator/pkg/log"
 	"github.com/libopenstorage/operator/pkg/version"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	"k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/rest"
@@ -30,10 +32,14 @@ const (
 	flagLeaderElectLockName      = "leader-elect-lock-name"
 	flagLeaderElectLockNamespace = "leader-elect-lock-namespace"
 	flagMetricsPort              = "metrics-port"
+	flagEnableWebhook            = "enable-webhook"
+	flagWebhookPort              = "webhook-port"
+	flagWebhookCertDir           = "webhook-cert-dir"
 	defaultLockObjectName        = "openstorage-operator"
 	defaultLockObjectNamespace   = "kube-system"
 	defaultResyncPeriod          = 30 * time.Second
 	defaultMetricsPort           = 8999
+	defaultWebhookPort           = 9443
 	metricsPortName              = "metrics"
 )
 
@@ -52,6 +58,7 @@ func main() {
 	app.Usage = "Operator to manage openstorage clusters"
 	app.Version = version.Version
 	app.Action = run
+	app.Commands = []cli.Command{renderCommand(), migrateCommand()}
 
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
@@ -81,6 +88,19 @@ func main() {
 			Usage: "Port on which the operator metrics are to be exposed",
 			Value: defaultMetricsPort,
 		},
+		cli.BoolFlag{
+			Name:  flagEnableWebhook,
+			Usage: "Enable the validating admission webhook for StorageCluster objects",
+		},
+		cli.IntFlag{
+			Name:  flagWebhookPort,
+			Usage: "Port on which the admission webhook server listens",
+			Value: defaultWebhookPort,
+		},
+		cli.StringFlag{
+			Name:  flagWebhookCertDir,
+			Usage: "Directory containing the tls.crt and tls.key files for the admission webhook server",
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -136,6 +156,9 @@ func run(c *cli.Context) {
 	if err := monitoringv1.AddToScheme(mgr.GetScheme()); err != nil {
 		log.Fatalf("Failed to add prometheus resources to the scheme: %v", err)
 	}
+	if err := apiextensionsv1beta1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Fatalf("Failed to add apiextensions resources to the scheme: %v", err)
+	}
 
 	// Create Service and ServiceMonitor objects to expose the metrics to Prometheus
 	metricsPort := c.Int(flagMetricsPort)
@@ -170,6 +193,17 @@ func run(c *cli.Context) {
 		log.Fatalf("Error initializing storage cluster controller: %v", err)
 	}
 
+	// Setup storage node controller
+	storageNodeController := storagenode.Controller{Driver: d}
+	if err := storageNodeController.Init(mgr); err != nil {
+		log.Fatalf("Error initializing storage node controller: %v", err)
+	}
+
+	if c.Bool(flagEnableWebhook) {
+		log.Infof("Registering StorageCluster validating webhook at %s", storagecluster.ValidatingWebhookPath)
+		storageClusterController.RegisterWebhook(mgr)
+	}
+
 	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
 		log.Fatalf("Manager exited non-zero error: %v", err)
 	}
@@ -180,6 +214,8 @@ func createManager(c *cli.Context, config *rest.Config) (manager.Manager, error)
 	managerOpts := manager.Options{
 		SyncPeriod:         &syncPeriod,
 		MetricsBindAddress: fmt.Sprintf("0.0.0.0:%d", c.Int(flagMetricsPort)),
+		Port:               c.Int(flagWebhookPort),
+		CertDir:            c.String(flagWebhookCertDir),
 	}
 	if c.BoolT(flagLeaderElect) {
 		managerOpts.LeaderElection = true
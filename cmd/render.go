@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	"github.com/libopenstorage/operator/drivers/storage"
+	"github.com/libopenstorage/operator/pkg/apis"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/controller/storagecluster"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	flagFile                = "file"
+	flagK8sVersion          = "k8s-version"
+	flagNodeLabels          = "node-labels"
+	defaultRenderDriver     = "portworx"
+	defaultRenderK8sVersion = "1.16.0"
+)
+
+func renderCommand() cli.Command {
+	return cli.Command{
+		Name: "render",
+		Usage: "Print the objects that the operator would create for a StorageCluster, " +
+			"without connecting to a Kubernetes cluster",
+		Action: render,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  flagFile + ",f",
+				Usage: "File containing the StorageCluster spec",
+			},
+			cli.StringFlag{
+				Name:  flagK8sVersion,
+				Usage: "Kubernetes version of the cluster the objects are rendered for",
+				Value: defaultRenderK8sVersion,
+			},
+			cli.StringFlag{
+				Name:  flagNodeLabels,
+				Usage: "Labels of the node the storage pod is rendered for, as key1=value1,key2=value2",
+			},
+			cli.StringFlag{
+				Name:  "driver,d",
+				Usage: "Storage driver name",
+				Value: defaultRenderDriver,
+			},
+		},
+	}
+}
+
+func render(c *cli.Context) {
+	log.SetLevel(log.WarnLevel)
+
+	filePath := c.String(flagFile)
+	if len(filePath) == 0 {
+		log.Fatalf("%s option is required", flagFile)
+	}
+	k8sVersion, err := version.NewVersion(c.String(flagK8sVersion))
+	if err != nil {
+		log.Fatalf("Invalid kubernetes version %v: %v", c.String(flagK8sVersion), err)
+	}
+	nodeLabels, err := labels.ConvertSelectorToLabelsMap(c.String(flagNodeLabels))
+	if err != nil {
+		log.Fatalf("Invalid node labels %v: %v", c.String(flagNodeLabels), err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := apis.AddToScheme(scheme); err != nil {
+		log.Fatalf("Failed to add resources to the scheme: %v", err)
+	}
+	cluster := &corev1alpha1.StorageCluster{}
+	if err := k8sutil.ParseObjectFromFile(filePath, scheme, cluster); err != nil {
+		log.Fatalf("Error reading StorageCluster from %v: %v", filePath, err)
+	}
+
+	driverName := c.String("driver")
+	d, err := storage.Get(driverName)
+	if err != nil {
+		log.Fatalf("Error getting Storage driver %v: %v", driverName, err)
+	}
+
+	objects, err := storagecluster.Render(d, cluster, k8sVersion, nodeLabels)
+	if err != nil {
+		log.Fatalf("Error rendering StorageCluster %v: %v", cluster.Name, err)
+	}
+	for _, obj := range objects {
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			log.Fatalf("Error encoding %v: %v", obj.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+		fmt.Printf("---\n%s", out)
+	}
+}
@@ -15,8 +15,8 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -51,17 +51,28 @@ var (
 )
 
 type autopilot struct {
-	isCreated bool
+	created   util.ClusterSet
 	k8sClient client.Client
+	recorder  record.EventRecorder
 }
 
 func (c *autopilot) Initialize(
 	k8sClient client.Client,
 	_ version.Version,
 	_ *runtime.Scheme,
-	_ record.EventRecorder,
+	recorder record.EventRecorder,
 ) {
 	c.k8sClient = k8sClient
+	c.recorder = recorder
+	c.created.Clear()
+}
+
+func (c *autopilot) Priority() int32 {
+	return PriorityDefault
+}
+
+func (c *autopilot) Dependencies() []string {
+	return nil
 }
 
 func (c *autopilot) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
@@ -76,10 +87,10 @@ func (c *autopilot) Reconcile(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.createServiceAccount(cluster.Namespace, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createClusterRole(ownerRef); err != nil {
+	if err := c.createClusterRole(cluster, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createClusterRoleBinding(cluster.Namespace, ownerRef); err != nil {
+	if err := c.createClusterRoleBinding(cluster, ownerRef); err != nil {
 		return err
 	}
 	if err := c.createDeployment(cluster, ownerRef); err != nil {
@@ -96,21 +107,21 @@ func (c *autopilot) Delete(cluster *corev1alpha1.StorageCluster) error {
 	if err := k8sutil.DeleteServiceAccount(c.k8sClient, AutopilotServiceAccountName, cluster.Namespace, *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRole(c.k8sClient, AutopilotClusterRoleName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRole(c.k8sClient, util.ClusterScopedName(cluster, AutopilotClusterRoleName), *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, AutopilotClusterRoleBindingName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, util.ClusterScopedName(cluster, AutopilotClusterRoleBindingName), *ownerRef); err != nil {
 		return err
 	}
 	if err := k8sutil.DeleteDeployment(c.k8sClient, AutopilotDeploymentName, cluster.Namespace, *ownerRef); err != nil {
 		return err
 	}
-	c.isCreated = false
+	c.created.Remove(cluster)
 	return nil
 }
 
-func (c *autopilot) MarkDeleted() {
-	c.isCreated = false
+func (c *autopilot) MarkDeleted(cluster *corev1alpha1.StorageCluster) {
+	c.created.Remove(cluster)
 }
 
 func (c *autopilot) createConfigMap(
@@ -143,7 +154,7 @@ func (c *autopilot) createConfigMap(
 		}
 	}
 
-	return k8sutil.CreateOrUpdateConfigMap(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&v1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
@@ -163,7 +174,7 @@ func (c *autopilot) createServiceAccount(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
@@ -176,13 +187,15 @@ func (c *autopilot) createServiceAccount(
 	)
 }
 
-func (c *autopilot) createClusterRole(ownerRef *metav1.OwnerReference) error {
-	return k8sutil.CreateOrUpdateClusterRole(
+func (c *autopilot) createClusterRole(
+	cluster *corev1alpha1.StorageCluster,
+	ownerRef *metav1.OwnerReference,
+) error {
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRole{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            AutopilotClusterRoleName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, AutopilotClusterRoleName),
 			},
 			Rules: []rbacv1.PolicyRule{
 				{
@@ -197,26 +210,25 @@ func (c *autopilot) createClusterRole(ownerRef *metav1.OwnerReference) error {
 }
 
 func (c *autopilot) createClusterRoleBinding(
-	clusterNamespace string,
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateClusterRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            AutopilotClusterRoleBindingName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, AutopilotClusterRoleBindingName),
 			},
 			Subjects: []rbacv1.Subject{
 				{
 					Kind:      "ServiceAccount",
 					Name:      AutopilotServiceAccountName,
-					Namespace: clusterNamespace,
+					Namespace: cluster.Namespace,
 				},
 			},
 			RoleRef: rbacv1.RoleRef{
 				Kind:     "ClusterRole",
-				Name:     AutopilotClusterRoleName,
+				Name:     util.ClusterScopedName(cluster, AutopilotClusterRoleName),
 				APIGroup: "rbac.authorization.k8s.io",
 			},
 		},
@@ -245,11 +257,8 @@ func (c *autopilot) createDeployment(
 		return err
 	}
 
-	targetCPU := defaultAutopilotCPU
-	if cpuStr, ok := cluster.Annotations[pxutil.AnnotationAutopilotCPU]; ok {
-		targetCPU = cpuStr
-	}
-	targetCPUQuantity, err := resource.ParseQuantity(targetCPU)
+	targetResources, err := util.ResourceRequirements(cluster, cluster.Spec.Autopilot.Resources,
+		pxutil.AnnotationAutopilotCPU, defaultAutopilotCPU)
 	if err != nil {
 		return err
 	}
@@ -290,14 +299,14 @@ func (c *autopilot) createDeployment(
 	var existingImage string
 	var existingCommand []string
 	var existingEnvs []v1.EnvVar
-	var existingCPUQuantity resource.Quantity
+	var existingResources v1.ResourceRequirements
 	for _, c := range existingDeployment.Spec.Template.Spec.Containers {
 		if c.Name == AutopilotContainerName {
 			existingImage = c.Image
 			existingCommand = c.Command
 			existingEnvs = append([]v1.EnvVar{}, c.Env...)
 			sort.Sort(envByName(existingEnvs))
-			existingCPUQuantity = c.Resources.Requests[v1.ResourceCPU]
+			existingResources = c.Resources
 			break
 		}
 	}
@@ -306,16 +315,28 @@ func (c *autopilot) createDeployment(
 	modified := existingImage != imageName ||
 		!reflect.DeepEqual(existingCommand, command) ||
 		!reflect.DeepEqual(existingEnvs, envVars) ||
-		existingCPUQuantity.Cmp(targetCPUQuantity) != 0
+		!equality.Semantic.DeepEqual(existingResources, targetResources)
+
+	deployment := c.getAutopilotDeploymentSpec(cluster, ownerRef, imageName,
+		command, envVars, targetResources)
+	modified = modified ||
+		util.PlacementChanged(&existingDeployment.Spec.Template.Spec, &deployment.Spec.Template.Spec)
+	// Revert the deployment if it was deleted or changed out of band,
+	// unless it is being updated anyway
+	drifted := false
+	if c.created.Contains(cluster) && (!modified || existingDeployment.Name == "") {
+		drifted, err = k8sutil.DetectDrift(c.recorder, cluster, deployment, existingDeployment)
+		if err != nil {
+			return err
+		}
+	}
 
-	if !c.isCreated || modified {
-		deployment := c.getAutopilotDeploymentSpec(cluster, ownerRef, imageName,
-			command, envVars, targetCPUQuantity)
-		if err = k8sutil.CreateOrUpdateDeployment(c.k8sClient, deployment, ownerRef); err != nil {
+	if !c.created.Contains(cluster) || modified || drifted {
+		if err = k8sutil.CreateOrUpdate(c.k8sClient, deployment, ownerRef); err != nil {
 			return err
 		}
 	}
-	c.isCreated = true
+	c.created.Add(cluster)
 	return nil
 }
 
@@ -325,7 +346,7 @@ func (c *autopilot) getAutopilotDeploymentSpec(
 	imageName string,
 	command []string,
 	envVars []v1.EnvVar,
-	cpuQuantity resource.Quantity,
+	resources v1.ResourceRequirements,
 ) *appsv1.Deployment {
 	deploymentLabels := map[string]string{
 		"tier": "control-plane",
@@ -377,11 +398,7 @@ func (c *autopilot) getAutopilotDeploymentSpec(
 							Image:           imageName,
 							ImagePullPolicy: imagePullPolicy,
 							Command:         command,
-							Resources: v1.ResourceRequirements{
-								Requests: map[v1.ResourceName]resource.Quantity{
-									v1.ResourceCPU: cpuQuantity,
-								},
-							},
+							Resources:       resources,
 							VolumeMounts: []v1.VolumeMount{
 								{
 									Name:      "config-volume",
@@ -446,6 +463,7 @@ func (c *autopilot) getAutopilotDeploymentSpec(
 		deployment.Spec.Template.Spec.Containers[0].Env = envVars
 	}
 
+	util.ApplyPlacement(&deployment.Spec.Template.Spec, cluster.Spec.Autopilot.Placement)
 	return deployment
 }
 
@@ -1,6 +1,8 @@
 package component
 
 import (
+	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/hashicorp/go-version"
@@ -11,6 +13,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// PriorityCritical is the priority of the components that the storage
+	// pods need to come up
+	PriorityCritical = int32(100)
+	// PriorityDefault is the priority of the other components
+	PriorityDefault = int32(0)
+)
+
 var (
 	registerLock sync.Mutex
 )
@@ -25,14 +35,21 @@ type PortworxComponent interface {
 		scheme *runtime.Scheme,
 		recorder record.EventRecorder,
 	)
+	// Priority returns the priority of the component. Components with a higher
+	// priority are reconciled first, once their dependencies are reconciled.
+	Priority() int32
+	// Dependencies returns the names of the components that have to be
+	// reconciled before this component
+	Dependencies() []string
 	// IsEnabled checks if the components needs to be enabled based on the StorageCluster
 	IsEnabled(cluster *corev1alpha1.StorageCluster) bool
 	// Reconcile reconciles the component to match the current state of the StorageCluster
 	Reconcile(cluster *corev1alpha1.StorageCluster) error
 	// Delete deletes the component if present
 	Delete(cluster *corev1alpha1.StorageCluster) error
-	// MarkDeleted marks the component as deleted in situations like StorageCluster deletion
-	MarkDeleted()
+	// MarkDeleted marks the component of the given cluster as deleted in
+	// situations like StorageCluster deletion
+	MarkDeleted(cluster *corev1alpha1.StorageCluster)
 }
 
 var (
@@ -62,6 +79,120 @@ func GetAll() map[string]PortworxComponent {
 	return componentsCopy
 }
 
+// RunInOrder runs the given function for all the registered components, in
+// the order of their dependencies, with at most maxParallel components running
+// at a time. Among the components whose dependencies are done, the ones with a
+// higher priority are started first. A component is not run if one of its
+// enabled dependencies failed, and fails with an ErrDependency error instead.
+// A failure of a dependency that is not enabled, like one failing to delete
+// its objects, does not affect the components depending on it. Once a
+// component fails with a critical error no more components are started.
+// It returns the result of every component that was run or failed, which is
+// nil for the components that succeeded. Dependencies on components that are
+// not registered are ignored.
+func RunInOrder(
+	maxParallel int,
+	enabled map[string]bool,
+	fn func(name string, comp PortworxComponent) error,
+) map[string]error {
+	type result struct {
+		name string
+		err  error
+	}
+
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+	comps := GetAll()
+	pending := make([]string, 0, len(comps))
+	for name := range comps {
+		pending = append(pending, name)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		pi, pj := comps[pending[i]].Priority(), comps[pending[j]].Priority()
+		if pi != pj {
+			return pi > pj
+		}
+		return pending[i] < pending[j]
+	})
+
+	errs := make(map[string]error)
+	done := make(map[string]bool)
+	results := make(chan result)
+	running := 0
+	stopped := false
+	for len(pending) > 0 || running > 0 {
+		// Keep scanning as long as failures are propagated to the dependents
+		for scan := !stopped; scan; {
+			scan = false
+			var waiting []string
+			for _, name := range pending {
+				ready, failedDep := dependenciesDone(comps, name, enabled, done, errs)
+				switch {
+				case failedDep != "":
+					errs[name] = NewError(ErrDependency,
+						fmt.Errorf("dependency %s failed", failedDep))
+					done[name] = true
+					scan = true
+				case ready && running < maxParallel:
+					running++
+					go func(name string, comp PortworxComponent) {
+						results <- result{name: name, err: fn(name, comp)}
+					}(name, comps[name])
+				default:
+					waiting = append(waiting, name)
+				}
+			}
+			pending = waiting
+		}
+
+		if running == 0 {
+			// Nothing is running and nothing can be started, so the remaining
+			// components either depend on each other or were stopped
+			if !stopped {
+				for _, name := range pending {
+					errs[name] = NewError(ErrDependency,
+						fmt.Errorf("circular dependency between components"))
+				}
+			}
+			break
+		}
+
+		res := <-results
+		running--
+		done[res.name] = true
+		errs[res.name] = res.err
+		if IsCritical(res.err) {
+			stopped = true
+		}
+	}
+	return errs
+}
+
+// dependenciesDone checks if all the dependencies of the given component are
+// done. It returns the name of a dependency if one of them has failed.
+func dependenciesDone(
+	comps map[string]PortworxComponent,
+	name string,
+	enabled map[string]bool,
+	done map[string]bool,
+	errs map[string]error,
+) (bool, string) {
+	ready := true
+	for _, dep := range comps[name].Dependencies() {
+		if _, exists := comps[dep]; !exists {
+			continue
+		}
+		if errs[dep] != nil && enabled[dep] {
+			return false, dep
+		}
+		if !done[dep] {
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
 // DeregisterAllComponents removes all registered components from the list.
 // This is used only for testing.
 func DeregisterAllComponents() {
@@ -46,20 +46,34 @@ const (
 )
 
 type csi struct {
-	isCreated             bool
+	created               util.ClusterSet
+	usesDeployment        util.ClusterSet
 	csiNodeInfoCRDCreated bool
 	k8sClient             client.Client
 	k8sVersion            version.Version
+	recorder              record.EventRecorder
 }
 
 func (c *csi) Initialize(
 	k8sClient client.Client,
 	k8sVersion version.Version,
 	_ *runtime.Scheme,
-	_ record.EventRecorder,
+	recorder record.EventRecorder,
 ) {
 	c.k8sClient = k8sClient
 	c.k8sVersion = k8sVersion
+	c.recorder = recorder
+	c.created.Clear()
+	c.usesDeployment.Clear()
+	c.csiNodeInfoCRDCreated = false
+}
+
+func (c *csi) Priority() int32 {
+	return PriorityDefault
+}
+
+func (c *csi) Dependencies() []string {
+	return []string{PriorityClassComponentName}
 }
 
 func (c *csi) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
@@ -88,6 +102,16 @@ func (c *csi) Reconcile(cluster *corev1alpha1.StorageCluster) error {
 			return err
 		}
 	}
+	// The sidecars move between a Deployment and a StatefulSet when the
+	// Kubernetes version changes, so the new object has not been created yet
+	if csiConfig.UseDeployment != c.usesDeployment.Contains(cluster) {
+		c.created.Remove(cluster)
+		if csiConfig.UseDeployment {
+			c.usesDeployment.Add(cluster)
+		} else {
+			c.usesDeployment.Remove(cluster)
+		}
+	}
 	if csiConfig.UseDeployment {
 		if err := k8sutil.DeleteStatefulSet(c.k8sClient, CSIApplicationName, cluster.Namespace, *ownerRef); err != nil {
 			return err
@@ -116,10 +140,10 @@ func (c *csi) Delete(cluster *corev1alpha1.StorageCluster) error {
 	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
 	// We don't delete the service account for CSI because it is part of CSV. If
 	// we disable CSI then the CSV upgrades would fail as requirements are not met.
-	if err := k8sutil.DeleteClusterRole(c.k8sClient, CSIClusterRoleName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRole(c.k8sClient, util.ClusterScopedName(cluster, CSIClusterRoleName), *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, CSIClusterRoleBindingName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, util.ClusterScopedName(cluster, CSIClusterRoleBindingName), *ownerRef); err != nil {
 		return err
 	}
 	if err := k8sutil.DeleteService(c.k8sClient, CSIServiceName, cluster.Namespace, *ownerRef); err != nil {
@@ -131,7 +155,7 @@ func (c *csi) Delete(cluster *corev1alpha1.StorageCluster) error {
 	if err := k8sutil.DeleteDeployment(c.k8sClient, CSIApplicationName, cluster.Namespace, *ownerRef); err != nil {
 		return err
 	}
-	c.isCreated = false
+	c.created.Remove(cluster)
 
 	pxVersion := pxutil.GetPortworxVersion(cluster)
 	csiConfig := c.getCSIConfiguration(cluster, pxVersion)
@@ -143,8 +167,8 @@ func (c *csi) Delete(cluster *corev1alpha1.StorageCluster) error {
 	return nil
 }
 
-func (c *csi) MarkDeleted() {
-	c.isCreated = false
+func (c *csi) MarkDeleted(cluster *corev1alpha1.StorageCluster) {
+	c.created.Remove(cluster)
 	c.csiNodeInfoCRDCreated = false
 }
 
@@ -152,7 +176,7 @@ func (c *csi) createServiceAccount(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
@@ -172,8 +196,7 @@ func (c *csi) createClusterRole(
 ) error {
 	clusterRole := &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            CSIClusterRoleName,
-			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+			Name: util.ClusterScopedName(cluster, CSIClusterRoleName),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -290,19 +313,18 @@ func (c *csi) createClusterRole(
 			},
 		)
 	}
-	return k8sutil.CreateOrUpdateClusterRole(c.k8sClient, clusterRole, ownerRef)
+	return k8sutil.CreateOrUpdate(c.k8sClient, clusterRole, ownerRef)
 }
 
 func (c *csi) createClusterRoleBinding(
 	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateClusterRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            CSIClusterRoleBindingName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, CSIClusterRoleBindingName),
 			},
 			Subjects: []rbacv1.Subject{
 				{
@@ -313,7 +335,7 @@ func (c *csi) createClusterRoleBinding(
 			},
 			RoleRef: rbacv1.RoleRef{
 				Kind:     "ClusterRole",
-				Name:     CSIClusterRoleName,
+				Name:     util.ClusterScopedName(cluster, CSIClusterRoleName),
 				APIGroup: "rbac.authorization.k8s.io",
 			},
 		},
@@ -325,7 +347,7 @@ func (c *csi) createService(
 	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateService(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&v1.Service{
 			ObjectMeta: metav1.ObjectMeta{
@@ -393,18 +415,33 @@ func (c *csi) createDeployment(
 		)
 	}
 
-	if !c.isCreated ||
-		provisionerImage != existingProvisionerImage ||
+	modified := provisionerImage != existingProvisionerImage ||
 		attacherImage != existingAttacherImage ||
 		snapshotterImage != existingSnapshotterImage ||
-		resizerImage != existingResizerImage {
-		deployment := getCSIDeploymentSpec(cluster, csiConfig, ownerRef,
-			provisionerImage, attacherImage, snapshotterImage, resizerImage)
-		if err = k8sutil.CreateOrUpdateDeployment(c.k8sClient, deployment, ownerRef); err != nil {
+		resizerImage != existingResizerImage ||
+		cluster.Spec.PriorityClassName != existingDeployment.Spec.Template.Spec.PriorityClassName
+
+	deployment := getCSIDeploymentSpec(cluster, csiConfig, ownerRef,
+		provisionerImage, attacherImage, snapshotterImage, resizerImage)
+	modified = modified ||
+		util.PlacementChanged(&existingDeployment.Spec.Template.Spec, &deployment.Spec.Template.Spec) ||
+		util.ContainerResourcesChanged(&existingDeployment.Spec.Template.Spec, &deployment.Spec.Template.Spec)
+	// Revert the deployment if it was deleted or changed out of band,
+	// unless it is being updated anyway
+	drifted := false
+	if c.created.Contains(cluster) && (!modified || existingDeployment.Name == "") {
+		drifted, err = k8sutil.DetectDrift(c.recorder, cluster, deployment, existingDeployment)
+		if err != nil {
 			return err
 		}
 	}
-	c.isCreated = true
+
+	if !c.created.Contains(cluster) || modified || drifted {
+		if err = k8sutil.CreateOrUpdate(c.k8sClient, deployment, ownerRef); err != nil {
+			return err
+		}
+	}
+	c.created.Add(cluster)
 	return nil
 }
 
@@ -445,6 +482,7 @@ func getCSIDeploymentSpec(
 				},
 				Spec: v1.PodSpec{
 					ServiceAccountName: CSIServiceAccountName,
+					PriorityClassName:  cluster.Spec.PriorityClassName,
 					Containers: []v1.Container{
 						{
 							Name:            csiProvisionerContainerName,
@@ -586,10 +624,9 @@ func getCSIDeploymentSpec(
 		)
 	}
 
-	if cluster.Spec.Placement != nil && cluster.Spec.Placement.NodeAffinity != nil {
-		deployment.Spec.Template.Spec.Affinity = &v1.Affinity{
-			NodeAffinity: cluster.Spec.Placement.NodeAffinity.DeepCopy(),
-		}
+	util.ApplyPlacement(&deployment.Spec.Template.Spec, cluster.Spec.Placement)
+	if cluster.Spec.CSI != nil {
+		util.SetContainerResources(&deployment.Spec.Template.Spec, cluster.Spec.CSI.Resources)
 	}
 
 	return deployment
@@ -629,15 +666,30 @@ func (c *csi) createStatefulSet(
 		csiConfig.Attacher,
 	)
 
-	if !c.isCreated ||
-		provisionerImage != existingProvisionerImage ||
-		attacherImage != existingAttacherImage {
-		statefulSet := getCSIStatefulSetSpec(cluster, csiConfig, ownerRef, provisionerImage, attacherImage)
-		if err = k8sutil.CreateOrUpdateStatefulSet(c.k8sClient, statefulSet, ownerRef); err != nil {
+	modified := provisionerImage != existingProvisionerImage ||
+		attacherImage != existingAttacherImage ||
+		cluster.Spec.PriorityClassName != existingSS.Spec.Template.Spec.PriorityClassName
+
+	statefulSet := getCSIStatefulSetSpec(cluster, csiConfig, ownerRef, provisionerImage, attacherImage)
+	modified = modified ||
+		util.PlacementChanged(&existingSS.Spec.Template.Spec, &statefulSet.Spec.Template.Spec) ||
+		util.ContainerResourcesChanged(&existingSS.Spec.Template.Spec, &statefulSet.Spec.Template.Spec)
+	// Revert the stateful set if it was deleted or changed out of band,
+	// unless it is being updated anyway
+	drifted := false
+	if c.created.Contains(cluster) && (!modified || existingSS.Name == "") {
+		drifted, err = k8sutil.DetectDrift(c.recorder, cluster, statefulSet, existingSS)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !c.created.Contains(cluster) || modified || drifted {
+		if err = k8sutil.CreateOrUpdate(c.k8sClient, statefulSet, ownerRef); err != nil {
 			return err
 		}
 	}
-	c.isCreated = true
+	c.created.Add(cluster)
 	return nil
 }
 
@@ -671,6 +723,7 @@ func getCSIStatefulSetSpec(
 				},
 				Spec: v1.PodSpec{
 					ServiceAccountName: CSIServiceAccountName,
+					PriorityClassName:  cluster.Spec.PriorityClassName,
 					Containers: []v1.Container{
 						{
 							Name:            csiProvisionerContainerName,
@@ -738,10 +791,9 @@ func getCSIStatefulSetSpec(
 		},
 	}
 
-	if cluster.Spec.Placement != nil && cluster.Spec.Placement.NodeAffinity != nil {
-		statefulSet.Spec.Template.Spec.Affinity = &v1.Affinity{
-			NodeAffinity: cluster.Spec.Placement.NodeAffinity.DeepCopy(),
-		}
+	util.ApplyPlacement(&statefulSet.Spec.Template.Spec, cluster.Spec.Placement)
+	if cluster.Spec.CSI != nil {
+		util.SetContainerResources(&statefulSet.Spec.Template.Spec, cluster.Spec.CSI.Resources)
 	}
 
 	return statefulSet
@@ -751,12 +803,13 @@ func (c *csi) createCSIDriver(
 	csiConfig *pxutil.CSIConfiguration,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateCSIDriver(
+	// The CSIDriver name is the name of the CSI driver itself, so the object is
+	// shared by all the StorageClusters. Every cluster adds itself as an owner.
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&storagev1beta1.CSIDriver{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            csiConfig.DriverName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: csiConfig.DriverName,
 			},
 			Spec: storagev1beta1.CSIDriverSpec{
 				AttachRequired: boolPtr(false),
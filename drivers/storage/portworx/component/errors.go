@@ -6,6 +6,9 @@ type Code string
 const (
 	// ErrCritical code for a critical error
 	ErrCritical Code = "Critical"
+	// ErrDependency code for a component that was not reconciled because
+	// of its dependencies
+	ErrDependency Code = "Dependency"
 )
 
 // Error error returned for component operations
@@ -19,6 +22,12 @@ func NewError(code Code, err error) error {
 	return &Error{errorCode: code, originalError: err}
 }
 
+// IsCritical returns true if the given error is a critical component error
+func IsCritical(err error) bool {
+	ce, ok := err.(*Error)
+	return ok && ce.Code() == ErrCritical
+}
+
 // Code returns the error code for the component error
 func (e *Error) Code() Code {
 	return e.errorCode
@@ -56,17 +56,28 @@ const (
 )
 
 type lighthouse struct {
-	isCreated bool
+	created   util.ClusterSet
 	k8sClient client.Client
+	recorder  record.EventRecorder
 }
 
 func (c *lighthouse) Initialize(
 	k8sClient client.Client,
 	_ version.Version,
 	_ *runtime.Scheme,
-	_ record.EventRecorder,
+	recorder record.EventRecorder,
 ) {
 	c.k8sClient = k8sClient
+	c.recorder = recorder
+	c.created.Clear()
+}
+
+func (c *lighthouse) Priority() int32 {
+	return PriorityDefault
+}
+
+func (c *lighthouse) Dependencies() []string {
+	return nil
 }
 
 func (c *lighthouse) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
@@ -78,10 +89,10 @@ func (c *lighthouse) Reconcile(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.createServiceAccount(cluster.Namespace, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createClusterRole(ownerRef); err != nil {
+	if err := c.createClusterRole(cluster, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createClusterRoleBinding(cluster.Namespace, ownerRef); err != nil {
+	if err := c.createClusterRoleBinding(cluster, ownerRef); err != nil {
 		return err
 	}
 	if err := c.createService(cluster, ownerRef); err != nil {
@@ -97,10 +108,10 @@ func (c *lighthouse) Delete(cluster *corev1alpha1.StorageCluster) error {
 	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
 	// We don't delete the service account for Lighthouse because it is part of CSV. If
 	// we disable Lighthouse then the CSV upgrades would fail as requirements are not met.
-	if err := k8sutil.DeleteClusterRole(c.k8sClient, LhClusterRoleName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRole(c.k8sClient, util.ClusterScopedName(cluster, LhClusterRoleName), *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, LhClusterRoleBindingName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, util.ClusterScopedName(cluster, LhClusterRoleBindingName), *ownerRef); err != nil {
 		return err
 	}
 	if err := k8sutil.DeleteService(c.k8sClient, LhServiceName, cluster.Namespace, *ownerRef); err != nil {
@@ -109,19 +120,19 @@ func (c *lighthouse) Delete(cluster *corev1alpha1.StorageCluster) error {
 	if err := k8sutil.DeleteDeployment(c.k8sClient, LhDeploymentName, cluster.Namespace, *ownerRef); err != nil {
 		return err
 	}
-	c.isCreated = false
+	c.created.Remove(cluster)
 	return nil
 }
 
-func (c *lighthouse) MarkDeleted() {
-	c.isCreated = false
+func (c *lighthouse) MarkDeleted(cluster *corev1alpha1.StorageCluster) {
+	c.created.Remove(cluster)
 }
 
 func (c *lighthouse) createServiceAccount(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
@@ -134,13 +145,15 @@ func (c *lighthouse) createServiceAccount(
 	)
 }
 
-func (c *lighthouse) createClusterRole(ownerRef *metav1.OwnerReference) error {
-	return k8sutil.CreateOrUpdateClusterRole(
+func (c *lighthouse) createClusterRole(
+	cluster *corev1alpha1.StorageCluster,
+	ownerRef *metav1.OwnerReference,
+) error {
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRole{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            LhClusterRoleName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, LhClusterRoleName),
 			},
 			Rules: []rbacv1.PolicyRule{
 				{
@@ -196,26 +209,25 @@ func (c *lighthouse) createClusterRole(ownerRef *metav1.OwnerReference) error {
 }
 
 func (c *lighthouse) createClusterRoleBinding(
-	clusterNamespace string,
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateClusterRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            LhClusterRoleBindingName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, LhClusterRoleBindingName),
 			},
 			Subjects: []rbacv1.Subject{
 				{
 					Kind:      "ServiceAccount",
 					Name:      LhServiceAccountName,
-					Namespace: clusterNamespace,
+					Namespace: cluster.Namespace,
 				},
 			},
 			RoleRef: rbacv1.RoleRef{
 				Kind:     "ClusterRole",
-				Name:     LhClusterRoleName,
+				Name:     util.ClusterScopedName(cluster, LhClusterRoleName),
 				APIGroup: "rbac.authorization.k8s.io",
 			},
 		},
@@ -261,7 +273,7 @@ func (c *lighthouse) createService(
 		newService.Spec.Type = v1.ServiceTypeNodePort
 	}
 
-	return k8sutil.CreateOrUpdateService(c.k8sClient, newService, ownerRef)
+	return k8sutil.CreateOrUpdate(c.k8sClient, newService, ownerRef)
 }
 
 func (c *lighthouse) createDeployment(
@@ -315,13 +327,26 @@ func (c *lighthouse) createDeployment(
 		configSyncImage != existingConfigSyncImage ||
 		storkConnectorImage != existingStorkConnectorImage
 
-	if !c.isCreated || modified {
-		deployment := getLighthouseDeploymentSpec(cluster, ownerRef, lhImage, configSyncImage, storkConnectorImage)
-		if err = k8sutil.CreateOrUpdateDeployment(c.k8sClient, deployment, ownerRef); err != nil {
+	deployment := getLighthouseDeploymentSpec(cluster, ownerRef, lhImage, configSyncImage, storkConnectorImage)
+	modified = modified ||
+		util.PlacementChanged(&existingDeployment.Spec.Template.Spec, &deployment.Spec.Template.Spec) ||
+		util.ContainerResourcesChanged(&existingDeployment.Spec.Template.Spec, &deployment.Spec.Template.Spec)
+	// Revert the deployment if it was deleted or changed out of band,
+	// unless it is being updated anyway
+	drifted := false
+	if c.created.Contains(cluster) && (!modified || existingDeployment.Name == "") {
+		drifted, err = k8sutil.DetectDrift(c.recorder, cluster, deployment, existingDeployment)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !c.created.Contains(cluster) || modified || drifted {
+		if err = k8sutil.CreateOrUpdate(c.k8sClient, deployment, ownerRef); err != nil {
 			return err
 		}
 	}
-	c.isCreated = true
+	c.created.Add(cluster)
 	return nil
 }
 
@@ -338,7 +363,7 @@ func getLighthouseDeploymentSpec(
 	maxSurge := intstr.FromInt(1)
 	imagePullPolicy := pxutil.ImagePullPolicy(cluster)
 
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            LhDeploymentName,
 			Namespace:       cluster.Namespace,
@@ -440,6 +465,10 @@ func getLighthouseDeploymentSpec(
 			},
 		},
 	}
+
+	util.ApplyPlacement(&deployment.Spec.Template.Spec, cluster.Spec.UserInterface.Placement)
+	util.SetContainerResources(&deployment.Spec.Template.Spec, cluster.Spec.UserInterface.Resources)
+	return deployment
 }
 
 func getLighthouseLabels() map[string]string {
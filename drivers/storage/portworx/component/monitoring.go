@@ -47,6 +47,14 @@ func (c *monitoring) Initialize(
 	c.recorder = recorder
 }
 
+func (c *monitoring) Priority() int32 {
+	return PriorityDefault
+}
+
+func (c *monitoring) Dependencies() []string {
+	return []string{PortworxBasicComponentName}
+}
+
 func (c *monitoring) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
 	return cluster.Spec.Monitoring != nil && cluster.Spec.Monitoring.EnableMetrics
 }
@@ -83,7 +91,7 @@ func (c *monitoring) Delete(cluster *corev1alpha1.StorageCluster) error {
 	return nil
 }
 
-func (c *monitoring) MarkDeleted() {}
+func (c *monitoring) MarkDeleted(_ *corev1alpha1.StorageCluster) {}
 
 func (c *monitoring) createServiceMonitor(
 	cluster *corev1alpha1.StorageCluster,
@@ -121,7 +129,7 @@ func (c *monitoring) createServiceMonitor(
 		)
 	}
 
-	return k8sutil.CreateOrUpdateServiceMonitor(c.k8sClient, svcMonitor, ownerRef)
+	return k8sutil.CreateOrUpdate(c.k8sClient, svcMonitor, ownerRef)
 }
 
 func (c *monitoring) createPrometheusRule(
@@ -141,7 +149,7 @@ func (c *monitoring) createPrometheusRule(
 		},
 		OwnerReferences: []metav1.OwnerReference{*ownerRef},
 	}
-	return k8sutil.CreateOrUpdatePrometheusRule(c.k8sClient, prometheusRule, ownerRef)
+	return k8sutil.CreateOrUpdate(c.k8sClient, prometheusRule, ownerRef)
 }
 
 func (c *monitoring) warningEvent(
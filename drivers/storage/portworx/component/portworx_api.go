@@ -1,6 +1,8 @@
 package component
 
 import (
+	"context"
+
 	"github.com/hashicorp/go-version"
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
@@ -8,8 +10,10 @@ import (
 	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -25,17 +29,28 @@ const (
 )
 
 type portworxAPI struct {
-	isCreated bool
+	created   util.ClusterSet
 	k8sClient client.Client
+	recorder  record.EventRecorder
 }
 
 func (c *portworxAPI) Initialize(
 	k8sClient client.Client,
 	_ version.Version,
 	_ *runtime.Scheme,
-	_ record.EventRecorder,
+	recorder record.EventRecorder,
 ) {
 	c.k8sClient = k8sClient
+	c.recorder = recorder
+	c.created.Clear()
+}
+
+func (c *portworxAPI) Priority() int32 {
+	return PriorityDefault
+}
+
+func (c *portworxAPI) Dependencies() []string {
+	return []string{PortworxBasicComponentName}
 }
 
 func (c *portworxAPI) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
@@ -47,12 +62,10 @@ func (c *portworxAPI) Reconcile(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.createService(cluster, ownerRef); err != nil {
 		return err
 	}
-	if !c.isCreated {
-		if err := c.createDaemonSet(cluster, ownerRef); err != nil {
-			return err
-		}
-		c.isCreated = true
+	if err := c.createDaemonSet(cluster, ownerRef); err != nil {
+		return err
 	}
+	c.created.Add(cluster)
 	return nil
 }
 
@@ -60,8 +73,8 @@ func (c *portworxAPI) Delete(cluster *corev1alpha1.StorageCluster) error {
 	return nil
 }
 
-func (c *portworxAPI) MarkDeleted() {
-	c.isCreated = false
+func (c *portworxAPI) MarkDeleted(cluster *corev1alpha1.StorageCluster) {
+	c.created.Remove(cluster)
 }
 
 func (c *portworxAPI) createService(
@@ -109,7 +122,7 @@ func (c *portworxAPI) createService(
 		newService.Spec.Type = serviceType
 	}
 
-	return k8sutil.CreateOrUpdateService(c.k8sClient, newService, ownerRef)
+	return k8sutil.CreateOrUpdate(c.k8sClient, newService, ownerRef)
 }
 
 func (c *portworxAPI) createDaemonSet(
@@ -166,13 +179,32 @@ func (c *portworxAPI) createDaemonSet(
 		},
 	}
 
-	if cluster.Spec.Placement != nil && cluster.Spec.Placement.NodeAffinity != nil {
-		newDaemonSet.Spec.Template.Spec.Affinity = &v1.Affinity{
-			NodeAffinity: cluster.Spec.Placement.NodeAffinity.DeepCopy(),
+	util.ApplyPlacement(&newDaemonSet.Spec.Template.Spec, cluster.Spec.Placement)
+
+	if c.created.Contains(cluster) {
+		existingDaemonSet := &appsv1.DaemonSet{}
+		err := c.k8sClient.Get(
+			context.TODO(),
+			types.NamespacedName{
+				Name:      PxAPIDaemonSetName,
+				Namespace: cluster.Namespace,
+			},
+			existingDaemonSet,
+		)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		// Update the daemon set only if the placement has changed,
+		// or if it was deleted or changed out of band
+		if !util.PlacementChanged(&existingDaemonSet.Spec.Template.Spec, &newDaemonSet.Spec.Template.Spec) {
+			drifted, err := k8sutil.DetectDrift(c.recorder, cluster, newDaemonSet, existingDaemonSet)
+			if err != nil || !drifted {
+				return err
+			}
 		}
 	}
 
-	return k8sutil.CreateOrUpdateDaemonSet(c.k8sClient, newDaemonSet, ownerRef)
+	return k8sutil.CreateOrUpdate(c.k8sClient, newDaemonSet, ownerRef)
 }
 
 func getPortworxAPIServiceLabels() map[string]string {
@@ -4,6 +4,7 @@ import (
 	"github.com/hashicorp/go-version"
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
 	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
 	"k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -36,6 +37,14 @@ func (c *portworxBasic) Initialize(
 	c.k8sClient = k8sClient
 }
 
+func (c *portworxBasic) Priority() int32 {
+	return PriorityCritical
+}
+
+func (c *portworxBasic) Dependencies() []string {
+	return nil
+}
+
 func (c *portworxBasic) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
 	return true
 }
@@ -45,10 +54,10 @@ func (c *portworxBasic) Reconcile(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.createServiceAccount(cluster.Namespace, ownerRef); err != nil {
 		return NewError(ErrCritical, err)
 	}
-	if err := c.createClusterRole(ownerRef); err != nil {
+	if err := c.createClusterRole(cluster, ownerRef); err != nil {
 		return NewError(ErrCritical, err)
 	}
-	if err := c.createClusterRoleBinding(cluster.Namespace, ownerRef); err != nil {
+	if err := c.createClusterRoleBinding(cluster, ownerRef); err != nil {
 		return NewError(ErrCritical, err)
 	}
 	if err := c.createRole(cluster.Namespace, ownerRef); err != nil {
@@ -67,13 +76,13 @@ func (c *portworxBasic) Delete(cluster *corev1alpha1.StorageCluster) error {
 	return nil
 }
 
-func (c *portworxBasic) MarkDeleted() {}
+func (c *portworxBasic) MarkDeleted(_ *corev1alpha1.StorageCluster) {}
 
 func (c *portworxBasic) createServiceAccount(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
@@ -87,7 +96,7 @@ func (c *portworxBasic) createServiceAccount(
 }
 
 func (c *portworxBasic) createRole(clusterNamespace string, ownerRef *metav1.OwnerReference) error {
-	return k8sutil.CreateOrUpdateRole(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.Role{
 			ObjectMeta: metav1.ObjectMeta{
@@ -111,7 +120,7 @@ func (c *portworxBasic) createRoleBinding(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.RoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
@@ -136,13 +145,15 @@ func (c *portworxBasic) createRoleBinding(
 	)
 }
 
-func (c *portworxBasic) createClusterRole(ownerRef *metav1.OwnerReference) error {
-	return k8sutil.CreateOrUpdateClusterRole(
+func (c *portworxBasic) createClusterRole(
+	cluster *corev1alpha1.StorageCluster,
+	ownerRef *metav1.OwnerReference,
+) error {
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRole{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            pxClusterRoleName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, pxClusterRoleName),
 			},
 			Rules: []rbacv1.PolicyRule{
 				{
@@ -198,26 +209,25 @@ func (c *portworxBasic) createClusterRole(ownerRef *metav1.OwnerReference) error
 }
 
 func (c *portworxBasic) createClusterRoleBinding(
-	clusterNamespace string,
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateClusterRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            pxClusterRoleBindingName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, pxClusterRoleBindingName),
 			},
 			Subjects: []rbacv1.Subject{
 				{
 					Kind:      "ServiceAccount",
 					Name:      pxutil.PortworxServiceAccountName,
-					Namespace: clusterNamespace,
+					Namespace: cluster.Namespace,
 				},
 			},
 			RoleRef: rbacv1.RoleRef{
 				Kind:     "ClusterRole",
-				Name:     pxClusterRoleName,
+				Name:     util.ClusterScopedName(cluster, pxClusterRoleName),
 				APIGroup: "rbac.authorization.k8s.io",
 			},
 		},
@@ -276,7 +286,7 @@ func (c *portworxBasic) createPortworxService(
 		newService.Spec.Type = serviceType
 	}
 
-	return k8sutil.CreateOrUpdateService(c.k8sClient, newService, ownerRef)
+	return k8sutil.CreateOrUpdate(c.k8sClient, newService, ownerRef)
 }
 
 // RegisterPortworxBasicComponent registers the Portworx Basic component
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/go-version"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
 	"github.com/portworx/sched-ops/k8s"
 	"github.com/sirupsen/logrus"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -34,6 +35,14 @@ func (c *portworxCRD) Initialize(
 	// k8sClient is not needed as we use k8s.Instance for CRDs
 }
 
+func (c *portworxCRD) Priority() int32 {
+	return PriorityCritical
+}
+
+func (c *portworxCRD) Dependencies() []string {
+	return nil
+}
+
 func (c *portworxCRD) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
 	return true
 }
@@ -52,7 +61,7 @@ func (c *portworxCRD) Delete(cluster *corev1alpha1.StorageCluster) error {
 	return nil
 }
 
-func (c *portworxCRD) MarkDeleted() {
+func (c *portworxCRD) MarkDeleted(cluster *corev1alpha1.StorageCluster) {
 	c.isVolumePlacementStrategyCRDCreated = false
 }
 
@@ -67,6 +76,11 @@ func createVolumePlacementStrategyCRD() error {
 	crd := &apiextensionsv1beta1.CustomResourceDefinition{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: fmt.Sprintf("%s.%s", resource.Plural, resource.Group),
+			// The CRD is shared by all the clusters, so it is not owned by
+			// any; the label is enough for the operator to delete it
+			Labels: map[string]string{
+				util.LabelManaged: "true",
+			},
 		},
 		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
 			Group: resource.Group,
@@ -5,6 +5,7 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
 	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
 	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -52,6 +53,14 @@ func (c *portworxStorageClass) Initialize(
 	c.k8sClient = k8sClient
 }
 
+func (c *portworxStorageClass) Priority() int32 {
+	return PriorityDefault
+}
+
+func (c *portworxStorageClass) Dependencies() []string {
+	return nil
+}
+
 func (c *portworxStorageClass) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
 	return true
 }
@@ -75,9 +84,8 @@ func (c *portworxStorageClass) Reconcile(cluster *corev1alpha1.StorageCluster) e
 	storageClasses := []*storagev1.StorageClass{
 		{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            PxDbStorageClass,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
-				Annotations:     docAnnotations,
+				Name:        PxDbStorageClass,
+				Annotations: docAnnotations,
 			},
 			Provisioner: portworxProvisioner,
 			Parameters: map[string]string{
@@ -87,8 +95,7 @@ func (c *portworxStorageClass) Reconcile(cluster *corev1alpha1.StorageCluster) e
 		},
 		{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            PxDbEncryptedStorageClass,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: PxDbEncryptedStorageClass,
 				Annotations: map[string]string{
 					"params/note": "Ensure that you have a cluster-wide secret created in the configured secrets provider",
 				},
@@ -102,8 +109,7 @@ func (c *portworxStorageClass) Reconcile(cluster *corev1alpha1.StorageCluster) e
 		},
 		{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            PxReplicatedStorageClass,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: PxReplicatedStorageClass,
 			},
 			Provisioner: portworxProvisioner,
 			Parameters: map[string]string{
@@ -112,8 +118,7 @@ func (c *portworxStorageClass) Reconcile(cluster *corev1alpha1.StorageCluster) e
 		},
 		{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            PxReplicatedEncryptedStorageClass,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: PxReplicatedEncryptedStorageClass,
 			},
 			Provisioner: portworxProvisioner,
 			Parameters: map[string]string{
@@ -127,8 +132,7 @@ func (c *portworxStorageClass) Reconcile(cluster *corev1alpha1.StorageCluster) e
 		storageClasses = append(storageClasses,
 			&storagev1.StorageClass{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:            PxDbLocalSnapshotStorageClass,
-					OwnerReferences: []metav1.OwnerReference{*ownerRef},
+					Name: PxDbLocalSnapshotStorageClass,
 				},
 				Provisioner: portworxProvisioner,
 				Parameters: map[string]string{
@@ -141,8 +145,7 @@ annotations:
 			},
 			&storagev1.StorageClass{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:            PxDbLocalSnapshotEncryptedStorageClass,
-					OwnerReferences: []metav1.OwnerReference{*ownerRef},
+					Name: PxDbLocalSnapshotEncryptedStorageClass,
 				},
 				Provisioner: portworxProvisioner,
 				Parameters: map[string]string{
@@ -156,8 +159,7 @@ annotations:
 			},
 			&storagev1.StorageClass{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:            PxDbCloudSnapshotStorageClass,
-					OwnerReferences: []metav1.OwnerReference{*ownerRef},
+					Name: PxDbCloudSnapshotStorageClass,
 				},
 				Provisioner: portworxProvisioner,
 				Parameters: map[string]string{
@@ -170,8 +172,7 @@ annotations:
 			},
 			&storagev1.StorageClass{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:            PxDbCloudSnapshotEncryptedStorageClass,
-					OwnerReferences: []metav1.OwnerReference{*ownerRef},
+					Name: PxDbCloudSnapshotEncryptedStorageClass,
 				},
 				Provisioner: portworxProvisioner,
 				Parameters: map[string]string{
@@ -187,7 +188,8 @@ annotations:
 	}
 
 	for _, sc := range storageClasses {
-		if err := k8sutil.CreateStorageClass(c.k8sClient, sc); err != nil {
+		sc.Name = util.ClusterScopedName(cluster, sc.Name)
+		if err := k8sutil.CreateStorageClass(c.k8sClient, sc, ownerRef); err != nil {
 			return err
 		}
 	}
@@ -198,7 +200,7 @@ func (c *portworxStorageClass) Delete(cluster *corev1alpha1.StorageCluster) erro
 	return nil
 }
 
-func (c *portworxStorageClass) MarkDeleted() {}
+func (c *portworxStorageClass) MarkDeleted(_ *corev1alpha1.StorageCluster) {}
 
 // RegisterPortworxStorageClassComponent registers the Portworx StorageClass component
 func RegisterPortworxStorageClassComponent() {
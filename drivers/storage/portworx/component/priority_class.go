@@ -0,0 +1,82 @@
+package component
+
+import (
+	"github.com/hashicorp/go-version"
+	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	schedulingv1beta1 "k8s.io/api/scheduling/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PriorityClassComponentName name of the PriorityClass component
+	PriorityClassComponentName = "Portworx PriorityClass"
+	// PxPriorityClassValue is the priority of the Portworx PriorityClass. It is
+	// the highest value allowed for classes that are not system classes.
+	PxPriorityClassValue = int32(1000000000)
+)
+
+type priorityClass struct {
+	k8sClient client.Client
+}
+
+func (c *priorityClass) Initialize(
+	k8sClient client.Client,
+	_ version.Version,
+	_ *runtime.Scheme,
+	_ record.EventRecorder,
+) {
+	c.k8sClient = k8sClient
+}
+
+func (c *priorityClass) Priority() int32 {
+	return PriorityCritical
+}
+
+func (c *priorityClass) Dependencies() []string {
+	return nil
+}
+
+func (c *priorityClass) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
+	return cluster.Spec.PriorityClassName == pxutil.PortworxPriorityClassName
+}
+
+func (c *priorityClass) Reconcile(cluster *corev1alpha1.StorageCluster) error {
+	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
+	return c.createPriorityClass(ownerRef)
+}
+
+func (c *priorityClass) Delete(cluster *corev1alpha1.StorageCluster) error {
+	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
+	return k8sutil.DeletePriorityClass(c.k8sClient, pxutil.PortworxPriorityClassName, *ownerRef)
+}
+
+func (c *priorityClass) MarkDeleted(_ *corev1alpha1.StorageCluster) {}
+
+func (c *priorityClass) createPriorityClass(ownerRef *metav1.OwnerReference) error {
+	return k8sutil.CreateOrUpdate(
+		c.k8sClient,
+		&schedulingv1beta1.PriorityClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: pxutil.PortworxPriorityClassName,
+			},
+			Value: PxPriorityClassValue,
+			Description: "Used for the Portworx pods and its control plane components, " +
+				"so they are not preempted by application pods",
+		},
+		ownerRef,
+	)
+}
+
+// RegisterPriorityClassComponent registers the PriorityClass component
+func RegisterPriorityClassComponent() {
+	Register(PriorityClassComponentName, &priorityClass{})
+}
+
+func init() {
+	RegisterPriorityClassComponent()
+}
@@ -3,7 +3,6 @@ package component
 import (
 	"context"
 	"reflect"
-	"strconv"
 
 	"github.com/hashicorp/go-version"
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
@@ -13,8 +12,8 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -40,25 +39,35 @@ const (
 )
 
 type pvcController struct {
-	isCreated  bool
+	created    util.ClusterSet
 	k8sClient  client.Client
 	k8sVersion version.Version
+	recorder   record.EventRecorder
 }
 
 func (c *pvcController) Initialize(
 	k8sClient client.Client,
 	k8sVersion version.Version,
 	_ *runtime.Scheme,
-	_ record.EventRecorder,
+	recorder record.EventRecorder,
 ) {
 	c.k8sClient = k8sClient
 	c.k8sVersion = k8sVersion
+	c.recorder = recorder
+	c.created.Clear()
+}
+
+func (c *pvcController) Priority() int32 {
+	return PriorityDefault
+}
+
+func (c *pvcController) Dependencies() []string {
+	return []string{PriorityClassComponentName}
 }
 
 func (c *pvcController) IsEnabled(cluster *corev1alpha1.StorageCluster) bool {
-	enabled, err := strconv.ParseBool(cluster.Annotations[pxutil.AnnotationPVCController])
-	if err == nil {
-		return enabled
+	if enabled := pxutil.PVCControllerEnabled(cluster); enabled != nil {
+		return *enabled
 	}
 
 	// Enable PVC controller for managed kubernetes services. Also enable it for openshift,
@@ -76,10 +85,10 @@ func (c *pvcController) Reconcile(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.createServiceAccount(cluster.Namespace, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createClusterRole(ownerRef); err != nil {
+	if err := c.createClusterRole(cluster, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createClusterRoleBinding(cluster.Namespace, ownerRef); err != nil {
+	if err := c.createClusterRoleBinding(cluster, ownerRef); err != nil {
 		return err
 	}
 	if err := c.createDeployment(cluster, ownerRef); err != nil {
@@ -92,28 +101,28 @@ func (c *pvcController) Delete(cluster *corev1alpha1.StorageCluster) error {
 	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
 	// We don't delete the service account for PVC controller because it is part of CSV. If
 	// we disable PVC controller then the CSV upgrades would fail as requirements are not met.
-	if err := k8sutil.DeleteClusterRole(c.k8sClient, PVCClusterRoleName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRole(c.k8sClient, util.ClusterScopedName(cluster, PVCClusterRoleName), *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, PVCClusterRoleBindingName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRoleBinding(c.k8sClient, util.ClusterScopedName(cluster, PVCClusterRoleBindingName), *ownerRef); err != nil {
 		return err
 	}
 	if err := k8sutil.DeleteDeployment(c.k8sClient, PVCDeploymentName, cluster.Namespace, *ownerRef); err != nil {
 		return err
 	}
-	c.isCreated = false
+	c.created.Remove(cluster)
 	return nil
 }
 
-func (c *pvcController) MarkDeleted() {
-	c.isCreated = false
+func (c *pvcController) MarkDeleted(cluster *corev1alpha1.StorageCluster) {
+	c.created.Remove(cluster)
 }
 
 func (c *pvcController) createServiceAccount(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
@@ -126,13 +135,15 @@ func (c *pvcController) createServiceAccount(
 	)
 }
 
-func (c *pvcController) createClusterRole(ownerRef *metav1.OwnerReference) error {
-	return k8sutil.CreateOrUpdateClusterRole(
+func (c *pvcController) createClusterRole(
+	cluster *corev1alpha1.StorageCluster,
+	ownerRef *metav1.OwnerReference,
+) error {
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRole{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            PVCClusterRoleName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, PVCClusterRoleName),
 			},
 			Rules: []rbacv1.PolicyRule{
 				{
@@ -202,26 +213,25 @@ func (c *pvcController) createClusterRole(ownerRef *metav1.OwnerReference) error
 }
 
 func (c *pvcController) createClusterRoleBinding(
-	clusterNamespace string,
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateClusterRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.k8sClient,
 		&rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            PVCClusterRoleBindingName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, PVCClusterRoleBindingName),
 			},
 			Subjects: []rbacv1.Subject{
 				{
 					Kind:      "ServiceAccount",
 					Name:      PVCServiceAccountName,
-					Namespace: clusterNamespace,
+					Namespace: cluster.Namespace,
 				},
 			},
 			RoleRef: rbacv1.RoleRef{
 				Kind:     "ClusterRole",
-				Name:     PVCClusterRoleName,
+				Name:     util.ClusterScopedName(cluster, PVCClusterRoleName),
 				APIGroup: "rbac.authorization.k8s.io",
 			},
 		},
@@ -233,11 +243,12 @@ func (c *pvcController) createDeployment(
 	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	targetCPU := defaultPVCControllerCPU
-	if cpuStr, ok := cluster.Annotations[pxutil.AnnotationPVCControllerCPU]; ok {
-		targetCPU = cpuStr
+	var resources *v1.ResourceRequirements
+	if cluster.Spec.PVCController != nil {
+		resources = cluster.Spec.PVCController.Resources
 	}
-	targetCPUQuantity, err := resource.ParseQuantity(targetCPU)
+	targetResources, err := util.ResourceRequirements(cluster, resources,
+		pxutil.AnnotationPVCControllerCPU, defaultPVCControllerCPU)
 	if err != nil {
 		return err
 	}
@@ -273,26 +284,37 @@ func (c *pvcController) createDeployment(
 
 	var existingImage string
 	var existingCommand []string
-	var existingCPUQuantity resource.Quantity
+	var existingResources v1.ResourceRequirements
 	for _, container := range existingDeployment.Spec.Template.Spec.Containers {
 		if container.Name == pvcContainerName {
 			existingImage = container.Image
 			existingCommand = container.Command
-			existingCPUQuantity = container.Resources.Requests[v1.ResourceCPU]
+			existingResources = container.Resources
 		}
 	}
 
 	modified := existingImage != imageName ||
 		!reflect.DeepEqual(existingCommand, command) ||
-		existingCPUQuantity.Cmp(targetCPUQuantity) != 0
+		!equality.Semantic.DeepEqual(existingResources, targetResources) ||
+		cluster.Spec.PriorityClassName != existingDeployment.Spec.Template.Spec.PriorityClassName
+
+	deployment := getPVCControllerDeploymentSpec(cluster, ownerRef, imageName, command, targetResources)
+	// Revert the deployment if it was deleted or changed out of band,
+	// unless it is being updated anyway
+	drifted := false
+	if c.created.Contains(cluster) && (!modified || existingDeployment.Name == "") {
+		drifted, err = k8sutil.DetectDrift(c.recorder, cluster, deployment, existingDeployment)
+		if err != nil {
+			return err
+		}
+	}
 
-	if !c.isCreated || modified {
-		deployment := getPVCControllerDeploymentSpec(cluster, ownerRef, imageName, command, targetCPUQuantity)
-		if err = k8sutil.CreateOrUpdateDeployment(c.k8sClient, deployment, ownerRef); err != nil {
+	if !c.created.Contains(cluster) || modified || drifted {
+		if err = k8sutil.CreateOrUpdate(c.k8sClient, deployment, ownerRef); err != nil {
 			return err
 		}
 	}
-	c.isCreated = true
+	c.created.Add(cluster)
 	return nil
 }
 
@@ -301,7 +323,7 @@ func getPVCControllerDeploymentSpec(
 	ownerRef *metav1.OwnerReference,
 	imageName string,
 	command []string,
-	cpuQuantity resource.Quantity,
+	resources v1.ResourceRequirements,
 ) *appsv1.Deployment {
 	replicas := int32(3)
 	maxUnavailable := intstr.FromInt(1)
@@ -345,6 +367,7 @@ func getPVCControllerDeploymentSpec(
 				},
 				Spec: v1.PodSpec{
 					ServiceAccountName: PVCServiceAccountName,
+					PriorityClassName:  cluster.Spec.PriorityClassName,
 					HostNetwork:        true,
 					Containers: []v1.Container{
 						{
@@ -364,11 +387,7 @@ func getPVCControllerDeploymentSpec(
 									},
 								},
 							},
-							Resources: v1.ResourceRequirements{
-								Requests: map[v1.ResourceName]resource.Quantity{
-									v1.ResourceCPU: cpuQuantity,
-								},
-							},
+							Resources: resources,
 						},
 					},
 					Affinity: &v1.Affinity{
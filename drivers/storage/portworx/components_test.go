@@ -3,9 +3,13 @@ package portworx
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	goversion "github.com/hashicorp/go-version"
 	"github.com/libopenstorage/operator/drivers/storage/portworx/component"
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
@@ -17,6 +21,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1beta1 "k8s.io/api/scheduling/v1beta1"
 	storagev1 "k8s.io/api/storage/v1"
 	storagev1beta1 "k8s.io/api/storage/v1beta1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -25,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/version"
 	fakediscovery "k8s.io/client-go/discovery/fake"
 	fakek8sclient "k8s.io/client-go/kubernetes/fake"
@@ -97,8 +103,8 @@ func TestBasicComponentsInstall(t *testing.T) {
 	expectedCR := testutil.GetExpectedClusterRole(t, "portworxClusterRole.yaml")
 	actualCR := clusterRoleList.Items[0]
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(&actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 
 	// Portworx ClusterRoleBinding
@@ -110,8 +116,8 @@ func TestBasicComponentsInstall(t *testing.T) {
 	expectedCRB := testutil.GetExpectedClusterRoleBinding(t, "portworxClusterRoleBinding.yaml")
 	actualCRB := crbList.Items[0]
 	require.Equal(t, expectedCRB.Name, actualCRB.Name)
-	require.Len(t, actualCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCRB.OwnerReferences[0].Name)
+	require.Empty(t, actualCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(&actualCRB))
 	require.ElementsMatch(t, expectedCRB.Subjects, actualCRB.Subjects)
 	require.Equal(t, expectedCRB.RoleRef, actualCRB.RoleRef)
 
@@ -221,8 +227,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxDbStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxDbStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -232,8 +238,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxDbEncryptedStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxDbEncryptedStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -243,8 +249,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxReplicatedStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxReplicatedStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -254,8 +260,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxReplicatedEncryptedStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxReplicatedEncryptedStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -265,8 +271,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxDbLocalSnapshotStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxDbLocalSnapshotStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -276,8 +282,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxDbLocalSnapshotEncryptedStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxDbLocalSnapshotEncryptedStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -287,8 +293,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxDbCloudSnapshotStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxDbCloudSnapshotStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -298,8 +304,8 @@ func TestDefaultStorageClassesWithStork(t *testing.T) {
 	err = testutil.Get(k8sClient, actualSC, component.PxDbCloudSnapshotEncryptedStorageClass, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSC.Name, component.PxDbCloudSnapshotEncryptedStorageClass)
-	require.Len(t, actualSC.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualSC.OwnerReferences[0].Name)
+	require.Empty(t, actualSC.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualSC))
 	require.Equal(t, expectedSC.Annotations, actualSC.Annotations)
 	require.Equal(t, expectedSC.Provisioner, actualSC.Provisioner)
 	require.Equal(t, expectedSC.Parameters, actualSC.Parameters)
@@ -364,6 +370,51 @@ func TestDefaultStorageClassesWithoutStork(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestClusterScopedComponentsWithResourceSuffix(t *testing.T) {
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), record.NewFakeRecorder(0))
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "analytics",
+			Annotations: map[string]string{
+				util.AnnotationResourceSuffix: "analytics",
+			},
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	// Cluster scoped objects should have the resource suffix
+	clusterRole := &rbacv1.ClusterRole{}
+	err = testutil.Get(k8sClient, clusterRole, "portworx-analytics", "")
+	require.NoError(t, err)
+
+	crb := &rbacv1.ClusterRoleBinding{}
+	err = testutil.Get(k8sClient, crb, "portworx-analytics", "")
+	require.NoError(t, err)
+	require.Equal(t, "portworx-analytics", crb.RoleRef.Name)
+	require.Equal(t, cluster.Namespace, crb.Subjects[0].Namespace)
+
+	storageClassList := &storagev1.StorageClassList{}
+	err = testutil.List(k8sClient, storageClassList)
+	require.NoError(t, err)
+	require.Len(t, storageClassList.Items, 4)
+	for _, sc := range storageClassList.Items {
+		require.True(t, strings.HasSuffix(sc.Name, "-analytics"))
+	}
+
+	// Namespaced objects should not have the resource suffix
+	sa := &v1.ServiceAccount{}
+	err = testutil.Get(k8sClient, sa, pxutil.PortworxServiceAccountName, cluster.Namespace)
+	require.NoError(t, err)
+}
+
 func TestPortworxServiceTypeWithOverride(t *testing.T) {
 	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
 	reregisterComponents()
@@ -446,6 +497,55 @@ func TestPortworxServiceTypeWithOverride(t *testing.T) {
 	require.Equal(t, v1.ServiceTypeClusterIP, pxAPIService.Spec.Type)
 }
 
+func TestPortworxServiceTypeFromSpec(t *testing.T) {
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), record.NewFakeRecorder(0))
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				annotationServiceType: "NodePort",
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Service: &corev1alpha1.ServiceSpec{
+				Type: v1.ServiceTypeLoadBalancer,
+			},
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	// The spec should take precedence over the deprecated annotation
+	pxService := &v1.Service{}
+	err = testutil.Get(k8sClient, pxService, pxutil.PortworxServiceName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, v1.ServiceTypeLoadBalancer, pxService.Spec.Type)
+
+	pxAPIService := &v1.Service{}
+	err = testutil.Get(k8sClient, pxAPIService, component.PxAPIServiceName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, v1.ServiceTypeLoadBalancer, pxAPIService.Spec.Type)
+
+	// Without a service type, the default service type should be used
+	cluster.Annotations = nil
+	cluster.Spec.Service = nil
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	pxService = &v1.Service{}
+	err = testutil.Get(k8sClient, pxService, pxutil.PortworxServiceName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, v1.ServiceTypeClusterIP, pxService.Spec.Type)
+}
+
 func TestPVCControllerInstall(t *testing.T) {
 	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
 	reregisterComponents()
@@ -759,6 +859,54 @@ func TestPVCControllerWhenPVCControllerDisabledExplicitly(t *testing.T) {
 	require.True(t, errors.IsNotFound(err))
 }
 
+func TestPVCControllerEnabledFromSpec(t *testing.T) {
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), record.NewFakeRecorder(0))
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			PVCController: &corev1alpha1.PVCControllerSpec{
+				Enabled: boolPtr(true),
+			},
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	verifyPVCControllerInstall(t, cluster, k8sClient)
+
+	// The spec should take precedence over the deprecated annotation and platform
+	cluster.Annotations = map[string]string{
+		annotationPVCController: "true",
+	}
+	cluster.Spec.Platform = corev1alpha1.PlatformPKS
+	cluster.Spec.PVCController.Enabled = boolPtr(false)
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	deployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, component.PVCDeploymentName, cluster.Namespace)
+	require.True(t, errors.IsNotFound(err))
+
+	// The PVC controller should be enabled for the platform if not set in the spec
+	cluster.Annotations = nil
+	cluster.Spec.PVCController.Enabled = nil
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	verifyPVCControllerInstall(t, cluster, k8sClient)
+}
+
 func verifyPVCControllerInstall(
 	t *testing.T,
 	cluster *corev1alpha1.StorageCluster,
@@ -789,8 +937,8 @@ func verifyPVCControllerInstall(
 	err = testutil.Get(k8sClient, actualCR, component.PVCClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 
 	// PVC Controller ClusterRoleBinding
@@ -804,8 +952,8 @@ func verifyPVCControllerInstall(
 	err = testutil.Get(k8sClient, actualCRB, component.PVCClusterRoleBindingName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCRB.Name, actualCRB.Name)
-	require.Len(t, actualCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCRB.OwnerReferences[0].Name)
+	require.Empty(t, actualCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCRB))
 	require.ElementsMatch(t, expectedCRB.Subjects, actualCRB.Subjects)
 	require.Equal(t, expectedCRB.RoleRef, actualCRB.RoleRef)
 }
@@ -829,6 +977,9 @@ func verifyPVCControllerDeployment(
 	require.Len(t, actualDeployment.OwnerReferences, 1)
 	require.Equal(t, cluster.Name, actualDeployment.OwnerReferences[0].Name)
 	require.Equal(t, expectedDeployment.Labels, actualDeployment.Labels)
+	require.Contains(t, actualDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(actualDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(actualDeployment.Annotations, util.AnnotationLastAppliedLabels)
 	require.Equal(t, expectedDeployment.Annotations, actualDeployment.Annotations)
 	require.Equal(t, expectedDeployment.Spec, actualDeployment.Spec)
 }
@@ -874,6 +1025,62 @@ func TestPVCControllerCustomCPU(t *testing.T) {
 	require.Zero(t, expectedCPUQuantity.Cmp(deployment.Spec.Template.Spec.Containers[0].Resources.Requests[v1.ResourceCPU]))
 }
 
+func TestPVCControllerCustomResources(t *testing.T) {
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), record.NewFakeRecorder(0))
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				annotationPVCController:    "true",
+				annotationPVCControllerCPU: "300m",
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			PVCController: &corev1alpha1.PVCControllerSpec{
+				Resources: &v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("500m"),
+						v1.ResourceMemory: resource.MustParse("128Mi"),
+					},
+					Limits: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("500m"),
+						v1.ResourceMemory: resource.MustParse("128Mi"),
+					},
+				},
+			},
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	// Resources from the spec should take precedence over the annotation
+	deployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, component.PVCDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, *cluster.Spec.PVCController.Resources,
+		deployment.Spec.Template.Spec.Containers[0].Resources)
+
+	// Removing the resources from the spec should fall back to the annotation
+	cluster.Spec.PVCController = nil
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	expectedCPUQuantity := resource.MustParse("300m")
+	deployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, component.PVCDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Zero(t, expectedCPUQuantity.Cmp(deployment.Spec.Template.Spec.Containers[0].Resources.Requests[v1.ResourceCPU]))
+	require.Empty(t, deployment.Spec.Template.Spec.Containers[0].Resources.Limits)
+}
+
 func TestPVCControllerInvalidCPU(t *testing.T) {
 	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
 	k8sClient := testutil.FakeK8sClient()
@@ -1040,8 +1247,8 @@ func TestLighthouseInstall(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCR, component.LhClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 
 	// Lighthouse ClusterRoleBinding
@@ -1055,8 +1262,8 @@ func TestLighthouseInstall(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCRB, component.LhClusterRoleBindingName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCRB.Name, actualCRB.Name)
-	require.Len(t, actualCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCRB.OwnerReferences[0].Name)
+	require.Empty(t, actualCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCRB))
 	require.ElementsMatch(t, expectedCRB.Subjects, actualCRB.Subjects)
 	require.Equal(t, expectedCRB.RoleRef, actualCRB.RoleRef)
 
@@ -1092,7 +1299,10 @@ func TestLighthouseInstall(t *testing.T) {
 	require.Len(t, lhDeployment.OwnerReferences, 1)
 	require.Equal(t, cluster.Name, lhDeployment.OwnerReferences[0].Name)
 	require.Equal(t, expectedDeployment.Labels, lhDeployment.Labels)
-	require.Equal(t, expectedDeployment.Annotations, lhDeployment.Annotations)
+	require.Contains(t, lhDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(lhDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(lhDeployment.Annotations, util.AnnotationLastAppliedLabels)
+	require.Empty(t, lhDeployment.Annotations)
 	require.Equal(t, expectedDeployment.Spec, lhDeployment.Spec)
 }
 
@@ -1596,8 +1806,8 @@ func TestAutopilotInstall(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCR, component.AutopilotClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 
 	// Autopilot ClusterRoleBinding
@@ -1606,8 +1816,8 @@ func TestAutopilotInstall(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCRB, component.AutopilotClusterRoleBindingName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCRB.Name, actualCRB.Name)
-	require.Len(t, actualCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCRB.OwnerReferences[0].Name)
+	require.Empty(t, actualCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCRB))
 	require.ElementsMatch(t, expectedCRB.Subjects, actualCRB.Subjects)
 	require.Equal(t, expectedCRB.RoleRef, actualCRB.RoleRef)
 
@@ -1621,6 +1831,9 @@ func TestAutopilotInstall(t *testing.T) {
 	require.Len(t, autopilotDeployment.OwnerReferences, 1)
 	require.Equal(t, cluster.Name, autopilotDeployment.OwnerReferences[0].Name)
 	require.Equal(t, expectedDeployment.Labels, autopilotDeployment.Labels)
+	require.Contains(t, autopilotDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(autopilotDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(autopilotDeployment.Annotations, util.AnnotationLastAppliedLabels)
 	require.Equal(t, expectedDeployment.Annotations, autopilotDeployment.Annotations)
 	// Ignoring resource comparison as the parsing from string creates different objects
 	expectedDeployment.Spec.Template.Spec.Containers[0].Resources.Requests = nil
@@ -2107,6 +2320,150 @@ func TestAutopilotInvalidCPU(t *testing.T) {
 		fmt.Sprintf("%v %v Failed to setup Autopilot.", v1.EventTypeWarning, util.FailedComponentReason))
 }
 
+func TestAutopilotDrift(t *testing.T) {
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	recorder := record.NewFakeRecorder(10)
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), recorder)
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Autopilot: &corev1alpha1.AutopilotSpec{
+				Enabled: true,
+				Image:   "portworx/autopilot:v1",
+			},
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+	require.Empty(t, recorder.Events)
+
+	// Changes to the deployment made out of band should be reverted
+	autopilotDeployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, autopilotDeployment, component.AutopilotDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	replicas := int32(3)
+	autopilotDeployment.Spec.Replicas = &replicas
+	autopilotDeployment.Spec.Template.Spec.ServiceAccountName = "test-sa"
+	err = k8sClient.Update(context.TODO(), autopilotDeployment)
+	require.NoError(t, err)
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	autopilotDeployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, autopilotDeployment, component.AutopilotDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), *autopilotDeployment.Spec.Replicas)
+	require.Equal(t, component.AutopilotServiceAccountName,
+		autopilotDeployment.Spec.Template.Spec.ServiceAccountName)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t,
+		fmt.Sprintf("%v %v Reverting changes to Deployment kube-test/autopilot: "+
+			"spec.replicas, spec.template.spec.serviceAccountName",
+			v1.EventTypeWarning, util.DriftedReason),
+		<-recorder.Events)
+
+	// Deployment deleted out of band should be recreated
+	err = k8sClient.Delete(context.TODO(), autopilotDeployment)
+	require.NoError(t, err)
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	autopilotDeployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, autopilotDeployment, component.AutopilotDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t,
+		fmt.Sprintf("%v %v Deployment kube-test/autopilot was deleted, recreating it",
+			v1.EventTypeWarning, util.DriftedReason),
+		<-recorder.Events)
+
+	// Changes made through the cluster spec should not be reported as drift
+	cluster.Spec.Autopilot.Image = "portworx/autopilot:v2"
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	autopilotDeployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, autopilotDeployment, component.AutopilotDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "portworx/autopilot:v2",
+		autopilotDeployment.Spec.Template.Spec.Containers[0].Image)
+	require.Empty(t, recorder.Events)
+}
+
+func TestPortworxAPIDaemonSetDrift(t *testing.T) {
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	recorder := record.NewFakeRecorder(10)
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), recorder)
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+	require.Empty(t, recorder.Events)
+
+	// Reconciling again without any changes should not update the daemon set
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+	require.Empty(t, recorder.Events)
+
+	// Changes to the daemon set made out of band should be reverted
+	pxAPIDaemonSet := &appsv1.DaemonSet{}
+	err = testutil.Get(k8sClient, pxAPIDaemonSet, component.PxAPIDaemonSetName, cluster.Namespace)
+	require.NoError(t, err)
+	expectedImage := pxAPIDaemonSet.Spec.Template.Spec.Containers[0].Image
+	pxAPIDaemonSet.Spec.Template.Spec.Containers[0].Image = "test/image:v1"
+	err = k8sClient.Update(context.TODO(), pxAPIDaemonSet)
+	require.NoError(t, err)
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	pxAPIDaemonSet = &appsv1.DaemonSet{}
+	err = testutil.Get(k8sClient, pxAPIDaemonSet, component.PxAPIDaemonSetName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, expectedImage, pxAPIDaemonSet.Spec.Template.Spec.Containers[0].Image)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t,
+		fmt.Sprintf("%v %v Reverting changes to DaemonSet kube-test/portworx-api: "+
+			"spec.template.spec.containers[0].image", v1.EventTypeWarning, util.DriftedReason),
+		<-recorder.Events)
+
+	// Daemon set deleted out of band should be recreated
+	err = k8sClient.Delete(context.TODO(), pxAPIDaemonSet)
+	require.NoError(t, err)
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	pxAPIDaemonSet = &appsv1.DaemonSet{}
+	err = testutil.Get(k8sClient, pxAPIDaemonSet, component.PxAPIDaemonSetName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t,
+		fmt.Sprintf("%v %v DaemonSet kube-test/portworx-api was deleted, recreating it",
+			v1.EventTypeWarning, util.DriftedReason),
+		<-recorder.Events)
+}
+
 func TestCompleteInstallWithCustomRegistry(t *testing.T) {
 	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
 	reregisterComponents()
@@ -2670,8 +3027,8 @@ func TestCSIInstall(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCR, component.CSIClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 
 	// CSI ClusterRoleBinding
@@ -2685,8 +3042,8 @@ func TestCSIInstall(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCRB, component.CSIClusterRoleBindingName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCRB.Name, actualCRB.Name)
-	require.Len(t, actualCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCRB.OwnerReferences[0].Name)
+	require.Empty(t, actualCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCRB))
 	require.ElementsMatch(t, expectedCRB.Subjects, actualCRB.Subjects)
 	require.Equal(t, expectedCRB.RoleRef, actualCRB.RoleRef)
 
@@ -2807,8 +3164,8 @@ func TestCSIInstallWithOlderPortworxVersion(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCR, component.CSIClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 
 	// CSI ClusterRoleBinding
@@ -2822,8 +3179,8 @@ func TestCSIInstallWithOlderPortworxVersion(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCRB, component.CSIClusterRoleBindingName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCRB.Name, actualCRB.Name)
-	require.Len(t, actualCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCRB.OwnerReferences[0].Name)
+	require.Empty(t, actualCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCRB))
 	require.ElementsMatch(t, expectedCRB.Subjects, actualCRB.Subjects)
 	require.Equal(t, expectedCRB.RoleRef, actualCRB.RoleRef)
 
@@ -2924,8 +3281,8 @@ func TestCSIInstallWithNewerCSIVersion(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCR, component.CSIClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 
 	expectedDeployment := testutil.GetExpectedDeployment(t, "csiDeployment_1.0.yaml")
@@ -2972,8 +3329,8 @@ func TestCSIInstallWithNewerCSIVersion(t *testing.T) {
 	csiDriver = &storagev1beta1.CSIDriver{}
 	err = testutil.Get(k8sClient, csiDriver, pxutil.CSIDriverName, "")
 	require.NoError(t, err)
-	require.Len(t, csiDriver.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, csiDriver.OwnerReferences[0].Name)
+	require.Empty(t, csiDriver.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(csiDriver))
 	require.False(t, *csiDriver.Spec.AttachRequired)
 	require.False(t, *csiDriver.Spec.PodInfoOnMount)
 
@@ -3032,7 +3389,7 @@ func TestCSIInstallShouldCreateNodeInfoCRD(t *testing.T) {
 		Delete(expectedCRD.Name, nil)
 	require.NoError(t, err)
 	csiComponent, _ := component.Get(component.CSIComponentName)
-	csiComponent.MarkDeleted()
+	csiComponent.MarkDeleted(cluster)
 	versionClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
 		GitVersion: "v1.13.99",
 	}
@@ -3060,7 +3417,7 @@ func TestCSIInstallShouldCreateNodeInfoCRD(t *testing.T) {
 		CustomResourceDefinitions().
 		Delete(expectedCRD.Name, nil)
 	require.NoError(t, err)
-	csiComponent.MarkDeleted()
+	csiComponent.MarkDeleted(cluster)
 	versionClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
 		GitVersion: "v1.11.99",
 	}
@@ -3076,7 +3433,7 @@ func TestCSIInstallShouldCreateNodeInfoCRD(t *testing.T) {
 	require.True(t, errors.IsNotFound(err))
 
 	// CRD should not to be created for k8s version 1.14+
-	csiComponent.MarkDeleted()
+	csiComponent.MarkDeleted(cluster)
 	versionClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
 		GitVersion: "v1.14.0",
 	}
@@ -3143,8 +3500,8 @@ func TestCSIInstallWithDeprecatedCSIDriverName(t *testing.T) {
 	csiDriver := &storagev1beta1.CSIDriver{}
 	err = testutil.Get(k8sClient, csiDriver, pxutil.DeprecatedCSIDriverName, "")
 	require.NoError(t, err)
-	require.Len(t, csiDriver.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, csiDriver.OwnerReferences[0].Name)
+	require.Empty(t, csiDriver.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(csiDriver))
 	require.False(t, *csiDriver.Spec.AttachRequired)
 	require.False(t, *csiDriver.Spec.PodInfoOnMount)
 
@@ -3184,8 +3541,8 @@ func TestCSIClusterRoleK8sVersionGreaterThan_1_14(t *testing.T) {
 	err = testutil.Get(k8sClient, actualCR, component.CSIClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedCR.Name, actualCR.Name)
-	require.Len(t, actualCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, actualCR.OwnerReferences[0].Name)
+	require.Empty(t, actualCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(actualCR))
 	require.ElementsMatch(t, expectedCR.Rules, actualCR.Rules)
 }
 
@@ -3975,6 +4332,303 @@ func TestDisableMonitoring(t *testing.T) {
 	require.True(t, errors.IsNotFound(err))
 }
 
+func TestComponentPlacementChange(t *testing.T) {
+	versionClient := fakek8sclient.NewSimpleClientset()
+	k8s.Instance().SetBaseClient(versionClient)
+	versionClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: "v1.11.4",
+	}
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), record.NewFakeRecorder(0))
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Image: "portworx/image:2.1.2",
+			FeatureGates: map[string]string{
+				string(pxutil.FeatureCSI): "true",
+			},
+			UserInterface: &corev1alpha1.UserInterfaceSpec{
+				Enabled: true,
+				Image:   "portworx/px-lighthouse:2.1.1",
+			},
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	// The components running with the storage pods should get the tolerations
+	// of the cluster, while the other components use their own placement
+	storageToleration := v1.Toleration{
+		Key:      "storage",
+		Operator: v1.TolerationOpExists,
+		Effect:   v1.TaintEffectNoSchedule,
+	}
+	uiToleration := v1.Toleration{
+		Key:      "ui",
+		Operator: v1.TolerationOpExists,
+		Effect:   v1.TaintEffectNoSchedule,
+	}
+	cluster.Spec.Placement = &corev1alpha1.PlacementSpec{
+		Tolerations: []v1.Toleration{storageToleration},
+	}
+	cluster.Spec.UserInterface.Placement = &corev1alpha1.PlacementSpec{
+		Tolerations: []v1.Toleration{uiToleration},
+	}
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	csiStatefulSet := &appsv1.StatefulSet{}
+	err = testutil.Get(k8sClient, csiStatefulSet, component.CSIApplicationName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, []v1.Toleration{storageToleration}, csiStatefulSet.Spec.Template.Spec.Tolerations)
+
+	apiDaemonSet := &appsv1.DaemonSet{}
+	err = testutil.Get(k8sClient, apiDaemonSet, component.PxAPIDaemonSetName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, []v1.Toleration{storageToleration}, apiDaemonSet.Spec.Template.Spec.Tolerations)
+
+	lhDeployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, lhDeployment, component.LhDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, []v1.Toleration{uiToleration}, lhDeployment.Spec.Template.Spec.Tolerations)
+
+	// A toleration repeated in the placement should be added only once
+	cluster.Spec.Placement.Tolerations = []v1.Toleration{storageToleration, storageToleration}
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, apiDaemonSet, component.PxAPIDaemonSetName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, []v1.Toleration{storageToleration}, apiDaemonSet.Spec.Template.Spec.Tolerations)
+}
+
+func TestPriorityClass(t *testing.T) {
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	reregisterComponents()
+	k8sClient := testutil.FakeK8sClient()
+	driver := portworx{}
+	driver.Init(k8sClient, runtime.NewScheme(), record.NewFakeRecorder(0))
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				annotationPVCController: "true",
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			PriorityClassName: pxutil.PortworxPriorityClassName,
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	priorityClass := &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, priorityClass, pxutil.PortworxPriorityClassName, "")
+	require.NoError(t, err)
+	require.Equal(t, component.PxPriorityClassValue, priorityClass.Value)
+	require.False(t, priorityClass.GlobalDefault)
+	require.Empty(t, priorityClass.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(priorityClass))
+
+	deployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, component.PVCDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, pxutil.PortworxPriorityClassName, deployment.Spec.Template.Spec.PriorityClassName)
+
+	// Remove the operator owned priority class if the user chooses a different one
+	cluster.Spec.PriorityClassName = "system-cluster-critical"
+
+	err = driver.PreInstall(cluster)
+	require.NoError(t, err)
+
+	priorityClass = &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, priorityClass, pxutil.PortworxPriorityClassName, "")
+	require.True(t, errors.IsNotFound(err))
+
+	deployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, component.PVCDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "system-cluster-critical", deployment.Spec.Template.Spec.PriorityClassName)
+}
+
+func TestComponentsReconcileInDependencyOrder(t *testing.T) {
+	defer reregisterComponents()
+	component.DeregisterAllComponents()
+	var lock sync.Mutex
+	var order []string
+	trackOrder := func(name string, _ component.PortworxComponent) error {
+		lock.Lock()
+		defer lock.Unlock()
+		order = append(order, name)
+		return nil
+	}
+	component.Register("a", &fakeComponent{dependencies: []string{"c"}})
+	component.Register("b", &fakeComponent{priority: component.PriorityCritical})
+	component.Register("c", &fakeComponent{})
+	component.Register("d", &fakeComponent{
+		priority:     component.PriorityCritical,
+		dependencies: []string{"a", "missing"},
+	})
+
+	results := component.RunInOrder(1, nil, trackOrder)
+	require.Equal(t, []string{"b", "c", "a", "d"}, order)
+	require.Len(t, results, 4)
+	for _, err := range results {
+		require.NoError(t, err)
+	}
+
+	// Components that depend on each other should never be reconciled
+	component.Register("c", &fakeComponent{dependencies: []string{"a"}})
+	order = nil
+
+	results = component.RunInOrder(1, nil, trackOrder)
+	require.Equal(t, []string{"b"}, order)
+	require.NoError(t, results["b"])
+	for _, name := range []string{"a", "c", "d"} {
+		require.Error(t, results[name])
+		require.Equal(t, component.ErrDependency, results[name].(*component.Error).Code())
+	}
+}
+
+func TestComponentsReconcileInParallel(t *testing.T) {
+	defer reregisterComponents()
+	component.DeregisterAllComponents()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		component.Register(name, &fakeComponent{})
+	}
+
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	results := component.RunInOrder(2, nil, func(string, component.PortworxComponent) error {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return nil
+	})
+	require.Len(t, results, 5)
+	require.Equal(t, 2, maxRunning)
+}
+
+func TestComponentsReconcileWithFailures(t *testing.T) {
+	defer reregisterComponents()
+	component.DeregisterAllComponents()
+	component.Register("critical", &fakeComponent{
+		err: component.NewError(component.ErrCritical, fmt.Errorf("critical error")),
+	})
+	component.Register("failing", &fakeComponent{
+		priority: component.PriorityCritical,
+		err:      fmt.Errorf("some error"),
+	})
+	component.Register("dependent", &fakeComponent{
+		priority:     component.PriorityCritical,
+		dependencies: []string{"failing"},
+	})
+	component.Register("other", &fakeComponent{})
+
+	enabled := map[string]bool{"critical": true, "failing": true, "dependent": true, "other": true}
+	runFakeComponent := func(_ string, comp component.PortworxComponent) error {
+		return comp.(*fakeComponent).err
+	}
+
+	results := component.RunInOrder(1, enabled, runFakeComponent)
+
+	// Components should not be started after a critical failure
+	require.Len(t, results, 3)
+	require.True(t, component.IsCritical(results["critical"]))
+	require.EqualError(t, results["failing"], "some error")
+	require.EqualError(t, results["dependent"], "dependency failing failed")
+	require.Equal(t, component.ErrDependency, results["dependent"].(*component.Error).Code())
+
+	// A failure of a dependency that is not enabled should not stop the dependents
+	enabled["failing"] = false
+
+	results = component.RunInOrder(1, enabled, runFakeComponent)
+	require.EqualError(t, results["failing"], "some error")
+	require.NoError(t, results["dependent"])
+}
+
+func TestComponentStatus(t *testing.T) {
+	defer reregisterComponents()
+	component.DeregisterAllComponents()
+	component.Register("healthy", &fakeComponent{enabled: true})
+	component.Register("failing", &fakeComponent{enabled: true, err: fmt.Errorf("some error")})
+	component.Register("dependent", &fakeComponent{enabled: true, dependencies: []string{"failing"}})
+	component.Register("disabled", &fakeComponent{})
+	component.Register("cleanup", &fakeComponent{err: fmt.Errorf("cleanup error")})
+	recorder := record.NewFakeRecorder(10)
+	driver := portworx{recorder: recorder}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+	}
+
+	err := driver.PreInstall(cluster)
+	require.NoError(t, err)
+	require.Equal(t,
+		[]corev1alpha1.ComponentStatus{
+			{
+				Name:    "cleanup",
+				Status:  corev1alpha1.ClusterOperationFailed,
+				Message: "Failed to cleanup cleanup. cleanup error",
+			},
+			{
+				Name:    "dependent",
+				Status:  corev1alpha1.ClusterOperationFailed,
+				Message: "Failed to setup dependent. dependency failing failed",
+			},
+			{
+				Name:    "failing",
+				Status:  corev1alpha1.ClusterOperationFailed,
+				Message: "Failed to setup failing. some error",
+			},
+			{
+				Name:   "healthy",
+				Status: corev1alpha1.ClusterOperationCompleted,
+			},
+		},
+		cluster.Status.Components,
+	)
+	require.Len(t, recorder.Events, 3)
+	require.Equal(t,
+		fmt.Sprintf("%v %v Failed to cleanup cleanup. cleanup error",
+			v1.EventTypeWarning, util.FailedComponentReason),
+		<-recorder.Events)
+
+	// Critical failures should be returned instead of raising an event
+	component.Register("critical", &fakeComponent{
+		enabled: true,
+		err:     component.NewError(component.ErrCritical, fmt.Errorf("critical error")),
+	})
+	recorder = record.NewFakeRecorder(10)
+	driver.recorder = recorder
+
+	err = driver.PreInstall(cluster)
+	require.EqualError(t, err, "Failed to setup critical. critical error")
+	require.Equal(t, corev1alpha1.ClusterOperationFailed, cluster.Status.Components[1].Status)
+	require.Equal(t, "critical", cluster.Status.Components[1].Name)
+}
+
 func createFakeCRD(fakeClient *fakeextclient.Clientset, crdName string) error {
 	crd := &apiextensionsv1beta1.CustomResourceDefinition{
 		ObjectMeta: metav1.ObjectMeta{
@@ -4000,4 +4654,38 @@ func reregisterComponents() {
 	component.RegisterLighthouseComponent()
 	component.RegisterPVCControllerComponent()
 	component.RegisterMonitoringComponent()
+	component.RegisterPriorityClassComponent()
 }
+
+// fakeComponent is a component that returns the given error for any operation
+type fakeComponent struct {
+	priority     int32
+	dependencies []string
+	enabled      bool
+	err          error
+}
+
+func (c *fakeComponent) Initialize(client.Client, goversion.Version, *runtime.Scheme, record.EventRecorder) {
+}
+
+func (c *fakeComponent) Priority() int32 {
+	return c.priority
+}
+
+func (c *fakeComponent) Dependencies() []string {
+	return c.dependencies
+}
+
+func (c *fakeComponent) IsEnabled(*corev1alpha1.StorageCluster) bool {
+	return c.enabled
+}
+
+func (c *fakeComponent) Reconcile(*corev1alpha1.StorageCluster) error {
+	return c.err
+}
+
+func (c *fakeComponent) Delete(*corev1alpha1.StorageCluster) error {
+	return c.err
+}
+
+func (c *fakeComponent) MarkDeleted(*corev1alpha1.StorageCluster) {}
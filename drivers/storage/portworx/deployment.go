@@ -7,7 +7,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/google/shlex"
 	"github.com/hashicorp/go-version"
 	"github.com/libopenstorage/cloudops"
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
@@ -301,11 +300,7 @@ func (p *portworx) GetStoragePodSpec(
 		}
 	}
 
-	if t.cluster.Spec.Placement != nil && t.cluster.Spec.Placement.NodeAffinity != nil {
-		podSpec.Affinity = &v1.Affinity{
-			NodeAffinity: t.cluster.Spec.Placement.NodeAffinity.DeepCopy(),
-		}
-	}
+	util.ApplyPlacement(&podSpec, t.cluster.Spec.Placement)
 
 	if t.cluster.Spec.ImagePullSecret != nil && *t.cluster.Spec.ImagePullSecret != "" {
 		podSpec.ImagePullSecrets = append(
@@ -394,7 +389,7 @@ func configureStorageNodeSpec(node *corev1alpha1.StorageNode, config *cloudstora
 
 func (t *template) portworxContainer() v1.Container {
 	pxImage := util.GetImageURN(t.cluster.Spec.CustomImageRegistry, t.cluster.Spec.Image)
-	return v1.Container{
+	container := v1.Container{
 		Name:            pxContainerName,
 		Image:           pxImage,
 		ImagePullPolicy: t.imagePullPolicy,
@@ -427,6 +422,10 @@ func (t *template) portworxContainer() v1.Container {
 		},
 		VolumeMounts: t.getVolumeMounts(),
 	}
+	if t.cluster.Spec.Resources != nil {
+		container.Resources = *t.cluster.Spec.Resources.DeepCopy()
+	}
+	return container
 }
 
 func (t *template) csiRegistrarContainer() *v1.Container {
@@ -665,8 +664,8 @@ func (t *template) getArguments() []string {
 		args = append(args, "--keep-px-up")
 	}
 
-	if t.cluster.Annotations[annotationLogFile] != "" {
-		args = append(args, "--log", t.cluster.Annotations[annotationLogFile])
+	if logFile := pxutil.LogFile(t.cluster); logFile != "" {
+		args = append(args, "--log", logFile)
 	}
 
 	rtOpts := make([]string, 0)
@@ -686,13 +685,11 @@ func (t *template) getArguments() []string {
 		args = append(args, "-rt_opts", strings.Join(rtOpts, ","))
 	}
 
-	if t.cluster.Annotations[annotationMiscArgs] != "" {
-		parts, err := shlex.Split(t.cluster.Annotations[annotationMiscArgs])
-		if err == nil {
-			args = append(args, parts...)
-		} else {
-			logrus.Warnf("error parsing misc args: %v", err)
-		}
+	extraArgs, err := pxutil.ExtraArgs(t.cluster)
+	if err == nil {
+		args = append(args, extraArgs...)
+	} else {
+		logrus.Warnf("error parsing misc args: %v", err)
 	}
 
 	return args
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/version"
 	fakediscovery "k8s.io/client-go/discovery/fake"
@@ -1108,6 +1109,46 @@ func TestPodSpecWithLogAnnotation(t *testing.T) {
 	assert.ElementsMatch(t, expectedArgs, actual.Containers[0].Args)
 }
 
+func TestPodSpecWithLogFileAndExtraArgs(t *testing.T) {
+	fakeClient := fakek8sclient.NewSimpleClientset()
+	k8s.Instance().SetBaseClient(fakeClient)
+	fakeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: "v1.12.8",
+	}
+
+	nodeName := "testNode"
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-system",
+			Annotations: map[string]string{
+				annotationLogFile:  "/tmp/log",
+				annotationMiscArgs: "-fruit apple",
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			CommonConfig: corev1alpha1.CommonConfig{
+				LogFile:   "/var/log/px.log",
+				ExtraArgs: []string{"-person", "john doe"},
+			},
+		},
+	}
+	driver := portworx{}
+
+	// The spec fields should take precedence over the deprecated annotations
+	expectedArgs := []string{
+		"-c", "px-cluster",
+		"-x", "kubernetes",
+		"--log", "/var/log/px.log",
+		"-person", "john doe",
+	}
+
+	actual, err := driver.GetStoragePodSpec(cluster, nodeName)
+	require.NoError(t, err, "Unexpected error on GetStoragePodSpec")
+	assert.ElementsMatch(t, expectedArgs, actual.Containers[0].Args)
+}
+
 func TestPodSpecWithRuntimeOptions(t *testing.T) {
 	fakeClient := fakek8sclient.NewSimpleClientset()
 	k8s.Instance().SetBaseClient(fakeClient)
@@ -1196,6 +1237,45 @@ func TestPodSpecWithMiscArgs(t *testing.T) {
 	assert.ElementsMatch(t, expectedArgs, actual.Containers[0].Args)
 }
 
+func TestPodSpecWithResources(t *testing.T) {
+	fakeClient := fakek8sclient.NewSimpleClientset()
+	k8s.Instance().SetBaseClient(fakeClient)
+	fakeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: "v1.12.8",
+	}
+
+	nodeName := "testNode"
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-system",
+		},
+	}
+	driver := portworx{}
+
+	// No resources should be set by default
+	actual, err := driver.GetStoragePodSpec(cluster, nodeName)
+	require.NoError(t, err)
+	assert.Empty(t, actual.Containers[0].Resources)
+
+	expectedResources := v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("2"),
+			v1.ResourceMemory: resource.MustParse("4Gi"),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("2"),
+			v1.ResourceMemory: resource.MustParse("4Gi"),
+		},
+	}
+	cluster.Spec.Resources = expectedResources.DeepCopy()
+
+	actual, err = driver.GetStoragePodSpec(cluster, nodeName)
+	require.NoError(t, err)
+	assert.Equal(t, expectedResources, actual.Containers[0].Resources)
+}
+
 func TestPodSpecWithInvalidMiscArgs(t *testing.T) {
 	fakeClient := fakek8sclient.NewSimpleClientset()
 	k8s.Instance().SetBaseClient(fakeClient)
@@ -0,0 +1,68 @@
+package portworx
+
+import (
+	"github.com/libopenstorage/openstorage/api"
+	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// StoragePodDisruptionBudgetName name of the PodDisruptionBudget for portworx pods
+	StoragePodDisruptionBudgetName = "px-storage"
+)
+
+// createStoragePodDisruptionBudget creates or updates the PodDisruptionBudget
+// for the portworx pods, so that voluntary disruptions like node drains cannot
+// evict enough pods for the cluster to lose quorum. Storageless nodes are not
+// part of the quorum, but the budget selects their pods too, so all of them
+// have to be available in addition to the quorum of storage nodes.
+func (p *portworx) createStoragePodDisruptionBudget(
+	cluster *corev1alpha1.StorageCluster,
+	nodes []*api.StorageNode,
+) error {
+	storageNodes, storagelessNodes := 0, 0
+	for _, node := range nodes {
+		if mapNodeStatus(node.Status) == corev1alpha1.NodeDecommissioned {
+			continue
+		} else if isQuorumMember(node) {
+			storageNodes++
+		} else {
+			storagelessNodes++
+		}
+	}
+	// Nothing to protect until storage nodes have joined the cluster
+	if storageNodes == 0 {
+		return nil
+	}
+
+	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
+	minAvailable := intstr.FromInt(storagelessNodes + quorumSize(storageNodes))
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            StoragePodDisruptionBudgetName,
+			Namespace:       cluster.Namespace,
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: p.GetSelectorLabels(),
+			},
+		},
+	}
+	return k8sutil.CreateOrUpdate(p.k8sClient, pdb, ownerRef)
+}
+
+// deleteStoragePodDisruptionBudget deletes the PodDisruptionBudget for the
+// portworx pods, if it is owned by the given cluster
+func (p *portworx) deleteStoragePodDisruptionBudget(
+	cluster *corev1alpha1.StorageCluster,
+) error {
+	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
+	return k8sutil.DeletePodDisruptionBudget(
+		p.k8sClient, StoragePodDisruptionBudgetName, cluster.Namespace, *ownerRef)
+}
@@ -0,0 +1,136 @@
+package portworx
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/libopenstorage/openstorage/api"
+	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/mock"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestStoragePodDisruptionBudget(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Create the mock servers that can be used to mock SDK calls
+	mockClusterServer := mock.NewMockOpenStorageClusterServer(mockCtrl)
+	mockNodeServer := mock.NewMockOpenStorageNodeServer(mockCtrl)
+
+	// Start a sdk server that implements the mock servers
+	sdkServerIP := "127.0.0.1"
+	sdkServerPort := 21883
+	mockSdk := mock.NewSdkServer(mock.SdkServers{
+		Cluster: mockClusterServer,
+		Node:    mockNodeServer,
+	})
+	mockSdk.StartOnAddress(sdkServerIP, strconv.Itoa(sdkServerPort))
+	defer mockSdk.Stop()
+
+	// Create fake k8s client with fake service that will point the client
+	// to the mock sdk server address
+	k8sClient := testutil.FakeK8sClient(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pxutil.PortworxServiceName,
+			Namespace: "kube-test",
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: sdkServerIP,
+			Ports: []v1.ServicePort{
+				{
+					Name: pxutil.PortworxSDKPortName,
+					Port: int32(sdkServerPort),
+				},
+			},
+		},
+	})
+	driver := portworx{
+		k8sClient: k8sClient,
+		recorder:  record.NewFakeRecorder(10),
+	}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			DeleteStrategy: &corev1alpha1.StorageClusterDeleteStrategy{
+				Type: corev1alpha1.UninstallStorageClusterStrategyType,
+			},
+		},
+		Status: corev1alpha1.StorageClusterStatus{
+			Phase: string(corev1alpha1.ClusterOnline),
+		},
+	}
+
+	mockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), &api.SdkClusterInspectCurrentRequest{}).
+		Return(&api.SdkClusterInspectCurrentResponse{Cluster: &api.StorageCluster{}}, nil).
+		AnyTimes()
+
+	storageNode := func(name string, status api.Status) *api.StorageNode {
+		return &api.StorageNode{
+			Id:                name,
+			SchedulerNodeName: name,
+			Status:            status,
+			Pools:             []*api.StoragePool{{ID: 0}},
+		}
+	}
+	storagelessNode := storageNode("node5", api.Status_STATUS_OK)
+	storagelessNode.Pools = nil
+	nodes := []*api.StorageNode{
+		storageNode("node1", api.Status_STATUS_OK),
+		storageNode("node2", api.Status_STATUS_OK),
+		storageNode("node3", api.Status_STATUS_OFFLINE),
+		storageNode("node4", api.Status_STATUS_DECOMMISSION),
+		storagelessNode,
+	}
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(&api.SdkNodeEnumerateWithFiltersResponse{Nodes: nodes}, nil).
+		Times(1)
+
+	// The storageless node and a quorum of the 3 storage nodes should be available
+	err := driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+
+	pdb := &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, StoragePodDisruptionBudgetName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, 3, pdb.Spec.MinAvailable.IntValue())
+	require.Equal(t, driver.GetSelectorLabels(), pdb.Spec.Selector.MatchLabels)
+	require.Len(t, pdb.OwnerReferences, 1)
+	require.Equal(t, cluster.Name, pdb.OwnerReferences[0].Name)
+
+	// The budget should be recomputed as storage nodes join the cluster
+	nodes = append(nodes, storageNode("node6", api.Status_STATUS_OK), storageNode("node7", api.Status_STATUS_INIT))
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(&api.SdkNodeEnumerateWithFiltersResponse{Nodes: nodes}, nil).
+		Times(1)
+
+	err = driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+
+	pdb = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, StoragePodDisruptionBudgetName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, 4, pdb.Spec.MinAvailable.IntValue())
+
+	// The budget should be removed when portworx is uninstalled
+	_, err = driver.DeleteStorage(cluster)
+	require.NoError(t, err)
+
+	pdb = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, StoragePodDisruptionBudgetName, cluster.Namespace)
+	require.True(t, errors.IsNotFound(err))
+}
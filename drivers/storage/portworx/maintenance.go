@@ -0,0 +1,128 @@
+package portworx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/libopenstorage/openstorage/api"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/portworx/sched-ops/k8s"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	pxctlPath = "/opt/pwx/bin/pxctl"
+)
+
+// SetNodeMaintenance moves Portworx on the given node in or out of maintenance.
+// The Portworx SDK does not have an API for maintenance, so pxctl is run in the
+// storage pod on the node instead.
+func (p *portworx) SetNodeMaintenance(
+	cluster *corev1alpha1.StorageCluster,
+	node *corev1alpha1.StorageNode,
+	enabled bool,
+) error {
+	pod, err := p.storagePodOnNode(cluster, node.Name)
+	if err != nil {
+		return err
+	}
+
+	operation := "--exit"
+	if enabled {
+		operation = "--enter"
+	}
+	cmd := []string{pxctlPath, "service", "maintenance", operation, "-y"}
+	if _, err := k8s.Instance().RunCommandInPod(cmd, pod.Name, pxContainerName, pod.Namespace); err != nil {
+		return fmt.Errorf("failed to run %q in pod %s/%s: %v",
+			strings.Join(cmd, " "), pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
+
+// CanTakeNodeOffline checks that a majority of the Portworx storage nodes
+// stay online if Portworx on the given node is taken offline. Storageless
+// nodes do not participate in the quorum, so they are not counted.
+func (p *portworx) CanTakeNodeOffline(
+	cluster *corev1alpha1.StorageCluster,
+	nodeName string,
+) error {
+	clientConn, err := p.getPortworxClient(cluster)
+	if err != nil {
+		return err
+	}
+
+	nodeClient := api.NewOpenStorageNodeClient(clientConn)
+	nodeEnumerateResponse, err := nodeClient.EnumerateWithFilters(
+		context.TODO(),
+		&api.SdkNodeEnumerateWithFiltersRequest{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enumerate nodes: %v", err)
+	}
+
+	storageNodes, onlineStorageNodes := 0, 0
+	for _, node := range nodeEnumerateResponse.Nodes {
+		if err := setSchedulerNodeName(node); err != nil {
+			logrus.Debug(err)
+			continue
+		}
+		status := mapNodeStatus(node.Status)
+		if !isQuorumMember(node) || status == corev1alpha1.NodeDecommissioned {
+			continue
+		}
+		storageNodes++
+		if node.SchedulerNodeName != nodeName && status == corev1alpha1.NodeOnline {
+			onlineStorageNodes++
+		}
+	}
+
+	quorum := quorumSize(storageNodes)
+	if onlineStorageNodes < quorum {
+		return fmt.Errorf("only %d of %d portworx storage nodes would be online, "+
+			"which is less than the quorum of %d", onlineStorageNodes, storageNodes, quorum)
+	}
+	return nil
+}
+
+// isQuorumMember returns true if the given portworx node takes part in the
+// quorum of the cluster, which is only the case for nodes with storage
+func isQuorumMember(node *api.StorageNode) bool {
+	return len(node.Pools) > 0
+}
+
+// quorumSize returns the number of storage nodes that need to be online
+// for a cluster with the given number of storage nodes to be in quorum
+func quorumSize(storageNodes int) int {
+	return storageNodes/2 + 1
+}
+
+// storagePodOnNode returns the Portworx pod running on the given node
+func (p *portworx) storagePodOnNode(
+	cluster *corev1alpha1.StorageCluster,
+	nodeName string,
+) (*v1.Pod, error) {
+	podList := &v1.PodList{}
+	err := p.k8sClient.List(
+		context.TODO(),
+		podList,
+		&client.ListOptions{
+			Namespace:     cluster.Namespace,
+			LabelSelector: labels.SelectorFromSet(p.GetSelectorLabels()),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list portworx pods: %v", err)
+	}
+
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == nodeName && pod.DeletionTimestamp == nil &&
+			pod.Status.Phase == v1.PodRunning {
+			return pod.DeepCopy(), nil
+		}
+	}
+	return nil, fmt.Errorf("portworx pod is not running on node %s", nodeName)
+}
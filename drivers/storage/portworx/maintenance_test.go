@@ -0,0 +1,161 @@
+package portworx
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/libopenstorage/openstorage/api"
+	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/mock"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetNodeMaintenanceWithoutPortworxPod(t *testing.T) {
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+	}
+	node := &corev1alpha1.StorageNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "node1",
+			Namespace: cluster.Namespace,
+		},
+	}
+	driver := portworx{}
+
+	// Pods that are not portworx pods, not running or running on other
+	// nodes should not be used for maintenance
+	otherPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-pod",
+			Namespace: cluster.Namespace,
+		},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+		},
+	}
+	pendingPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-pod-1",
+			Namespace: cluster.Namespace,
+			Labels:    driver.GetSelectorLabels(),
+		},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+		},
+	}
+	podOnOtherNode := pendingPod.DeepCopy()
+	podOnOtherNode.Name = "px-pod-2"
+	podOnOtherNode.Spec.NodeName = "node2"
+	podOnOtherNode.Status.Phase = v1.PodRunning
+
+	driver.k8sClient = testutil.FakeK8sClient(otherPod, pendingPod, podOnOtherNode)
+
+	err := driver.SetNodeMaintenance(cluster, node, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "portworx pod is not running on node node1")
+
+	err = driver.SetNodeMaintenance(cluster, node, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "portworx pod is not running on node node1")
+}
+
+func TestCanTakeNodeOffline(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Create the mock server that can be used to mock SDK calls
+	mockNodeServer := mock.NewMockOpenStorageNodeServer(mockCtrl)
+
+	// Start a sdk server that implements the mock servers
+	sdkServerIP := "127.0.0.1"
+	sdkServerPort := 21883
+	mockSdk := mock.NewSdkServer(mock.SdkServers{
+		Node: mockNodeServer,
+	})
+	mockSdk.StartOnAddress(sdkServerIP, strconv.Itoa(sdkServerPort))
+	defer mockSdk.Stop()
+
+	// Create fake k8s client with fake service that will point the client
+	// to the mock sdk server address
+	k8sClient := testutil.FakeK8sClient(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pxutil.PortworxServiceName,
+			Namespace: "kube-test",
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: sdkServerIP,
+			Ports: []v1.ServicePort{
+				{
+					Name: pxutil.PortworxSDKPortName,
+					Port: int32(sdkServerPort),
+				},
+			},
+		},
+	})
+	driver := portworx{
+		k8sClient: k8sClient,
+	}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+	}
+
+	storageNode := func(name string, status api.Status) *api.StorageNode {
+		return &api.StorageNode{
+			Id:                name,
+			SchedulerNodeName: name,
+			Status:            status,
+			Pools:             []*api.StoragePool{{ID: 0}},
+		}
+	}
+	// Storageless and decommissioned nodes should not count towards quorum
+	storagelessNode := storageNode("node5", api.Status_STATUS_OK)
+	storagelessNode.Pools = nil
+	nodes := []*api.StorageNode{
+		storageNode("node1", api.Status_STATUS_OK),
+		storageNode("node2", api.Status_STATUS_OK),
+		storageNode("node3", api.Status_STATUS_OK),
+		storageNode("node4", api.Status_STATUS_DECOMMISSION),
+		storagelessNode,
+	}
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(&api.SdkNodeEnumerateWithFiltersResponse{Nodes: nodes}, nil).
+		Times(2)
+
+	// Two of the three storage nodes stay online
+	err := driver.CanTakeNodeOffline(cluster, "node1")
+	require.NoError(t, err)
+
+	// Storageless nodes can always be taken offline if the cluster is in quorum
+	err = driver.CanTakeNodeOffline(cluster, "node5")
+	require.NoError(t, err)
+
+	// Only one of the three storage nodes would stay online
+	nodes[1].Status = api.Status_STATUS_MAINTENANCE
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(&api.SdkNodeEnumerateWithFiltersResponse{Nodes: nodes}, nil).
+		Times(1)
+
+	err = driver.CanTakeNodeOffline(cluster, "node1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(),
+		"only 1 of 3 portworx storage nodes would be online, which is less than the quorum of 2")
+}
@@ -0,0 +1,339 @@
+package portworx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	pksHostPathPrefix = "/var/vcap"
+)
+
+var (
+	// generatedEnvNames are the env variables that are always generated by the
+	// template, so they are not copied from the DaemonSet to the cluster spec
+	generatedEnvNames = map[string]bool{
+		"PX_TEMPLATE_VERSION": true,
+		"CSI_ENDPOINT":        true,
+		"PORTWORX_CSIVERSION": true,
+		"REGISTRY_CONFIG":     true,
+		"PRE-EXEC":            true,
+	}
+	// migratedVolumeNames are the volumes that are always generated by the template
+	migratedVolumeNames = map[string]bool{
+		"registration-dir": true,
+		"csi-driver-path":  true,
+	}
+)
+
+// StorageClusterFromDaemonSet returns a StorageCluster equivalent to the given
+// Portworx DaemonSet. The arguments, env variables, image and placement of the
+// Portworx container are converted to the cluster spec, reversing what the
+// template does when generating the storage pod. Arguments that do not have an
+// dedicated field in the spec are passed through the extra arguments. The
+// returned cluster is annotated to migrate the pods of the DaemonSet, so once
+// it is created the operator replaces the pods one node at a time.
+func StorageClusterFromDaemonSet(ds *appsv1.DaemonSet) (*corev1alpha1.StorageCluster, error) {
+	var container *v1.Container
+	for i := range ds.Spec.Template.Spec.Containers {
+		if ds.Spec.Template.Spec.Containers[i].Name == pxContainerName {
+			container = &ds.Spec.Template.Spec.Containers[i]
+			break
+		}
+	}
+	if container == nil {
+		return nil, fmt.Errorf("DaemonSet %s/%s does not have a %s container",
+			ds.Namespace, ds.Name, pxContainerName)
+	}
+
+	cluster := &corev1alpha1.StorageCluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "StorageCluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ds.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationMigrateDaemonSet: ds.Name,
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Image:           container.Image,
+			ImagePullPolicy: container.ImagePullPolicy,
+		},
+	}
+
+	podSpec := &ds.Spec.Template.Spec
+	for _, volume := range podSpec.Volumes {
+		if volume.HostPath != nil && strings.HasPrefix(volume.HostPath.Path, pksHostPathPrefix) {
+			cluster.Spec.Platform = corev1alpha1.PlatformPKS
+			break
+		}
+	}
+
+	if err := parseDaemonSetArgs(cluster, container.Args); err != nil {
+		return nil, err
+	}
+	if errs := validation.IsDNS1123Subdomain(cluster.Name); len(errs) > 0 {
+		return nil, fmt.Errorf("cluster name %q cannot be used as the name of a StorageCluster: %s",
+			cluster.Name, strings.Join(errs, ", "))
+	}
+
+	defaultEnvValues := map[string]string{
+		"PX_SECRETS_NAMESPACE":               ds.Namespace,
+		"AUTO_NODE_RECOVERY_TIMEOUT_IN_SECS": "1500",
+	}
+	for _, env := range container.Env {
+		if generatedEnvNames[env.Name] ||
+			(env.ValueFrom == nil && defaultEnvValues[env.Name] == env.Value) {
+			continue
+		}
+		cluster.Spec.Env = append(cluster.Spec.Env, *env.DeepCopy())
+	}
+
+	if len(podSpec.ImagePullSecrets) > 0 {
+		cluster.Spec.ImagePullSecret = stringPtr(podSpec.ImagePullSecrets[0].Name)
+	}
+
+	if podSpec.Affinity != nil && podSpec.Affinity.NodeAffinity != nil {
+		cluster.Spec.Placement = &corev1alpha1.PlacementSpec{
+			NodeAffinity: podSpec.Affinity.NodeAffinity.DeepCopy(),
+		}
+		if hasInfraNodeRequirement(podSpec.Affinity.NodeAffinity) {
+			cluster.Spec.Platform = corev1alpha1.PlatformOpenShift
+		}
+	}
+	if len(podSpec.Tolerations) > 0 {
+		if cluster.Spec.Placement == nil {
+			cluster.Spec.Placement = &corev1alpha1.PlacementSpec{}
+		}
+		for _, toleration := range podSpec.Tolerations {
+			cluster.Spec.Placement.Tolerations = append(cluster.Spec.Placement.Tolerations,
+				*toleration.DeepCopy())
+		}
+	}
+
+	for _, c := range podSpec.Containers {
+		if c.Name == "csi-node-driver-registrar" || c.Name == "csi-driver-registrar" {
+			cluster.Spec.FeatureGates = map[string]string{
+				string(pxutil.FeatureCSI): "true",
+			}
+		}
+	}
+
+	knownVolumes := make(map[string]bool)
+	for _, v := range defaultVolumeInfoList {
+		knownVolumes[v.name] = true
+	}
+	for _, volume := range podSpec.Volumes {
+		if !knownVolumes[volume.Name] && !migratedVolumeNames[volume.Name] {
+			logrus.Warnf("Volume %s of DaemonSet %s/%s is not migrated to the StorageCluster",
+				volume.Name, ds.Namespace, ds.Name)
+		}
+	}
+
+	return cluster, nil
+}
+
+// parseDaemonSetArgs sets the cluster spec from the arguments of the Portworx
+// container. It is the reverse of template.getArguments.
+func parseDaemonSetArgs(cluster *corev1alpha1.StorageCluster, args []string) error {
+	var (
+		devices, journal, metadata []string
+		extraArgs                  []string
+		kvdb                       = &corev1alpha1.KvdbSpec{}
+		storage                    = &corev1alpha1.StorageSpec{}
+		cloudStorage               = &corev1alpha1.CloudStorageSpec{}
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("missing value for argument %s", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var (
+			val string
+			err error
+		)
+		switch arg {
+		case "-x":
+			_, err = value()
+		case "-c":
+			cluster.Name, err = value()
+		case "-b":
+			kvdb.Internal = true
+		case "-k":
+			if val, err = value(); err == nil {
+				kvdb.Endpoints = strings.Split(val, ",")
+			}
+		case "-d":
+			if val, err = value(); err == nil {
+				network(cluster).DataInterface = stringPtr(val)
+			}
+		case "-m":
+			if val, err = value(); err == nil {
+				network(cluster).MgmtInterface = stringPtr(val)
+			}
+		case "-s":
+			if val, err = value(); err == nil {
+				devices = append(devices, val)
+			}
+		case "-j":
+			if val, err = value(); err == nil {
+				journal = append(journal, val)
+			}
+		case "-metadata":
+			if val, err = value(); err == nil {
+				metadata = append(metadata, val)
+			}
+		case "-a":
+			storage.UseAll = boolPtr(true)
+		case "-A":
+			storage.UseAllWithPartitions = boolPtr(true)
+		case "-f":
+			storage.ForceUseDisks = boolPtr(true)
+		case "-max_drive_set_count":
+			if val, err = value(); err == nil {
+				cloudStorage.MaxStorageNodes, err = uint32Ptr(arg, val)
+			}
+		case "-max_storage_nodes_per_zone":
+			if val, err = value(); err == nil {
+				cloudStorage.MaxStorageNodesPerZone, err = uint32Ptr(arg, val)
+			}
+		case "-secret_type":
+			if val, err = value(); err == nil {
+				cluster.Spec.SecretsProvider = stringPtr(val)
+			}
+		case "-r":
+			if val, err = value(); err == nil {
+				cluster.Spec.StartPort, err = uint32Ptr(arg, val)
+			}
+		case "--pull":
+			if val, err = value(); err == nil {
+				cluster.Spec.ImagePullPolicy = v1.PullPolicy(val)
+			}
+		case "--log":
+			if val, err = value(); err == nil {
+				cluster.Spec.LogFile = val
+			}
+		case "-rt_opts":
+			if val, err = value(); err == nil {
+				cluster.Spec.RuntimeOpts, err = parseRuntimeOpts(val)
+			}
+		case "--keep-px-up":
+			// Added by the template for PKS, and the default for other clusters
+			if !pxutil.IsPKS(cluster) {
+				extraArgs = append(extraArgs, arg)
+			}
+		default:
+			if arg == "-cert" || arg == "-ca" || arg == "-key" {
+				logrus.Warnf("Argument %s refers to a file in a volume of the DaemonSet. Use the "+
+					"kvdb auth secret of the StorageCluster instead.", arg)
+			}
+			extraArgs = append(extraArgs, arg)
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				extraArgs = append(extraArgs, args[i])
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if cluster.Name == "" {
+		return fmt.Errorf("cluster name argument -c is missing")
+	}
+	if kvdb.Internal || len(kvdb.Endpoints) > 0 {
+		cluster.Spec.Kvdb = kvdb
+	}
+
+	// Drives are cloud drive specs if they are key value pairs, like type=gp2,size=100
+	if len(devices) > 0 && strings.Contains(devices[0], "=") {
+		cloudStorage.DeviceSpecs = &devices
+		if len(journal) > 0 {
+			cloudStorage.JournalDeviceSpec = stringPtr(journal[0])
+		}
+		if len(metadata) > 0 {
+			cloudStorage.SystemMdDeviceSpec = stringPtr(metadata[0])
+		}
+		cluster.Spec.CloudStorage = cloudStorage
+	} else {
+		if len(devices) > 0 {
+			storage.Devices = &devices
+		}
+		if len(journal) > 0 {
+			storage.JournalDevice = stringPtr(journal[0])
+		}
+		if len(metadata) > 0 {
+			storage.SystemMdDevice = stringPtr(metadata[0])
+		}
+		if *storage != (corev1alpha1.StorageSpec{}) {
+			cluster.Spec.Storage = storage
+		}
+		if cloudStorage.MaxStorageNodes != nil || cloudStorage.MaxStorageNodesPerZone != nil {
+			cluster.Spec.CloudStorage = cloudStorage
+		}
+	}
+
+	cluster.Spec.ExtraArgs = extraArgs
+	return nil
+}
+
+func parseRuntimeOpts(value string) (map[string]string, error) {
+	rtOpts := make(map[string]string)
+	for _, opt := range strings.Split(value, ",") {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid runtime option %q", opt)
+		}
+		rtOpts[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return rtOpts, nil
+}
+
+func network(cluster *corev1alpha1.StorageCluster) *corev1alpha1.NetworkSpec {
+	if cluster.Spec.Network == nil {
+		cluster.Spec.Network = &corev1alpha1.NetworkSpec{}
+	}
+	return cluster.Spec.Network
+}
+
+func hasInfraNodeRequirement(nodeAffinity *v1.NodeAffinity) bool {
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+	for _, term := range nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, requirement := range term.MatchExpressions {
+			if requirement.Key == "node-role.kubernetes.io/infra" &&
+				requirement.Operator == v1.NodeSelectorOpDoesNotExist {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func uint32Ptr(arg, value string) (*uint32, error) {
+	val, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for argument %s: %v", value, arg, err)
+	}
+	result := uint32(val)
+	return &result, nil
+}
@@ -0,0 +1,241 @@
+package portworx
+
+import (
+	"testing"
+
+	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStorageClusterFromDaemonSet(t *testing.T) {
+	nodeAffinity := &v1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{
+				{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{
+							Key:      "px/enabled",
+							Operator: v1.NodeSelectorOpNotIn,
+							Values:   []string{"false"},
+						},
+						{
+							Key:      "node-role.kubernetes.io/infra",
+							Operator: v1.NodeSelectorOpDoesNotExist,
+						},
+					},
+				},
+			},
+		},
+	}
+	tolerations := []v1.Toleration{
+		{
+			Key:      "storage",
+			Operator: v1.TolerationOpEqual,
+			Value:    "true",
+			Effect:   v1.TaintEffectNoSchedule,
+		},
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "portworx",
+			Namespace: "kube-test",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Affinity:         &v1.Affinity{NodeAffinity: nodeAffinity},
+					ImagePullSecrets: []v1.LocalObjectReference{{Name: "pull-secret"}},
+					Tolerations:      tolerations,
+					Containers: []v1.Container{
+						{
+							Name:            "portworx",
+							Image:           "portworx/oci-monitor:2.3.2",
+							ImagePullPolicy: v1.PullIfNotPresent,
+							Args: []string{
+								"-c", "px-cluster",
+								"-x", "kubernetes",
+								"-k", "etcd:http://etcd-1:2379,etcd:http://etcd-2:2379",
+								"-d", "eth1",
+								"-m", "eth0",
+								"-s", "/dev/sdb",
+								"-s", "/dev/sdc",
+								"-j", "/dev/sdd",
+								"-metadata", "/dev/sde",
+								"-f",
+								"-secret_type", "k8s",
+								"-r", "10001",
+								"--log", "/tmp/px.log",
+								"-rt_opts", "num_threads=4,num_io_threads=10",
+								"-userpwd", "user:pass",
+								"--keep-px-up",
+								"-extra", "value with spaces",
+							},
+							Env: []v1.EnvVar{
+								{Name: "PX_TEMPLATE_VERSION", Value: "v3"},
+								{Name: "PX_SECRETS_NAMESPACE", Value: "kube-test"},
+								{Name: "AUTO_NODE_RECOVERY_TIMEOUT_IN_SECS", Value: "1500"},
+								{Name: "PX_LOGLEVEL", Value: "debug"},
+							},
+						},
+						{
+							Name:  "csi-node-driver-registrar",
+							Image: "quay.io/k8scsi/csi-node-driver-registrar:v1.1.0",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cluster, err := StorageClusterFromDaemonSet(ds)
+	require.NoError(t, err)
+
+	require.Equal(t, "px-cluster", cluster.Name)
+	require.Equal(t, "kube-test", cluster.Namespace)
+	require.Equal(t, "StorageCluster", cluster.Kind)
+	require.Equal(t, corev1alpha1.SchemeGroupVersion.String(), cluster.APIVersion)
+	require.Equal(t, "portworx", cluster.Annotations[util.AnnotationMigrateDaemonSet])
+	require.Equal(t, corev1alpha1.PlatformOpenShift, cluster.Spec.Platform)
+	require.Equal(t, "/tmp/px.log", cluster.Spec.LogFile)
+	require.Equal(t, []string{"-userpwd", "user:pass", "--keep-px-up", "-extra", "value with spaces"},
+		cluster.Spec.ExtraArgs)
+	require.Len(t, cluster.Annotations, 1)
+
+	require.Equal(t, "portworx/oci-monitor:2.3.2", cluster.Spec.Image)
+	require.Equal(t, v1.PullIfNotPresent, cluster.Spec.ImagePullPolicy)
+	require.Equal(t, "pull-secret", *cluster.Spec.ImagePullSecret)
+	require.False(t, cluster.Spec.Kvdb.Internal)
+	require.Equal(t, []string{"etcd:http://etcd-1:2379", "etcd:http://etcd-2:2379"}, cluster.Spec.Kvdb.Endpoints)
+	require.Equal(t, "eth1", *cluster.Spec.Network.DataInterface)
+	require.Equal(t, "eth0", *cluster.Spec.Network.MgmtInterface)
+	require.Equal(t, []string{"/dev/sdb", "/dev/sdc"}, *cluster.Spec.Storage.Devices)
+	require.Equal(t, "/dev/sdd", *cluster.Spec.Storage.JournalDevice)
+	require.Equal(t, "/dev/sde", *cluster.Spec.Storage.SystemMdDevice)
+	require.True(t, *cluster.Spec.Storage.ForceUseDisks)
+	require.Nil(t, cluster.Spec.Storage.UseAll)
+	require.Nil(t, cluster.Spec.CloudStorage)
+	require.Equal(t, "k8s", *cluster.Spec.SecretsProvider)
+	require.Equal(t, uint32(10001), *cluster.Spec.StartPort)
+	require.Equal(t, map[string]string{"num_threads": "4", "num_io_threads": "10"}, cluster.Spec.RuntimeOpts)
+	require.Equal(t, []v1.EnvVar{{Name: "PX_LOGLEVEL", Value: "debug"}}, cluster.Spec.Env)
+	require.Equal(t, nodeAffinity, cluster.Spec.Placement.NodeAffinity)
+	require.Equal(t, tolerations, cluster.Spec.Placement.Tolerations)
+	require.Equal(t, "true", cluster.Spec.FeatureGates[string(pxutil.FeatureCSI)])
+
+	// The generated arguments should match the arguments of the DaemonSet
+	cluster.Spec.Kvdb.Endpoints = nil
+	cluster.Spec.Kvdb.Internal = true
+	template := &template{
+		cluster:         cluster,
+		startPort:       pxutil.StartPort(cluster),
+		imagePullPolicy: pxutil.ImagePullPolicy(cluster),
+	}
+	args := template.getArguments()
+	require.Contains(t, args, "-b")
+	require.Subset(t, args, []string{"-s", "/dev/sdb", "/dev/sdc", "-j", "/dev/sdd", "-metadata", "/dev/sde",
+		"-f", "-r", "10001", "--pull", "IfNotPresent", "-userpwd", "user:pass", "value with spaces"})
+}
+
+func TestStorageClusterFromDaemonSetWithCloudStorage(t *testing.T) {
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "portworx",
+			Namespace: "kube-test",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name:  "portworx",
+							Image: "portworx/oci-monitor:2.3.2",
+							Args: []string{
+								"-c", "px-cluster",
+								"-b",
+								"-s", "type=gp2,size=150",
+								"-j", "auto",
+								"-max_drive_set_count", "3",
+								"-max_storage_nodes_per_zone", "1",
+								"--keep-px-up",
+							},
+						},
+					},
+					Volumes: []v1.Volume{
+						{
+							Name: "diagsdump",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{
+									Path: "/var/vcap/store/cores",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cluster, err := StorageClusterFromDaemonSet(ds)
+	require.NoError(t, err)
+
+	require.True(t, cluster.Spec.Kvdb.Internal)
+	require.Empty(t, cluster.Spec.Kvdb.Endpoints)
+	require.Nil(t, cluster.Spec.Storage)
+	require.Equal(t, []string{"type=gp2,size=150"}, *cluster.Spec.CloudStorage.DeviceSpecs)
+	require.Equal(t, "auto", *cluster.Spec.CloudStorage.JournalDeviceSpec)
+	require.Equal(t, uint32(3), *cluster.Spec.CloudStorage.MaxStorageNodes)
+	require.Equal(t, uint32(1), *cluster.Spec.CloudStorage.MaxStorageNodesPerZone)
+	// The keep-px-up argument is added by the template for PKS clusters
+	require.Equal(t, corev1alpha1.PlatformPKS, cluster.Spec.Platform)
+	require.Empty(t, cluster.Spec.ExtraArgs)
+	require.Nil(t, cluster.Spec.Placement)
+	require.Nil(t, cluster.Spec.FeatureGates)
+}
+
+func TestStorageClusterFromDaemonSetFailures(t *testing.T) {
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "portworx",
+			Namespace: "kube-test",
+		},
+	}
+
+	// Portworx container is missing
+	_, err := StorageClusterFromDaemonSet(ds)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not have a portworx container")
+
+	// Cluster name is missing
+	ds.Spec.Template.Spec.Containers = []v1.Container{
+		{
+			Name: "portworx",
+			Args: []string{"-a"},
+		},
+	}
+	_, err = StorageClusterFromDaemonSet(ds)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cluster name argument -c is missing")
+
+	// Cluster name is not a valid object name
+	ds.Spec.Template.Spec.Containers[0].Args = []string{"-c", "PX_Cluster"}
+	_, err = StorageClusterFromDaemonSet(ds)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot be used as the name of a StorageCluster")
+
+	// Value of an argument is missing
+	ds.Spec.Template.Spec.Containers[0].Args = []string{"-c", "px-cluster", "-k"}
+	_, err = StorageClusterFromDaemonSet(ds)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing value for argument -k")
+
+	// Invalid start port
+	ds.Spec.Template.Spec.Containers[0].Args = []string{"-c", "px-cluster", "-r", "port"}
+	_, err = StorageClusterFromDaemonSet(ds)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid value \"port\" for argument -r")
+}
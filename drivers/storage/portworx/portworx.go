@@ -5,8 +5,10 @@ import (
 	"crypto/x509"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	version "github.com/hashicorp/go-version"
 	"github.com/libopenstorage/openstorage/api"
@@ -17,6 +19,7 @@ import (
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
 	"github.com/libopenstorage/operator/pkg/cloudstorage"
+	"github.com/libopenstorage/operator/pkg/metrics"
 	"github.com/libopenstorage/operator/pkg/util"
 	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
 	"github.com/portworx/sched-ops/k8s"
@@ -25,6 +28,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -48,7 +52,15 @@ const (
 	storageClusterDeleteMsg           = "Portworx service NOT removed. Portworx drives and data NOT wiped."
 	storageClusterUninstallMsg        = "Portworx service removed. Portworx drives and data NOT wiped."
 	storageClusterUninstallAndWipeMsg = "Portworx service removed. Portworx drives and data wiped."
+	deleteCompletedReason             = "DeleteCompleted"
+	nodeWiperStartedReason            = "NodeWiperStarted"
+	nodeWiperFailedReason             = "NodeWiperFailed"
+	wipeInProgressReason              = "WipeInProgress"
+	wipeMetadataFailedReason          = "WipeMetadataFailed"
 	labelPortworxVersion              = "PX Version"
+	// maxParallelComponents is the number of components that are reconciled
+	// at the same time
+	maxParallelComponents = 4
 )
 
 type portworx struct {
@@ -56,9 +68,15 @@ type portworx struct {
 	k8sVersion         *version.Version
 	scheme             *runtime.Scheme
 	recorder           record.EventRecorder
-	sdkConn            *grpc.ClientConn
+	sdkConns           map[types.NamespacedName]*grpc.ClientConn
+	sdkConnsLock       sync.Mutex
 	zoneToInstancesMap map[string]int
 	cloudProvider      string
+	// warnedAnnotations are the deprecated annotations and the generation of
+	// each cluster that were last warned about, so the warnings are not
+	// raised again on every reconcile
+	warnedAnnotations     map[types.NamespacedName]string
+	warnedAnnotationsLock sync.Mutex
 }
 
 func (p *portworx) String() string {
@@ -124,11 +142,21 @@ func (p *portworx) GetSelectorLabels() map[string]string {
 func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageCluster) {
 	releases, err := manifest.NewReleaseManifest()
 	if err != nil {
-		logrus.Warnf(err.Error())
+		logrus.Warn(err.Error())
 	}
 
+	p.migrateDeprecatedAnnotations(toUpdate)
+
+	// If the image is not specified, keep using the image that was last computed
+	// for the cluster, so an operator upgrade does not silently upgrade Portworx.
+	// Use the default image from the manifest only if nothing was computed yet.
 	if len(strings.TrimSpace(toUpdate.Spec.Image)) == 0 {
-		toUpdate.Spec.Image = defaultPortworxImage + ":" + defaultPortworxImageVersion(releases)
+		if toUpdate.Status.DesiredImages != nil &&
+			len(toUpdate.Status.DesiredImages.StorageDriver) > 0 {
+			toUpdate.Spec.Image = toUpdate.Status.DesiredImages.StorageDriver
+		} else {
+			toUpdate.Spec.Image = defaultPortworxImage + ":" + defaultPortworxImageVersion(releases)
+		}
 	}
 
 	t, err := newTemplate(toUpdate)
@@ -136,9 +164,14 @@ func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageClu
 		return
 	}
 
+	// The version from the spec is kept only if the image tag is not a valid
+	// version, as the version is needed to decide what features to enable
 	partitions := strings.Split(toUpdate.Spec.Image, ":")
 	if len(partitions) > 1 {
-		toUpdate.Spec.Version = partitions[len(partitions)-1]
+		tag := partitions[len(partitions)-1]
+		if _, err := version.NewSemver(tag); err == nil || toUpdate.Spec.Version == "" {
+			toUpdate.Spec.Version = tag
+		}
 	}
 
 	if toUpdate.Spec.Kvdb == nil {
@@ -150,6 +183,9 @@ func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageClu
 	if toUpdate.Spec.SecretsProvider == nil {
 		toUpdate.Spec.SecretsProvider = stringPtr(defaultSecretsProvider)
 	}
+	if toUpdate.Spec.PriorityClassName == "" {
+		toUpdate.Spec.PriorityClassName = pxutil.PortworxPriorityClassName
+	}
 	startPort := uint32(t.startPort)
 	toUpdate.Spec.StartPort = &startPort
 
@@ -167,14 +203,15 @@ func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageClu
 
 	setNodeSpecDefaults(toUpdate)
 
-	if toUpdate.Spec.Placement == nil || toUpdate.Spec.Placement.NodeAffinity == nil {
-		toUpdate.Spec.Placement = &corev1alpha1.PlacementSpec{
-			NodeAffinity: &v1.NodeAffinity{
-				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
-					NodeSelectorTerms: []v1.NodeSelectorTerm{
-						{
-							MatchExpressions: t.getSelectorRequirements(),
-						},
+	if toUpdate.Spec.Placement == nil {
+		toUpdate.Spec.Placement = &corev1alpha1.PlacementSpec{}
+	}
+	if toUpdate.Spec.Placement.NodeAffinity == nil {
+		toUpdate.Spec.Placement.NodeAffinity = &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{
+						MatchExpressions: t.getSelectorRequirements(),
 					},
 				},
 			},
@@ -183,15 +220,25 @@ func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageClu
 
 	components, err := componentVersions(releases, t.pxVersion)
 	if err != nil {
-		logrus.Warnf(err.Error())
+		logrus.Warn(err.Error())
+	}
+
+	// Images of locked components that are not specified fall back to the images
+	// last computed for the cluster, like the storage driver image above
+	desiredImages := &corev1alpha1.ComponentImages{}
+	if toUpdate.Status.DesiredImages != nil {
+		desiredImages = toUpdate.Status.DesiredImages
 	}
 
 	// Use the lighthouse image from release manifest if the current image is not locked,
-	// else keep using the existing image. If the current image is empty then use the
-	// default image from manifest else a hardcoded one if absent in manifest.
+	// else keep using the existing or last computed image. If there is no image yet then
+	// use the default image from manifest else a hardcoded one if absent in manifest.
 	if toUpdate.Spec.UserInterface != nil &&
 		toUpdate.Spec.UserInterface.Enabled {
 		toUpdate.Spec.UserInterface.Image = strings.TrimSpace(toUpdate.Spec.UserInterface.Image)
+		if toUpdate.Spec.UserInterface.LockImage && len(toUpdate.Spec.UserInterface.Image) == 0 {
+			toUpdate.Spec.UserInterface.Image = desiredImages.UserInterface
+		}
 		if len(components.Lighthouse) > 0 {
 			if !toUpdate.Spec.UserInterface.LockImage ||
 				len(toUpdate.Spec.UserInterface.Image) == 0 {
@@ -203,11 +250,14 @@ func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageClu
 	}
 
 	// Use the autopilot image from release manifest if the current image is not locked,
-	// else keep using the existing image. If the current image is empty then use the
-	// default image from manifest else a hardcoded one if absent in manifest.
+	// else keep using the existing or last computed image. If there is no image yet then
+	// use the default image from manifest else a hardcoded one if absent in manifest.
 	if toUpdate.Spec.Autopilot != nil &&
 		toUpdate.Spec.Autopilot.Enabled {
 		toUpdate.Spec.Autopilot.Image = strings.TrimSpace(toUpdate.Spec.Autopilot.Image)
+		if toUpdate.Spec.Autopilot.LockImage && len(toUpdate.Spec.Autopilot.Image) == 0 {
+			toUpdate.Spec.Autopilot.Image = desiredImages.Autopilot
+		}
 		if len(components.Autopilot) > 0 {
 			if !toUpdate.Spec.Autopilot.LockImage ||
 				len(toUpdate.Spec.Autopilot.Image) == 0 {
@@ -235,10 +285,13 @@ func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageClu
 		toUpdate.Spec.Stork = &corev1alpha1.StorkSpec{Enabled: true}
 	}
 	// Use the stork image from release manifest if the current image is not locked,
-	// else keep using the existing image. If the current image is empty then use the
-	// default image from manifest else a hardcoded one if absent in manifest.
+	// else keep using the existing or last computed image. If there is no image yet then
+	// use the default image from manifest else a hardcoded one if absent in manifest.
 	if toUpdate.Spec.Stork.Enabled {
 		toUpdate.Spec.Stork.Image = strings.TrimSpace(toUpdate.Spec.Stork.Image)
+		if toUpdate.Spec.Stork.LockImage && len(toUpdate.Spec.Stork.Image) == 0 {
+			toUpdate.Spec.Stork.Image = desiredImages.Stork
+		}
 		if len(components.Stork) > 0 {
 			if !toUpdate.Spec.Stork.LockImage ||
 				len(toUpdate.Spec.Stork.Image) == 0 {
@@ -251,42 +304,93 @@ func (p *portworx) SetDefaultsOnStorageCluster(toUpdate *corev1alpha1.StorageClu
 }
 
 func (p *portworx) PreInstall(cluster *corev1alpha1.StorageCluster) error {
+	enabled := make(map[string]bool)
 	for componentName, comp := range component.GetAll() {
-		if comp.IsEnabled(cluster) {
-			err := comp.Reconcile(cluster)
-			if ce, ok := err.(*component.Error); ok &&
-				ce.Code() == component.ErrCritical {
-				return err
-			} else if err != nil {
-				msg := fmt.Sprintf("Failed to setup %s. %v", componentName, err)
-				p.warningEvent(cluster, util.FailedComponentReason, msg)
+		enabled[componentName] = comp.IsEnabled(cluster)
+	}
+
+	results := component.RunInOrder(
+		maxParallelComponents,
+		enabled,
+		func(componentName string, comp component.PortworxComponent) error {
+			if enabled[componentName] {
+				return comp.Reconcile(cluster)
 			}
-		} else {
-			if err := comp.Delete(cluster); err != nil {
-				msg := fmt.Sprintf("Failed to cleanup %v. %v", componentName, err)
-				p.warningEvent(cluster, util.FailedComponentReason, msg)
+			return comp.Delete(cluster)
+		},
+	)
+
+	componentNames := make([]string, 0, len(results))
+	for componentName := range results {
+		componentNames = append(componentNames, componentName)
+	}
+	sort.Strings(componentNames)
+
+	var statuses []corev1alpha1.ComponentStatus
+	var criticalErrs []string
+	for _, componentName := range componentNames {
+		err := results[componentName]
+		if err == nil {
+			if enabled[componentName] {
+				statuses = append(statuses, corev1alpha1.ComponentStatus{
+					Name:   componentName,
+					Status: corev1alpha1.ClusterOperationCompleted,
+				})
 			}
+			continue
+		}
+
+		var msg string
+		if enabled[componentName] {
+			metrics.IncComponentFailures(cluster.Namespace, cluster.Name, componentName)
+			msg = fmt.Sprintf("Failed to setup %s. %v", componentName, err)
+		} else {
+			msg = fmt.Sprintf("Failed to cleanup %v. %v", componentName, err)
+		}
+		statuses = append(statuses, corev1alpha1.ComponentStatus{
+			Name:    componentName,
+			Status:  corev1alpha1.ClusterOperationFailed,
+			Message: msg,
+		})
+		if component.IsCritical(err) {
+			criticalErrs = append(criticalErrs, msg)
+		} else {
+			p.warningEvent(cluster, util.FailedComponentReason, msg)
 		}
 	}
+	cluster.Status.Components = statuses
+
+	if len(criticalErrs) > 0 {
+		return fmt.Errorf("%s", strings.Join(criticalErrs, "; "))
+	}
 	return nil
 }
 
 func (p *portworx) DeleteStorage(
 	cluster *corev1alpha1.StorageCluster,
 ) (*corev1alpha1.ClusterCondition, error) {
-	p.markComponentsAsDeleted()
+	p.markComponentsAsDeleted(cluster)
+	// The SDK is not used once the cluster is being deleted
+	p.closePortworxClient(cluster)
 
 	if cluster.Spec.DeleteStrategy == nil {
 		// No Delete strategy provided. Do not wipe portworx
 		status := &corev1alpha1.ClusterCondition{
-			Type:   corev1alpha1.ClusterConditionTypeDelete,
-			Status: corev1alpha1.ClusterOperationCompleted,
-			Reason: storageClusterDeleteMsg,
+			Type:    corev1alpha1.ClusterConditionTypeDelete,
+			Status:  corev1alpha1.ClusterOperationCompleted,
+			Reason:  deleteCompletedReason,
+			Message: storageClusterDeleteMsg,
 		}
 		return status, nil
 	}
 
-	// Portworx needs to be removed if DeleteStrategy is specified
+	// Portworx needs to be removed if DeleteStrategy is specified. The disruption
+	// budget is removed first, so that it does not block draining nodes while
+	// the portworx pods are being removed.
+	if err := p.deleteStoragePodDisruptionBudget(cluster); err != nil {
+		return nil, fmt.Errorf("failed to delete PodDisruptionBudget for portworx pods: %v", err)
+	}
+
 	removeData := false
 	completeMsg := storageClusterUninstallMsg
 	if cluster.Spec.DeleteStrategy.Type == corev1alpha1.UninstallAndWipeStorageClusterStrategyType {
@@ -301,20 +405,24 @@ func (p *portworx) DeleteStorage(
 		nodeWiperImage := k8sutil.GetValueFromEnv(envKeyNodeWiperImage, cluster.Spec.Env)
 		if err := u.RunNodeWiper(nodeWiperImage, removeData); err != nil {
 			return &corev1alpha1.ClusterCondition{
-				Type:   corev1alpha1.ClusterConditionTypeDelete,
-				Status: corev1alpha1.ClusterOperationFailed,
-				Reason: "Failed to run node wiper: " + err.Error(),
+				Type:    corev1alpha1.ClusterConditionTypeDelete,
+				Status:  corev1alpha1.ClusterOperationFailed,
+				Reason:  nodeWiperFailedReason,
+				Message: "Failed to run node wiper: " + err.Error(),
 			}, nil
 		}
 		return &corev1alpha1.ClusterCondition{
-			Type:   corev1alpha1.ClusterConditionTypeDelete,
-			Status: corev1alpha1.ClusterOperationInProgress,
-			Reason: "Started node wiper daemonset",
+			Type:    corev1alpha1.ClusterConditionTypeDelete,
+			Status:  corev1alpha1.ClusterOperationInProgress,
+			Reason:  nodeWiperStartedReason,
+			Message: "Started node wiper daemonset",
 		}, nil
 	} else if err != nil {
 		// We could not get the node wiper status and it does exist
 		return nil, err
 	}
+	metrics.SetNodeWiperProgress(cluster.Namespace, cluster.Name,
+		int(completed), int(inProgress), int(total))
 
 	if completed != 0 && total != 0 && completed == total {
 		// all the nodes are wiped
@@ -323,29 +431,33 @@ func (p *portworx) DeleteStorage(
 			if err := u.WipeMetadata(); err != nil {
 				logrus.Errorf("Failed to delete portworx metadata: %v", err)
 				return &corev1alpha1.ClusterCondition{
-					Type:   corev1alpha1.ClusterConditionTypeDelete,
-					Status: corev1alpha1.ClusterOperationFailed,
-					Reason: "Failed to wipe metadata: " + err.Error(),
+					Type:    corev1alpha1.ClusterConditionTypeDelete,
+					Status:  corev1alpha1.ClusterOperationFailed,
+					Reason:  wipeMetadataFailedReason,
+					Message: "Failed to wipe metadata: " + err.Error(),
 				}, nil
 			}
 		}
 		return &corev1alpha1.ClusterCondition{
-			Type:   corev1alpha1.ClusterConditionTypeDelete,
-			Status: corev1alpha1.ClusterOperationCompleted,
-			Reason: completeMsg,
+			Type:    corev1alpha1.ClusterConditionTypeDelete,
+			Status:  corev1alpha1.ClusterOperationCompleted,
+			Reason:  deleteCompletedReason,
+			Message: completeMsg,
 		}, nil
 	}
 
 	return &corev1alpha1.ClusterCondition{
 		Type:   corev1alpha1.ClusterConditionTypeDelete,
 		Status: corev1alpha1.ClusterOperationInProgress,
-		Reason: fmt.Sprintf("Wipe operation still in progress: Completed [%v] In Progress [%v] Total [%v]", completed, inProgress, total),
+		Reason: wipeInProgressReason,
+		Message: fmt.Sprintf("Wipe operation still in progress: Completed [%v] In Progress [%v] Total [%v]",
+			completed, inProgress, total),
 	}, nil
 }
 
-func (p *portworx) markComponentsAsDeleted() {
+func (p *portworx) markComponentsAsDeleted(cluster *corev1alpha1.StorageCluster) {
 	for _, comp := range component.GetAll() {
-		comp.MarkDeleted()
+		comp.MarkDeleted(cluster)
 	}
 }
 
@@ -366,10 +478,7 @@ func (p *portworx) UpdateStorageClusterStatus(
 	clusterClient := api.NewOpenStorageClusterClient(clientConn)
 	pxCluster, err := clusterClient.InspectCurrent(context.TODO(), &api.SdkClusterInspectCurrentRequest{})
 	if err != nil {
-		if closeErr := p.sdkConn.Close(); closeErr != nil {
-			logrus.Warnf("Failed to close grpc connection. %v", closeErr)
-		}
-		p.sdkConn = nil
+		p.closePortworxClient(cluster)
 		return fmt.Errorf("failed to inspect cluster: %v", err)
 	} else if pxCluster.Cluster == nil {
 		return fmt.Errorf("empty ClusterInspect response")
@@ -396,24 +505,22 @@ func (p *portworx) updateStorageNodes(
 	}
 
 	currentNodes := make(map[string]bool)
+	nodeCounts := make(map[corev1alpha1.ConditionStatus]int)
+	clusterStorage := corev1alpha1.Storage{
+		StorageNodesPerZone: cluster.Status.Storage.StorageNodesPerZone,
+	}
 
 	ownerRef := metav1.NewControllerRef(cluster, pxutil.StorageClusterKind())
 	for _, node := range nodeEnumerateResponse.Nodes {
-		if node.SchedulerNodeName == "" {
-			k8sNode, err := k8s.Instance().SearchNodeByAddresses(
-				[]string{node.DataIp, node.MgmtIp, node.Hostname},
-			)
-			if err != nil {
-				msg := fmt.Sprintf("Unable to find kubernetes node name for nodeID %v: %v", node.Id, err)
-				p.warningEvent(cluster, util.FailedSyncReason, msg)
-				continue
-			}
-			node.SchedulerNodeName = k8sNode.Name
+		if err := setSchedulerNodeName(node); err != nil {
+			p.warningEvent(cluster, util.FailedSyncReason, err.Error())
+			continue
 		}
 
 		currentNodes[node.SchedulerNodeName] = true
 
 		phase := mapNodeStatus(node.Status)
+		nodeCounts[phase]++
 		storageNode := &corev1alpha1.StorageNode{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:            node.SchedulerNodeName,
@@ -435,19 +542,35 @@ func (p *portworx) updateStorageNodes(
 						Status: phase,
 					},
 				},
+				Storage: nodeStorageStatus(node),
 			},
 		}
 
+		if len(node.Pools) > 0 {
+			clusterStorage.StorageNodes++
+		} else {
+			clusterStorage.StoragelessNodes++
+		}
+		clusterStorage.TotalSize.Add(storageNode.Status.Storage.TotalSize)
+		clusterStorage.UsedSize.Add(storageNode.Status.Storage.UsedSize)
+
+		var desiredVersion string
+		partitions := strings.Split(cluster.Spec.Image, ":")
+		if len(partitions) > 1 {
+			desiredVersion = partitions[len(partitions)-1]
+		}
 		if version, ok := node.NodeLabels[labelPortworxVersion]; ok {
 			storageNode.Spec = corev1alpha1.StorageNodeSpec{
 				Version: version,
 			}
-		} else {
-			partitions := strings.Split(cluster.Spec.Image, ":")
-			if len(partitions) > 1 {
-				storageNode.Spec = corev1alpha1.StorageNodeSpec{
-					Version: partitions[len(partitions)-1],
-				}
+			// The running version has the build appended to the version in the image
+			// tag, so the node is behind only if it does not start with the tag
+			if desiredVersion != "" && !strings.HasPrefix(version, desiredVersion) {
+				storageNode.Status.DesiredVersion = desiredVersion
+			}
+		} else if desiredVersion != "" {
+			storageNode.Spec = corev1alpha1.StorageNodeSpec{
+				Version: desiredVersion,
 			}
 		}
 
@@ -457,13 +580,34 @@ func (p *portworx) updateStorageNodes(
 			p.warningEvent(cluster, util.FailedSyncReason, msg)
 		}
 	}
+	metrics.SetStorageNodeCounts(cluster.Namespace, cluster.Name, nodeCounts)
+
+	// Recompute the disruption budget of the portworx pods as nodes join or leave
+	if err := p.createStoragePodDisruptionBudget(cluster, nodeEnumerateResponse.Nodes); err != nil {
+		msg := fmt.Sprintf("Failed to setup PodDisruptionBudget for portworx pods. %v", err)
+		p.warningEvent(cluster, util.FailedComponentReason, msg)
+	}
+	clusterStorage.AvailableSize = clusterStorage.TotalSize.DeepCopy()
+	clusterStorage.AvailableSize.Sub(clusterStorage.UsedSize)
+	cluster.Status.Storage = clusterStorage
 
 	nodeStatusList := &corev1alpha1.StorageNodeList{}
-	if err = p.k8sClient.List(context.TODO(), nodeStatusList, &client.ListOptions{}); err != nil {
+	err = p.k8sClient.List(
+		context.TODO(),
+		nodeStatusList,
+		&client.ListOptions{Namespace: cluster.Namespace},
+	)
+	if err != nil {
 		return fmt.Errorf("failed to get a list of StorageNode: %v", err)
 	}
 
+	// Only delete the StorageNodes of this cluster, as other clusters
+	// manage their own StorageNodes
 	for _, nodeStatus := range nodeStatusList.Items {
+		owner := metav1.GetControllerOf(&nodeStatus)
+		if owner == nil || owner.UID != cluster.UID {
+			continue
+		}
 		if _, exists := currentNodes[nodeStatus.Name]; !exists {
 			logrus.Debugf("Deleting orphan StorageNode %v/%v",
 				nodeStatus.Namespace, nodeStatus.Name)
@@ -478,11 +622,125 @@ func (p *portworx) updateStorageNodes(
 	return nil
 }
 
+func (p *portworx) CanUpdateStoragePods(
+	cluster *corev1alpha1.StorageCluster,
+	updatedNodes []string,
+) error {
+	clientConn, err := p.getPortworxClient(cluster)
+	if err != nil {
+		return err
+	}
+
+	clusterClient := api.NewOpenStorageClusterClient(clientConn)
+	pxCluster, err := clusterClient.InspectCurrent(context.TODO(), &api.SdkClusterInspectCurrentRequest{})
+	if err != nil {
+		p.closePortworxClient(cluster)
+		return fmt.Errorf("failed to inspect cluster: %v", err)
+	} else if pxCluster.Cluster == nil {
+		return fmt.Errorf("empty ClusterInspect response")
+	}
+	if status := mapClusterStatus(pxCluster.Cluster.Status); status != corev1alpha1.ClusterOnline {
+		return fmt.Errorf("portworx cluster is %s", status)
+	}
+
+	nodeClient := api.NewOpenStorageNodeClient(clientConn)
+	nodeEnumerateResponse, err := nodeClient.EnumerateWithFilters(
+		context.TODO(),
+		&api.SdkNodeEnumerateWithFiltersRequest{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enumerate nodes: %v", err)
+	}
+	pxNodes := make(map[string]*api.StorageNode)
+	for _, node := range nodeEnumerateResponse.Nodes {
+		if err := setSchedulerNodeName(node); err != nil {
+			logrus.Debug(err)
+			continue
+		}
+		pxNodes[node.SchedulerNodeName] = node
+	}
+	updatedNodeIDs := make(map[string]bool)
+	for _, nodeName := range updatedNodes {
+		node, exists := pxNodes[nodeName]
+		if !exists {
+			return fmt.Errorf("portworx on node %s has not joined the cluster", nodeName)
+		} else if status := mapNodeStatus(node.Status); status != corev1alpha1.NodeOnline {
+			return fmt.Errorf("portworx on node %s is %s", nodeName, status)
+		}
+		updatedNodeIDs[node.Id] = true
+	}
+	if len(updatedNodeIDs) == 0 {
+		return nil
+	}
+
+	// The SDK cannot filter volumes by node, so only the degraded volumes
+	// with replicas on the updated nodes are considered below.
+	volumeClient := api.NewOpenStorageVolumeClient(clientConn)
+	volumeInspectResponse, err := volumeClient.InspectWithFilters(
+		context.TODO(),
+		&api.SdkVolumeInspectWithFiltersRequest{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to inspect volumes: %v", err)
+	}
+	var degradedVolumes []string
+	for _, volume := range volumeInspectResponse.Volumes {
+		if volume.Volume != nil &&
+			volume.Volume.Status == api.VolumeStatus_VOLUME_STATUS_DEGRADED &&
+			hasReplicaOnNodes(volume.Volume, updatedNodeIDs) {
+			degradedVolumes = append(degradedVolumes, volume.Name)
+		}
+	}
+	if len(degradedVolumes) > 0 {
+		return fmt.Errorf("portworx volumes %v are degraded", degradedVolumes)
+	}
+	return nil
+}
+
+// hasReplicaOnNodes returns true if any replica of the given volume is on
+// one of the given portworx nodes
+func hasReplicaOnNodes(volume *api.Volume, nodeIDs map[string]bool) bool {
+	for _, replicaSet := range volume.ReplicaSets {
+		if replicaSet == nil {
+			continue
+		}
+		for _, nodeID := range replicaSet.Nodes {
+			if nodeIDs[nodeID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setSchedulerNodeName sets the name of the kubernetes node on the given
+// portworx node, if it is not already set, by searching the node addresses
+func setSchedulerNodeName(node *api.StorageNode) error {
+	if node.SchedulerNodeName != "" {
+		return nil
+	}
+	k8sNode, err := k8s.Instance().SearchNodeByAddresses(
+		[]string{node.DataIp, node.MgmtIp, node.Hostname},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to find kubernetes node name for nodeID %v: %v", node.Id, err)
+	}
+	node.SchedulerNodeName = k8sNode.Name
+	return nil
+}
+
+// getPortworxClient returns the SDK connection to the Portworx cluster of the
+// given StorageCluster. The connection is cached per StorageCluster, as each
+// of them runs its own Portworx cluster.
 func (p *portworx) getPortworxClient(
 	cluster *corev1alpha1.StorageCluster,
 ) (*grpc.ClientConn, error) {
-	if p.sdkConn != nil {
-		return p.sdkConn, nil
+	p.sdkConnsLock.Lock()
+	defer p.sdkConnsLock.Unlock()
+
+	key := types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}
+	if conn, exists := p.sdkConns[key]; exists {
+		return conn, nil
 	}
 
 	pxService := &v1.Service{}
@@ -511,7 +769,30 @@ func (p *portworx) getPortworxClient(
 	}
 
 	endpoint = fmt.Sprintf("%s:%d", endpoint, sdkPort)
-	return p.getGrpcConn(endpoint)
+	conn, err := p.getGrpcConn(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if p.sdkConns == nil {
+		p.sdkConns = make(map[types.NamespacedName]*grpc.ClientConn)
+	}
+	p.sdkConns[key] = conn
+	return conn, nil
+}
+
+// closePortworxClient closes the cached SDK connection of the given
+// StorageCluster, so a new connection is made the next time
+func (p *portworx) closePortworxClient(cluster *corev1alpha1.StorageCluster) {
+	p.sdkConnsLock.Lock()
+	defer p.sdkConnsLock.Unlock()
+
+	key := types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}
+	if conn, exists := p.sdkConns[key]; exists {
+		if err := conn.Close(); err != nil {
+			logrus.Warnf("Failed to close grpc connection. %v", err)
+		}
+		delete(p.sdkConns, key)
+	}
 }
 
 func (p *portworx) warningEvent(
@@ -527,11 +808,12 @@ func (p *portworx) getGrpcConn(endpoint string) (*grpc.ClientConn, error) {
 	if err != nil {
 		return nil, err
 	}
-	p.sdkConn, err = grpcserver.Connect(endpoint, dialOptions)
+	dialOptions = append(dialOptions, grpc.WithUnaryInterceptor(metrics.SDKUnaryClientInterceptor()))
+	conn, err := grpcserver.Connect(endpoint, dialOptions)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to GRPC server [%s]: %v", endpoint, err)
 	}
-	return p.sdkConn, nil
+	return conn, nil
 }
 
 func (p *portworx) storageNodeToCloudSpec(storageNodes []*corev1alpha1.StorageNode, cluster *corev1alpha1.StorageCluster) *cloudstorage.Config {
@@ -608,6 +890,68 @@ func mapClusterStatus(status api.Status) corev1alpha1.ClusterConditionStatus {
 	}
 }
 
+// nodeStorageStatus returns the capacity, pools and drives of the given node.
+// The capacity of the node is the sum of the capacity of its storage pools.
+func nodeStorageStatus(node *api.StorageNode) corev1alpha1.NodeStorageStatus {
+	storage := corev1alpha1.NodeStorageStatus{
+		TotalSize: *resource.NewQuantity(0, resource.BinarySI),
+		UsedSize:  *resource.NewQuantity(0, resource.BinarySI),
+	}
+	for _, pool := range node.Pools {
+		if pool == nil {
+			continue
+		}
+		totalSize := resource.NewQuantity(int64(pool.TotalSize), resource.BinarySI)
+		usedSize := resource.NewQuantity(int64(pool.Used), resource.BinarySI)
+		storage.TotalSize.Add(*totalSize)
+		storage.UsedSize.Add(*usedSize)
+		storage.Pools = append(storage.Pools, corev1alpha1.StoragePoolStatus{
+			ID:        pool.ID,
+			Medium:    mapStorageMedium(pool.Medium),
+			TotalSize: *totalSize,
+			UsedSize:  *usedSize,
+			Labels:    pool.Labels,
+		})
+	}
+	sort.Slice(storage.Pools, func(i, j int) bool {
+		return storage.Pools[i].ID < storage.Pools[j].ID
+	})
+
+	// Sort the drives, so the status does not change with the order of the map
+	driveIDs := make([]string, 0, len(node.Disks))
+	for driveID, drive := range node.Disks {
+		if drive != nil {
+			driveIDs = append(driveIDs, driveID)
+		}
+	}
+	sort.Strings(driveIDs)
+	for _, driveID := range driveIDs {
+		drive := node.Disks[driveID]
+		storage.Drives = append(storage.Drives, corev1alpha1.StorageDriveStatus{
+			ID:        drive.Id,
+			Path:      drive.Path,
+			Medium:    mapStorageMedium(drive.Medium),
+			Online:    drive.Online,
+			TotalSize: *resource.NewQuantity(int64(drive.Size), resource.BinarySI),
+			UsedSize:  *resource.NewQuantity(int64(drive.Used), resource.BinarySI),
+			Metadata:  drive.Metadata,
+		})
+	}
+	return storage
+}
+
+func mapStorageMedium(medium api.StorageMedium) string {
+	switch medium {
+	case api.StorageMedium_STORAGE_MEDIUM_MAGNETIC:
+		return "Magnetic"
+	case api.StorageMedium_STORAGE_MEDIUM_SSD:
+		return "SSD"
+	case api.StorageMedium_STORAGE_MEDIUM_NVME:
+		return "NVMe"
+	}
+	return ""
+}
+
 func mapNodeStatus(status api.Status) corev1alpha1.ConditionStatus {
 	switch status {
 	case api.Status_STATUS_NONE:
@@ -681,6 +1025,81 @@ func defaultPortworxImageVersion(releases *manifest.ReleaseManifest) string {
 	return defaultPortworxVersion
 }
 
+// migrateDeprecatedAnnotations copies the values of the deprecated annotations
+// to the spec fields that replace them, unless the fields are already set.
+// A warning event is raised for every deprecated annotation still in use, once
+// per generation of the cluster or when the deprecated annotations change.
+func (p *portworx) migrateDeprecatedAnnotations(toUpdate *corev1alpha1.StorageCluster) {
+	var annotations []string
+	for annotation := range pxutil.DeprecatedAnnotations {
+		if _, exists := toUpdate.Annotations[annotation]; exists {
+			annotations = append(annotations, annotation)
+		}
+	}
+	sort.Strings(annotations)
+	warn := p.shouldWarnAboutAnnotations(toUpdate, annotations)
+	if len(annotations) == 0 {
+		return
+	}
+
+	if warn {
+		for _, annotation := range annotations {
+			p.recorder.Event(toUpdate, v1.EventTypeWarning, util.DeprecatedAnnotationReason,
+				fmt.Sprintf("Annotation %s is deprecated, use %s instead",
+					annotation, pxutil.DeprecatedAnnotations[annotation]))
+		}
+	}
+
+	toUpdate.Spec.Platform = pxutil.Platform(toUpdate)
+	if enabled := pxutil.PVCControllerEnabled(toUpdate); enabled != nil {
+		if toUpdate.Spec.PVCController == nil {
+			toUpdate.Spec.PVCController = &corev1alpha1.PVCControllerSpec{}
+		}
+		toUpdate.Spec.PVCController.Enabled = enabled
+	}
+	toUpdate.Spec.LogFile = pxutil.LogFile(toUpdate)
+	if extraArgs, err := pxutil.ExtraArgs(toUpdate); err == nil {
+		toUpdate.Spec.ExtraArgs = extraArgs
+	} else if warn {
+		p.warningEvent(toUpdate, util.DeprecatedAnnotationReason,
+			fmt.Sprintf("Failed to parse annotation %s: %v", pxutil.AnnotationMiscArgs, err))
+	}
+	if serviceType := pxutil.ServiceType(toUpdate); serviceType != "" {
+		if toUpdate.Spec.Service == nil {
+			toUpdate.Spec.Service = &corev1alpha1.ServiceSpec{}
+		}
+		toUpdate.Spec.Service.Type = serviceType
+	}
+	if toUpdate.Spec.Version == "" {
+		toUpdate.Spec.Version = toUpdate.Annotations[pxutil.AnnotationPXVersion]
+	}
+}
+
+// shouldWarnAboutAnnotations returns true if the given deprecated annotations
+// of the cluster have not been warned about for the current generation
+func (p *portworx) shouldWarnAboutAnnotations(
+	cluster *corev1alpha1.StorageCluster,
+	annotations []string,
+) bool {
+	p.warnedAnnotationsLock.Lock()
+	defer p.warnedAnnotationsLock.Unlock()
+
+	key := types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}
+	if len(annotations) == 0 {
+		delete(p.warnedAnnotations, key)
+		return false
+	}
+	warned := fmt.Sprintf("%d/%s", cluster.Generation, strings.Join(annotations, ","))
+	if p.warnedAnnotations[key] == warned {
+		return false
+	}
+	if p.warnedAnnotations == nil {
+		p.warnedAnnotations = make(map[types.NamespacedName]string)
+	}
+	p.warnedAnnotations[key] = warned
+	return true
+}
+
 func setNodeSpecDefaults(toUpdate *corev1alpha1.StorageCluster) {
 	if len(toUpdate.Spec.Nodes) == 0 {
 		return
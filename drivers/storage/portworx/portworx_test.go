@@ -3,6 +3,7 @@ package portworx
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"strconv"
@@ -15,6 +16,7 @@ import (
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
 	"github.com/libopenstorage/operator/pkg/mock"
+	"github.com/libopenstorage/operator/pkg/util"
 	testutil "github.com/libopenstorage/operator/pkg/util/test"
 	"github.com/portworx/kvdb"
 	"github.com/portworx/kvdb/consul"
@@ -134,6 +136,12 @@ func TestSetDefaultsOnStorageCluster(t *testing.T) {
 	require.Equal(t, uint32(pxutil.DefaultStartPort), *cluster.Spec.StartPort)
 	require.True(t, *cluster.Spec.Storage.UseAll)
 	require.Equal(t, expectedPlacement, cluster.Spec.Placement)
+	require.Equal(t, pxutil.PortworxPriorityClassName, cluster.Spec.PriorityClassName)
+
+	// Don't overwrite the priority class given by the user
+	cluster.Spec.PriorityClassName = "system-node-critical"
+	driver.SetDefaultsOnStorageCluster(cluster)
+	require.Equal(t, "system-node-critical", cluster.Spec.PriorityClassName)
 
 	// Use default image from release manifest when spec.image has empty value
 	manifestSetup()
@@ -142,6 +150,17 @@ func TestSetDefaultsOnStorageCluster(t *testing.T) {
 	driver.SetDefaultsOnStorageCluster(cluster)
 	require.Equal(t, "portworx/oci-monitor:2.1.5.1", cluster.Spec.Image)
 
+	// Use the previously computed image from status instead of the default
+	// image from release manifest when spec.image is empty
+	cluster.Spec.Image = ""
+	cluster.Status.DesiredImages = &corev1alpha1.ComponentImages{
+		StorageDriver: "portworx/oci-monitor:2.1.4",
+	}
+	driver.SetDefaultsOnStorageCluster(cluster)
+	require.Equal(t, "portworx/oci-monitor:2.1.4", cluster.Spec.Image)
+	require.Equal(t, "2.1.4", cluster.Spec.Version)
+	cluster.Status.DesiredImages = nil
+
 	// Don't use default image when spec.image has a value
 	cluster.Spec.Image = "foo/image:1.0.0"
 	driver.SetDefaultsOnStorageCluster(cluster)
@@ -244,6 +263,21 @@ func TestSetDefaultsOnStorageCluster(t *testing.T) {
 	cluster.Spec.Placement = &corev1alpha1.PlacementSpec{}
 	driver.SetDefaultsOnStorageCluster(cluster)
 	require.Equal(t, expectedPlacement, cluster.Spec.Placement)
+
+	// Keep the tolerations when adding the default node affinity
+	tolerations := []v1.Toleration{
+		{
+			Key:      "storage",
+			Operator: v1.TolerationOpExists,
+			Effect:   v1.TaintEffectNoSchedule,
+		},
+	}
+	cluster.Spec.Placement = &corev1alpha1.PlacementSpec{
+		Tolerations: tolerations,
+	}
+	driver.SetDefaultsOnStorageCluster(cluster)
+	require.Equal(t, expectedPlacement.NodeAffinity, cluster.Spec.Placement.NodeAffinity)
+	require.Equal(t, tolerations, cluster.Spec.Placement.Tolerations)
 }
 
 func TestStorageClusterDefaultsForLighthouse(t *testing.T) {
@@ -525,6 +559,87 @@ func TestStorageClusterDefaultsForStork(t *testing.T) {
 	require.Equal(t, defaultStorkImage, cluster.Spec.Stork.Image)
 }
 
+func TestStorageClusterDefaultsKeepLockedImagesOnManifestChange(t *testing.T) {
+	os.Setenv(manifest.EnvKeyReleaseManifestURL, "foo")
+	os.RemoveAll(manifest.ManifestDir)
+	err := os.Mkdir(manifest.ManifestDir, 0755)
+	require.NoError(t, err)
+	defer manifestCleanup()
+	writeManifest := func(componentVersion string) {
+		content := fmt.Sprintf(`defaultRelease: 2.1.5.1
+releases:
+  2.1.5.1:
+    stork: openstorage/stork:%[1]s
+    lighthouse: portworx/px-lighthouse:%[1]s
+    autopilot: portworx/autopilot:%[1]s
+`, componentVersion)
+		err := ioutil.WriteFile(
+			path.Join(manifest.ManifestDir, manifest.LocalReleaseManifest),
+			[]byte(content), 0644,
+		)
+		require.NoError(t, err)
+	}
+
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	driver := portworx{}
+	userSpec := corev1alpha1.StorageClusterSpec{
+		Image: "px/image:2.1.5.1",
+		Stork: &corev1alpha1.StorkSpec{
+			Enabled:   true,
+			LockImage: true,
+		},
+		UserInterface: &corev1alpha1.UserInterfaceSpec{
+			Enabled:   true,
+			LockImage: true,
+		},
+		Autopilot: &corev1alpha1.AutopilotSpec{
+			Enabled:   true,
+			LockImage: true,
+		},
+	}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: *userSpec.DeepCopy(),
+	}
+
+	// Locked components without images should use the images from the manifest
+	// if no images have been computed for the cluster yet
+	writeManifest("2.3.4")
+	driver.SetDefaultsOnStorageCluster(cluster)
+	require.Equal(t, "openstorage/stork:2.3.4", cluster.Spec.Stork.Image)
+	require.Equal(t, "portworx/px-lighthouse:2.3.4", cluster.Spec.UserInterface.Image)
+	require.Equal(t, "portworx/autopilot:2.3.4", cluster.Spec.Autopilot.Image)
+
+	// The defaulted spec is not saved, so the next pass starts from the user's
+	// spec again. Locked components should keep the images computed before,
+	// even if the manifest has changed since then.
+	cluster.Status.DesiredImages = &corev1alpha1.ComponentImages{
+		StorageDriver: cluster.Spec.Image,
+		Stork:         cluster.Spec.Stork.Image,
+		UserInterface: cluster.Spec.UserInterface.Image,
+		Autopilot:     cluster.Spec.Autopilot.Image,
+	}
+	cluster.Spec = *userSpec.DeepCopy()
+	writeManifest("2.4.0")
+	driver.SetDefaultsOnStorageCluster(cluster)
+	require.Equal(t, "openstorage/stork:2.3.4", cluster.Spec.Stork.Image)
+	require.Equal(t, "portworx/px-lighthouse:2.3.4", cluster.Spec.UserInterface.Image)
+	require.Equal(t, "portworx/autopilot:2.3.4", cluster.Spec.Autopilot.Image)
+
+	// Components that are not locked should use the images from the new manifest
+	cluster.Spec = *userSpec.DeepCopy()
+	cluster.Spec.Stork.LockImage = false
+	cluster.Spec.UserInterface.LockImage = false
+	cluster.Spec.Autopilot.LockImage = false
+	driver.SetDefaultsOnStorageCluster(cluster)
+	require.Equal(t, "openstorage/stork:2.4.0", cluster.Spec.Stork.Image)
+	require.Equal(t, "portworx/px-lighthouse:2.4.0", cluster.Spec.UserInterface.Image)
+	require.Equal(t, "portworx/autopilot:2.4.0", cluster.Spec.Autopilot.Image)
+}
+
 func TestStorageClusterDefaultsForNodeSpecs(t *testing.T) {
 	manifestSetup()
 	defer manifestCleanup()
@@ -715,7 +830,8 @@ func TestSetDefaultsOnStorageClusterForOpenshift(t *testing.T) {
 	defer manifestCleanup()
 
 	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
-	driver := portworx{}
+	recorder := record.NewFakeRecorder(10)
+	driver := portworx{recorder: recorder}
 	cluster := &corev1alpha1.StorageCluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "px-cluster",
@@ -758,6 +874,118 @@ func TestSetDefaultsOnStorageClusterForOpenshift(t *testing.T) {
 	require.Equal(t, defaultSecretsProvider, *cluster.Spec.SecretsProvider)
 	require.Equal(t, uint32(pxutil.DefaultStartPort), *cluster.Spec.StartPort)
 	require.Equal(t, expectedPlacement, cluster.Spec.Placement)
+	require.Equal(t, corev1alpha1.PlatformOpenShift, cluster.Spec.Platform)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, fmt.Sprintf("%v %v Annotation %s is deprecated, use spec.platform instead",
+		v1.EventTypeWarning, util.DeprecatedAnnotationReason, annotationIsOpenshift),
+		<-recorder.Events)
+}
+
+func TestSetDefaultsMigratesDeprecatedAnnotations(t *testing.T) {
+	manifestSetup()
+	defer manifestCleanup()
+
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	recorder := record.NewFakeRecorder(20)
+	driver := portworx{recorder: recorder}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				annotationIsPKS:         "true",
+				annotationPVCController: "false",
+				annotationLogFile:       "/tmp/px.log",
+				annotationMiscArgs:      "-fruit apple -person 'john doe'",
+				annotationServiceType:   "NodePort",
+				annotationPXVersion:     "2.1.5",
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Image: "portworx/oci-monitor:custom",
+		},
+	}
+
+	driver.SetDefaultsOnStorageCluster(cluster)
+
+	require.Equal(t, corev1alpha1.PlatformPKS, cluster.Spec.Platform)
+	require.False(t, *cluster.Spec.PVCController.Enabled)
+	require.Equal(t, "/tmp/px.log", cluster.Spec.LogFile)
+	require.Equal(t, []string{"-fruit", "apple", "-person", "john doe"}, cluster.Spec.ExtraArgs)
+	require.Equal(t, v1.ServiceTypeNodePort, cluster.Spec.Service.Type)
+	require.Equal(t, "2.1.5", cluster.Spec.Version)
+	require.Len(t, recorder.Events, 6)
+	require.Equal(t, fmt.Sprintf("%v %v Annotation %s is deprecated, use spec.platform instead",
+		v1.EventTypeWarning, util.DeprecatedAnnotationReason, annotationIsPKS),
+		<-recorder.Events)
+	require.Equal(t, fmt.Sprintf("%v %v Annotation %s is deprecated, use spec.logFile instead",
+		v1.EventTypeWarning, util.DeprecatedAnnotationReason, annotationLogFile),
+		<-recorder.Events)
+
+	// The warnings should not be raised again for the same generation
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+
+	driver.SetDefaultsOnStorageCluster(cluster)
+
+	require.Empty(t, recorder.Events)
+
+	// Fields that are already set in the spec should not be overwritten
+	cluster.Generation++
+	cluster.Spec = corev1alpha1.StorageClusterSpec{
+		Image:    "portworx/oci-monitor:2.3.0",
+		Platform: corev1alpha1.PlatformOpenShift,
+		PVCController: &corev1alpha1.PVCControllerSpec{
+			Enabled: boolPtr(true),
+		},
+		CommonConfig: corev1alpha1.CommonConfig{
+			LogFile:   "/var/log/px.log",
+			ExtraArgs: []string{"-fruit", "banana"},
+		},
+		Service: &corev1alpha1.ServiceSpec{
+			Type: v1.ServiceTypeLoadBalancer,
+		},
+	}
+
+	driver.SetDefaultsOnStorageCluster(cluster)
+
+	require.Equal(t, corev1alpha1.PlatformOpenShift, cluster.Spec.Platform)
+	require.True(t, *cluster.Spec.PVCController.Enabled)
+	require.Equal(t, "/var/log/px.log", cluster.Spec.LogFile)
+	require.Equal(t, []string{"-fruit", "banana"}, cluster.Spec.ExtraArgs)
+	require.Equal(t, v1.ServiceTypeLoadBalancer, cluster.Spec.Service.Type)
+	// The version from the image tag takes precedence
+	require.Equal(t, "2.3.0", cluster.Spec.Version)
+	require.Len(t, recorder.Events, 6)
+
+	// Invalid arguments in the annotation should be reported, along with the
+	// warning for the changed annotations
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	cluster.Spec.ExtraArgs = nil
+	cluster.Annotations = map[string]string{
+		annotationMiscArgs: "-person 'john doe",
+	}
+
+	driver.SetDefaultsOnStorageCluster(cluster)
+
+	require.Empty(t, cluster.Spec.ExtraArgs)
+	require.Len(t, recorder.Events, 2)
+	require.Equal(t, fmt.Sprintf("%v %v Annotation %s is deprecated, use spec.extraArgs instead",
+		v1.EventTypeWarning, util.DeprecatedAnnotationReason, annotationMiscArgs),
+		<-recorder.Events)
+	require.Contains(t, <-recorder.Events,
+		fmt.Sprintf("%v %v Failed to parse annotation %s:",
+			v1.EventTypeWarning, util.DeprecatedAnnotationReason, annotationMiscArgs))
+
+	// No events should be raised if the deprecated annotations are not used
+	cluster.Annotations = nil
+
+	driver.SetDefaultsOnStorageCluster(cluster)
+
+	require.Empty(t, recorder.Events)
 }
 
 func TestSetDefaultsOnStorageClusterOnError(t *testing.T) {
@@ -1044,6 +1272,256 @@ func TestUpdateClusterStatus(t *testing.T) {
 	require.Equal(t, "Unknown", cluster.Status.Phase)
 }
 
+func TestUpdateClusterStatusForMultipleClusters(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Start a separate sdk server for the Portworx cluster of each StorageCluster
+	sdkServerIP := "127.0.0.1"
+	sdkServerPort := 21883
+	mockClusterServer := mock.NewMockOpenStorageClusterServer(mockCtrl)
+	mockNodeServer := mock.NewMockOpenStorageNodeServer(mockCtrl)
+	mockSdk := mock.NewSdkServer(mock.SdkServers{
+		Cluster: mockClusterServer,
+		Node:    mockNodeServer,
+	})
+	mockSdk.StartOnAddress(sdkServerIP, strconv.Itoa(sdkServerPort))
+	defer mockSdk.Stop()
+
+	otherSdkServerPort := 21884
+	otherMockClusterServer := mock.NewMockOpenStorageClusterServer(mockCtrl)
+	otherMockNodeServer := mock.NewMockOpenStorageNodeServer(mockCtrl)
+	otherMockSdk := mock.NewSdkServer(mock.SdkServers{
+		Cluster: otherMockClusterServer,
+		Node:    otherMockNodeServer,
+	})
+	otherMockSdk.StartOnAddress(sdkServerIP, strconv.Itoa(otherSdkServerPort))
+	defer otherMockSdk.Stop()
+
+	k8sClient := testutil.FakeK8sClient(
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pxutil.PortworxServiceName,
+				Namespace: "kube-test",
+			},
+			Spec: v1.ServiceSpec{
+				ClusterIP: sdkServerIP,
+				Ports: []v1.ServicePort{
+					{
+						Name: pxutil.PortworxSDKPortName,
+						Port: int32(sdkServerPort),
+					},
+				},
+			},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pxutil.PortworxServiceName,
+				Namespace: "other-ns",
+			},
+			Spec: v1.ServiceSpec{
+				ClusterIP: sdkServerIP,
+				Ports: []v1.ServicePort{
+					{
+						Name: pxutil.PortworxSDKPortName,
+						Port: int32(otherSdkServerPort),
+					},
+				},
+			},
+		},
+	)
+
+	driver := portworx{
+		k8sClient: k8sClient,
+	}
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Status: corev1alpha1.StorageClusterStatus{
+			Phase: "Initializing",
+		},
+	}
+	otherCluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "other-ns",
+		},
+		Status: corev1alpha1.StorageClusterStatus{
+			Phase: "Initializing",
+		},
+	}
+
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), gomock.Any()).
+		Return(&api.SdkNodeEnumerateWithFiltersResponse{}, nil).
+		AnyTimes()
+	otherMockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), gomock.Any()).
+		Return(&api.SdkNodeEnumerateWithFiltersResponse{}, nil).
+		AnyTimes()
+	mockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), gomock.Any()).
+		Return(&api.SdkClusterInspectCurrentResponse{
+			Cluster: &api.StorageCluster{
+				Id:     "cluster-id",
+				Name:   "cluster-name",
+				Status: api.Status_STATUS_OK,
+			},
+		}, nil).
+		Times(2)
+	otherMockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), gomock.Any()).
+		Return(&api.SdkClusterInspectCurrentResponse{
+			Cluster: &api.StorageCluster{
+				Id:     "other-cluster-id",
+				Name:   "other-cluster-name",
+				Status: api.Status_STATUS_OK,
+			},
+		}, nil).
+		Times(2)
+
+	// Each StorageCluster should talk to its own Portworx cluster, even
+	// after the connection of the other cluster has been cached
+	for i := 0; i < 2; i++ {
+		err := driver.UpdateStorageClusterStatus(cluster)
+		require.NoError(t, err)
+		require.Equal(t, "cluster-name", cluster.Status.ClusterName)
+		require.Equal(t, "cluster-id", cluster.Status.ClusterUID)
+
+		err = driver.UpdateStorageClusterStatus(otherCluster)
+		require.NoError(t, err)
+		require.Equal(t, "other-cluster-name", otherCluster.Status.ClusterName)
+		require.Equal(t, "other-cluster-id", otherCluster.Status.ClusterUID)
+	}
+
+	// Deleting one cluster should not close the connection of the other
+	_, err := driver.DeleteStorage(otherCluster)
+	require.NoError(t, err)
+
+	mockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), gomock.Any()).
+		Return(&api.SdkClusterInspectCurrentResponse{
+			Cluster: &api.StorageCluster{
+				Id:     "cluster-id",
+				Name:   "cluster-name",
+				Status: api.Status_STATUS_OK,
+			},
+		}, nil).
+		Times(1)
+
+	err = driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+	require.Equal(t, "Online", cluster.Status.Phase)
+}
+
+func TestUpdateClusterStatusKeepsStorageNodesOfOtherClusters(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	sdkServerIP := "127.0.0.1"
+	sdkServerPort := 21883
+	mockClusterServer := mock.NewMockOpenStorageClusterServer(mockCtrl)
+	mockNodeServer := mock.NewMockOpenStorageNodeServer(mockCtrl)
+	mockSdk := mock.NewSdkServer(mock.SdkServers{
+		Cluster: mockClusterServer,
+		Node:    mockNodeServer,
+	})
+	mockSdk.StartOnAddress(sdkServerIP, strconv.Itoa(sdkServerPort))
+	defer mockSdk.Stop()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			UID:       "px-cluster-uid",
+		},
+		Status: corev1alpha1.StorageClusterStatus{
+			Phase: "Initializing",
+		},
+	}
+	otherCluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-px-cluster",
+			Namespace: "kube-test",
+			UID:       "other-px-cluster-uid",
+		},
+	}
+	otherNamespaceCluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "other-ns",
+			UID:       "other-ns-px-cluster-uid",
+		},
+	}
+	storageNodeOf := func(owner *corev1alpha1.StorageCluster, name string) *corev1alpha1.StorageNode {
+		return &corev1alpha1.StorageNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: owner.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(owner, pxutil.StorageClusterKind()),
+				},
+			},
+		}
+	}
+
+	k8sClient := testutil.FakeK8sClient(
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pxutil.PortworxServiceName,
+				Namespace: "kube-test",
+			},
+			Spec: v1.ServiceSpec{
+				ClusterIP: sdkServerIP,
+				Ports: []v1.ServicePort{
+					{
+						Name: pxutil.PortworxSDKPortName,
+						Port: int32(sdkServerPort),
+					},
+				},
+			},
+		},
+		storageNodeOf(cluster, "node-1"),
+		storageNodeOf(otherCluster, "node-2"),
+		storageNodeOf(otherNamespaceCluster, "node-3"),
+	)
+	driver := portworx{
+		k8sClient: k8sClient,
+		recorder:  record.NewFakeRecorder(10),
+	}
+
+	mockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), gomock.Any()).
+		Return(&api.SdkClusterInspectCurrentResponse{
+			Cluster: &api.StorageCluster{Status: api.Status_STATUS_OK},
+		}, nil).
+		Times(1)
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), gomock.Any()).
+		Return(&api.SdkNodeEnumerateWithFiltersResponse{}, nil).
+		Times(1)
+
+	// Only the orphan StorageNodes of the cluster should be deleted, not the
+	// StorageNodes of other clusters in the same or other namespaces
+	err := driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+
+	nodeStatusList := &corev1alpha1.StorageNodeList{}
+	err = testutil.List(k8sClient, nodeStatusList)
+	require.NoError(t, err)
+	require.Len(t, nodeStatusList.Items, 2)
+	require.ElementsMatch(t,
+		[]string{"other-px-cluster-uid", "other-ns-px-cluster-uid"},
+		[]string{
+			string(nodeStatusList.Items[0].OwnerReferences[0].UID),
+			string(nodeStatusList.Items[1].OwnerReferences[0].UID),
+		},
+	)
+}
+
 func TestUpdateClusterStatusForNodes(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -1490,11 +1968,26 @@ func TestUpdateClusterStatusForNodeVersions(t *testing.T) {
 	err = testutil.Get(k8sClient, nodeStatus, "node-one", cluster.Namespace)
 	require.NoError(t, err)
 	require.Equal(t, "5.6.7.8", nodeStatus.Spec.Version)
+	require.Equal(t, "1.2.3.4", nodeStatus.Status.DesiredVersion)
 
 	nodeStatus = &corev1alpha1.StorageNode{}
 	err = testutil.Get(k8sClient, nodeStatus, "node-two", cluster.Namespace)
 	require.NoError(t, err)
 	require.Equal(t, "1.2.3.4", nodeStatus.Spec.Version)
+	require.Empty(t, nodeStatus.Status.DesiredVersion)
+
+	// The desired version should not be set if the running version has the
+	// build appended to the version in the image tag
+	cluster.Spec.Image = "test/image:5.6.7"
+
+	err = driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+
+	nodeStatus = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, nodeStatus, "node-one", cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "5.6.7.8", nodeStatus.Spec.Version)
+	require.Empty(t, nodeStatus.Status.DesiredVersion)
 
 	// If the PX imgae does not have a tag then don't add version to status object
 	cluster.Spec.Image = "test/image"
@@ -1508,6 +2001,224 @@ func TestUpdateClusterStatusForNodeVersions(t *testing.T) {
 	require.Empty(t, nodeStatus.Spec.Version)
 }
 
+func TestUpdateClusterStatusForNodeStorage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Create the mock servers that can be used to mock SDK calls
+	mockClusterServer := mock.NewMockOpenStorageClusterServer(mockCtrl)
+	mockNodeServer := mock.NewMockOpenStorageNodeServer(mockCtrl)
+
+	// Start a sdk server that implements the mock servers
+	sdkServerIP := "127.0.0.1"
+	sdkServerPort := 21883
+	mockSdk := mock.NewSdkServer(mock.SdkServers{
+		Cluster: mockClusterServer,
+		Node:    mockNodeServer,
+	})
+	mockSdk.StartOnAddress(sdkServerIP, strconv.Itoa(sdkServerPort))
+	defer mockSdk.Stop()
+
+	// Create fake k8s client with fake service that will point the client
+	// to the mock sdk server address
+	k8sClient := testutil.FakeK8sClient(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pxutil.PortworxServiceName,
+			Namespace: "kube-test",
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: sdkServerIP,
+			Ports: []v1.ServicePort{
+				{
+					Name: pxutil.PortworxSDKPortName,
+					Port: int32(sdkServerPort),
+				},
+			},
+		},
+	})
+
+	// Create driver object with the fake k8s client
+	driver := portworx{
+		k8sClient: k8sClient,
+	}
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Status: corev1alpha1.StorageClusterStatus{
+			Phase: "Initializing",
+			Storage: corev1alpha1.Storage{
+				StorageNodesPerZone: 2,
+			},
+		},
+	}
+
+	// Mock cluster inspect response
+	expectedClusterResp := &api.SdkClusterInspectCurrentResponse{
+		Cluster: &api.StorageCluster{},
+	}
+	mockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), &api.SdkClusterInspectCurrentRequest{}).
+		Return(expectedClusterResp, nil).
+		AnyTimes()
+
+	// Mock node enumerate response
+	gi := uint64(1024 * 1024 * 1024)
+	expectedNodeOne := &api.StorageNode{
+		Id:                "node-1",
+		SchedulerNodeName: "node-one",
+		Pools: []*api.StoragePool{
+			{
+				ID:        1,
+				Medium:    api.StorageMedium_STORAGE_MEDIUM_SSD,
+				TotalSize: 100 * gi,
+				Used:      40 * gi,
+			},
+			{
+				ID:        0,
+				Medium:    api.StorageMedium_STORAGE_MEDIUM_MAGNETIC,
+				TotalSize: 50 * gi,
+				Used:      10 * gi,
+				Labels:    map[string]string{"medium": "magnetic"},
+			},
+		},
+		Disks: map[string]*api.StorageResource{
+			"/dev/sdc": {
+				Id:     "disk-2",
+				Path:   "/dev/sdc",
+				Medium: api.StorageMedium_STORAGE_MEDIUM_SSD,
+				Online: true,
+				Size:   100 * gi,
+				Used:   40 * gi,
+			},
+			"/dev/sdb": {
+				Id:     "disk-1",
+				Path:   "/dev/sdb",
+				Medium: api.StorageMedium_STORAGE_MEDIUM_MAGNETIC,
+				Online: true,
+				Size:   50 * gi,
+				Used:   10 * gi,
+			},
+			"/dev/sdd": {
+				Id:       "disk-3",
+				Path:     "/dev/sdd",
+				Medium:   api.StorageMedium_STORAGE_MEDIUM_NVME,
+				Size:     10 * gi,
+				Metadata: true,
+			},
+		},
+	}
+	expectedNodeTwo := &api.StorageNode{
+		Id:                "node-2",
+		SchedulerNodeName: "node-two",
+		Pools: []*api.StoragePool{
+			{
+				ID:        0,
+				Medium:    api.StorageMedium_STORAGE_MEDIUM_NVME,
+				TotalSize: 50 * gi,
+				Used:      50 * gi,
+			},
+		},
+	}
+	expectedNodeThree := &api.StorageNode{
+		Id:                "node-3",
+		SchedulerNodeName: "node-three",
+	}
+	expectedNodeEnumerateResp := &api.SdkNodeEnumerateWithFiltersResponse{
+		Nodes: []*api.StorageNode{expectedNodeOne, expectedNodeTwo, expectedNodeThree},
+	}
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(expectedNodeEnumerateResp, nil).
+		AnyTimes()
+
+	err := driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+
+	// The cluster status should have the capacity of all the storage nodes
+	require.Equal(t, "200Gi", cluster.Status.Storage.TotalSize.String())
+	require.Equal(t, "100Gi", cluster.Status.Storage.UsedSize.String())
+	require.Equal(t, "100Gi", cluster.Status.Storage.AvailableSize.String())
+	require.Equal(t, int32(2), cluster.Status.Storage.StorageNodes)
+	require.Equal(t, int32(1), cluster.Status.Storage.StoragelessNodes)
+	require.Equal(t, int32(2), cluster.Status.Storage.StorageNodesPerZone)
+
+	// The pools and drives should be sorted, and the node capacity should
+	// not include drives that are not part of a pool
+	storageNode := &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node-one", cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "150Gi", storageNode.Status.Storage.TotalSize.String())
+	require.Equal(t, "50Gi", storageNode.Status.Storage.UsedSize.String())
+
+	pools := storageNode.Status.Storage.Pools
+	require.Len(t, pools, 2)
+	require.Equal(t, int32(0), pools[0].ID)
+	require.Equal(t, "Magnetic", pools[0].Medium)
+	require.Equal(t, "50Gi", pools[0].TotalSize.String())
+	require.Equal(t, "10Gi", pools[0].UsedSize.String())
+	require.Equal(t, map[string]string{"medium": "magnetic"}, pools[0].Labels)
+	require.Equal(t, int32(1), pools[1].ID)
+	require.Equal(t, "SSD", pools[1].Medium)
+	require.Equal(t, "100Gi", pools[1].TotalSize.String())
+	require.Equal(t, "40Gi", pools[1].UsedSize.String())
+
+	drives := storageNode.Status.Storage.Drives
+	require.Len(t, drives, 3)
+	require.Equal(t, "disk-1", drives[0].ID)
+	require.Equal(t, "/dev/sdb", drives[0].Path)
+	require.Equal(t, "Magnetic", drives[0].Medium)
+	require.True(t, drives[0].Online)
+	require.Equal(t, "50Gi", drives[0].TotalSize.String())
+	require.Equal(t, "10Gi", drives[0].UsedSize.String())
+	require.Equal(t, "disk-2", drives[1].ID)
+	require.Equal(t, "SSD", drives[1].Medium)
+	require.Equal(t, "disk-3", drives[2].ID)
+	require.Equal(t, "NVMe", drives[2].Medium)
+	require.False(t, drives[2].Online)
+	require.True(t, drives[2].Metadata)
+
+	// Storageless nodes should not have any capacity
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node-three", cluster.Namespace)
+	require.NoError(t, err)
+	require.True(t, storageNode.Status.Storage.TotalSize.IsZero())
+	require.True(t, storageNode.Status.Storage.UsedSize.IsZero())
+	require.Empty(t, storageNode.Status.Storage.Pools)
+	require.Empty(t, storageNode.Status.Storage.Drives)
+
+	// The StorageNode should not be updated if the storage has not changed
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node-one", cluster.Namespace)
+	require.NoError(t, err)
+	resourceVersion := storageNode.ResourceVersion
+
+	err = driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node-one", cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, resourceVersion, storageNode.ResourceVersion)
+
+	// Capacity should be updated as the usage changes
+	expectedNodeTwo.Pools[0].Used = 20 * gi
+
+	err = driver.UpdateStorageClusterStatus(cluster)
+	require.NoError(t, err)
+
+	require.Equal(t, "200Gi", cluster.Status.Storage.TotalSize.String())
+	require.Equal(t, "70Gi", cluster.Status.Storage.UsedSize.String())
+	require.Equal(t, "130Gi", cluster.Status.Storage.AvailableSize.String())
+
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node-two", cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "20Gi", storageNode.Status.Storage.UsedSize.String())
+}
+
 func TestUpdateClusterStatusWithoutPortworxService(t *testing.T) {
 	// Fake client without service
 	k8sClient := testutil.FakeK8sClient()
@@ -2346,6 +3057,190 @@ func TestUpdateClusterStatusShouldDeleteStatusIfSchedulerNodeNameNotPresent(t *t
 	require.Equal(t, "node-2", nodeStatusList.Items[0].Status.NodeUID)
 }
 
+func TestCanUpdateStoragePods(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Create the mock servers that can be used to mock SDK calls
+	mockClusterServer := mock.NewMockOpenStorageClusterServer(mockCtrl)
+	mockNodeServer := mock.NewMockOpenStorageNodeServer(mockCtrl)
+	mockVolumeServer := mock.NewMockOpenStorageVolumeServer(mockCtrl)
+
+	// Start a sdk server that implements the mock servers
+	sdkServerIP := "127.0.0.1"
+	sdkServerPort := 21883
+	mockSdk := mock.NewSdkServer(mock.SdkServers{
+		Cluster: mockClusterServer,
+		Node:    mockNodeServer,
+		Volume:  mockVolumeServer,
+	})
+	mockSdk.StartOnAddress(sdkServerIP, strconv.Itoa(sdkServerPort))
+	defer mockSdk.Stop()
+
+	// Create fake k8s client with fake service that will point the client
+	// to the mock sdk server address
+	k8sClient := testutil.FakeK8sClient(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pxutil.PortworxServiceName,
+			Namespace: "kube-test",
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: sdkServerIP,
+			Ports: []v1.ServicePort{
+				{
+					Name: pxutil.PortworxSDKPortName,
+					Port: int32(sdkServerPort),
+				},
+			},
+		},
+	})
+
+	// Create driver object with the fake k8s client
+	driver := portworx{
+		k8sClient: k8sClient,
+	}
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Status: corev1alpha1.StorageClusterStatus{
+			Phase: string(corev1alpha1.ClusterOnline),
+		},
+	}
+
+	// Should not update if the cluster is out of quorum
+	mockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), &api.SdkClusterInspectCurrentRequest{}).
+		Return(&api.SdkClusterInspectCurrentResponse{
+			Cluster: &api.StorageCluster{Status: api.Status_STATUS_NOT_IN_QUORUM},
+		}, nil).
+		Times(1)
+
+	err := driver.CanUpdateStoragePods(cluster, []string{"node-one"})
+	require.EqualError(t, err, "portworx cluster is NotInQuorum")
+
+	// Should not update if the updated node is not online
+	mockClusterServer.EXPECT().
+		InspectCurrent(gomock.Any(), &api.SdkClusterInspectCurrentRequest{}).
+		Return(&api.SdkClusterInspectCurrentResponse{
+			Cluster: &api.StorageCluster{Status: api.Status_STATUS_OK},
+		}, nil).
+		AnyTimes()
+	nodeEnumerateResp := &api.SdkNodeEnumerateWithFiltersResponse{
+		Nodes: []*api.StorageNode{
+			{
+				Id:                "node-1",
+				SchedulerNodeName: "node-one",
+				Status:            api.Status_STATUS_STORAGE_REBALANCE,
+			},
+			{
+				Id:                "node-2",
+				SchedulerNodeName: "node-two",
+				Status:            api.Status_STATUS_OK,
+			},
+		},
+	}
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(nodeEnumerateResp, nil).
+		Times(1)
+
+	err = driver.CanUpdateStoragePods(cluster, []string{"node-one", "node-two"})
+	require.EqualError(t, err, "portworx on node node-one is Degraded")
+
+	// Should not update if the updated node has not joined the cluster yet
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(nodeEnumerateResp, nil).
+		Times(1)
+
+	err = driver.CanUpdateStoragePods(cluster, []string{"node-two", "node-three"})
+	require.EqualError(t, err, "portworx on node node-three has not joined the cluster")
+
+	// Should not inspect volumes if no nodes have been updated yet
+	mockNodeServer.EXPECT().
+		EnumerateWithFilters(gomock.Any(), &api.SdkNodeEnumerateWithFiltersRequest{}).
+		Return(nodeEnumerateResp, nil).
+		AnyTimes()
+
+	err = driver.CanUpdateStoragePods(cluster, nil)
+	require.NoError(t, err)
+
+	// Should not update if there are degraded volumes with replicas
+	// on the updated nodes
+	volumeInspectResp := &api.SdkVolumeInspectWithFiltersResponse{
+		Volumes: []*api.SdkVolumeInspectResponse{
+			{
+				Name: "volume-1",
+				Volume: &api.Volume{
+					Status:      api.VolumeStatus_VOLUME_STATUS_UP,
+					ReplicaSets: []*api.ReplicaSet{{Nodes: []string{"node-2"}}},
+				},
+			},
+			{
+				Name: "volume-2",
+				Volume: &api.Volume{
+					Status:      api.VolumeStatus_VOLUME_STATUS_DEGRADED,
+					ReplicaSets: []*api.ReplicaSet{{Nodes: []string{"node-1", "node-2"}}},
+				},
+			},
+			{
+				Name: "volume-3",
+				Volume: &api.Volume{
+					Status:      api.VolumeStatus_VOLUME_STATUS_DEGRADED,
+					ReplicaSets: []*api.ReplicaSet{{Nodes: []string{"node-1"}}},
+				},
+			},
+		},
+	}
+	mockVolumeServer.EXPECT().
+		InspectWithFilters(gomock.Any(), &api.SdkVolumeInspectWithFiltersRequest{}).
+		Return(volumeInspectResp, nil).
+		Times(1)
+
+	err = driver.CanUpdateStoragePods(cluster, []string{"node-two"})
+	require.EqualError(t, err, "portworx volumes [volume-2] are degraded")
+
+	// Should not update if volumes cannot be inspected
+	mockVolumeServer.EXPECT().
+		InspectWithFilters(gomock.Any(), &api.SdkVolumeInspectWithFiltersRequest{}).
+		Return(nil, fmt.Errorf("InspectWithFilters error")).
+		Times(1)
+
+	err = driver.CanUpdateStoragePods(cluster, []string{"node-two"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to inspect volumes")
+
+	// Should update if the cluster, updated nodes and their volumes are healthy,
+	// even if volumes without replicas on the updated nodes are degraded
+	volumeInspectResp.Volumes[1].Volume.Status = api.VolumeStatus_VOLUME_STATUS_UP
+	mockVolumeServer.EXPECT().
+		InspectWithFilters(gomock.Any(), &api.SdkVolumeInspectWithFiltersRequest{}).
+		Return(volumeInspectResp, nil).
+		Times(1)
+
+	err = driver.CanUpdateStoragePods(cluster, []string{"node-two"})
+	require.NoError(t, err)
+}
+
+func TestCanUpdateStoragePodsWithoutPortworxService(t *testing.T) {
+	driver := portworx{
+		k8sClient: testutil.FakeK8sClient(),
+	}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+	}
+
+	err := driver.CanUpdateStoragePods(cluster, []string{"node-one"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to get k8s service spec")
+}
+
 func TestDeleteClusterWithoutDeleteStrategy(t *testing.T) {
 	driver := portworx{}
 
@@ -2362,7 +3257,8 @@ func TestDeleteClusterWithoutDeleteStrategy(t *testing.T) {
 	// If no delete strategy is provided, condition should be complete
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Equal(t, storageClusterDeleteMsg, condition.Reason)
+	require.Equal(t, deleteCompletedReason, condition.Reason)
+	require.Equal(t, storageClusterDeleteMsg, condition.Message)
 }
 
 func TestDeleteClusterWithUninstallStrategy(t *testing.T) {
@@ -2388,7 +3284,8 @@ func TestDeleteClusterWithUninstallStrategy(t *testing.T) {
 	// Check condition
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Equal(t, "Started node wiper daemonset", condition.Reason)
+	require.Equal(t, nodeWiperStartedReason, condition.Reason)
+	require.Equal(t, "Started node wiper daemonset", condition.Message)
 
 	// Check wiper service account
 	sa := &v1.ServiceAccount{}
@@ -2533,7 +3430,7 @@ func TestDeleteClusterWithUninstallStrategyForPKS(t *testing.T) {
 	// Check condition
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Equal(t, "Started node wiper daemonset", condition.Reason)
+	require.Equal(t, "Started node wiper daemonset", condition.Message)
 
 	// Check wiper service account
 	sa := &v1.ServiceAccount{}
@@ -2577,7 +3474,7 @@ func TestDeleteClusterWithUninstallAndWipeStrategy(t *testing.T) {
 	// Check condition
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Equal(t, "Started node wiper daemonset", condition.Reason)
+	require.Equal(t, "Started node wiper daemonset", condition.Message)
 
 	// Check wiper service account
 	sa := &v1.ServiceAccount{}
@@ -2681,7 +3578,7 @@ func TestDeleteClusterWithUninstallWhenNodeWiperCreated(t *testing.T) {
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Contains(t, condition.Reason,
+	require.Contains(t, condition.Message,
 		"Wipe operation still in progress: Completed [0] In Progress [0] Total [0]")
 
 	// Check when daemon set's status is updated
@@ -2694,7 +3591,7 @@ func TestDeleteClusterWithUninstallWhenNodeWiperCreated(t *testing.T) {
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Contains(t, condition.Reason,
+	require.Contains(t, condition.Message,
 		"Wipe operation still in progress: Completed [0] In Progress [2] Total [2]")
 
 	// Check when only few pods are ready
@@ -2720,7 +3617,7 @@ func TestDeleteClusterWithUninstallWhenNodeWiperCreated(t *testing.T) {
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Contains(t, condition.Reason,
+	require.Contains(t, condition.Message,
 		"Wipe operation still in progress: Completed [1] In Progress [1] Total [2]")
 
 	// Check when all pods are ready
@@ -2746,7 +3643,7 @@ func TestDeleteClusterWithUninstallWhenNodeWiperCreated(t *testing.T) {
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallMsg)
 }
 
 func TestDeleteClusterWithUninstallWipeStrategyWhenNodeWiperCreated(t *testing.T) {
@@ -2783,7 +3680,7 @@ func TestDeleteClusterWithUninstallWipeStrategyWhenNodeWiperCreated(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Contains(t, condition.Reason,
+	require.Contains(t, condition.Message,
 		"Wipe operation still in progress: Completed [0] In Progress [0] Total [0]")
 
 	// Check when daemon set's status is updated
@@ -2796,7 +3693,7 @@ func TestDeleteClusterWithUninstallWipeStrategyWhenNodeWiperCreated(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Contains(t, condition.Reason,
+	require.Contains(t, condition.Message,
 		"Wipe operation still in progress: Completed [0] In Progress [2] Total [2]")
 
 	// Check when only few pods are ready
@@ -2822,7 +3719,7 @@ func TestDeleteClusterWithUninstallWipeStrategyWhenNodeWiperCreated(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
-	require.Contains(t, condition.Reason,
+	require.Contains(t, condition.Message,
 		"Wipe operation still in progress: Completed [1] In Progress [1] Total [2]")
 
 	// Check when all pods are ready
@@ -2848,7 +3745,7 @@ func TestDeleteClusterWithUninstallWipeStrategyWhenNodeWiperCreated(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallAndWipeMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
 }
 
 func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveConfigMaps(t *testing.T) {
@@ -2914,7 +3811,7 @@ func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveConfigMaps(t *testing
 	// Check condition
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallAndWipeMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
 
 	// Check config maps are deleted
 	configMaps = &v1.ConfigMapList{}
@@ -2923,6 +3820,48 @@ func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveConfigMaps(t *testing
 	require.Empty(t, configMaps.Items)
 }
 
+func TestDeleteClusterWithUninstallWipeStrategyWithoutKvdbSpec(t *testing.T) {
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			DeleteStrategy: &corev1alpha1.StorageClusterDeleteStrategy{
+				Type: corev1alpha1.UninstallAndWipeStorageClusterStrategyType,
+			},
+		},
+	}
+	wiperDS := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pxNodeWiperDaemonSetName,
+			Namespace: cluster.Namespace,
+			UID:       types.UID("wiper-ds-uid"),
+		},
+		Status: appsv1.DaemonSetStatus{
+			DesiredNumberScheduled: 1,
+		},
+	}
+	wiperPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       cluster.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{UID: wiperDS.UID}},
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{{Ready: true}},
+		},
+	}
+	driver := portworx{
+		k8sClient: testutil.FakeK8sClient(wiperDS, wiperPod),
+	}
+
+	// Metadata should be wiped even if the kvdb was never configured
+	condition, err := driver.DeleteStorage(cluster)
+	require.NoError(t, err)
+	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
+}
+
 func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveKvdbData(t *testing.T) {
 	cluster := &corev1alpha1.StorageCluster{
 		ObjectMeta: metav1.ObjectMeta{
@@ -2970,7 +3909,7 @@ func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallAndWipeMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
 
 	_, err = kvdbMem.Get(cluster.Name + "/foo")
 	require.Error(t, err)
@@ -2998,7 +3937,7 @@ func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallAndWipeMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
 
 	_, err = kvdbMem.Get(cluster.Name + "/foo")
 	require.Error(t, err)
@@ -3026,7 +3965,7 @@ func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallAndWipeMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
 
 	_, err = kvdbMem.Get(cluster.Name + "/foo")
 	require.Error(t, err)
@@ -3057,7 +3996,7 @@ func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallAndWipeMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
 
 	_, err = kvdbMem.Get(cluster.Name + "/foo")
 	require.Error(t, err)
@@ -3088,7 +4027,7 @@ func TestDeleteClusterWithUninstallWipeStrategyShouldRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
-	require.Contains(t, condition.Reason, storageClusterUninstallAndWipeMsg)
+	require.Contains(t, condition.Message, storageClusterUninstallAndWipeMsg)
 
 	_, err = kvdbMem.Get(cluster.Name + "/foo")
 	require.Error(t, err)
@@ -3129,7 +4068,7 @@ func TestDeleteClusterWithUninstallWipeStrategyFailedRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
-	require.Contains(t, condition.Reason, "Failed to wipe metadata")
+	require.Contains(t, condition.Message, "Failed to wipe metadata")
 
 	// Fail if unknown kvdb type given in url
 	cluster.Spec.Kvdb.Endpoints = []string{"zookeeper://kvdb.com:2001"}
@@ -3139,7 +4078,7 @@ func TestDeleteClusterWithUninstallWipeStrategyFailedRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
-	require.Contains(t, condition.Reason, "Failed to wipe metadata")
+	require.Contains(t, condition.Message, "Failed to wipe metadata")
 
 	// Fail if unknown kvdb version found
 	cluster.Spec.Kvdb.Endpoints = []string{"etcd://kvdb.com:2001"}
@@ -3152,7 +4091,7 @@ func TestDeleteClusterWithUninstallWipeStrategyFailedRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
-	require.Contains(t, condition.Reason, "Failed to wipe metadata")
+	require.Contains(t, condition.Message, "Failed to wipe metadata")
 
 	// Fail if error getting kvdb version
 	cluster.Spec.Kvdb.Endpoints = []string{"etcd://kvdb.com:2001"}
@@ -3165,8 +4104,8 @@ func TestDeleteClusterWithUninstallWipeStrategyFailedRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
-	require.Contains(t, condition.Reason, "Failed to wipe metadata")
-	require.Contains(t, condition.Reason, "kvdb version error")
+	require.Contains(t, condition.Message, "Failed to wipe metadata")
+	require.Contains(t, condition.Message, "kvdb version error")
 
 	// Fail if error initializing kvdb
 	cluster.Spec.Kvdb.Endpoints = []string{"etcd://kvdb.com:2001"}
@@ -3182,8 +4121,8 @@ func TestDeleteClusterWithUninstallWipeStrategyFailedRemoveKvdbData(t *testing.T
 
 	require.Equal(t, corev1alpha1.ClusterConditionTypeDelete, condition.Type)
 	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
-	require.Contains(t, condition.Reason, "Failed to wipe metadata")
-	require.Contains(t, condition.Reason, "kvdb initialize error")
+	require.Contains(t, condition.Message, "Failed to wipe metadata")
+	require.Contains(t, condition.Message, "kvdb initialize error")
 }
 
 func fakeClientWithWiperPod(namespace string) client.Client {
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"path"
 	"regexp"
-	"strconv"
 	"strings"
 
 	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
@@ -129,7 +128,7 @@ func (u *uninstallPortworx) WipeMetadata() error {
 			return err
 		}
 	}
-	if u.cluster.Spec.Kvdb.Internal {
+	if u.cluster.Spec.Kvdb == nil || u.cluster.Spec.Kvdb.Internal {
 		// no more work needed
 		return nil
 	}
@@ -153,10 +152,7 @@ func (u *uninstallPortworx) RunNodeWiper(
 ) error {
 	pwxHostPathRoot := "/"
 
-	enabled, err := strconv.ParseBool(u.cluster.Annotations[annotationIsPKS])
-	isPKS := err == nil && enabled
-
-	if isPKS {
+	if pxutil.IsPKS(u.cluster) {
 		pwxHostPathRoot = pksPersistentStoreRoot
 	}
 
@@ -177,7 +173,7 @@ func (u *uninstallPortworx) RunNodeWiper(
 
 	ownerRef := metav1.NewControllerRef(u.cluster, pxutil.StorageClusterKind())
 
-	err = u.createServiceAccount(ownerRef)
+	err := u.createServiceAccount(ownerRef)
 	if err != nil && !errors.IsAlreadyExists(err) {
 		return err
 	}
@@ -351,11 +347,7 @@ func (u *uninstallPortworx) RunNodeWiper(
 		},
 	}
 
-	if u.cluster.Spec.Placement != nil && u.cluster.Spec.Placement.NodeAffinity != nil {
-		ds.Spec.Template.Spec.Affinity = &v1.Affinity{
-			NodeAffinity: u.cluster.Spec.Placement.NodeAffinity.DeepCopy(),
-		}
-	}
+	util.ApplyPlacement(&ds.Spec.Template.Spec, u.cluster.Spec.Placement)
 
 	return u.k8sClient.Create(context.TODO(), ds)
 }
@@ -363,7 +355,7 @@ func (u *uninstallPortworx) RunNodeWiper(
 func (u *uninstallPortworx) createServiceAccount(
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		u.k8sClient,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
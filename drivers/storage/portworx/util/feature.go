@@ -1,6 +1,7 @@
 package util
 
 import (
+	"sort"
 	"strconv"
 )
 
@@ -12,8 +13,31 @@ const (
 	FeatureCSI Feature = "CSI"
 )
 
+var (
+	// knownFeatures is the set of all features that can be toggled
+	// through the feature gates in the StorageCluster spec
+	knownFeatures = map[Feature]bool{
+		FeatureCSI: true,
+	}
+)
+
 // IsEnabled checks if the feature is enabled in the given feature map
 func (feature Feature) IsEnabled(featureMap map[string]string) bool {
 	enabled, err := strconv.ParseBool(featureMap[string(feature)])
 	return err == nil && enabled
 }
+
+// IsKnownFeature checks if the given feature name is supported by the driver
+func IsKnownFeature(name string) bool {
+	return knownFeatures[Feature(name)]
+}
+
+// KnownFeatures returns the sorted names of all features supported by the driver
+func KnownFeatures() []string {
+	names := make([]string, 0, len(knownFeatures))
+	for feature := range knownFeatures {
+		names = append(names, string(feature))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/google/shlex"
 	"github.com/hashicorp/go-version"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
 	"github.com/sirupsen/logrus"
@@ -30,28 +31,45 @@ const (
 	PortworxSDKPortName = "px-sdk"
 	// PortworxKVDBPortName name of the Portworx internal KVDB port
 	PortworxKVDBPortName = "px-kvdb"
+	// PortworxPriorityClassName name of the PriorityClass created by the operator
+	// for the Portworx pods and the control plane components
+	PortworxPriorityClassName = "px-critical"
 
-	// AnnotationIsPKS annotation indicating whether it is a PKS cluster
+	// AnnotationIsPKS annotation indicating whether it is a PKS cluster.
+	// Deprecated: use spec.platform instead.
 	AnnotationIsPKS = pxAnnotationPrefix + "/is-pks"
-	// AnnotationIsGKE annotation indicating whether it is a GKE cluster
+	// AnnotationIsGKE annotation indicating whether it is a GKE cluster.
+	// Deprecated: use spec.platform instead.
 	AnnotationIsGKE = pxAnnotationPrefix + "/is-gke"
-	// AnnotationIsAKS annotation indicating whether it is an AKS cluster
+	// AnnotationIsAKS annotation indicating whether it is an AKS cluster.
+	// Deprecated: use spec.platform instead.
 	AnnotationIsAKS = pxAnnotationPrefix + "/is-aks"
-	// AnnotationIsEKS annotation indicating whether it is an EKS cluster
+	// AnnotationIsEKS annotation indicating whether it is an EKS cluster.
+	// Deprecated: use spec.platform instead.
 	AnnotationIsEKS = pxAnnotationPrefix + "/is-eks"
-	// AnnotationIsOpenshift annotation indicating whether it is an OpenShift cluster
+	// AnnotationIsOpenshift annotation indicating whether it is an OpenShift cluster.
+	// Deprecated: use spec.platform instead.
 	AnnotationIsOpenshift = pxAnnotationPrefix + "/is-openshift"
-	// AnnotationPVCController annotation indicating whether to deploy a PVC controller
+	// AnnotationPVCController annotation indicating whether to deploy a PVC controller.
+	// Deprecated: use spec.pvcController.enabled instead.
 	AnnotationPVCController = pxAnnotationPrefix + "/pvc-controller"
+	// AnnotationLogFile annotation for the file where Portworx writes its logs.
+	// Deprecated: use spec.logFile instead.
+	AnnotationLogFile = pxAnnotationPrefix + "/log-file"
+	// AnnotationMiscArgs annotation for additional arguments to Portworx.
+	// Deprecated: use spec.extraArgs instead.
+	AnnotationMiscArgs = pxAnnotationPrefix + "/misc-args"
 	// AnnotationPVCControllerCPU annotation for overriding the default CPU for PVC
-	// controller deployment
+	// controller deployment. It is ignored if spec.pvcController.resources is set.
 	AnnotationPVCControllerCPU = pxAnnotationPrefix + "/pvc-controller-cpu"
-	// AnnotationAutopilotCPU annotation for overriding the default CPU for Autopilot
+	// AnnotationAutopilotCPU annotation for overriding the default CPU for Autopilot.
+	// It is ignored if spec.autopilot.resources is set.
 	AnnotationAutopilotCPU = pxAnnotationPrefix + "/autopilot-cpu"
 	// AnnotationServiceType annotation indicating k8s service type for all services
-	// deployed by the operator
+	// deployed by the operator. Deprecated: use spec.service.type instead.
 	AnnotationServiceType = pxAnnotationPrefix + "/service-type"
-	// AnnotationPXVersion annotation indicating the portworx semantic version
+	// AnnotationPXVersion annotation indicating the portworx semantic version.
+	// Deprecated: use spec.version instead.
 	AnnotationPXVersion = pxAnnotationPrefix + "/px-version"
 
 	// EnvKeyPXImage key for the environment variable that specifies Portworx image
@@ -73,48 +91,124 @@ var (
 	SpecsBaseDir = getSpecsBaseDir
 )
 
-// IsPKS returns true if the annotation has a PKS annotation and is true value
+var (
+	// platformAnnotations are the deprecated annotations that were used to
+	// specify the platform of the cluster, in the order they are looked up
+	platformAnnotations = []struct {
+		annotation string
+		platform   corev1alpha1.PlatformType
+	}{
+		{AnnotationIsPKS, corev1alpha1.PlatformPKS},
+		{AnnotationIsOpenshift, corev1alpha1.PlatformOpenShift},
+		{AnnotationIsGKE, corev1alpha1.PlatformGKE},
+		{AnnotationIsAKS, corev1alpha1.PlatformAKS},
+		{AnnotationIsEKS, corev1alpha1.PlatformEKS},
+	}
+	// DeprecatedAnnotations maps the deprecated annotations to the spec
+	// fields that replace them
+	DeprecatedAnnotations = map[string]string{
+		AnnotationIsPKS:         "spec.platform",
+		AnnotationIsOpenshift:   "spec.platform",
+		AnnotationIsGKE:         "spec.platform",
+		AnnotationIsAKS:         "spec.platform",
+		AnnotationIsEKS:         "spec.platform",
+		AnnotationPVCController: "spec.pvcController.enabled",
+		AnnotationLogFile:       "spec.logFile",
+		AnnotationMiscArgs:      "spec.extraArgs",
+		AnnotationServiceType:   "spec.service.type",
+		AnnotationPXVersion:     "spec.version",
+	}
+)
+
+// Platform returns the platform from the cluster spec if present, else
+// the platform from the deprecated platform annotations
+func Platform(cluster *corev1alpha1.StorageCluster) corev1alpha1.PlatformType {
+	if cluster.Spec.Platform != "" {
+		return cluster.Spec.Platform
+	}
+	for _, pa := range platformAnnotations {
+		enabled, err := strconv.ParseBool(cluster.Annotations[pa.annotation])
+		if err == nil && enabled {
+			return pa.platform
+		}
+	}
+	return ""
+}
+
+// IsPKS returns true if the cluster is running on PKS
 func IsPKS(cluster *corev1alpha1.StorageCluster) bool {
-	enabled, err := strconv.ParseBool(cluster.Annotations[AnnotationIsPKS])
-	return err == nil && enabled
+	return Platform(cluster) == corev1alpha1.PlatformPKS
 }
 
-// IsGKE returns true if the annotation has a GKE annotation and is true value
+// IsGKE returns true if the cluster is running on GKE
 func IsGKE(cluster *corev1alpha1.StorageCluster) bool {
-	enabled, err := strconv.ParseBool(cluster.Annotations[AnnotationIsGKE])
-	return err == nil && enabled
+	return Platform(cluster) == corev1alpha1.PlatformGKE
 }
 
-// IsAKS returns true if the annotation has an AKS annotation and is true value
+// IsAKS returns true if the cluster is running on AKS
 func IsAKS(cluster *corev1alpha1.StorageCluster) bool {
-	enabled, err := strconv.ParseBool(cluster.Annotations[AnnotationIsAKS])
-	return err == nil && enabled
+	return Platform(cluster) == corev1alpha1.PlatformAKS
 }
 
-// IsEKS returns true if the annotation has an EKS annotation and is true value
+// IsEKS returns true if the cluster is running on EKS
 func IsEKS(cluster *corev1alpha1.StorageCluster) bool {
-	enabled, err := strconv.ParseBool(cluster.Annotations[AnnotationIsEKS])
-	return err == nil && enabled
+	return Platform(cluster) == corev1alpha1.PlatformEKS
 }
 
-// IsOpenshift returns true if the annotation has an OpenShift annotation and is true value
+// IsOpenshift returns true if the cluster is running on OpenShift
 func IsOpenshift(cluster *corev1alpha1.StorageCluster) bool {
-	enabled, err := strconv.ParseBool(cluster.Annotations[AnnotationIsOpenshift])
-	return err == nil && enabled
+	return Platform(cluster) == corev1alpha1.PlatformOpenShift
+}
+
+// PVCControllerEnabled returns the PVC controller setting from the cluster
+// spec if present, else from the deprecated annotation. Returns nil if the
+// PVC controller is not explicitly enabled or disabled.
+func PVCControllerEnabled(cluster *corev1alpha1.StorageCluster) *bool {
+	if cluster.Spec.PVCController != nil && cluster.Spec.PVCController.Enabled != nil {
+		enabled := *cluster.Spec.PVCController.Enabled
+		return &enabled
+	}
+	enabled, err := strconv.ParseBool(cluster.Annotations[AnnotationPVCController])
+	if err != nil {
+		return nil
+	}
+	return &enabled
+}
+
+// LogFile returns the Portworx log file from the cluster spec if present,
+// else from the deprecated annotation
+func LogFile(cluster *corev1alpha1.StorageCluster) string {
+	if cluster.Spec.LogFile != "" {
+		return cluster.Spec.LogFile
+	}
+	return cluster.Annotations[AnnotationLogFile]
+}
+
+// ExtraArgs returns the additional Portworx arguments from the cluster spec
+// if present, else the arguments parsed from the deprecated annotation
+func ExtraArgs(cluster *corev1alpha1.StorageCluster) ([]string, error) {
+	if len(cluster.Spec.ExtraArgs) > 0 {
+		return cluster.Spec.ExtraArgs, nil
+	}
+	if cluster.Annotations[AnnotationMiscArgs] == "" {
+		return nil, nil
+	}
+	return shlex.Split(cluster.Annotations[AnnotationMiscArgs])
 }
 
-// ServiceType returns the k8s service type from cluster annotations if present
+// ServiceType returns the k8s service type from the cluster spec if present,
+// else from the deprecated annotation
 func ServiceType(cluster *corev1alpha1.StorageCluster) v1.ServiceType {
-	var serviceType v1.ServiceType
-	if val, exists := cluster.Annotations[AnnotationServiceType]; exists {
-		st := v1.ServiceType(val)
-		if st == v1.ServiceTypeClusterIP ||
-			st == v1.ServiceTypeNodePort ||
-			st == v1.ServiceTypeLoadBalancer {
-			serviceType = st
-		}
+	st := v1.ServiceType(cluster.Annotations[AnnotationServiceType])
+	if cluster.Spec.Service != nil && cluster.Spec.Service.Type != "" {
+		st = cluster.Spec.Service.Type
+	}
+	if st == v1.ServiceTypeClusterIP ||
+		st == v1.ServiceTypeNodePort ||
+		st == v1.ServiceTypeLoadBalancer {
+		return st
 	}
-	return serviceType
+	return ""
 }
 
 // ImagePullPolicy returns the image pull policy from the cluster spec if present,
@@ -152,8 +246,9 @@ func UseDeprecatedCSIDriverName(cluster *corev1alpha1.StorageCluster) bool {
 
 // GetPortworxVersion returns the Portworx version based on the image provided.
 // We first look at spec.Image, if not valid image tag found, we check the PX_IMAGE
-// env variable. If that is not present or invalid semvar, then we fallback to an
-// annotation portworx.io/px-version; else we return int max as the version.
+// env variable. If that is not present or invalid semvar, then we fallback to
+// spec.Version and then to the deprecated annotation portworx.io/px-version;
+// else we return int max as the version.
 func GetPortworxVersion(cluster *corev1alpha1.StorageCluster) *version.Version {
 	var (
 		err       error
@@ -174,12 +269,8 @@ func GetPortworxVersion(cluster *corev1alpha1.StorageCluster) *version.Version {
 		pxVersion, err = version.NewSemver(pxVersionStr)
 		if err != nil {
 			logrus.Warnf("Invalid PX version %s extracted from image name: %v", pxVersionStr, err)
-			if pxVersionStr, exists := cluster.Annotations[AnnotationPXVersion]; exists {
-				pxVersion, err = version.NewSemver(pxVersionStr)
-				if err != nil {
-					logrus.Warnf("Invalid PX version %s extracted from annotation: %v", pxVersionStr, err)
-				}
-			}
+			pxVersion = versionFromSpec(cluster)
+
 		}
 	}
 
@@ -189,6 +280,24 @@ func GetPortworxVersion(cluster *corev1alpha1.StorageCluster) *version.Version {
 	return pxVersion
 }
 
+// versionFromSpec returns the Portworx version from spec.Version, or from the
+// deprecated annotation if the version in the spec is not a semantic version
+func versionFromSpec(cluster *corev1alpha1.StorageCluster) *version.Version {
+	if cluster.Spec.Version != "" {
+		if pxVersion, err := version.NewSemver(cluster.Spec.Version); err == nil {
+			return pxVersion
+		}
+	}
+	if pxVersionStr, exists := cluster.Annotations[AnnotationPXVersion]; exists {
+		pxVersion, err := version.NewSemver(pxVersionStr)
+		if err == nil {
+			return pxVersion
+		}
+		logrus.Warnf("Invalid PX version %s extracted from annotation: %v", pxVersionStr, err)
+	}
+	return nil
+}
+
 // SelectorLabels returns the labels that are used to select Portworx pods
 func SelectorLabels() map[string]string {
 	return map[string]string{
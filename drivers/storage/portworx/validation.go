@@ -0,0 +1,87 @@
+package portworx
+
+import (
+	"strconv"
+	"strings"
+
+	pxutil "github.com/libopenstorage/operator/drivers/storage/portworx/util"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateStorageCluster validates the Portworx fields of the spec for the admission webhook
+func (p *portworx) ValidateStorageCluster(cluster *corev1alpha1.StorageCluster) error {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateStorageSpec(cluster.Spec.Storage, specPath.Child("storage"))...)
+	allErrs = append(allErrs, validateRuntimeOpts(cluster.Spec.RuntimeOpts, specPath.Child("runtimeOptions"))...)
+	allErrs = append(allErrs, validateFeatureGates(cluster.Spec.FeatureGates, specPath.Child("featureGates"))...)
+
+	for i, nodeSpec := range cluster.Spec.Nodes {
+		nodePath := specPath.Child("nodes").Index(i)
+		allErrs = append(allErrs, validateStorageSpec(nodeSpec.Storage, nodePath.Child("storage"))...)
+		allErrs = append(allErrs, validateRuntimeOpts(nodeSpec.RuntimeOpts, nodePath.Child("runtimeOptions"))...)
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// validateStorageSpec ensures that explicitly listed devices are not mixed with
+// the options that ask Portworx to pick up all available devices.
+func validateStorageSpec(
+	storageSpec *corev1alpha1.StorageSpec,
+	fldPath *field.Path,
+) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if storageSpec == nil || storageSpec.Devices == nil {
+		return allErrs
+	}
+	if storageSpec.UseAll != nil && *storageSpec.UseAll {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("useAll"), true,
+			"cannot be used together with devices"))
+	}
+	if storageSpec.UseAllWithPartitions != nil && *storageSpec.UseAllWithPartitions {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("useAllWithPartitions"), true,
+			"cannot be used together with devices"))
+	}
+	return allErrs
+}
+
+// validateRuntimeOpts ensures all runtime options have integer values as
+// Portworx does not accept any other values for runtime options.
+func validateRuntimeOpts(
+	runtimeOpts map[string]string,
+	fldPath *field.Path,
+) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for k, v := range runtimeOpts {
+		if strings.TrimSpace(k) == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath, k, "key cannot be empty"))
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimSpace(v)); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(k), v, "must be an integer"))
+		}
+	}
+	return allErrs
+}
+
+// validateFeatureGates ensures only features known to the driver are present
+// and that their values can be parsed as booleans.
+func validateFeatureGates(
+	featureGates map[string]string,
+	fldPath *field.Path,
+) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for k, v := range featureGates {
+		if !pxutil.IsKnownFeature(k) {
+			allErrs = append(allErrs, field.NotSupported(fldPath, k, pxutil.KnownFeatures()))
+			continue
+		}
+		if _, err := strconv.ParseBool(v); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(k), v, "must be a boolean"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,103 @@
+package portworx
+
+import (
+	"testing"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateStorageCluster(t *testing.T) {
+	driver := portworx{}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-system",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			FeatureGates: map[string]string{
+				"CSI": "true",
+			},
+			CommonConfig: corev1alpha1.CommonConfig{
+				Storage: &corev1alpha1.StorageSpec{
+					Devices: &[]string{"/dev/sda"},
+				},
+				RuntimeOpts: map[string]string{
+					"num_threads": "10",
+				},
+			},
+		},
+	}
+
+	// Valid spec
+	err := driver.ValidateStorageCluster(cluster)
+	require.NoError(t, err)
+
+	// Empty spec is valid
+	err = driver.ValidateStorageCluster(&corev1alpha1.StorageCluster{})
+	require.NoError(t, err)
+
+	// Devices together with useAll
+	cluster.Spec.Storage.UseAll = boolPtr(true)
+	err = driver.ValidateStorageCluster(cluster)
+	require.EqualError(t, err,
+		"spec.storage.useAll: Invalid value: true: cannot be used together with devices")
+
+	// useAll false together with devices is allowed
+	cluster.Spec.Storage.UseAll = boolPtr(false)
+	err = driver.ValidateStorageCluster(cluster)
+	require.NoError(t, err)
+
+	// Devices together with useAllWithPartitions
+	cluster.Spec.Storage.UseAllWithPartitions = boolPtr(true)
+	err = driver.ValidateStorageCluster(cluster)
+	require.EqualError(t, err,
+		"spec.storage.useAllWithPartitions: Invalid value: true: cannot be used together with devices")
+	cluster.Spec.Storage.UseAllWithPartitions = nil
+
+	// Non-integer runtime options
+	cluster.Spec.RuntimeOpts["num_threads"] = "ten"
+	err = driver.ValidateStorageCluster(cluster)
+	require.EqualError(t, err,
+		"spec.runtimeOptions[num_threads]: Invalid value: \"ten\": must be an integer")
+	cluster.Spec.RuntimeOpts["num_threads"] = "10"
+
+	// Unknown feature gates
+	cluster.Spec.FeatureGates["Unknown"] = "true"
+	err = driver.ValidateStorageCluster(cluster)
+	require.EqualError(t, err,
+		"spec.featureGates: Unsupported value: \"Unknown\": supported values: \"CSI\"")
+	delete(cluster.Spec.FeatureGates, "Unknown")
+
+	// Non-boolean feature gate values
+	cluster.Spec.FeatureGates["CSI"] = "enabled"
+	err = driver.ValidateStorageCluster(cluster)
+	require.EqualError(t, err,
+		"spec.featureGates[CSI]: Invalid value: \"enabled\": must be a boolean")
+	cluster.Spec.FeatureGates["CSI"] = "false"
+
+	// Invalid configuration in node specs
+	cluster.Spec.Nodes = []corev1alpha1.NodeSpec{
+		{
+			Selector: corev1alpha1.NodeSelector{
+				NodeName: "node1",
+			},
+			CommonConfig: corev1alpha1.CommonConfig{
+				Storage: &corev1alpha1.StorageSpec{
+					Devices: &[]string{"/dev/sdb"},
+					UseAll:  boolPtr(true),
+				},
+				RuntimeOpts: map[string]string{
+					"num_io_threads": "",
+				},
+			},
+		},
+	}
+	err = driver.ValidateStorageCluster(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(),
+		"spec.nodes[0].storage.useAll: Invalid value: true: cannot be used together with devices")
+	require.Contains(t, err.Error(),
+		"spec.nodes[0].runtimeOptions[num_io_threads]: Invalid value: \"\": must be an integer")
+}
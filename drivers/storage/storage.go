@@ -35,6 +35,20 @@ type Driver interface {
 	ClusterPluginInterface
 	// StorkInterface interface to manage Stork related operations
 	StorkInterface
+	// NodePluginInterface interface to manage storage nodes
+	NodePluginInterface
+}
+
+// NodePluginInterface interface to manage storage nodes
+type NodePluginInterface interface {
+	// SetNodeMaintenance puts the given storage node in maintenance if enabled
+	// is true, else takes it out of maintenance. It only starts the transition;
+	// the phase of the storage node reflects when the transition is complete.
+	SetNodeMaintenance(*corev1alpha1.StorageCluster, *corev1alpha1.StorageNode, bool) error
+	// CanTakeNodeOffline checks if the storage driver on the given kubernetes
+	// node can be taken offline without the cluster losing quorum. It returns
+	// an error with the reason if the node has to stay online.
+	CanTakeNodeOffline(*corev1alpha1.StorageCluster, string) error
 }
 
 // StorkInterface interface to manage Stork related operations
@@ -50,6 +64,7 @@ type ClusterPluginInterface interface {
 	// PreInstall the driver should do whatever it is needed before the pods
 	// start to make sure the cluster comes up correctly. This should be
 	// idempotent and subsequent calls should result in the same result.
+	// The driver may report the status of its components in the cluster status.
 	PreInstall(*corev1alpha1.StorageCluster) error
 	// GetStoragePodSpec given the storage cluster spec and node name it returns the pod spec for a specific node
 	GetStoragePodSpec(*corev1alpha1.StorageCluster, string) (v1.PodSpec, error)
@@ -58,8 +73,18 @@ type ClusterPluginInterface interface {
 	// SetDefaultsOnStorageCluster sets the driver specific defaults on the storage
 	// cluster spec if they are not set
 	SetDefaultsOnStorageCluster(*corev1alpha1.StorageCluster)
+	// ValidateStorageCluster validates the driver specific configuration in the
+	// storage cluster spec. It returns an error describing all the invalid fields.
+	ValidateStorageCluster(*corev1alpha1.StorageCluster) error
 	// UpdateStorageClusterStatus update the status of storage cluster
 	UpdateStorageClusterStatus(*corev1alpha1.StorageCluster) error
+	// CanUpdateStoragePods checks if the storage cluster is healthy enough to take
+	// down more storage pods during a rolling update. The given kubernetes nodes
+	// already run the updated storage pods, so the driver should check that the
+	// storage service on those nodes is back online, along with the overall health
+	// of the cluster. It returns an error describing why the rolling update has to
+	// wait, or nil if it is safe to continue.
+	CanUpdateStoragePods(*corev1alpha1.StorageCluster, []string) error
 	// DeleteStorage is going to uninstall and delete the storage service based on
 	// StorageClusterDeleteStrategy. DeleteStorage should provide idempotent behavior
 	// and subsequent calls should result in the same result.
@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -42,22 +43,40 @@ type StorageClusterSpec struct {
 	UpdateStrategy StorageClusterUpdateStrategy `json:"updateStrategy,omitempty"`
 	// A delete strategy to uninstall and wipe an existing StorageCluster
 	DeleteStrategy *StorageClusterDeleteStrategy `json:"deleteStrategy,omitempty"`
+	// CordonAction is the action taken on the storage driver of a node when
+	// the Kubernetes node is cordoned. The action is reverted once the node
+	// is uncordoned. No action is taken if not specified.
+	CordonAction CordonActionType `json:"cordonAction,omitempty"`
 	// RevisionHistoryLimit is the number of old history to retain to allow rollback.
 	// This is a pointer to distinguish between explicit zero and not specified.
 	// Defaults to 10.
 	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+	// RollbackTo is the config this cluster is rolling back to. It will be
+	// cleared after the rollback is done. The same can be requested using the
+	// storagecluster.core.libopenstorage.org/rollback-to annotation.
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty"`
 	// Placement configuration for the storage cluster nodes
 	Placement *PlacementSpec `json:"placement,omitempty"`
 	// Image is docker image of the storage driver
 	Image string `json:"image,omitempty"`
-	// Version is the version of storage driver
+	// Version is the version of storage driver. It is derived from the tag of
+	// the image, so it needs to be specified only if the image tag is not a
+	// semantic version.
 	Version string `json:"version,omitempty"`
+	// Platform is the Kubernetes distribution the cluster is running on. The
+	// storage pods and components are adjusted for the platform. Assumes a
+	// plain Kubernetes cluster if not specified.
+	Platform PlatformType `json:"platform,omitempty"`
 	// ImagePullPolicy is the image pull policy.
 	// One of Always, Never, IfNotPresent. Defaults to Always.
 	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
 	// ImagePullSecret is a reference to secret in the same namespace as the
 	// storage cluster, used for pulling images used by this StorageClusterSpec
 	ImagePullSecret *string `json:"imagePullSecret,omitempty"`
+	// PriorityClassName is the name of the PriorityClass of the storage pods and
+	// the control plane components of the cluster, like Stork and the CSI
+	// controller. If not specified, the storage driver decides the priority.
+	PriorityClassName string `json:"priorityClassName,omitempty"`
 	// CustomImageRegistry is a custom container registry server (may include
 	// repository) that will be used instead of index.docker.io to download Docker
 	// images. (Example: myregistry.net:5443 or myregistry.com/myrepository)
@@ -75,6 +94,9 @@ type StorageClusterSpec struct {
 	// FeatureGates are a set of key-value pairs that describe what experimental
 	// features need to be enabled
 	FeatureGates map[string]string `json:"featureGates,omitempty"`
+	// Service contains the configuration of the Kubernetes services created
+	// for the storage driver and its components
+	Service *ServiceSpec `json:"service,omitempty"`
 	// CommonConfig contains specifications for storage, network, environment
 	// variables, etc for all the nodes in the cluster. These config options
 	// can be overriden using the CommonConfig in NodeSpec.
@@ -88,6 +110,12 @@ type StorageClusterSpec struct {
 	// to the storage driver. The autopilot component could augment the storage
 	// driver to take intelligent actions based on the current state of the cluster.
 	Autopilot *AutopilotSpec `json:"autopilot,omitempty"`
+	// CSI contains the configuration of the CSI components, if CSI is enabled
+	// using the feature gate
+	CSI *CSISpec `json:"csi,omitempty"`
+	// PVCController contains the configuration of the PVC controller, if the
+	// storage driver runs one
+	PVCController *PVCControllerSpec `json:"pvcController,omitempty"`
 	// Monitoring contains monitoring configuration for the storage cluster.
 	Monitoring *MonitoringSpec `json:"monitoring,omitempty"`
 	// Nodes node level configurations that will override the ones at cluster
@@ -118,6 +146,14 @@ type CommonConfig struct {
 	Env []v1.EnvVar `json:"env,omitempty"`
 	// RuntimeOpts is a map of options with extra configs for storage driver
 	RuntimeOpts map[string]string `json:"runtimeOptions,omitempty"`
+	// Resources are the compute resources required by the storage driver container
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+	// LogFile is the path of the file on the host where the storage driver
+	// writes its logs
+	LogFile string `json:"logFile,omitempty"`
+	// ExtraArgs is a list of additional arguments passed to the storage driver,
+	// for options that are not exposed in the spec
+	ExtraArgs []string `json:"extraArgs,omitempty"`
 }
 
 // NodeSelector let's the user select a node or group of nodes based on either
@@ -135,6 +171,9 @@ type NodeSelector struct {
 type PlacementSpec struct {
 	// NodeAffinity describes node affinity scheduling rules for the pods
 	NodeAffinity *v1.NodeAffinity `json:"nodeAffinity,omitempty"`
+	// Tolerations are the tolerations of the pods, so they can be scheduled
+	// on nodes with matching taints
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
 }
 
 // StorageClusterUpdateStrategy is used to control the update strategy for a StorageCluster
@@ -173,6 +212,31 @@ type RollingUpdateStorageCluster struct {
 	// that at least 70% of original number of StorageCluster pods are available at
 	// all times during the update.
 	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// Partition limits the rolling update to the first N nodes that should
+	// run the storage pod, ordered by node name. Storage pods on the rest of
+	// the nodes are left on their current revision until the partition is
+	// increased. If not set, all the nodes are updated.
+	Partition *int32 `json:"partition,omitempty"`
+	// CanarySelector limits the rolling update to the nodes that match the
+	// given label selector. Storage pods on the rest of the nodes are left on
+	// their current revision until the selector is widened or removed. If set
+	// together with Partition, only the first N matching nodes are updated.
+	CanarySelector *meta.LabelSelector `json:"canarySelector,omitempty"`
+	// ProgressDeadlineSeconds is the number of seconds the rolling update can
+	// go without updating another node, before the upgrade is considered to
+	// be stuck. A stuck upgrade is marked as timed out in the upgrade condition.
+	// If not set, the upgrade never times out.
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// AutoRollback rolls back the cluster to the revision it was running before
+	// the upgrade, if the upgrade exceeds the progress deadline.
+	AutoRollback bool `json:"autoRollback,omitempty"`
+}
+
+// RollbackConfig is the config to rollback a StorageCluster to a previous revision
+type RollbackConfig struct {
+	// Revision of the ControllerRevision to rollback to. If set to 0,
+	// the cluster is rolled back to the last revision before the current one.
+	Revision int64 `json:"revision"`
 }
 
 // StorageClusterDeleteStrategyType is enum for storage cluster delete strategies
@@ -195,6 +259,42 @@ type StorageClusterDeleteStrategy struct {
 	Type StorageClusterDeleteStrategyType `json:"type,omitempty"`
 }
 
+// CordonActionType is the action taken on a storage node when the
+// Kubernetes node is cordoned
+type CordonActionType string
+
+const (
+	// CordonActionMaintenance puts the storage node in maintenance mode
+	CordonActionMaintenance CordonActionType = "Maintenance"
+	// CordonActionShutdown stops the storage pod on the node, so that the
+	// storage driver shuts down cleanly before the node is drained
+	CordonActionShutdown CordonActionType = "Shutdown"
+)
+
+// PlatformType is the Kubernetes distribution the storage cluster is running on
+type PlatformType string
+
+const (
+	// PlatformPKS is the VMware Pivotal Container Service (PKS)
+	PlatformPKS PlatformType = "pks"
+	// PlatformOpenShift is the Red Hat OpenShift Container Platform
+	PlatformOpenShift PlatformType = "openshift"
+	// PlatformGKE is the Google Kubernetes Engine
+	PlatformGKE PlatformType = "gke"
+	// PlatformAKS is the Azure Kubernetes Service
+	PlatformAKS PlatformType = "aks"
+	// PlatformEKS is the Amazon Elastic Kubernetes Service
+	PlatformEKS PlatformType = "eks"
+)
+
+// ServiceSpec contains the configuration of the Kubernetes services
+type ServiceSpec struct {
+	// Type is the type of the Kubernetes services created for the storage
+	// driver and its components. One of ClusterIP, NodePort, LoadBalancer.
+	// If not specified, each service uses the type suitable for the platform.
+	Type v1.ServiceType `json:"type,omitempty"`
+}
+
 // KvdbSpec contains the details to access kvdb
 type KvdbSpec struct {
 	// Internal flag indicates whether to use internal kvdb or an external one
@@ -298,6 +398,10 @@ type UserInterfaceSpec struct {
 	LockImage bool `json:"lockImage,omitempty"`
 	// Env is a list of environment variables used by UI component
 	Env []v1.EnvVar `json:"env,omitempty"`
+	// Placement configuration for the user interface pods
+	Placement *PlacementSpec `json:"placement,omitempty"`
+	// Resources are the compute resources required by each user interface container
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // StorkSpec contains STORK related spec
@@ -314,6 +418,15 @@ type StorkSpec struct {
 	Args map[string]string `json:"args,omitempty"`
 	// Env is a list of environment variables used by stork
 	Env []v1.EnvVar `json:"env,omitempty"`
+	// Placement configuration for the stork and stork scheduler pods
+	Placement *PlacementSpec `json:"placement,omitempty"`
+	// Resources are the compute resources required by the stork container.
+	// If not specified, the CPU request from the stork-cpu annotation is used.
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+	// SchedulerResources are the compute resources required by the stork
+	// scheduler container. If not specified, the CPU request from the
+	// stork-scheduler-cpu annotation is used.
+	SchedulerResources *v1.ResourceRequirements `json:"schedulerResources,omitempty"`
 }
 
 // AutopilotSpec contains details of an autopilot component
@@ -332,6 +445,11 @@ type AutopilotSpec struct {
 	Args map[string]string `json:"args,omitempty"`
 	// Env is a list of environment variables used by autopilot
 	Env []v1.EnvVar `json:"env,omitempty"`
+	// Placement configuration for the autopilot pods
+	Placement *PlacementSpec `json:"placement,omitempty"`
+	// Resources are the compute resources required by the autopilot container.
+	// If not specified, the CPU request from the autopilot-cpu annotation is used.
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // DataProviderSpec contains the details for data providers for components like autopilot
@@ -344,6 +462,23 @@ type DataProviderSpec struct {
 	Params map[string]string `json:"params,omitempty"`
 }
 
+// CSISpec contains the configuration of the CSI components
+type CSISpec struct {
+	// Resources are the compute resources required by each CSI sidecar container
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// PVCControllerSpec contains the configuration of the PVC controller
+type PVCControllerSpec struct {
+	// Enabled decides whether the PVC controller is deployed. If not specified,
+	// it is deployed only on platforms where the Kubernetes controller manager
+	// cannot provision volumes of the storage driver.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Resources are the compute resources required by the PVC controller container.
+	// If not specified, the CPU request from the pvc-controller-cpu annotation is used.
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+}
+
 // MonitoringSpec contains monitoring configuration for the storage cluster.
 type MonitoringSpec struct {
 	// EnableMetrics this exposes the storage cluster metrics to external
@@ -359,6 +494,49 @@ type StorageClusterStatus struct {
 	ClusterUID string `json:"clusterUid,omitempty"`
 	// Phase is current status of the storage cluster
 	Phase string `json:"phase,omitempty"`
+	// Version is the version of the storage driver that the cluster is
+	// configured to run, after applying the defaults to the spec
+	Version string `json:"version,omitempty"`
+	// DesiredImages contains the images that the operator has computed for
+	// the storage driver and its components, after applying the defaults
+	// to the spec. The user's spec is never updated with these defaults.
+	DesiredImages *ComponentImages `json:"desiredImages,omitempty"`
+	// ObservedGeneration is the most recent generation of the StorageCluster
+	// that has been reconciled by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// DesiredNumberScheduled is the total number of nodes that should be
+	// running the storage pod, including nodes correctly running it
+	DesiredNumberScheduled int32 `json:"desiredNumberScheduled"`
+	// CurrentNumberScheduled is the number of nodes that are running at least
+	// one storage pod and are supposed to run the storage pod
+	CurrentNumberScheduled int32 `json:"currentNumberScheduled"`
+	// NumberMisscheduled is the number of nodes that are running the storage
+	// pod, but are not supposed to run the storage pod
+	NumberMisscheduled int32 `json:"numberMisscheduled"`
+	// NumberReady is the number of nodes that should be running the storage
+	// pod and have one or more of the storage pods running and ready
+	NumberReady int32 `json:"numberReady"`
+	// UpdatedNumberScheduled is the number of nodes that should be running the
+	// storage pod and are running the storage pod of the latest revision
+	UpdatedNumberScheduled int32 `json:"updatedNumberScheduled,omitempty"`
+	// NumberAvailable is the number of nodes that should be running the
+	// storage pod and have one or more of the storage pods running and available
+	NumberAvailable int32 `json:"numberAvailable,omitempty"`
+	// NumberUnavailable is the number of nodes that should be running the
+	// storage pod and have none of the storage pods running and available
+	NumberUnavailable int32 `json:"numberUnavailable,omitempty"`
+	// UpdatedNodes is the number of nodes running the storage pod of the
+	// latest revision of the StorageCluster
+	UpdatedNodes int32 `json:"updatedNodes"`
+	// OutdatedNodes is the number of nodes running the storage pod of an
+	// older revision of the StorageCluster
+	OutdatedNodes int32 `json:"outdatedNodes"`
+	// CurrentRevision is the hash of the revision of the StorageCluster that
+	// all the storage pods were running when the last rollout completed
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// UpdateRevision is the hash of the latest revision of the StorageCluster
+	// that is being rolled out to the storage pods
+	UpdateRevision string `json:"updateRevision,omitempty"`
 	// Count of hash collisions for the StorageCluster. The StorageCluster
 	// controller uses this field as a collision avoidance mechanism when it
 	// needs to create the name of the newest ControllerRevision.
@@ -367,12 +545,63 @@ type StorageClusterStatus struct {
 	Conditions []ClusterCondition `json:"conditions,omitempty"`
 	// Storage represents cluster storage details
 	Storage Storage `json:"storage,omitempty"`
+	// ClusterScopedObjects are the cluster scoped objects created for the
+	// cluster. They cannot be garbage collected through the cluster, so the
+	// operator deletes them when the cluster is deleted.
+	ClusterScopedObjects []ObjectReference `json:"clusterScopedObjects,omitempty"`
+	// Components is the result of the last reconcile of the components of
+	// the storage driver, like CSI or monitoring
+	Components []ComponentStatus `json:"components,omitempty"`
+}
+
+// ComponentStatus is the result of the last reconcile of a component of the
+// storage driver
+type ComponentStatus struct {
+	// Name is the name of the component
+	Name string `json:"name"`
+	// Status is Completed if the component was reconciled, or Failed otherwise
+	Status ClusterConditionStatus `json:"status"`
+	// Message is a human readable message indicating why the component failed
+	Message string `json:"message,omitempty"`
+}
+
+// ObjectReference is a reference to a cluster scoped object
+type ObjectReference struct {
+	// APIVersion is the API version of the object
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the object
+	Kind string `json:"kind"`
+	// Name is the name of the object
+	Name string `json:"name"`
+}
+
+// ComponentImages contains the images used by the storage driver and its components
+type ComponentImages struct {
+	// StorageDriver is the docker image of the storage driver
+	StorageDriver string `json:"storageDriver,omitempty"`
+	// Stork is the docker image of the STORK container
+	Stork string `json:"stork,omitempty"`
+	// UserInterface is the docker image of the user interface container
+	UserInterface string `json:"userInterface,omitempty"`
+	// Autopilot is the docker image of the autopilot container
+	Autopilot string `json:"autopilot,omitempty"`
 }
 
 // Storage represents cluster storage details
 type Storage struct {
 	// StorageNodesPerZone describes the amount of instances per zone
 	StorageNodesPerZone int32 `json:"storageNodesPerZone,omitempty"`
+	// TotalSize is the total capacity of the storage pools in the cluster
+	TotalSize resource.Quantity `json:"totalSize,omitempty"`
+	// UsedSize is the used capacity of the storage pools in the cluster
+	UsedSize resource.Quantity `json:"usedSize,omitempty"`
+	// AvailableSize is the capacity of the storage pools in the cluster
+	// that is not used yet
+	AvailableSize resource.Quantity `json:"availableSize,omitempty"`
+	// StorageNodes is the number of nodes in the cluster with storage
+	StorageNodes int32 `json:"storageNodes,omitempty"`
+	// StoragelessNodes is the number of nodes in the cluster without storage
+	StoragelessNodes int32 `json:"storagelessNodes,omitempty"`
 }
 
 // ClusterCondition contains condition information for the cluster
@@ -381,8 +610,17 @@ type ClusterCondition struct {
 	Type ClusterConditionType `json:"type"`
 	// Status of the condition
 	Status ClusterConditionStatus `json:"status"`
-	// Reason is human readable message indicating details about the current state of the cluster
+	// Reason is a brief CamelCase reason for the last transition of the condition
 	Reason string `json:"reason"`
+	// Message is human readable message indicating details about the current
+	// state of the cluster
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is the last time the condition transitioned from one
+	// status to another
+	LastTransitionTime meta.Time `json:"lastTransitionTime,omitempty"`
+	// LastUpdateTime is the last time the condition was updated. For the upgrade
+	// condition, it is the last time the upgrade made progress.
+	LastUpdateTime meta.Time `json:"lastUpdateTime,omitempty"`
 }
 
 // ClusterConditionType is the enum type for different cluster conditions
@@ -396,6 +634,24 @@ const (
 	ClusterConditionTypeDelete ClusterConditionType = "Delete"
 	// ClusterConditionTypeInstall indicates the status for an install operation on the cluster
 	ClusterConditionTypeInstall ClusterConditionType = "Install"
+	// ClusterConditionTypeValidation indicates the status of the validation of the
+	// cluster, for instance a conflict with other clusters
+	ClusterConditionTypeValidation ClusterConditionType = "Validation"
+	// ClusterConditionTypeRollback indicates the status of the last rollback of
+	// the cluster to a previous revision
+	ClusterConditionTypeRollback ClusterConditionType = "Rollback"
+	// ClusterConditionTypeReady indicates whether all the storage pods of the
+	// cluster are running the latest revision and are ready
+	ClusterConditionTypeReady ClusterConditionType = "Ready"
+	// ClusterConditionTypeReconciling indicates that the controller is still
+	// working towards the desired state of the cluster
+	ClusterConditionTypeReconciling ClusterConditionType = "Reconciling"
+	// ClusterConditionTypeStalled indicates that the controller cannot make
+	// progress towards the desired state of the cluster without intervention
+	ClusterConditionTypeStalled ClusterConditionType = "Stalled"
+	// ClusterConditionTypeMigration indicates the status of the migration of the
+	// pods of an existing DaemonSet to the cluster
+	ClusterConditionTypeMigration ClusterConditionType = "Migration"
 )
 
 // ClusterConditionStatus is the enum type for cluster condition statuses
@@ -421,6 +677,12 @@ const (
 	ClusterOperationFailed ClusterConditionStatus = "Failed"
 	// ClusterOperationTimeout means the cluster operation timedout
 	ClusterOperationTimeout ClusterConditionStatus = "Timeout"
+	// ClusterConditionTrue means the condition holds. It is used by the
+	// Ready, Reconciling and Stalled conditions.
+	ClusterConditionTrue ClusterConditionStatus = "True"
+	// ClusterConditionFalse means the condition does not hold. It is used
+	// by the Ready, Reconciling and Stalled conditions.
+	ClusterConditionFalse ClusterConditionStatus = "False"
 )
 
 func init() {
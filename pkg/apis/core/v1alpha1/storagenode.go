@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -39,6 +40,10 @@ type StorageNodeSpec struct {
 	Version string `json:"version,omitempty"`
 	// CloudStorage configuration specifying storage for the node in cloud environments
 	CloudStorage StorageNodeCloudDriveConfigs `json:"cloudStorage,omitempty"`
+	// Maintenance puts the storage node in maintenance when true and takes it
+	// out of maintenance when false. If not set, the maintenance state of the
+	// node is not changed by the operator.
+	Maintenance *bool `json:"maintenance,omitempty"`
 }
 
 // StorageNodeCloudDriveConfigs specifies storage for the node in cloud environments
@@ -71,6 +76,73 @@ type NodeStatus struct {
 	Geo Geography `json:"geography,omitempty"`
 	// Conditions is an array of current node conditions
 	Conditions []NodeCondition `json:"conditions,omitempty"`
+	// DesiredVersion is the version of the storage driver that the node is
+	// expected to run. It is set only while the node runs a different version.
+	DesiredVersion string `json:"desiredVersion,omitempty"`
+	// Pod is the status of the storage pod running on the node
+	Pod *StoragePodStatus `json:"pod,omitempty"`
+	// Storage contains the capacity, storage pools and drives of the node
+	Storage NodeStorageStatus `json:"storage,omitempty"`
+}
+
+// NodeStorageStatus contains the storage details of the storage node
+type NodeStorageStatus struct {
+	// TotalSize is the total capacity of the storage pools on the node
+	TotalSize resource.Quantity `json:"totalSize,omitempty"`
+	// UsedSize is the used capacity of the storage pools on the node
+	UsedSize resource.Quantity `json:"usedSize,omitempty"`
+	// Pools are the storage pools on the node
+	Pools []StoragePoolStatus `json:"pools,omitempty"`
+	// Drives are the drives used by the storage driver on the node
+	Drives []StorageDriveStatus `json:"drives,omitempty"`
+}
+
+// StoragePoolStatus contains the details of a storage pool on the node
+type StoragePoolStatus struct {
+	// ID of the storage pool on the node
+	ID int32 `json:"id"`
+	// Medium is the type of the storage underlying the pool
+	Medium string `json:"medium,omitempty"`
+	// TotalSize is the total capacity of the pool
+	TotalSize resource.Quantity `json:"totalSize,omitempty"`
+	// UsedSize is the used capacity of the pool
+	UsedSize resource.Quantity `json:"usedSize,omitempty"`
+	// Labels are the labels of the pool
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// StorageDriveStatus contains the details of a drive on the node
+type StorageDriveStatus struct {
+	// ID of the drive
+	ID string `json:"id,omitempty"`
+	// Path of the drive on the node
+	Path string `json:"path,omitempty"`
+	// Medium is the type of the drive
+	Medium string `json:"medium,omitempty"`
+	// Online is true if the drive is online
+	Online bool `json:"online"`
+	// TotalSize is the total capacity of the drive
+	TotalSize resource.Quantity `json:"totalSize,omitempty"`
+	// UsedSize is the used capacity of the drive
+	UsedSize resource.Quantity `json:"usedSize,omitempty"`
+	// Metadata is true if the drive is dedicated to metadata
+	Metadata bool `json:"metadata,omitempty"`
+}
+
+// StoragePodStatus contains the status of the storage pod running on a node
+type StoragePodStatus struct {
+	// Name of the storage pod
+	Name string `json:"name,omitempty"`
+	// Revision is the controller revision hash of the storage pod
+	Revision string `json:"revision,omitempty"`
+	// Updated is true if the storage pod runs the current revision of the cluster
+	Updated bool `json:"updated"`
+	// Image running in the storage container of the pod
+	Image string `json:"image,omitempty"`
+	// RestartCount is the number of times the storage container has restarted
+	RestartCount int32 `json:"restartCount,omitempty"`
+	// LastRestartTime is the time at which the storage container last started
+	LastRestartTime *meta.Time `json:"lastRestartTime,omitempty"`
 }
 
 // NetworkStatus network status of the storage node
@@ -89,6 +161,9 @@ type NodeCondition struct {
 	Status ConditionStatus `json:"status,omitempty"`
 	// Reason is human readable message indicating details about the condition status
 	Reason string `json:"reason,omitempty"`
+	// LastTransitionTime is the last time the condition transitioned from one
+	// status or reason to another
+	LastTransitionTime meta.Time `json:"lastTransitionTime,omitempty"`
 }
 
 // NodeConditionType is the enum type for different node conditions
@@ -101,6 +176,12 @@ const (
 	NodeState NodeConditionType = "NodeState"
 	// StorageState is used for the state of storage in the node
 	StorageState NodeConditionType = "StorageState"
+	// MaintenanceState is used for the progress of moving the node in or out
+	// of maintenance, as requested in the spec of the node
+	MaintenanceState NodeConditionType = "MaintenanceState"
+	// CordonState is used for the progress of taking the node offline when
+	// the Kubernetes node is cordoned, and bringing it back when uncordoned
+	CordonState NodeConditionType = "CordonState"
 )
 
 // ConditionStatus is the enum type for node condition statuses
@@ -124,6 +205,12 @@ const (
 	NodeOffline ConditionStatus = "Offline"
 	// NodeUnknown means the node condition is not known
 	NodeUnknown ConditionStatus = "Unknown"
+	// NodeOperationInProgress means the operation on the node is in progress
+	NodeOperationInProgress ConditionStatus = "InProgress"
+	// NodeOperationCompleted means the operation on the node has completed
+	NodeOperationCompleted ConditionStatus = "Completed"
+	// NodeOperationFailed means the operation on the node has failed
+	NodeOperationFailed ConditionStatus = "Failed"
 )
 
 func init() {
@@ -51,6 +51,16 @@ func (in *AutopilotSpec) DeepCopyInto(out *AutopilotSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(PlacementSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -64,6 +74,27 @@ func (in *AutopilotSpec) DeepCopy() *AutopilotSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CSISpec) DeepCopyInto(out *CSISpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSISpec.
+func (in *CSISpec) DeepCopy() *CSISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CSISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudStorageCapacitySpec) DeepCopyInto(out *CloudStorageCapacitySpec) {
 	*out = *in
@@ -142,6 +173,8 @@ func (in *CloudStorageSpec) DeepCopy() *CloudStorageSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
 	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
 	return
 }
 
@@ -182,6 +215,16 @@ func (in *CommonConfig) DeepCopyInto(out *CommonConfig) {
 			(*out)[key] = val
 		}
 	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtraArgs != nil {
+		in, out := &in.ExtraArgs, &out.ExtraArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -195,6 +238,38 @@ func (in *CommonConfig) DeepCopy() *CommonConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentImages) DeepCopyInto(out *ComponentImages) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentImages.
+func (in *ComponentImages) DeepCopy() *ComponentImages {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentImages)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataProviderSpec) DeepCopyInto(out *DataProviderSpec) {
 	*out = *in
@@ -316,6 +391,7 @@ func (in *NetworkStatus) DeepCopy() *NetworkStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeCondition) DeepCopyInto(out *NodeCondition) {
 	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 	return
 }
 
@@ -376,8 +452,16 @@ func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]NodeCondition, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Pod != nil {
+		in, out := &in.Pod, &out.Pod
+		*out = new(StoragePodStatus)
+		(*in).DeepCopyInto(*out)
 	}
+	in.Storage.DeepCopyInto(&out.Storage)
 	return
 }
 
@@ -391,6 +475,80 @@ func (in *NodeStatus) DeepCopy() *NodeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeStorageStatus) DeepCopyInto(out *NodeStorageStatus) {
+	*out = *in
+	out.TotalSize = in.TotalSize.DeepCopy()
+	out.UsedSize = in.UsedSize.DeepCopy()
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = make([]StoragePoolStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Drives != nil {
+		in, out := &in.Drives, &out.Drives
+		*out = make([]StorageDriveStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStorageStatus.
+func (in *NodeStorageStatus) DeepCopy() *NodeStorageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeStorageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectReference.
+func (in *ObjectReference) DeepCopy() *ObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PVCControllerSpec) DeepCopyInto(out *PVCControllerSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCControllerSpec.
+func (in *PVCControllerSpec) DeepCopy() *PVCControllerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PVCControllerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlacementSpec) DeepCopyInto(out *PlacementSpec) {
 	*out = *in
@@ -399,6 +557,13 @@ func (in *PlacementSpec) DeepCopyInto(out *PlacementSpec) {
 		*out = new(v1.NodeAffinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -412,6 +577,22 @@ func (in *PlacementSpec) DeepCopy() *PlacementSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackConfig) DeepCopyInto(out *RollbackConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackConfig.
+func (in *RollbackConfig) DeepCopy() *RollbackConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RollingUpdateStorageCluster) DeepCopyInto(out *RollingUpdateStorageCluster) {
 	*out = *in
@@ -420,6 +601,21 @@ func (in *RollingUpdateStorageCluster) DeepCopyInto(out *RollingUpdateStorageClu
 		*out = new(intstr.IntOrString)
 		**out = **in
 	}
+	if in.Partition != nil {
+		in, out := &in.Partition, &out.Partition
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CanarySelector != nil {
+		in, out := &in.CanarySelector, &out.CanarySelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -433,9 +629,28 @@ func (in *RollingUpdateStorageCluster) DeepCopy() *RollingUpdateStorageCluster {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceSpec) DeepCopyInto(out *ServiceSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceSpec.
+func (in *ServiceSpec) DeepCopy() *ServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Storage) DeepCopyInto(out *Storage) {
 	*out = *in
+	out.TotalSize = in.TotalSize.DeepCopy()
+	out.UsedSize = in.UsedSize.DeepCopy()
+	out.AvailableSize = in.AvailableSize.DeepCopy()
 	return
 }
 
@@ -540,6 +755,11 @@ func (in *StorageClusterSpec) DeepCopyInto(out *StorageClusterSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(RollbackConfig)
+		**out = **in
+	}
 	if in.Placement != nil {
 		in, out := &in.Placement, &out.Placement
 		*out = new(PlacementSpec)
@@ -577,6 +797,11 @@ func (in *StorageClusterSpec) DeepCopyInto(out *StorageClusterSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServiceSpec)
+		**out = **in
+	}
 	in.CommonConfig.DeepCopyInto(&out.CommonConfig)
 	if in.UserInterface != nil {
 		in, out := &in.UserInterface, &out.UserInterface
@@ -593,6 +818,16 @@ func (in *StorageClusterSpec) DeepCopyInto(out *StorageClusterSpec) {
 		*out = new(AutopilotSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(CSISpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PVCController != nil {
+		in, out := &in.PVCController, &out.PVCController
+		*out = new(PVCControllerSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Monitoring != nil {
 		in, out := &in.Monitoring, &out.Monitoring
 		*out = new(MonitoringSpec)
@@ -621,6 +856,11 @@ func (in *StorageClusterSpec) DeepCopy() *StorageClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageClusterStatus) DeepCopyInto(out *StorageClusterStatus) {
 	*out = *in
+	if in.DesiredImages != nil {
+		in, out := &in.DesiredImages, &out.DesiredImages
+		*out = new(ComponentImages)
+		**out = **in
+	}
 	if in.CollisionCount != nil {
 		in, out := &in.CollisionCount, &out.CollisionCount
 		*out = new(int32)
@@ -629,9 +869,21 @@ func (in *StorageClusterStatus) DeepCopyInto(out *StorageClusterStatus) {
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]ClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.ClusterScopedObjects != nil {
+		in, out := &in.ClusterScopedObjects, &out.ClusterScopedObjects
+		*out = make([]ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentStatus, len(*in))
 		copy(*out, *in)
 	}
-	out.Storage = in.Storage
 	return
 }
 
@@ -666,6 +918,24 @@ func (in *StorageClusterUpdateStrategy) DeepCopy() *StorageClusterUpdateStrategy
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageDriveStatus) DeepCopyInto(out *StorageDriveStatus) {
+	*out = *in
+	out.TotalSize = in.TotalSize.DeepCopy()
+	out.UsedSize = in.UsedSize.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageDriveStatus.
+func (in *StorageDriveStatus) DeepCopy() *StorageDriveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageDriveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageNode) DeepCopyInto(out *StorageNode) {
 	*out = *in
@@ -777,6 +1047,11 @@ func (in *StorageNodeList) DeepCopyObject() runtime.Object {
 func (in *StorageNodeSpec) DeepCopyInto(out *StorageNodeSpec) {
 	*out = *in
 	in.CloudStorage.DeepCopyInto(&out.CloudStorage)
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -790,6 +1065,51 @@ func (in *StorageNodeSpec) DeepCopy() *StorageNodeSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoragePodStatus) DeepCopyInto(out *StoragePodStatus) {
+	*out = *in
+	if in.LastRestartTime != nil {
+		in, out := &in.LastRestartTime, &out.LastRestartTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoragePodStatus.
+func (in *StoragePodStatus) DeepCopy() *StoragePodStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StoragePodStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoragePoolStatus) DeepCopyInto(out *StoragePoolStatus) {
+	*out = *in
+	out.TotalSize = in.TotalSize.DeepCopy()
+	out.UsedSize = in.UsedSize.DeepCopy()
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoragePoolStatus.
+func (in *StoragePoolStatus) DeepCopy() *StoragePoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StoragePoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
 	*out = *in
@@ -857,6 +1177,21 @@ func (in *StorkSpec) DeepCopyInto(out *StorkSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(PlacementSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchedulerResources != nil {
+		in, out := &in.SchedulerResources, &out.SchedulerResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -880,6 +1215,16 @@ func (in *UserInterfaceSpec) DeepCopyInto(out *UserInterfaceSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(PlacementSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
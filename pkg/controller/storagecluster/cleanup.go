@@ -0,0 +1,285 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	"github.com/sirupsen/logrus"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1beta1 "k8s.io/api/scheduling/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterScopedType is a type of cluster scoped objects that the operator
+// creates for StorageClusters
+type clusterScopedType struct {
+	kind      schema.GroupVersionKind
+	newObject func() runtime.Object
+	newList   func() runtime.Object
+}
+
+var (
+	crdKind = apiextensionsv1beta1.SchemeGroupVersion.WithKind("CustomResourceDefinition")
+
+	clusterScopedTypes = []clusterScopedType{
+		{
+			kind:      rbacv1.SchemeGroupVersion.WithKind("ClusterRole"),
+			newObject: func() runtime.Object { return &rbacv1.ClusterRole{} },
+			newList:   func() runtime.Object { return &rbacv1.ClusterRoleList{} },
+		},
+		{
+			kind:      rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"),
+			newObject: func() runtime.Object { return &rbacv1.ClusterRoleBinding{} },
+			newList:   func() runtime.Object { return &rbacv1.ClusterRoleBindingList{} },
+		},
+		{
+			kind:      storagev1.SchemeGroupVersion.WithKind("StorageClass"),
+			newObject: func() runtime.Object { return &storagev1.StorageClass{} },
+			newList:   func() runtime.Object { return &storagev1.StorageClassList{} },
+		},
+		{
+			kind:      storagev1beta1.SchemeGroupVersion.WithKind("CSIDriver"),
+			newObject: func() runtime.Object { return &storagev1beta1.CSIDriver{} },
+			newList:   func() runtime.Object { return &storagev1beta1.CSIDriverList{} },
+		},
+		{
+			kind:      schedulingv1beta1.SchemeGroupVersion.WithKind("PriorityClass"),
+			newObject: func() runtime.Object { return &schedulingv1beta1.PriorityClass{} },
+			newList:   func() runtime.Object { return &schedulingv1beta1.PriorityClassList{} },
+		},
+		{
+			kind:      crdKind,
+			newObject: func() runtime.Object { return &apiextensionsv1beta1.CustomResourceDefinition{} },
+			newList:   func() runtime.Object { return &apiextensionsv1beta1.CustomResourceDefinitionList{} },
+		},
+	}
+)
+
+// managedObject is a cluster scoped object with the managed label
+type managedObject struct {
+	ref  corev1alpha1.ObjectReference
+	meta metav1.Object
+}
+
+// setClusterScopedObjects records the cluster scoped objects of the given
+// cluster in its status, so they are deleted along with the cluster even if
+// they have lost the managed label
+func (c *Controller) setClusterScopedObjects(cluster *corev1alpha1.StorageCluster) error {
+	objects, err := c.getManagedObjects()
+	if err != nil {
+		return err
+	}
+	var refs []corev1alpha1.ObjectReference
+	for _, obj := range objects {
+		if belongsToCluster(obj.meta, cluster) {
+			refs = append(refs, obj.ref)
+		}
+	}
+	cluster.Status.ClusterScopedObjects = refs
+	return nil
+}
+
+// deleteClusterScopedObjects deletes the cluster scoped objects of the given
+// cluster, or removes the cluster from their owners if they are shared with
+// other clusters. The CRDs are shared by all the clusters and may contain
+// objects created by the users, so they are deleted only when the storage
+// driver is uninstalled from the last cluster.
+func (c *Controller) deleteClusterScopedObjects(cluster *corev1alpha1.StorageCluster) error {
+	objects, err := c.getManagedObjects()
+	if err != nil {
+		return err
+	}
+	refs := make(map[corev1alpha1.ObjectReference]bool)
+	for _, ref := range cluster.Status.ClusterScopedObjects {
+		refs[ref] = true
+	}
+	for _, obj := range objects {
+		if belongsToCluster(obj.meta, cluster) {
+			refs[obj.ref] = true
+		}
+	}
+
+	lastCluster, err := c.isLastStorageCluster(cluster)
+	if err != nil {
+		return err
+	}
+	uninstall := cluster.Spec.DeleteStrategy != nil && lastCluster
+	owner := metav1.NewControllerRef(cluster, controllerKind)
+	for _, ref := range sortedObjectReferences(refs) {
+		objType := clusterScopedTypeOf(ref)
+		if objType == nil {
+			logrus.Warnf("Cannot delete %s %s of StorageCluster %s/%s as the kind is not known",
+				ref.Kind, ref.Name, cluster.Namespace, cluster.Name)
+			continue
+		}
+		obj := objType.newObject()
+		objMeta, _ := meta.Accessor(obj)
+		objMeta.SetName(ref.Name)
+
+		if objType.kind == crdKind {
+			if !uninstall {
+				continue
+			}
+			err = k8sutil.Delete(c.client, obj)
+		} else {
+			err = k8sutil.Delete(c.client, obj, *owner)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete %s %s: %v", ref.Kind, ref.Name, err)
+		}
+	}
+	return nil
+}
+
+// deleteOrphanedObjects removes the StorageClusters that no longer exist
+// from the owners of the managed cluster scoped objects, and deletes the
+// objects that are left without an owner. This cleans up the objects of the
+// clusters that were deleted without the operator running.
+func (c *Controller) deleteOrphanedObjects() error {
+	// The objects are listed before the clusters, so the objects of a cluster
+	// created in the meantime are not mistaken for orphans
+	objects, err := c.getManagedObjects()
+	if err != nil {
+		return err
+	}
+	clusterList := &corev1alpha1.StorageClusterList{}
+	if err := c.client.List(context.TODO(), clusterList, &client.ListOptions{}); err != nil {
+		return fmt.Errorf("failed to list storage clusters. %v", err)
+	}
+	clusterUIDs := make(map[types.UID]bool)
+	for _, cluster := range clusterList.Items {
+		clusterUIDs[cluster.UID] = true
+	}
+
+	for _, obj := range objects {
+		var staleOwners []metav1.OwnerReference
+		for _, uid := range k8sutil.StorageClusterOwners(obj.meta) {
+			if !clusterUIDs[uid] {
+				staleOwners = append(staleOwners, metav1.OwnerReference{
+					APIVersion: controllerKind.GroupVersion().String(),
+					Kind:       controllerKind.Kind,
+					UID:        uid,
+				})
+			}
+		}
+		if len(staleOwners) == 0 {
+			continue
+		}
+		logrus.Infof("Removing deleted StorageClusters from the owners of %s %s",
+			obj.ref.Kind, obj.ref.Name)
+		toDelete := clusterScopedTypeOf(obj.ref).newObject()
+		toDeleteMeta, _ := meta.Accessor(toDelete)
+		toDeleteMeta.SetName(obj.ref.Name)
+		if err := k8sutil.Delete(c.client, toDelete, staleOwners...); err != nil {
+			return fmt.Errorf("failed to delete orphaned %s %s: %v", obj.ref.Kind, obj.ref.Name, err)
+		}
+	}
+	return nil
+}
+
+// getManagedObjects returns the cluster scoped objects with the managed label.
+// Types that are not served by the Kubernetes cluster are skipped.
+func (c *Controller) getManagedObjects() ([]managedObject, error) {
+	var objects []managedObject
+	for _, objType := range clusterScopedTypes {
+		list := objType.newList()
+		err := c.client.List(
+			context.TODO(),
+			list,
+			&client.ListOptions{
+				LabelSelector: labels.SelectorFromSet(map[string]string{util.LabelManaged: "true"}),
+			},
+		)
+		if meta.IsNoMatchError(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to list %s objects. %v", objType.kind.Kind, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			itemMeta, err := meta.Accessor(item)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, managedObject{
+				ref: corev1alpha1.ObjectReference{
+					APIVersion: objType.kind.GroupVersion().String(),
+					Kind:       objType.kind.Kind,
+					Name:       itemMeta.GetName(),
+				},
+				meta: itemMeta,
+			})
+		}
+	}
+	return objects, nil
+}
+
+// isLastStorageCluster returns true if no other StorageCluster exists
+func (c *Controller) isLastStorageCluster(cluster *corev1alpha1.StorageCluster) (bool, error) {
+	clusterList := &corev1alpha1.StorageClusterList{}
+	if err := c.client.List(context.TODO(), clusterList, &client.ListOptions{}); err != nil {
+		return false, fmt.Errorf("failed to list storage clusters. %v", err)
+	}
+	for _, other := range clusterList.Items {
+		if other.UID != cluster.UID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// belongsToCluster returns true if the owner labels of the given managed
+// object have the cluster, or if the object has no owner labels as it is
+// shared by all the clusters
+func belongsToCluster(objMeta metav1.Object, cluster *corev1alpha1.StorageCluster) bool {
+	owners := k8sutil.StorageClusterOwners(objMeta)
+	if len(owners) == 0 {
+		return true
+	}
+	for _, owner := range owners {
+		if owner == cluster.UID {
+			return true
+		}
+	}
+	return false
+}
+
+func clusterScopedTypeOf(ref corev1alpha1.ObjectReference) *clusterScopedType {
+	for i := range clusterScopedTypes {
+		if clusterScopedTypes[i].kind.GroupVersion().String() == ref.APIVersion &&
+			clusterScopedTypes[i].kind.Kind == ref.Kind {
+			return &clusterScopedTypes[i]
+		}
+	}
+	return nil
+}
+
+func sortedObjectReferences(refs map[corev1alpha1.ObjectReference]bool) []corev1alpha1.ObjectReference {
+	sorted := make([]corev1alpha1.ObjectReference, 0, len(refs))
+	for ref := range refs {
+		sorted = append(sorted, ref)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Kind != sorted[j].Kind {
+			return sorted[i].Kind < sorted[j].Kind
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
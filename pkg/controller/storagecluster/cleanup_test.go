@@ -0,0 +1,197 @@
+package storagecluster
+
+import (
+	"testing"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSetClusterScopedObjects(t *testing.T) {
+	cluster := createCleanupTestCluster("px-cluster", "cluster-uid")
+	otherCluster := createCleanupTestCluster("other-cluster", "other-uid")
+	k8sClient := testutil.FakeK8sClient(
+		cluster,
+		managedClusterRole("owned-role", cluster),
+		managedClusterRole("other-role", otherCluster),
+		managedCSIDriver("shared-driver", cluster, otherCluster),
+		managedCRD("shared-crd"),
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "unlabelled-binding",
+				Labels: ownerLabels(cluster),
+			},
+		},
+	)
+	controller := Controller{client: k8sClient}
+
+	err := controller.setClusterScopedObjects(cluster)
+	require.NoError(t, err)
+	require.ElementsMatch(t,
+		[]corev1alpha1.ObjectReference{
+			{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "owned-role"},
+			{APIVersion: "storage.k8s.io/v1beta1", Kind: "CSIDriver", Name: "shared-driver"},
+			{APIVersion: "apiextensions.k8s.io/v1beta1", Kind: "CustomResourceDefinition", Name: "shared-crd"},
+		},
+		cluster.Status.ClusterScopedObjects,
+	)
+}
+
+func TestDeleteClusterScopedObjects(t *testing.T) {
+	cluster := createCleanupTestCluster("px-cluster", "cluster-uid")
+	otherCluster := createCleanupTestCluster("other-cluster", "other-uid")
+	// The storage class has lost the managed label, but is still in the inventory
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "owned-sc",
+			Labels: ownerLabels(cluster),
+		},
+	}
+	cluster.Status.ClusterScopedObjects = []corev1alpha1.ObjectReference{
+		{APIVersion: "storage.k8s.io/v1", Kind: "StorageClass", Name: "owned-sc"},
+		{APIVersion: "storage.k8s.io/v1", Kind: "StorageClass", Name: "missing-sc"},
+	}
+	k8sClient := testutil.FakeK8sClient(
+		cluster,
+		otherCluster,
+		storageClass,
+		managedClusterRole("owned-role", cluster),
+		managedClusterRole("other-role", otherCluster),
+		managedCSIDriver("shared-driver", cluster, otherCluster),
+		managedCRD("shared-crd"),
+	)
+	controller := Controller{client: k8sClient}
+
+	err := controller.deleteClusterScopedObjects(cluster)
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, &storagev1.StorageClass{}, "owned-sc", "")
+	require.True(t, errors.IsNotFound(err))
+	err = testutil.Get(k8sClient, &rbacv1.ClusterRole{}, "owned-role", "")
+	require.True(t, errors.IsNotFound(err))
+
+	// Objects of other clusters should not be deleted
+	err = testutil.Get(k8sClient, &rbacv1.ClusterRole{}, "other-role", "")
+	require.NoError(t, err)
+
+	// Shared objects should only be disowned
+	csiDriver := &storagev1beta1.CSIDriver{}
+	err = testutil.Get(k8sClient, csiDriver, "shared-driver", "")
+	require.NoError(t, err)
+	require.Equal(t, []types.UID{otherCluster.UID}, k8sutil.StorageClusterOwners(csiDriver))
+
+	// The CRD should not be deleted while other clusters exist
+	err = testutil.Get(k8sClient, &apiextensionsv1beta1.CustomResourceDefinition{}, "shared-crd", "")
+	require.NoError(t, err)
+}
+
+func TestDeleteClusterScopedObjectsOnUninstall(t *testing.T) {
+	cluster := createCleanupTestCluster("px-cluster", "cluster-uid")
+	k8sClient := testutil.FakeK8sClient(cluster, managedCRD("shared-crd"))
+	controller := Controller{client: k8sClient}
+
+	// The CRD should not be deleted if the storage driver is not uninstalled
+	err := controller.deleteClusterScopedObjects(cluster)
+	require.NoError(t, err)
+	err = testutil.Get(k8sClient, &apiextensionsv1beta1.CustomResourceDefinition{}, "shared-crd", "")
+	require.NoError(t, err)
+
+	// The CRD should be deleted when uninstalling from the last cluster
+	cluster.Spec.DeleteStrategy = &corev1alpha1.StorageClusterDeleteStrategy{
+		Type: corev1alpha1.UninstallStorageClusterStrategyType,
+	}
+	err = controller.deleteClusterScopedObjects(cluster)
+	require.NoError(t, err)
+	err = testutil.Get(k8sClient, &apiextensionsv1beta1.CustomResourceDefinition{}, "shared-crd", "")
+	require.True(t, errors.IsNotFound(err))
+}
+
+func TestDeleteOrphanedObjects(t *testing.T) {
+	cluster := createCleanupTestCluster("px-cluster", "cluster-uid")
+	deletedCluster := createCleanupTestCluster("deleted-cluster", "deleted-uid")
+	k8sClient := testutil.FakeK8sClient(
+		cluster,
+		managedClusterRole("live-role", cluster),
+		managedClusterRole("orphaned-role", deletedCluster),
+		managedCSIDriver("shared-driver", cluster, deletedCluster),
+		managedCRD("shared-crd"),
+	)
+	controller := Controller{client: k8sClient}
+
+	err := controller.deleteOrphanedObjects()
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, &rbacv1.ClusterRole{}, "live-role", "")
+	require.NoError(t, err)
+	err = testutil.Get(k8sClient, &rbacv1.ClusterRole{}, "orphaned-role", "")
+	require.True(t, errors.IsNotFound(err))
+
+	csiDriver := &storagev1beta1.CSIDriver{}
+	err = testutil.Get(k8sClient, csiDriver, "shared-driver", "")
+	require.NoError(t, err)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(csiDriver))
+
+	// Objects not owned by any cluster are left alone
+	err = testutil.Get(k8sClient, &apiextensionsv1beta1.CustomResourceDefinition{}, "shared-crd", "")
+	require.NoError(t, err)
+}
+
+func createCleanupTestCluster(name, uid string) *corev1alpha1.StorageCluster {
+	return &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "kube-test",
+			UID:       types.UID(uid),
+		},
+	}
+}
+
+func managedClusterRole(name string, owner *corev1alpha1.StorageCluster) runtime.Object {
+	labels := ownerLabels(owner)
+	labels[util.LabelManaged] = "true"
+	return &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func managedCSIDriver(name string, owners ...*corev1alpha1.StorageCluster) runtime.Object {
+	labels := ownerLabels(owners...)
+	labels[util.LabelManaged] = "true"
+	return &storagev1beta1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func ownerLabels(owners ...*corev1alpha1.StorageCluster) map[string]string {
+	labels := make(map[string]string)
+	for _, owner := range owners {
+		labels[util.LabelOwnerPrefix+string(owner.UID)] = "true"
+	}
+	return labels
+}
+
+func managedCRD(name string) runtime.Object {
+	return &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{util.LabelManaged: "true"},
+		},
+	}
+}
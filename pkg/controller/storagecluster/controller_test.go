@@ -238,7 +238,7 @@ func TestKubernetesVersionValidation(t *testing.T) {
 			v1.EventTypeWarning, util.FailedValidationReason))
 }
 
-func TestSingleClusterValidation(t *testing.T) {
+func TestMultipleClusterValidation(t *testing.T) {
 	existingCluster := &corev1alpha1.StorageCluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:       "main-cluster",
@@ -248,8 +248,9 @@ func TestSingleClusterValidation(t *testing.T) {
 	}
 	cluster := &corev1alpha1.StorageCluster{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "extra-cluster",
-			Namespace: "extra-ns",
+			Name:       "extra-cluster",
+			Namespace:  "extra-ns",
+			Generation: 2,
 		},
 	}
 
@@ -270,14 +271,174 @@ func TestSingleClusterValidation(t *testing.T) {
 	}
 	result, err := controller.Reconcile(request)
 	require.Empty(t, result)
-	require.Contains(t, err.Error(), fmt.Sprintf("only one StorageCluster is allowed in a Kubernetes cluster. "+
-		"StorageCluster %s/%s already exists", existingCluster.Namespace, existingCluster.Name))
+	expectedErr := fmt.Sprintf("StorageCluster %s/%s already runs on all the nodes. "+
+		"Multiple StorageClusters should use placement node affinity to select disjoint sets of nodes",
+		existingCluster.Namespace, existingCluster.Name)
+	require.EqualError(t, err, expectedErr)
 
 	require.Len(t, recorder.Events, 1)
 	require.Contains(t, <-recorder.Events,
-		fmt.Sprintf("%v %v only one StorageCluster is allowed in a Kubernetes cluster. "+
-			"StorageCluster %s/%s already exists", v1.EventTypeWarning, util.FailedValidationReason,
-			existingCluster.Namespace, existingCluster.Name))
+		fmt.Sprintf("%v %v %s", v1.EventTypeWarning, util.FailedValidationReason, expectedErr))
+
+	// The validation failure should be reported as a condition
+	updatedCluster := &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	condition := getClusterCondition(updatedCluster, corev1alpha1.ClusterConditionTypeValidation)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
+	require.Equal(t, validationFailedReason, condition.Reason)
+	require.Equal(t, expectedErr, condition.Message)
+	require.False(t, condition.LastTransitionTime.IsZero())
+	// The failed validation should be reported for the latest generation
+	require.Equal(t, cluster.Generation, updatedCluster.Status.ObservedGeneration)
+	// The cluster should be reported as stalled until the conflict is resolved
+	condition = getClusterCondition(updatedCluster, corev1alpha1.ClusterConditionTypeStalled)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterConditionTrue, condition.Status)
+	require.Equal(t, validationFailedReason, condition.Reason)
+	require.Equal(t, expectedErr, condition.Message)
+	condition = getClusterCondition(updatedCluster, corev1alpha1.ClusterConditionTypeReady)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+	require.Empty(t, updatedCluster.Finalizers)
+
+	// Clusters in the same namespace are not allowed
+	sameNamespaceCluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "same-ns-cluster",
+			Namespace: existingCluster.Namespace,
+		},
+	}
+	err = controller.validateClusterPlacement(sameNamespaceCluster)
+	require.EqualError(t, err, "StorageCluster main-ns/main-cluster already exists in namespace "+
+		"main-ns. Multiple StorageClusters should be in different namespaces")
+}
+
+func TestMultipleClusterValidationWithPlacement(t *testing.T) {
+	placement := func(pool string) *corev1alpha1.PlacementSpec {
+		return &corev1alpha1.PlacementSpec{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{
+									Key:      "pool",
+									Operator: v1.NodeSelectorOpIn,
+									Values:   []string{pool},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	existingCluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "db-cluster",
+			Namespace:  "db-ns",
+			Finalizers: []string{deleteFinalizerName},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Placement: placement("db"),
+		},
+	}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "analytics-cluster",
+			Namespace: "analytics-ns",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Placement: placement("analytics"),
+		},
+	}
+	dbNode := createK8sNode("node1", 10)
+	dbNode.Labels = map[string]string{"pool": "db"}
+	analyticsNode := createK8sNode("node2", 10)
+	analyticsNode.Labels = map[string]string{"pool": "analytics"}
+
+	k8sClient := testutil.FakeK8sClient(existingCluster, cluster, dbNode, analyticsNode)
+	controller := Controller{
+		client: k8sClient,
+	}
+
+	// Clusters with disjoint set of nodes are allowed
+	err := controller.validateClusterPlacement(cluster)
+	require.NoError(t, err)
+
+	// The existing cluster takes precedence as it has already been reconciled
+	err = controller.validateClusterPlacement(existingCluster)
+	require.NoError(t, err)
+
+	// Clusters with overlapping nodes are not allowed
+	cluster.Spec.Placement.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.
+		NodeSelectorTerms[0].MatchExpressions[0].Values = []string{"analytics", "db"}
+	err = controller.validateClusterPlacement(cluster)
+	require.EqualError(t, err, "node node1 is selected by StorageCluster db-ns/db-cluster as well. "+
+		"Multiple StorageClusters should use placement node affinity to select disjoint sets of nodes")
+
+	// A cluster without placement overlaps with all the other clusters
+	cluster.Spec.Placement = nil
+	err = controller.validateClusterPlacement(cluster)
+	require.EqualError(t, err, "node node1 is selected by StorageCluster db-ns/db-cluster as well. "+
+		"Multiple StorageClusters should use placement node affinity to select disjoint sets of nodes")
+
+	// If none of the clusters have been reconciled, the older cluster takes precedence
+	existingCluster.Finalizers = nil
+	existingCluster.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	cluster.CreationTimestamp = metav1.Now()
+	err = k8sClient.Update(context.TODO(), existingCluster)
+	require.NoError(t, err)
+	err = controller.validateClusterPlacement(existingCluster)
+	require.NoError(t, err)
+	err = controller.validateClusterPlacement(cluster)
+	require.Error(t, err)
+}
+
+func TestResourceSuffixForMultipleClusters(t *testing.T) {
+	existingCluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "main-cluster",
+			Namespace:  "main-ns",
+			Finalizers: []string{deleteFinalizerName},
+		},
+	}
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "extra-cluster",
+			Namespace: "extra-ns",
+		},
+	}
+
+	k8sClient := testutil.FakeK8sClient(existingCluster, cluster)
+	controller := Controller{
+		client: k8sClient,
+	}
+
+	// The first cluster should not get a resource suffix
+	err := controller.setResourceSuffix(existingCluster)
+	require.NoError(t, err)
+	require.Empty(t, existingCluster.Annotations)
+
+	// A cluster reconciled after another cluster should use its namespace as suffix
+	err = controller.addDeleteFinalizer(cluster)
+	require.NoError(t, err)
+	require.Equal(t, "extra-ns", cluster.Annotations[util.AnnotationResourceSuffix])
+	require.Equal(t, "px-db-extra-ns", util.ClusterScopedName(cluster, "px-db"))
+
+	updatedCluster := &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "extra-ns", updatedCluster.Annotations[util.AnnotationResourceSuffix])
+	require.Equal(t, []string{deleteFinalizerName}, updatedCluster.Finalizers)
+
+	// Do not overwrite the suffix if already present
+	cluster.Annotations[util.AnnotationResourceSuffix] = "analytics"
+	err = controller.setResourceSuffix(cluster)
+	require.NoError(t, err)
+	require.Equal(t, "analytics", cluster.Annotations[util.AnnotationResourceSuffix])
 }
 
 func TestStorageClusterDefaults(t *testing.T) {
@@ -302,27 +463,23 @@ func TestStorageClusterDefaults(t *testing.T) {
 	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
 
 	// Use default revision history limit if not set
-	err := controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, int32(defaultRevisionHistoryLimit), *cluster.Spec.RevisionHistoryLimit)
 
 	// Don't use default revision history limit if already set
 	revisionHistoryLimit := int32(20)
 	cluster.Spec.RevisionHistoryLimit = &revisionHistoryLimit
-	err = controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, revisionHistoryLimit, *cluster.Spec.RevisionHistoryLimit)
 
 	// Use default image pull policy if not set
 	cluster.Spec.ImagePullPolicy = ""
-	err = controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, v1.PullAlways, cluster.Spec.ImagePullPolicy)
 
 	// Don't use default image pull policy if already set
 	cluster.Spec.ImagePullPolicy = v1.PullNever
-	err = controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, v1.PullNever, cluster.Spec.ImagePullPolicy)
 }
 
@@ -348,8 +505,7 @@ func TestStorageClusterDefaultsForUpdateStrategy(t *testing.T) {
 	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
 
 	// Use rolling update as default update strategy if nothing specified
-	err := controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, corev1alpha1.RollingUpdateStorageClusterStrategyType, cluster.Spec.UpdateStrategy.Type)
 	require.Equal(t, 1, cluster.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable.IntValue())
 
@@ -357,8 +513,7 @@ func TestStorageClusterDefaultsForUpdateStrategy(t *testing.T) {
 	cluster.Spec.UpdateStrategy = corev1alpha1.StorageClusterUpdateStrategy{
 		Type: corev1alpha1.RollingUpdateStorageClusterStrategyType,
 	}
-	err = controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, corev1alpha1.RollingUpdateStorageClusterStrategyType, cluster.Spec.UpdateStrategy.Type)
 	require.Equal(t, 1, cluster.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable.IntValue())
 
@@ -367,8 +522,7 @@ func TestStorageClusterDefaultsForUpdateStrategy(t *testing.T) {
 		Type:          corev1alpha1.RollingUpdateStorageClusterStrategyType,
 		RollingUpdate: &corev1alpha1.RollingUpdateStorageCluster{},
 	}
-	err = controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, corev1alpha1.RollingUpdateStorageClusterStrategyType, cluster.Spec.UpdateStrategy.Type)
 	require.Equal(t, 1, cluster.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable.IntValue())
 
@@ -380,8 +534,7 @@ func TestStorageClusterDefaultsForUpdateStrategy(t *testing.T) {
 			MaxUnavailable: &maxUnavailable,
 		},
 	}
-	err = controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, corev1alpha1.RollingUpdateStorageClusterStrategyType, cluster.Spec.UpdateStrategy.Type)
 	require.Equal(t, "20%", cluster.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable.String())
 
@@ -389,16 +542,12 @@ func TestStorageClusterDefaultsForUpdateStrategy(t *testing.T) {
 	cluster.Spec.UpdateStrategy = corev1alpha1.StorageClusterUpdateStrategy{
 		Type: corev1alpha1.OnDeleteStorageClusterStrategyType,
 	}
-	err = controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 	require.Equal(t, corev1alpha1.OnDeleteStorageClusterStrategyType, cluster.Spec.UpdateStrategy.Type)
 	require.Nil(t, cluster.Spec.UpdateStrategy.RollingUpdate)
 }
 
-func TestStorageClusterDefaultsForFinalizer(t *testing.T) {
-	mockCtrl := gomock.NewController(t)
-	defer mockCtrl.Finish()
-
+func TestStorageClusterDeleteFinalizer(t *testing.T) {
 	cluster := &corev1alpha1.StorageCluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "test-cluster",
@@ -406,33 +555,29 @@ func TestStorageClusterDefaultsForFinalizer(t *testing.T) {
 		},
 	}
 
-	driver := testutil.MockDriver(mockCtrl)
 	k8sClient := testutil.FakeK8sClient(cluster)
 
 	controller := Controller{
 		client: k8sClient,
-		Driver: driver,
 	}
 
-	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
-
 	// Add delete finalizer if no finalizers are present
 	expectedFinalizers := []string{deleteFinalizerName}
-	err := controller.setStorageClusterDefaults(cluster)
+	err := controller.addDeleteFinalizer(cluster)
 	require.NoError(t, err)
 	require.Equal(t, expectedFinalizers, cluster.Finalizers)
 
 	// Add delete finalizer if it is not present
 	cluster.Finalizers = []string{"foo", "bar"}
 	expectedFinalizers = []string{"foo", "bar", deleteFinalizerName}
-	err = controller.setStorageClusterDefaults(cluster)
+	err = controller.addDeleteFinalizer(cluster)
 	require.NoError(t, err)
 	require.Equal(t, expectedFinalizers, cluster.Finalizers)
 
 	// Do not add delete finalizer if already present
 	expectedFinalizers = []string{"foo", deleteFinalizerName, "bar"}
 	cluster.Finalizers = []string{"foo", deleteFinalizerName, "bar"}
-	err = controller.setStorageClusterDefaults(cluster)
+	err = controller.addDeleteFinalizer(cluster)
 	require.NoError(t, err)
 	require.Equal(t, expectedFinalizers, cluster.Finalizers)
 }
@@ -472,8 +617,7 @@ func TestStorageClusterDefaultsWithDriverOverrides(t *testing.T) {
 		})
 
 	// The default values from from the storage driver should take precendence
-	err := controller.setStorageClusterDefaults(cluster)
-	require.NoError(t, err)
+	controller.setStorageClusterDefaults(cluster)
 
 	require.Equal(t, "test/image:1.2.3", cluster.Spec.Image)
 	require.Equal(t, int32(5), *cluster.Spec.RevisionHistoryLimit)
@@ -482,6 +626,53 @@ func TestStorageClusterDefaultsWithDriverOverrides(t *testing.T) {
 	require.Empty(t, cluster.Spec.Stork.Image)
 	require.Equal(t, corev1alpha1.OnDeleteStorageClusterStrategyType, cluster.Spec.UpdateStrategy.Type)
 	require.Empty(t, cluster.Spec.UpdateStrategy.RollingUpdate)
+
+	// The computed images should be reported in the status
+	require.Equal(t, "test/image:1.2.3", cluster.Status.DesiredImages.StorageDriver)
+	require.Empty(t, cluster.Status.DesiredImages.Stork)
+}
+
+func TestStorageClusterDefaultsForDesiredImages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Stork:         &corev1alpha1.StorkSpec{Enabled: true},
+			UserInterface: &corev1alpha1.UserInterfaceSpec{Enabled: true},
+			Autopilot:     &corev1alpha1.AutopilotSpec{Enabled: false},
+		},
+	}
+
+	driver := testutil.MockDriver(mockCtrl)
+	controller := Controller{
+		client: testutil.FakeK8sClient(cluster),
+		Driver: driver,
+	}
+
+	driver.EXPECT().
+		SetDefaultsOnStorageCluster(gomock.Any()).
+		Do(func(cluster *corev1alpha1.StorageCluster) {
+			cluster.Spec.Image = "test/image:1.2.3"
+			cluster.Spec.Version = "1.2.3"
+			cluster.Spec.Stork.Image = "test/stork:2.3.4"
+			cluster.Spec.UserInterface.Image = "test/lighthouse:3.4.5"
+			cluster.Spec.Autopilot.Image = "test/autopilot:4.5.6"
+		})
+
+	controller.setStorageClusterDefaults(cluster)
+
+	// Images of disabled components should not be reported
+	require.Equal(t, "1.2.3", cluster.Status.Version)
+	require.Equal(t, &corev1alpha1.ComponentImages{
+		StorageDriver: "test/image:1.2.3",
+		Stork:         "test/stork:2.3.4",
+		UserInterface: "test/lighthouse:3.4.5",
+	}, cluster.Status.DesiredImages)
 }
 
 func TestReconcileForNonExistingCluster(t *testing.T) {
@@ -676,6 +867,121 @@ func TestStoragePodGetsScheduled(t *testing.T) {
 	require.Equal(t, *clusterRef, podControl.ControllerRefs[1])
 }
 
+func TestStoragePodGetsScheduledWithPriorityClass(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.PriorityClassName = "px-critical"
+	k8sNode := createK8sNode("k8s-node-1", 1)
+
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster, k8sNode)
+	podControl := &k8scontroller.FakePodControl{}
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          record.NewFakeRecorder(10),
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil)
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return("mock-driver").AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil)
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil)
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any())
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).
+		Return(v1.PodSpec{Containers: []v1.Container{{Name: "test"}}}, nil).
+		AnyTimes()
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	// Verify the storage pod uses the priority class from the cluster spec
+	require.Len(t, podControl.Templates, 1)
+	require.Equal(t, "px-critical", podControl.Templates[0].Spec.PriorityClassName)
+}
+
+func TestStoragePodGetsScheduledOnTaintedNodes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storageToleration := v1.Toleration{
+		Key:      "storage",
+		Operator: v1.TolerationOpEqual,
+		Value:    "true",
+		Effect:   v1.TaintEffectNoSchedule,
+	}
+	cluster := createStorageCluster()
+	cluster.Spec.Placement = &corev1alpha1.PlacementSpec{
+		Tolerations: []v1.Toleration{storageToleration},
+	}
+
+	// Only the node with the taint tolerated by the cluster should get a pod
+	k8sNode1 := createK8sNode("k8s-node-1", 1)
+	k8sNode1.Spec.Taints = []v1.Taint{
+		{
+			Key:    "storage",
+			Value:  "true",
+			Effect: v1.TaintEffectNoSchedule,
+		},
+	}
+	k8sNode2 := createK8sNode("k8s-node-2", 1)
+	k8sNode2.Spec.Taints = []v1.Taint{
+		{
+			Key:    "dedicated",
+			Value:  "database",
+			Effect: v1.TaintEffectNoSchedule,
+		},
+	}
+
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster, k8sNode1, k8sNode2)
+	podControl := &k8scontroller.FakePodControl{}
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          record.NewFakeRecorder(10),
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil)
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return("mock-driver").AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil)
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil)
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any())
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).
+		Return(v1.PodSpec{Containers: []v1.Container{{Name: "test"}}}, nil).
+		AnyTimes()
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	// Verify the pod is created with the tolerations from the placement
+	require.Len(t, podControl.Templates, 1)
+	require.Contains(t, podControl.Templates[0].Spec.Tolerations, storageToleration)
+}
+
 func TestStoragePodGetsScheduledWithCustomNodeSpecs(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -1593,37 +1899,43 @@ func TestUpdateClusterStatusFromDriver(t *testing.T) {
 	require.Equal(t, "Online", newCluster.Status.Phase)
 }
 
-func TestUpdateClusterStatusErrorFromDriver(t *testing.T) {
+func TestStorageClusterSpecNotUpdatedWithDefaults(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
-	driverName := "mock-driver"
-	cluster := createStorageCluster()
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:       "test-uid",
+			Name:      "test-cluster",
+			Namespace: "test-ns",
+		},
+	}
 	k8sVersion, _ := version.NewVersion("1.11.0")
 	driver := testutil.MockDriver(mockCtrl)
 	k8sClient := testutil.FakeK8sClient(cluster)
-	podControl := &k8scontroller.FakePodControl{}
 	recorder := record.NewFakeRecorder(10)
 	controller := Controller{
 		client:            k8sClient,
 		Driver:            driver,
-		podControl:        podControl,
+		podControl:        &k8scontroller.FakePodControl{},
 		recorder:          recorder,
 		kubernetesVersion: k8sVersion,
 	}
 
-	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any())
+	driver.EXPECT().
+		SetDefaultsOnStorageCluster(gomock.Any()).
+		Do(func(c *corev1alpha1.StorageCluster) {
+			c.Spec.Image = "test/image:1.2.3"
+			c.Spec.Version = "1.2.3"
+			c.Spec.Stork = &corev1alpha1.StorkSpec{Enabled: false}
+		}).
+		AnyTimes()
 	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
-	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().String().Return("mock-driver").AnyTimes()
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil)
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil)
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
-	driver.EXPECT().
-		UpdateStorageClusterStatus(gomock.Any()).
-		Do(func(c *corev1alpha1.StorageCluster) {
-			c.Status.Phase = "Offline"
-		}).
-		Return(fmt.Errorf("update status error"))
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil)
 
 	request := reconcile.Request{
 		NamespacedName: types.NamespacedName{
@@ -1634,17 +1946,32 @@ func TestUpdateClusterStatusErrorFromDriver(t *testing.T) {
 	result, err := controller.Reconcile(request)
 	require.NoError(t, err)
 	require.Empty(t, result)
+	require.Len(t, recorder.Events, 0)
 
-	require.Len(t, recorder.Events, 1)
-	require.Equal(t, <-recorder.Events,
-		fmt.Sprintf("%v %v update status error", v1.EventTypeWarning, util.FailedSyncReason))
-
+	// Only the finalizer should be added, the spec should remain as is
 	newCluster := &corev1alpha1.StorageCluster{}
 	testutil.Get(k8sClient, newCluster, cluster.Name, cluster.Namespace)
-	require.Equal(t, "Offline", newCluster.Status.Phase)
+	require.Equal(t, []string{deleteFinalizerName}, newCluster.Finalizers)
+	require.Equal(t, corev1alpha1.StorageClusterSpec{}, newCluster.Spec)
+
+	// The effective configuration should be reported in the status
+	require.Equal(t, "1.2.3", newCluster.Status.Version)
+	require.Equal(t, "test/image:1.2.3", newCluster.Status.DesiredImages.StorageDriver)
+	require.Empty(t, newCluster.Status.DesiredImages.Stork)
+
+	// The revision hash should be computed from the effective spec
+	revisions := &appsv1.ControllerRevisionList{}
+	err = testutil.List(k8sClient, revisions)
+	require.NoError(t, err)
+	require.Len(t, revisions.Items, 1)
+	effectiveCluster := newCluster.DeepCopy()
+	controller.setStorageClusterDefaults(effectiveCluster)
+	expectedRevision, err := getRevision(k8sClient, effectiveCluster, "mock-driver")
+	require.NoError(t, err)
+	require.Equal(t, expectedRevision.Name, revisions.Items[0].Name)
 }
 
-func TestFailedPreInstallFromDriver(t *testing.T) {
+func TestUpdateClusterStatusErrorFromDriver(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
@@ -1666,7 +1993,15 @@ func TestFailedPreInstallFromDriver(t *testing.T) {
 	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any())
 	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
 	driver.EXPECT().String().Return(driverName).AnyTimes()
-	driver.EXPECT().PreInstall(gomock.Any()).Return(fmt.Errorf("pre-install error"))
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil)
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil)
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().
+		UpdateStorageClusterStatus(gomock.Any()).
+		Do(func(c *corev1alpha1.StorageCluster) {
+			c.Status.Phase = "Offline"
+		}).
+		Return(fmt.Errorf("update status error"))
 
 	request := reconcile.Request{
 		NamespacedName: types.NamespacedName{
@@ -1675,8 +2010,51 @@ func TestFailedPreInstallFromDriver(t *testing.T) {
 		},
 	}
 	result, err := controller.Reconcile(request)
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "pre-install error")
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, <-recorder.Events,
+		fmt.Sprintf("%v %v update status error", v1.EventTypeWarning, util.FailedSyncReason))
+
+	newCluster := &corev1alpha1.StorageCluster{}
+	testutil.Get(k8sClient, newCluster, cluster.Name, cluster.Namespace)
+	require.Equal(t, "Offline", newCluster.Status.Phase)
+}
+
+func TestFailedPreInstallFromDriver(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driverName := "mock-driver"
+	cluster := createStorageCluster()
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster)
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any())
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(fmt.Errorf("pre-install error"))
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pre-install error")
 	require.Empty(t, result)
 
 	require.Len(t, recorder.Events, 1)
@@ -1864,6 +2242,7 @@ func TestDeleteStorageClusterWithFinalizers(t *testing.T) {
 
 	// Empty delete condition should not remove finalizer
 	driver.EXPECT().DeleteStorage(gomock.Any()).Return(nil, nil)
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
 	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
 	driver.EXPECT().String().Return(driverName).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
@@ -1938,7 +2317,9 @@ func TestDeleteStorageClusterWithFinalizers(t *testing.T) {
 	updatedCluster = &corev1alpha1.StorageCluster{}
 	testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
 	require.Len(t, updatedCluster.Status.Conditions, 2)
-	require.Equal(t, *condition, updatedCluster.Status.Conditions[1])
+	require.Equal(t, condition.Type, updatedCluster.Status.Conditions[1].Type)
+	require.Equal(t, condition.Status, updatedCluster.Status.Conditions[1].Status)
+	require.False(t, updatedCluster.Status.Conditions[1].LastTransitionTime.IsZero())
 	require.Equal(t, "DeleteFailed", updatedCluster.Status.Phase)
 	require.Equal(t, []string{deleteFinalizerName}, updatedCluster.Finalizers)
 
@@ -1957,16 +2338,26 @@ func TestDeleteStorageClusterWithFinalizers(t *testing.T) {
 	updatedCluster = &corev1alpha1.StorageCluster{}
 	testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
 	require.Len(t, updatedCluster.Status.Conditions, 2)
-	require.Equal(t, *condition, updatedCluster.Status.Conditions[1])
+	require.Equal(t, condition.Type, updatedCluster.Status.Conditions[1].Type)
+	require.Equal(t, condition.Status, updatedCluster.Status.Conditions[1].Status)
+	require.False(t, updatedCluster.Status.Conditions[1].LastTransitionTime.IsZero())
 	require.Equal(t, "DeleteTimeout", updatedCluster.Status.Phase)
 	require.Equal(t, []string{deleteFinalizerName}, updatedCluster.Finalizers)
 
-	// If delete condition status is completed, then remove delete finalizer
+	// If delete condition status is completed, then remove delete finalizer.
+	// The driver should get the defaults, but they should not be saved.
+	updatedCluster.Spec.ImagePullPolicy = ""
+	k8sClient.Update(context.TODO(), updatedCluster)
 	condition = &corev1alpha1.ClusterCondition{
 		Type:   corev1alpha1.ClusterConditionTypeDelete,
 		Status: corev1alpha1.ClusterOperationCompleted,
 	}
-	driver.EXPECT().DeleteStorage(gomock.Any()).Return(condition, nil)
+	driver.EXPECT().
+		DeleteStorage(gomock.Any()).
+		DoAndReturn(func(c *corev1alpha1.StorageCluster) (*corev1alpha1.ClusterCondition, error) {
+			require.Equal(t, v1.PullAlways, c.Spec.ImagePullPolicy)
+			return condition, nil
+		})
 
 	result, err = controller.Reconcile(request)
 	require.NoError(t, err)
@@ -1975,8 +2366,11 @@ func TestDeleteStorageClusterWithFinalizers(t *testing.T) {
 
 	updatedCluster = &corev1alpha1.StorageCluster{}
 	testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
+	require.Empty(t, updatedCluster.Spec.ImagePullPolicy)
 	require.Len(t, updatedCluster.Status.Conditions, 2)
-	require.Equal(t, *condition, updatedCluster.Status.Conditions[1])
+	require.Equal(t, condition.Type, updatedCluster.Status.Conditions[1].Type)
+	require.Equal(t, condition.Status, updatedCluster.Status.Conditions[1].Status)
+	require.False(t, updatedCluster.Status.Conditions[1].LastTransitionTime.IsZero())
 	require.Empty(t, updatedCluster.Finalizers)
 }
 
@@ -2006,6 +2400,7 @@ func TestUpdateStorageClusterWithRollingUpdateStrategy(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	k8sNode := createK8sNode("k8s-node", 10)
@@ -2132,6 +2527,7 @@ func TestUpdateStorageClusterShouldNotExceedMaxUnavailable(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	k8sNode1 := createK8sNode("k8s-node-1", 10)
@@ -2318,6 +2714,7 @@ func TestUpdateStorageClusterWithPercentageMaxUnavailable(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	k8sNode1 := createK8sNode("k8s-node-1", 10)
@@ -2486,6 +2883,403 @@ func TestUpdateStorageClusterWithInvalidMaxUnavailableValue(t *testing.T) {
 		fmt.Sprintf("%v %v", v1.EventTypeWarning, util.FailedSyncReason))
 }
 
+func TestUpdateStorageClusterWithPartition(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driverName := "mock-driver"
+	cluster := createStorageCluster()
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	storageLabels := map[string]string{
+		labelKeyName:       cluster.Name,
+		labelKeyDriverName: driverName,
+	}
+	k8sClient := testutil.FakeK8sClient(cluster)
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+	clusterRef := metav1.NewControllerRef(cluster, controllerKind)
+
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
+
+	// Create the nodes in a different order than their names to ensure
+	// the partition picks the nodes sorted by name
+	oldPods := make(map[string]*v1.Pod)
+	rev1Hash, err := createRevision(k8sClient, cluster, driverName)
+	require.NoError(t, err)
+	storageLabels[defaultStorageClusterUniqueLabelKey] = rev1Hash
+	for _, nodeName := range []string{"k8s-node-3", "k8s-node-1", "k8s-node-4", "k8s-node-2"} {
+		k8sClient.Create(context.TODO(), createK8sNode(nodeName, 10))
+		oldPod := createStoragePod(cluster, "old-pod-"+nodeName, nodeName, storageLabels)
+		oldPod.Status.Conditions = []v1.PodCondition{
+			{
+				Type:   v1.PodReady,
+				Status: v1.ConditionTrue,
+			},
+		}
+		k8sClient.Create(context.TODO(), oldPod)
+		oldPods[nodeName] = oldPod
+	}
+
+	// Only the pods on the first 2 nodes should be updated,
+	// even though maxUnavailable allows more pods to go down
+	maxUnavailable := intstr.FromInt(4)
+	partition := int32(2)
+	cluster.Spec.UpdateStrategy = corev1alpha1.StorageClusterUpdateStrategy{
+		Type: corev1alpha1.RollingUpdateStorageClusterStrategyType,
+		RollingUpdate: &corev1alpha1.RollingUpdateStorageCluster{
+			MaxUnavailable: &maxUnavailable,
+			Partition:      &partition,
+		},
+	}
+	cluster.Spec.Image = "test/image:v2"
+	k8sClient.Update(context.TODO(), cluster)
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.ElementsMatch(t,
+		[]string{oldPods["k8s-node-1"].Name, oldPods["k8s-node-2"].Name},
+		podControl.DeletePodName,
+	)
+
+	updatedCluster := &corev1alpha1.StorageCluster{}
+	testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
+	require.Equal(t, int32(0), updatedCluster.Status.UpdatedNodes)
+	require.Equal(t, int32(4), updatedCluster.Status.OutdatedNodes)
+
+	// Replace the deleted pods with pods of the new revision
+	k8sClient.Delete(context.TODO(), oldPods["k8s-node-1"])
+	k8sClient.Delete(context.TODO(), oldPods["k8s-node-2"])
+	podControl.Templates = nil
+	podControl.DeletePodName = nil
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Empty(t, podControl.DeletePodName)
+	require.Len(t, podControl.Templates, 2)
+
+	for i, nodeName := range []string{"k8s-node-1", "k8s-node-2"} {
+		newPod, err := k8scontroller.GetPodFromTemplate(&podControl.Templates[i], cluster, clusterRef)
+		require.NoError(t, err)
+		newPod.Name = "new-pod-" + nodeName
+		newPod.Namespace = cluster.Namespace
+		newPod.Spec.NodeName = nodeName
+		newPod.Status.Conditions = []v1.PodCondition{
+			{
+				Type:   v1.PodReady,
+				Status: v1.ConditionTrue,
+			},
+		}
+		k8sClient.Create(context.TODO(), newPod)
+	}
+	podControl.Templates = nil
+
+	// The pods outside the partition should not be updated
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Empty(t, podControl.DeletePodName)
+	require.Empty(t, podControl.Templates)
+
+	updatedCluster = &corev1alpha1.StorageCluster{}
+	testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
+	require.Equal(t, int32(2), updatedCluster.Status.UpdatedNodes)
+	require.Equal(t, int32(2), updatedCluster.Status.OutdatedNodes)
+
+	// A pod recreated outside the partition should use the old revision
+	k8sClient.Delete(context.TODO(), oldPods["k8s-node-3"])
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Empty(t, podControl.DeletePodName)
+	require.Len(t, podControl.Templates, 1)
+	require.Equal(t, rev1Hash, podControl.Templates[0].Labels[defaultStorageClusterUniqueLabelKey])
+
+	newPod, err := k8scontroller.GetPodFromTemplate(&podControl.Templates[0], cluster, clusterRef)
+	require.NoError(t, err)
+	newPod.Name = oldPods["k8s-node-3"].Name
+	newPod.Namespace = cluster.Namespace
+	newPod.Spec.NodeName = "k8s-node-3"
+	k8sClient.Create(context.TODO(), newPod)
+	podControl.Templates = nil
+
+	// Increasing the partition should update the remaining pods
+	testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	*cluster.Spec.UpdateStrategy.RollingUpdate.Partition = 4
+	k8sClient.Update(context.TODO(), cluster)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.ElementsMatch(t,
+		[]string{oldPods["k8s-node-3"].Name, oldPods["k8s-node-4"].Name},
+		podControl.DeletePodName,
+	)
+}
+
+func TestUpdateStorageClusterWithCanarySelector(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driverName := "mock-driver"
+	cluster := createStorageCluster()
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	storageLabels := map[string]string{
+		labelKeyName:       cluster.Name,
+		labelKeyDriverName: driverName,
+	}
+	k8sClient := testutil.FakeK8sClient(cluster)
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
+
+	oldPods := make(map[string]*v1.Pod)
+	rev1Hash, err := createRevision(k8sClient, cluster, driverName)
+	require.NoError(t, err)
+	storageLabels[defaultStorageClusterUniqueLabelKey] = rev1Hash
+	for _, nodeName := range []string{"k8s-node-1", "k8s-node-2", "k8s-node-3", "k8s-node-4"} {
+		k8sNode := createK8sNode(nodeName, 10)
+		if nodeName != "k8s-node-1" {
+			k8sNode.Labels = map[string]string{"canary": "true"}
+		}
+		k8sClient.Create(context.TODO(), k8sNode)
+		oldPod := createStoragePod(cluster, "old-pod-"+nodeName, nodeName, storageLabels)
+		oldPod.Status.Conditions = []v1.PodCondition{
+			{
+				Type:   v1.PodReady,
+				Status: v1.ConditionTrue,
+			},
+		}
+		k8sClient.Create(context.TODO(), oldPod)
+		oldPods[nodeName] = oldPod
+	}
+
+	// Only the pods on the canary nodes should be updated
+	maxUnavailable := intstr.FromInt(4)
+	cluster.Spec.UpdateStrategy = corev1alpha1.StorageClusterUpdateStrategy{
+		Type: corev1alpha1.RollingUpdateStorageClusterStrategyType,
+		RollingUpdate: &corev1alpha1.RollingUpdateStorageCluster{
+			MaxUnavailable: &maxUnavailable,
+			CanarySelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"canary": "true"},
+			},
+		},
+	}
+	cluster.Spec.Image = "test/image:v2"
+	k8sClient.Update(context.TODO(), cluster)
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.ElementsMatch(t,
+		[]string{oldPods["k8s-node-2"].Name, oldPods["k8s-node-3"].Name, oldPods["k8s-node-4"].Name},
+		podControl.DeletePodName,
+	)
+
+	// With a partition, only the first N canary nodes should be updated
+	partition := int32(1)
+	testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	cluster.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	k8sClient.Update(context.TODO(), cluster)
+	podControl.DeletePodName = nil
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Equal(t, []string{oldPods["k8s-node-2"].Name}, podControl.DeletePodName)
+
+	// A partition of 0 should not update any node
+	testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	*cluster.Spec.UpdateStrategy.RollingUpdate.Partition = 0
+	k8sClient.Update(context.TODO(), cluster)
+	podControl.DeletePodName = nil
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Empty(t, podControl.DeletePodName)
+}
+
+func TestUpdateStorageClusterShouldWaitForStorageHealth(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driverName := "mock-driver"
+	cluster := createStorageCluster()
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	storageLabels := map[string]string{
+		labelKeyName:       cluster.Name,
+		labelKeyDriverName: driverName,
+	}
+	k8sClient := testutil.FakeK8sClient(cluster)
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+	clusterRef := metav1.NewControllerRef(cluster, controllerKind)
+
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
+
+	k8sNode1 := createK8sNode("k8s-node-1", 10)
+	k8sNode2 := createK8sNode("k8s-node-2", 10)
+	k8sClient.Create(context.TODO(), k8sNode1)
+	k8sClient.Create(context.TODO(), k8sNode2)
+
+	rev1Hash, err := createRevision(k8sClient, cluster, driverName)
+	require.NoError(t, err)
+	storageLabels[defaultStorageClusterUniqueLabelKey] = rev1Hash
+	oldPod1 := createStoragePod(cluster, "old-pod-1", k8sNode1.Name, storageLabels)
+	oldPod1.Status.Conditions = []v1.PodCondition{
+		{
+			Type:   v1.PodReady,
+			Status: v1.ConditionTrue,
+		},
+	}
+	oldPod2 := oldPod1.DeepCopy()
+	oldPod2.Name = "old-pod-2"
+	oldPod2.Spec.NodeName = k8sNode2.Name
+	k8sClient.Create(context.TODO(), oldPod1)
+	k8sClient.Create(context.TODO(), oldPod2)
+
+	maxUnavailable := intstr.FromInt(2)
+	cluster.Spec.UpdateStrategy = corev1alpha1.StorageClusterUpdateStrategy{
+		Type: corev1alpha1.RollingUpdateStorageClusterStrategyType,
+		RollingUpdate: &corev1alpha1.RollingUpdateStorageCluster{
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+	cluster.Spec.Image = "test/image:v2"
+	k8sClient.Update(context.TODO(), cluster)
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	// Should not delete any available pod if the storage cluster is not healthy
+	driver.EXPECT().
+		CanUpdateStoragePods(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("cluster is not in quorum")).
+		Times(1)
+
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Empty(t, podControl.DeletePodName)
+
+	// Unavailable pods should be deleted even if the storage cluster is not healthy
+	oldPod2.Status.Conditions[0].Status = v1.ConditionFalse
+	k8sClient.Update(context.TODO(), oldPod2)
+	driver.EXPECT().
+		CanUpdateStoragePods(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("cluster is not in quorum")).
+		Times(1)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Equal(t, []string{oldPod2.Name}, podControl.DeletePodName)
+
+	// Replace the deleted pod with a pod of the new revision
+	k8sClient.Delete(context.TODO(), oldPod2)
+	podControl.Templates = nil
+	podControl.DeletePodName = nil
+	driver.EXPECT().
+		CanUpdateStoragePods(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("cluster is not in quorum")).
+		Times(1)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Len(t, podControl.Templates, 1)
+
+	newPod2, err := k8scontroller.GetPodFromTemplate(&podControl.Templates[0], cluster, clusterRef)
+	require.NoError(t, err)
+	newPod2.Name = "new-pod-2"
+	newPod2.Namespace = cluster.Namespace
+	newPod2.Spec.NodeName = k8sNode2.Name
+	newPod2.Status.Conditions = []v1.PodCondition{
+		{
+			Type:   v1.PodReady,
+			Status: v1.ConditionTrue,
+		},
+	}
+	k8sClient.Create(context.TODO(), newPod2)
+	podControl.Templates = nil
+	podControl.DeletePodName = nil
+
+	// The driver should be asked about the updated nodes, and the remaining
+	// pods should be deleted once the storage cluster is healthy
+	driver.EXPECT().
+		CanUpdateStoragePods(gomock.Any(), []string{k8sNode2.Name}).
+		Return(nil).
+		Times(1)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Equal(t, []string{oldPod1.Name}, podControl.DeletePodName)
+}
+
 func TestUpdateStorageClusterShouldRestartPodIfItDoesNotHaveAnyHash(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -2515,6 +3309,7 @@ func TestUpdateStorageClusterShouldRestartPodIfItDoesNotHaveAnyHash(t *testing.T
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	k8sNode := createK8sNode("k8s-node", 10)
@@ -2571,6 +3366,7 @@ func TestUpdateStorageClusterKvdbSpec(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -2675,6 +3471,7 @@ func TestUpdateStorageClusterCloudStorageSpec(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -2830,6 +3627,7 @@ func TestUpdateStorageClusterStorageSpec(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -2971,6 +3769,7 @@ func TestUpdateStorageClusterNetworkSpec(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -3054,6 +3853,7 @@ func TestUpdateStorageClusterEnvVariables(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -3139,6 +3939,7 @@ func TestUpdateStorageClusterRuntimeOptions(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -3192,6 +3993,98 @@ func TestUpdateStorageClusterRuntimeOptions(t *testing.T) {
 	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
 }
 
+func TestUpdateStorageClusterLogFileAndExtraArgs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driverName := "mock-driver"
+	cluster := createStorageCluster()
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	storageLabels := map[string]string{
+		labelKeyName:       cluster.Name,
+		labelKeyDriverName: driverName,
+	}
+	k8sClient := testutil.FakeK8sClient(cluster)
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
+
+	// This will create a revision which we will map to our pre-created pods
+	rev1Hash, err := createRevision(k8sClient, cluster, driverName)
+	require.NoError(t, err)
+
+	// Kubernetes node with enough resources to create new pods
+	k8sNode := createK8sNode("k8s-node", 10)
+	k8sClient.Create(context.TODO(), k8sNode)
+
+	// Pods that are already running on the k8s nodes with same hash
+	storageLabels[defaultStorageClusterUniqueLabelKey] = rev1Hash
+	oldPod := createStoragePod(cluster, "old-pod", k8sNode.Name, storageLabels)
+	oldPod.Status.Conditions = []v1.PodCondition{
+		{
+			Type:   v1.PodReady,
+			Status: v1.ConditionTrue,
+		},
+	}
+	k8sClient.Create(context.TODO(), oldPod)
+
+	// TestCase: Add spec.logFile
+	cluster.Spec.LogFile = "/tmp/px.log"
+	k8sClient.Update(context.TODO(), cluster)
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	// The old pod should be marked for deletion, which means the pod
+	// is detected to be updated.
+	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+
+	// TestCase: Add spec.extraArgs
+	cluster.Spec.ExtraArgs = []string{"-fruit", "apple"}
+	k8sClient.Update(context.TODO(), cluster)
+
+	podControl.DeletePodName = nil
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+
+	// TestCase: Change spec.extraArgs
+	cluster.Spec.ExtraArgs = []string{"-fruit", "banana"}
+	k8sClient.Update(context.TODO(), cluster)
+
+	podControl.DeletePodName = nil
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+}
+
 func TestUpdateStorageClusterSecretsProvider(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -3221,6 +4114,7 @@ func TestUpdateStorageClusterSecretsProvider(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -3302,6 +4196,7 @@ func TestUpdateStorageClusterStartPort(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -3383,6 +4278,7 @@ func TestUpdateStorageClusterFeatureGates(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -3436,6 +4332,191 @@ func TestUpdateStorageClusterFeatureGates(t *testing.T) {
 	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
 }
 
+func TestUpdateStorageClusterPriorityClassName(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driverName := "mock-driver"
+	cluster := createStorageCluster()
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	storageLabels := map[string]string{
+		labelKeyName:       cluster.Name,
+		labelKeyDriverName: driverName,
+	}
+	k8sClient := testutil.FakeK8sClient(cluster)
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
+
+	// This will create a revision which we will map to our pre-created pods
+	rev1Hash, err := createRevision(k8sClient, cluster, driverName)
+	require.NoError(t, err)
+
+	// Kubernetes node with enough resources to create new pods
+	k8sNode := createK8sNode("k8s-node", 10)
+	k8sClient.Create(context.TODO(), k8sNode)
+
+	// Pods that are already running on the k8s nodes with same hash
+	storageLabels[defaultStorageClusterUniqueLabelKey] = rev1Hash
+	oldPod := createStoragePod(cluster, "old-pod", k8sNode.Name, storageLabels)
+	oldPod.Status.Conditions = []v1.PodCondition{
+		{
+			Type:   v1.PodReady,
+			Status: v1.ConditionTrue,
+		},
+	}
+	k8sClient.Create(context.TODO(), oldPod)
+
+	// TestCase: Add spec.priorityClassName
+	cluster.Spec.PriorityClassName = "px-critical"
+	k8sClient.Update(context.TODO(), cluster)
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	// The old pod should be marked for deletion, which means the pod
+	// is detected to be updated.
+	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+
+	// TestCase: Change spec.priorityClassName
+	cluster.Spec.PriorityClassName = "custom-priority"
+	k8sClient.Update(context.TODO(), cluster)
+
+	podControl.DeletePodName = nil
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+}
+
+func TestUpdateStorageClusterPlacement(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driverName := "mock-driver"
+	cluster := createStorageCluster()
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	storageLabels := map[string]string{
+		labelKeyName:       cluster.Name,
+		labelKeyDriverName: driverName,
+	}
+	k8sClient := testutil.FakeK8sClient(cluster)
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return(driverName).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
+
+	// This will create a revision which we will map to our pre-created pods
+	rev1Hash, err := createRevision(k8sClient, cluster, driverName)
+	require.NoError(t, err)
+
+	// Kubernetes node with enough resources to create new pods
+	k8sNode := createK8sNode("k8s-node", 10)
+	k8sClient.Create(context.TODO(), k8sNode)
+
+	// Pods that are already running on the k8s nodes with same hash
+	storageLabels[defaultStorageClusterUniqueLabelKey] = rev1Hash
+	oldPod := createStoragePod(cluster, "old-pod", k8sNode.Name, storageLabels)
+	oldPod.Status.Conditions = []v1.PodCondition{
+		{
+			Type:   v1.PodReady,
+			Status: v1.ConditionTrue,
+		},
+	}
+	k8sClient.Create(context.TODO(), oldPod)
+
+	// TestCase: Add spec.placement.tolerations
+	cluster.Spec.Placement = &corev1alpha1.PlacementSpec{
+		Tolerations: []v1.Toleration{
+			{
+				Key:      "storage",
+				Operator: v1.TolerationOpExists,
+				Effect:   v1.TaintEffectNoSchedule,
+			},
+		},
+	}
+	k8sClient.Update(context.TODO(), cluster)
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	// The old pod should be marked for deletion, which means the pod
+	// is detected to be updated.
+	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+
+	// TestCase: Change spec.placement.nodeAffinity
+	cluster.Spec.Placement.NodeAffinity = &v1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+			{
+				Weight: 1,
+				Preference: v1.NodeSelectorTerm{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{
+							Key:      "px/enabled",
+							Operator: v1.NodeSelectorOpNotIn,
+							Values:   []string{"false"},
+						},
+					},
+				},
+			},
+		},
+	}
+	k8sClient.Update(context.TODO(), cluster)
+
+	podControl.DeletePodName = nil
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+}
+
 func TestUpdateStorageClusterNodeSpec(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -3475,6 +4556,7 @@ func TestUpdateStorageClusterNodeSpec(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -3854,6 +4936,7 @@ func TestUpdateStorageClusterK8sNodeChanges(t *testing.T) {
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -4114,6 +5197,7 @@ func TestUpdateStorageClusterShouldRestartPodIfItsHistoryHasInvalidSpec(t *testi
 	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
 
 	// This will create a revision which we will map to our pre-created pods
@@ -4792,6 +5876,21 @@ func createStoragePod(
 	}
 }
 
+func createReadyStoragePod(
+	cluster *corev1alpha1.StorageCluster,
+	podName, nodeName string,
+	labels map[string]string,
+) *v1.Pod {
+	pod := createStoragePod(cluster, podName, nodeName, labels)
+	pod.Status.Conditions = []v1.PodCondition{
+		{
+			Type:   v1.PodReady,
+			Status: v1.ConditionTrue,
+		},
+	}
+	return pod
+}
+
 func createK8sNode(nodeName string, allowedPods int) *v1.Node {
 	return &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,263 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// annotationCordonAction is the annotation on the nodes on which the storage
+	// node has been taken offline because the node was cordoned. The value is
+	// the action that was taken, so it can be reverted once the node is uncordoned.
+	annotationCordonAction = operatorPrefix + "/cordon-action"
+	// nodeCordonedReason is added to an event when a storage node is taken
+	// offline because its node has been cordoned
+	nodeCordonedReason = "NodeCordoned"
+	// nodeUncordonedReason is added to an event when a storage node is
+	// brought back because its node has been uncordoned
+	nodeUncordonedReason = "NodeUncordoned"
+)
+
+// syncCordonedNodes takes the storage nodes on cordoned nodes offline using the
+// cordon action of the cluster, and brings them back once the nodes are
+// uncordoned. A storage node is taken offline only after the driver confirms
+// that the cluster stays in quorum without it, and only when no other storage
+// node is still going offline, so the quorum check sees the effect of the
+// previous one. The progress is reported in the CordonState condition of the
+// StorageNodes. Only the nodes with a StorageNode of the given cluster are
+// considered, so the annotations set for other clusters are left alone.
+func (c *Controller) syncCordonedNodes(cluster *corev1alpha1.StorageCluster) error {
+	nodeList := &v1.NodeList{}
+	if err := c.client.List(context.TODO(), nodeList, &client.ListOptions{}); err != nil {
+		return fmt.Errorf("failed to list nodes. %v", err)
+	}
+	sort.Slice(nodeList.Items, func(i, j int) bool {
+		return nodeList.Items[i].Name < nodeList.Items[j].Name
+	})
+
+	storageNodes, err := c.getStorageNodes(cluster)
+	if err != nil {
+		return err
+	}
+
+	var cordonedNodes []*v1.Node
+	offlinePending := false
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		storageNode := storageNodes[node.Name]
+		if storageNode == nil {
+			continue
+		}
+		cordoned := node.Spec.Unschedulable && cluster.Spec.CordonAction != ""
+		action := corev1alpha1.CordonActionType(node.Annotations[annotationCordonAction])
+
+		switch {
+		case action != "" && !cordoned:
+			if err := c.bringNodeOnline(cluster, node, storageNode, action); err != nil {
+				return err
+			}
+		case action != "":
+			if err := c.setCordonMaintenance(storageNode, action, true); err != nil {
+				return err
+			}
+			if storageNodeOffline(storageNode, action) {
+				c.setCordonCondition(storageNode, corev1alpha1.NodeOperationCompleted,
+					cordonActionCompletedMessage(action))
+			} else {
+				offlinePending = true
+			}
+		case cordoned:
+			// Nodes already put in maintenance by the user are left alone, so
+			// that uncordoning them does not take them out of maintenance
+			if cluster.Spec.CordonAction == corev1alpha1.CordonActionMaintenance &&
+				storageNode.Spec.Maintenance != nil && *storageNode.Spec.Maintenance {
+				continue
+			}
+			cordonedNodes = append(cordonedNodes, node)
+		default:
+			// The node was uncordoned before the storage node could be taken offline
+			if condition := getStorageNodeCondition(storageNode, corev1alpha1.CordonState); condition != nil &&
+				condition.Status == corev1alpha1.NodeOperationInProgress {
+				c.setCordonCondition(storageNode, corev1alpha1.NodeOperationCompleted, "Node is uncordoned")
+			}
+		}
+	}
+
+	if len(cordonedNodes) == 0 {
+		return nil
+	}
+	nextNode, waitingNodes := cordonedNodes[0], cordonedNodes[1:]
+	if offlinePending {
+		nextNode, waitingNodes = nil, cordonedNodes
+	}
+	for _, node := range waitingNodes {
+		c.setCordonCondition(storageNodes[node.Name], corev1alpha1.NodeOperationInProgress,
+			"Waiting for other cordoned nodes to be taken offline")
+	}
+	if nextNode == nil {
+		return nil
+	}
+	return c.takeNodeOffline(cluster, nextNode, storageNodes[nextNode.Name])
+}
+
+// takeNodeOffline takes the storage node on the given cordoned node offline
+// using the cordon action of the cluster, if the cluster stays in quorum
+func (c *Controller) takeNodeOffline(
+	cluster *corev1alpha1.StorageCluster,
+	node *v1.Node,
+	storageNode *corev1alpha1.StorageNode,
+) error {
+	if err := c.Driver.CanTakeNodeOffline(cluster, node.Name); err != nil {
+		logrus.Debugf("Cannot take storage node %s offline yet: %v", node.Name, err)
+		c.setCordonCondition(storageNode, corev1alpha1.NodeOperationInProgress,
+			fmt.Sprintf("Waiting to take the node offline: %v", err))
+		return nil
+	}
+
+	// The node is annotated before the action is taken, so that the action
+	// is reverted once the node is uncordoned, even if taking it fails
+	action := cluster.Spec.CordonAction
+	if err := c.setCordonActionAnnotation(node, action); err != nil {
+		return err
+	}
+	if err := c.setCordonMaintenance(storageNode, action, true); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Taking storage node %s offline as the node is cordoned", node.Name)
+	logrus.Infof("StorageCluster %v/%v: %s", cluster.Namespace, cluster.Name, message)
+	c.recorder.Event(cluster, v1.EventTypeNormal, nodeCordonedReason, message)
+	c.setCordonCondition(storageNode, corev1alpha1.NodeOperationInProgress,
+		cordonActionInProgressMessage(action))
+	return nil
+}
+
+// bringNodeOnline reverts the given action that was taken when the node was
+// cordoned. For the shutdown action, removing the annotation is enough for
+// the storage pod to be created on the node again.
+func (c *Controller) bringNodeOnline(
+	cluster *corev1alpha1.StorageCluster,
+	node *v1.Node,
+	storageNode *corev1alpha1.StorageNode,
+	action corev1alpha1.CordonActionType,
+) error {
+	if err := c.setCordonMaintenance(storageNode, action, false); err != nil {
+		return err
+	}
+	if err := c.setCordonActionAnnotation(node, ""); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Bringing storage node %s back as the node is uncordoned", node.Name)
+	logrus.Infof("StorageCluster %v/%v: %s", cluster.Namespace, cluster.Name, message)
+	c.recorder.Event(cluster, v1.EventTypeNormal, nodeUncordonedReason, message)
+	c.setCordonCondition(storageNode, corev1alpha1.NodeOperationCompleted, "Node is uncordoned")
+	return nil
+}
+
+// setCordonActionAnnotation sets the cordon action annotation on the node to
+// the given action, or removes the annotation if the action is empty
+func (c *Controller) setCordonActionAnnotation(
+	node *v1.Node,
+	action corev1alpha1.CordonActionType,
+) error {
+	toUpdate := node.DeepCopy()
+	if action == "" {
+		delete(toUpdate.Annotations, annotationCordonAction)
+	} else {
+		if toUpdate.Annotations == nil {
+			toUpdate.Annotations = make(map[string]string)
+		}
+		toUpdate.Annotations[annotationCordonAction] = string(action)
+	}
+	if err := c.client.Update(context.TODO(), toUpdate); err != nil {
+		return fmt.Errorf("failed to update cordon action annotation on node %s: %v", node.Name, err)
+	}
+	return nil
+}
+
+// setCordonCondition sets the cordon condition on the given StorageNode. A
+// failure is only logged, as the condition is set again in the next sync.
+func (c *Controller) setCordonCondition(
+	storageNode *corev1alpha1.StorageNode,
+	status corev1alpha1.ConditionStatus,
+	reason string,
+) {
+	if storageNode == nil {
+		return
+	}
+	err := c.setStorageNodeCondition(storageNode, corev1alpha1.NodeCondition{
+		Type:   corev1alpha1.CordonState,
+		Status: status,
+		Reason: reason,
+	})
+	if err != nil {
+		logrus.Warnf("Failed to update cordon status of StorageNode %s/%s: %v",
+			storageNode.Namespace, storageNode.Name, err)
+	}
+}
+
+// setCordonMaintenance requests the storage node to be moved in or out of
+// maintenance, if maintenance is the action taken for cordoned nodes
+func (c *Controller) setCordonMaintenance(
+	storageNode *corev1alpha1.StorageNode,
+	action corev1alpha1.CordonActionType,
+	enabled bool,
+) error {
+	if storageNode == nil || action != corev1alpha1.CordonActionMaintenance ||
+		(storageNode.Spec.Maintenance != nil && *storageNode.Spec.Maintenance == enabled) {
+		return nil
+	}
+	toUpdate := storageNode.DeepCopy()
+	toUpdate.Spec.Maintenance = &enabled
+	if err := c.client.Update(context.TODO(), toUpdate); err != nil {
+		return fmt.Errorf("failed to update maintenance of StorageNode %s/%s: %v",
+			storageNode.Namespace, storageNode.Name, err)
+	}
+	storageNode.Spec = toUpdate.Spec
+	storageNode.ResourceVersion = toUpdate.ResourceVersion
+	return nil
+}
+
+// storageNodeOffline returns true if the given action has taken the storage
+// node offline. A shut down node is offline once its storage pod is gone.
+func storageNodeOffline(
+	storageNode *corev1alpha1.StorageNode,
+	action corev1alpha1.CordonActionType,
+) bool {
+	if storageNode == nil {
+		return true
+	}
+	if action == corev1alpha1.CordonActionMaintenance {
+		return storageNode.Status.Phase == string(corev1alpha1.NodeMaintenance)
+	}
+	return storageNode.Status.Pod == nil &&
+		storageNode.Status.Phase != string(corev1alpha1.NodeOnline)
+}
+
+// nodeShutdownForCordon returns true if the storage pod should not run on the
+// node, because the node is cordoned and the storage node has been shut down
+func nodeShutdownForCordon(node *v1.Node) bool {
+	return node.Spec.Unschedulable &&
+		node.Annotations[annotationCordonAction] == string(corev1alpha1.CordonActionShutdown)
+}
+
+func cordonActionInProgressMessage(action corev1alpha1.CordonActionType) string {
+	if action == corev1alpha1.CordonActionMaintenance {
+		return "Entering maintenance as the node is cordoned"
+	}
+	return "Shutting down as the node is cordoned"
+}
+
+func cordonActionCompletedMessage(action corev1alpha1.CordonActionType) string {
+	if action == corev1alpha1.CordonActionMaintenance {
+		return "Node is in maintenance as it is cordoned"
+	}
+	return "Node is shut down as it is cordoned"
+}
@@ -0,0 +1,337 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCordonNodesWithMaintenance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createCordonTestCluster(corev1alpha1.CordonActionMaintenance)
+	node1 := createK8sNode("node1", 10)
+	node1.Spec.Unschedulable = true
+	node2 := createK8sNode("node2", 10)
+	node2.Spec.Unschedulable = true
+	node3 := createK8sNode("node3", 10)
+
+	driver := testutil.MockDriver(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	k8sClient := testutil.FakeK8sClient(
+		cluster, node1, node2, node3,
+		createOnlineStorageNode(cluster, "node1"),
+		createOnlineStorageNode(cluster, "node2"),
+		createOnlineStorageNode(cluster, "node3"),
+	)
+	controller := Controller{
+		client:   k8sClient,
+		Driver:   driver,
+		recorder: recorder,
+	}
+
+	// Only the first cordoned node should be taken offline
+	driver.EXPECT().CanTakeNodeOffline(gomock.Any(), "node1").Return(nil)
+
+	err := controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Equal(t, string(corev1alpha1.CordonActionMaintenance), cordonActionAnnotation(t, k8sClient, "node1"))
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node2"))
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node3"))
+	require.Equal(t, fmt.Sprintf("%v %v Taking storage node node1 offline as the node is cordoned",
+		v1.EventTypeNormal, nodeCordonedReason), <-recorder.Events)
+
+	storageNode := getTestStorageNode(t, k8sClient, "node1")
+	require.True(t, *storageNode.Spec.Maintenance)
+	condition := getStorageNodeCondition(storageNode, corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+	require.Equal(t, "Entering maintenance as the node is cordoned", condition.Reason)
+
+	condition = getStorageNodeCondition(getTestStorageNode(t, k8sClient, "node2"), corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+	require.Equal(t, "Waiting for other cordoned nodes to be taken offline", condition.Reason)
+
+	storageNode = getTestStorageNode(t, k8sClient, "node3")
+	require.Nil(t, storageNode.Spec.Maintenance)
+	require.Nil(t, getStorageNodeCondition(storageNode, corev1alpha1.CordonState))
+
+	// The next node should not be taken offline while the previous one is going offline
+	err = controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node2"))
+	require.Empty(t, recorder.Events)
+
+	// The next node should wait if the cluster would lose quorum
+	setStorageNodePhase(t, k8sClient, "node1", corev1alpha1.NodeMaintenance)
+	driver.EXPECT().
+		CanTakeNodeOffline(gomock.Any(), "node2").
+		Return(fmt.Errorf("not enough nodes online"))
+
+	err = controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node2"))
+	require.Empty(t, recorder.Events)
+
+	condition = getStorageNodeCondition(getTestStorageNode(t, k8sClient, "node1"), corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationCompleted, condition.Status)
+	require.Equal(t, "Node is in maintenance as it is cordoned", condition.Reason)
+
+	condition = getStorageNodeCondition(getTestStorageNode(t, k8sClient, "node2"), corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+	require.Equal(t, "Waiting to take the node offline: not enough nodes online", condition.Reason)
+
+	// The storage node should be brought back once the node is uncordoned
+	setUnschedulable(t, k8sClient, "node1", false)
+	driver.EXPECT().CanTakeNodeOffline(gomock.Any(), "node2").Return(nil)
+
+	err = controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node1"))
+	require.Equal(t, fmt.Sprintf("%v %v Bringing storage node node1 back as the node is uncordoned",
+		v1.EventTypeNormal, nodeUncordonedReason), <-recorder.Events)
+
+	storageNode = getTestStorageNode(t, k8sClient, "node1")
+	require.False(t, *storageNode.Spec.Maintenance)
+	condition = getStorageNodeCondition(storageNode, corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationCompleted, condition.Status)
+	require.Equal(t, "Node is uncordoned", condition.Reason)
+
+	// The next node can be taken offline as the first one is no longer pending
+	require.Equal(t, string(corev1alpha1.CordonActionMaintenance), cordonActionAnnotation(t, k8sClient, "node2"))
+	require.True(t, *getTestStorageNode(t, k8sClient, "node2").Spec.Maintenance)
+	require.Equal(t, fmt.Sprintf("%v %v Taking storage node node2 offline as the node is cordoned",
+		v1.EventTypeNormal, nodeCordonedReason), <-recorder.Events)
+}
+
+func TestCordonNodesWithShutdown(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createCordonTestCluster(corev1alpha1.CordonActionShutdown)
+	node := createK8sNode("node1", 10)
+	node.Spec.Unschedulable = true
+	storageNode := createOnlineStorageNode(cluster, "node1")
+	storageNode.Status.Pod = &corev1alpha1.StoragePodStatus{Name: "pod-1"}
+
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster, node, storageNode)
+	controller := Controller{
+		client:   k8sClient,
+		Driver:   driver,
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	// The storage pod should not run on the node once it has been shut down
+	driver.EXPECT().CanTakeNodeOffline(gomock.Any(), "node1").Return(nil)
+
+	err := controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Equal(t, string(corev1alpha1.CordonActionShutdown), cordonActionAnnotation(t, k8sClient, "node1"))
+	require.True(t, nodeShutdownForCordon(getTestNode(t, k8sClient, "node1")))
+
+	updatedNode := getTestStorageNode(t, k8sClient, "node1")
+	require.Nil(t, updatedNode.Spec.Maintenance)
+	condition := getStorageNodeCondition(updatedNode, corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+	require.Equal(t, "Shutting down as the node is cordoned", condition.Reason)
+
+	// The node is shut down once the storage pod is gone and the node is offline
+	updatedNode.Status.Pod = nil
+	updatedNode.Status.Phase = string(corev1alpha1.NodeOffline)
+	err = k8sClient.Status().Update(context.TODO(), updatedNode)
+	require.NoError(t, err)
+
+	err = controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	condition = getStorageNodeCondition(getTestStorageNode(t, k8sClient, "node1"), corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationCompleted, condition.Status)
+	require.Equal(t, "Node is shut down as it is cordoned", condition.Reason)
+
+	// The storage pod can run again once the node is uncordoned
+	setUnschedulable(t, k8sClient, "node1", false)
+	require.False(t, nodeShutdownForCordon(getTestNode(t, k8sClient, "node1")))
+
+	err = controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node1"))
+	condition = getStorageNodeCondition(getTestStorageNode(t, k8sClient, "node1"), corev1alpha1.CordonState)
+	require.Equal(t, corev1alpha1.NodeOperationCompleted, condition.Status)
+	require.Equal(t, "Node is uncordoned", condition.Reason)
+}
+
+func TestCordonNodesDisabled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createCordonTestCluster("")
+	cordonedNode := createK8sNode("node1", 10)
+	cordonedNode.Spec.Unschedulable = true
+	offlineNode := createK8sNode("node2", 10)
+	offlineNode.Spec.Unschedulable = true
+	offlineNode.Annotations = map[string]string{
+		annotationCordonAction: string(corev1alpha1.CordonActionMaintenance),
+	}
+	storageNode := createOnlineStorageNode(cluster, "node2")
+	storageNode.Spec.Maintenance = pointer.BoolPtr(true)
+
+	// The driver should not be called as no action is taken on cordoned nodes
+	k8sClient := testutil.FakeK8sClient(
+		cluster, cordonedNode, offlineNode,
+		createOnlineStorageNode(cluster, "node1"), storageNode,
+	)
+	controller := Controller{
+		client:   k8sClient,
+		Driver:   testutil.MockDriver(mockCtrl),
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	// Nodes taken offline before the action was removed should be brought back
+	err := controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node1"))
+	require.Nil(t, getTestStorageNode(t, k8sClient, "node1").Spec.Maintenance)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node2"))
+	require.False(t, *getTestStorageNode(t, k8sClient, "node2").Spec.Maintenance)
+}
+
+func TestCordonNodeAlreadyInMaintenance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createCordonTestCluster(corev1alpha1.CordonActionMaintenance)
+	node := createK8sNode("node1", 10)
+	node.Spec.Unschedulable = true
+	storageNode := createOnlineStorageNode(cluster, "node1")
+	storageNode.Spec.Maintenance = pointer.BoolPtr(true)
+
+	// The driver should not be called for nodes put in maintenance by the user
+	k8sClient := testutil.FakeK8sClient(cluster, node, storageNode)
+	controller := Controller{
+		client:   k8sClient,
+		Driver:   testutil.MockDriver(mockCtrl),
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	err := controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node1"))
+
+	// Uncordoning the node should not take it out of maintenance
+	setUnschedulable(t, k8sClient, "node1", false)
+	err = controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.True(t, *getTestStorageNode(t, k8sClient, "node1").Spec.Maintenance)
+}
+
+func TestCordonNodesOfOtherCluster(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createCordonTestCluster("")
+	otherCluster := createCordonTestCluster(corev1alpha1.CordonActionMaintenance)
+	otherCluster.Name = "other-cluster"
+	otherCluster.UID = "other-cluster-uid"
+	node := createK8sNode("node1", 10)
+	node.Spec.Unschedulable = true
+	node.Annotations = map[string]string{
+		annotationCordonAction: string(corev1alpha1.CordonActionMaintenance),
+	}
+	otherNode := createK8sNode("node2", 10)
+	otherNode.Spec.Unschedulable = true
+	storageNode := createOnlineStorageNode(otherCluster, "node1")
+	storageNode.Spec.Maintenance = pointer.BoolPtr(true)
+
+	// The driver should not be called as the cordoned nodes belong to the other cluster
+	k8sClient := testutil.FakeK8sClient(cluster, otherCluster, node, otherNode, storageNode)
+	controller := Controller{
+		client:   k8sClient,
+		Driver:   testutil.MockDriver(mockCtrl),
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	// The cordon action taken for the other cluster should not be reverted
+	err := controller.syncCordonedNodes(cluster)
+	require.NoError(t, err)
+	require.Equal(t, string(corev1alpha1.CordonActionMaintenance), cordonActionAnnotation(t, k8sClient, "node1"))
+	require.True(t, *getTestStorageNode(t, k8sClient, "node1").Spec.Maintenance)
+	require.Empty(t, cordonActionAnnotation(t, k8sClient, "node2"))
+}
+
+func createCordonTestCluster(action corev1alpha1.CordonActionType) *corev1alpha1.StorageCluster {
+	return &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			UID:       "cluster-uid",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			CordonAction: action,
+		},
+	}
+}
+
+func createOnlineStorageNode(
+	cluster *corev1alpha1.StorageCluster,
+	name string,
+) *corev1alpha1.StorageNode {
+	return &corev1alpha1.StorageNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cluster, controllerKind),
+			},
+		},
+		Status: corev1alpha1.NodeStatus{
+			Phase: string(corev1alpha1.NodeOnline),
+		},
+	}
+}
+
+func getTestStorageNode(t *testing.T, k8sClient client.Client, name string) *corev1alpha1.StorageNode {
+	storageNode := &corev1alpha1.StorageNode{}
+	err := testutil.Get(k8sClient, storageNode, name, "kube-test")
+	require.NoError(t, err)
+	return storageNode
+}
+
+func getTestNode(t *testing.T, k8sClient client.Client, name string) *v1.Node {
+	node := &v1.Node{}
+	err := testutil.Get(k8sClient, node, name, "")
+	require.NoError(t, err)
+	return node
+}
+
+func cordonActionAnnotation(t *testing.T, k8sClient client.Client, name string) string {
+	return getTestNode(t, k8sClient, name).Annotations[annotationCordonAction]
+}
+
+func setUnschedulable(t *testing.T, k8sClient client.Client, name string, unschedulable bool) {
+	node := getTestNode(t, k8sClient, name)
+	node.Spec.Unschedulable = unschedulable
+	err := k8sClient.Update(context.TODO(), node)
+	require.NoError(t, err)
+}
+
+func setStorageNodePhase(
+	t *testing.T,
+	k8sClient client.Client,
+	name string,
+	phase corev1alpha1.ConditionStatus,
+) {
+	storageNode := getTestStorageNode(t, k8sClient, name)
+	storageNode.Status.Phase = string(phase)
+	err := k8sClient.Status().Update(context.TODO(), storageNode)
+	require.NoError(t, err)
+}
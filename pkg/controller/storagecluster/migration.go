@@ -0,0 +1,350 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	"github.com/sirupsen/logrus"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// labelKeyMigration is the label on the nodes that are being migrated from
+	// the DaemonSet. The DaemonSet is changed to not run on nodes with this label.
+	labelKeyMigration = operatorPrefix + "/migration"
+	// migrationPending is the value of the migration label while the pod of the
+	// DaemonSet is being removed from the node
+	migrationPending = "pending"
+	// migrationDone is the value of the migration label once the storage pod
+	// of the cluster can run on the node
+	migrationDone = "done"
+	// migrationInProgressReason is added to an event and set on the migration
+	// condition while the pods of the DaemonSet are being replaced
+	migrationInProgressReason = "MigrationInProgress"
+	// migrationCompletedReason is added to an event and set on the migration
+	// condition once the DaemonSet has been replaced and removed
+	migrationCompletedReason = "MigrationCompleted"
+	// migrationFailedReason is added to an event and set on the migration
+	// condition when the DaemonSet to migrate from does not exist
+	migrationFailedReason = "MigrationFailed"
+)
+
+// migrateDaemonSet replaces the pods of the DaemonSet in the migrate-daemonset
+// annotation with the storage pods of the cluster, one node at a time. The pod
+// of the DaemonSet on a node is removed by excluding the node from the DaemonSet,
+// and the storage pod is created on the node only after that pod is gone. The
+// next node is migrated once the storage pod on the previous node is ready. When
+// no pods of the DaemonSet are left, the DaemonSet is deleted and the annotation
+// is removed from the cluster. If the DaemonSet does not exist before any node
+// has been migrated, the migration is marked as failed and the annotation is
+// kept, so storage pods are not created next to pods of a misnamed DaemonSet.
+func (c *Controller) migrateDaemonSet(cluster *corev1alpha1.StorageCluster) error {
+	dsName := cluster.Annotations[util.AnnotationMigrateDaemonSet]
+	if dsName == "" {
+		return nil
+	}
+
+	ds := &apps.DaemonSet{}
+	err := c.client.Get(
+		context.TODO(),
+		types.NamespacedName{Name: dsName, Namespace: cluster.Namespace},
+		ds,
+	)
+	if errors.IsNotFound(err) {
+		return c.handleMissingDaemonSet(cluster, dsName)
+	} else if err != nil {
+		return fmt.Errorf("failed to get DaemonSet %s/%s to migrate: %v", cluster.Namespace, dsName, err)
+	}
+
+	if err := c.excludeMigratedNodes(ds); err != nil {
+		return err
+	}
+
+	dsPodNodes, err := c.daemonSetPodNodes(ds)
+	if err != nil {
+		return err
+	}
+	nodeToStoragePods, err := c.getNodeToStoragePods(cluster)
+	if err != nil {
+		return err
+	}
+	nodeList := &v1.NodeList{}
+	if err := c.client.List(context.TODO(), nodeList, &client.ListOptions{}); err != nil {
+		return fmt.Errorf("failed to list nodes. %v", err)
+	}
+	sort.Slice(nodeList.Items, func(i, j int) bool {
+		return nodeList.Items[i].Name < nodeList.Items[j].Name
+	})
+
+	var pendingNode, unreadyNode, nextNode *v1.Node
+	migrated, total := 0, 0
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		switch node.Labels[labelKeyMigration] {
+		case migrationPending:
+			total++
+			if pendingNode == nil {
+				pendingNode = node
+			}
+		case migrationDone:
+			total++
+			if storagePodReady(nodeToStoragePods[node.Name]) {
+				migrated++
+			} else if unreadyNode == nil {
+				unreadyNode = node
+			}
+		default:
+			if dsPodNodes[node.Name] {
+				total++
+				if nextNode == nil {
+					nextNode = node
+				}
+			}
+		}
+	}
+
+	switch {
+	case pendingNode != nil && dsPodNodes[pendingNode.Name]:
+		c.setMigrationInProgress(cluster, ds.Name, migrated, total, fmt.Sprintf(
+			"Waiting for the pod of the DaemonSet to be removed from node %s", pendingNode.Name))
+		return nil
+	case pendingNode != nil:
+		// The storage pod can now be created on the node. The next node is
+		// migrated only after that pod is ready.
+		c.setMigrationInProgress(cluster, ds.Name, migrated, total, fmt.Sprintf(
+			"Creating the storage pod on node %s", pendingNode.Name))
+		return c.setMigrationLabel(pendingNode, migrationDone)
+	case unreadyNode != nil:
+		c.setMigrationInProgress(cluster, ds.Name, migrated, total, fmt.Sprintf(
+			"Waiting for the storage pod on node %s to be ready", unreadyNode.Name))
+		return nil
+	case nextNode != nil:
+		c.setMigrationInProgress(cluster, ds.Name, migrated, total, fmt.Sprintf(
+			"Removing the pod of the DaemonSet from node %s", nextNode.Name))
+		return c.setMigrationLabel(nextNode, migrationPending)
+	}
+
+	// Pods of the DaemonSet are not left on any node, so it is safe to remove it
+	if err := c.client.Delete(context.TODO(), ds); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete migrated DaemonSet %s/%s: %v", ds.Namespace, ds.Name, err)
+	}
+	return c.completeMigration(cluster)
+}
+
+// excludeMigratedNodes changes the DaemonSet to not run on the nodes that are
+// being migrated. The update strategy is changed to OnDelete in the same update,
+// so the change to the pod template does not restart the remaining pods.
+func (c *Controller) excludeMigratedNodes(ds *apps.DaemonSet) error {
+	requirement := v1.NodeSelectorRequirement{
+		Key:      labelKeyMigration,
+		Operator: v1.NodeSelectorOpDoesNotExist,
+	}
+
+	toUpdate := ds.DeepCopy()
+	modified := false
+	if toUpdate.Spec.UpdateStrategy.Type != apps.OnDeleteDaemonSetStrategyType {
+		toUpdate.Spec.UpdateStrategy = apps.DaemonSetUpdateStrategy{
+			Type: apps.OnDeleteDaemonSetStrategyType,
+		}
+		modified = true
+	}
+
+	podSpec := &toUpdate.Spec.Template.Spec
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &v1.Affinity{}
+	}
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	nodeAffinity := podSpec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{}
+	}
+	nodeSelector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []v1.NodeSelectorTerm{{}}
+	}
+	// Node selector terms are ORed, so the requirement is needed in every term
+	for i := range nodeSelector.NodeSelectorTerms {
+		term := &nodeSelector.NodeSelectorTerms[i]
+		if !hasNodeSelectorRequirement(term.MatchExpressions, requirement) {
+			term.MatchExpressions = append(term.MatchExpressions, requirement)
+			modified = true
+		}
+	}
+
+	if !modified {
+		return nil
+	}
+	logrus.Infof("Excluding migrated nodes from DaemonSet %s/%s", ds.Namespace, ds.Name)
+	if err := c.client.Update(context.TODO(), toUpdate); err != nil {
+		return fmt.Errorf("failed to update DaemonSet %s/%s for migration: %v", ds.Namespace, ds.Name, err)
+	}
+	return nil
+}
+
+// daemonSetPodNodes returns the nodes on which the pods of the given DaemonSet
+// are present, including the pods that are still terminating
+func (c *Controller) daemonSetPodNodes(ds *apps.DaemonSet) (map[string]bool, error) {
+	podList := &v1.PodList{}
+	if err := c.client.List(context.TODO(), podList, &client.ListOptions{Namespace: ds.Namespace}); err != nil {
+		return nil, fmt.Errorf("failed to list pods of DaemonSet %s/%s: %v", ds.Namespace, ds.Name, err)
+	}
+	nodes := make(map[string]bool)
+	for _, pod := range podList.Items {
+		if metav1.IsControlledBy(&pod, ds) && pod.Spec.NodeName != "" {
+			nodes[pod.Spec.NodeName] = true
+		}
+	}
+	return nodes, nil
+}
+
+// handleMissingDaemonSet completes the migration if nodes were already migrated,
+// which is when the DaemonSet was deleted after migrating all its pods but the
+// annotation could not be removed. Otherwise, the DaemonSet in the annotation
+// never existed, so the migration is marked as failed.
+func (c *Controller) handleMissingDaemonSet(
+	cluster *corev1alpha1.StorageCluster,
+	dsName string,
+) error {
+	nodeList := &v1.NodeList{}
+	if err := c.client.List(context.TODO(), nodeList, &client.ListOptions{}); err != nil {
+		return fmt.Errorf("failed to list nodes. %v", err)
+	}
+	for _, node := range nodeList.Items {
+		if _, exists := node.Labels[labelKeyMigration]; exists {
+			return c.completeMigration(cluster)
+		}
+	}
+
+	message := fmt.Sprintf("DaemonSet %s/%s to migrate from is not found", cluster.Namespace, dsName)
+	existing := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	if existing == nil || existing.Message != message {
+		logrus.Warnf("StorageCluster %v/%v: %s", cluster.Namespace, cluster.Name, message)
+		c.recorder.Event(cluster, v1.EventTypeWarning, migrationFailedReason, message)
+	}
+	setClusterCondition(cluster, corev1alpha1.ClusterCondition{
+		Type:    corev1alpha1.ClusterConditionTypeMigration,
+		Status:  corev1alpha1.ClusterOperationFailed,
+		Reason:  migrationFailedReason,
+		Message: message,
+	})
+	return nil
+}
+
+// completeMigration removes the migrate-daemonset annotation from the cluster
+// and the migration labels from the nodes
+func (c *Controller) completeMigration(cluster *corev1alpha1.StorageCluster) error {
+	dsName := cluster.Annotations[util.AnnotationMigrateDaemonSet]
+	toUpdate := cluster.DeepCopy()
+	delete(toUpdate.Annotations, util.AnnotationMigrateDaemonSet)
+	if err := c.client.Update(context.TODO(), toUpdate); err != nil {
+		return fmt.Errorf("failed to complete migration of StorageCluster %v/%v: %v",
+			cluster.Namespace, cluster.Name, err)
+	}
+	cluster.Annotations = toUpdate.Annotations
+	cluster.ResourceVersion = toUpdate.ResourceVersion
+
+	nodeList := &v1.NodeList{}
+	if err := c.client.List(context.TODO(), nodeList, &client.ListOptions{}); err != nil {
+		logrus.Warnf("Failed to list nodes to remove migration labels: %v", err)
+	}
+	for i := range nodeList.Items {
+		if _, exists := nodeList.Items[i].Labels[labelKeyMigration]; exists {
+			if err := c.setMigrationLabel(&nodeList.Items[i], ""); err != nil {
+				logrus.Warnf("Failed to remove migration label from node %s: %v",
+					nodeList.Items[i].Name, err)
+			}
+		}
+	}
+
+	message := fmt.Sprintf("Migrated all pods of DaemonSet %s", dsName)
+	logrus.Infof("StorageCluster %v/%v: %s", cluster.Namespace, cluster.Name, message)
+	c.recorder.Event(cluster, v1.EventTypeNormal, migrationCompletedReason, message)
+	setClusterCondition(cluster, corev1alpha1.ClusterCondition{
+		Type:    corev1alpha1.ClusterConditionTypeMigration,
+		Status:  corev1alpha1.ClusterOperationCompleted,
+		Reason:  migrationCompletedReason,
+		Message: message,
+	})
+	return nil
+}
+
+// setMigrationInProgress sets the migration condition on the cluster with the
+// number of nodes migrated so far. An event is recorded only when the message
+// changes, so the same step of the migration is not reported on every sync.
+func (c *Controller) setMigrationInProgress(
+	cluster *corev1alpha1.StorageCluster,
+	dsName string,
+	migrated, total int,
+	step string,
+) {
+	message := fmt.Sprintf("Migrated %d of %d nodes from DaemonSet %s. %s", migrated, total, dsName, step)
+	existing := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	if existing == nil || existing.Message != message {
+		logrus.Infof("StorageCluster %v/%v: %s", cluster.Namespace, cluster.Name, message)
+		c.recorder.Event(cluster, v1.EventTypeNormal, migrationInProgressReason, message)
+	}
+	setClusterCondition(cluster, corev1alpha1.ClusterCondition{
+		Type:    corev1alpha1.ClusterConditionTypeMigration,
+		Status:  corev1alpha1.ClusterOperationInProgress,
+		Reason:  migrationInProgressReason,
+		Message: message,
+	})
+}
+
+// setMigrationLabel sets the migration label on the node to the given value,
+// or removes the label if the value is empty
+func (c *Controller) setMigrationLabel(node *v1.Node, value string) error {
+	toUpdate := node.DeepCopy()
+	if value == "" {
+		delete(toUpdate.Labels, labelKeyMigration)
+	} else {
+		if toUpdate.Labels == nil {
+			toUpdate.Labels = make(map[string]string)
+		}
+		toUpdate.Labels[labelKeyMigration] = value
+	}
+	if err := c.client.Update(context.TODO(), toUpdate); err != nil {
+		return fmt.Errorf("failed to update migration label on node %s: %v", node.Name, err)
+	}
+	return nil
+}
+
+// nodeMigrated returns true if a storage pod of the given cluster can run on
+// the node, which is when the cluster is not migrating from a DaemonSet, or
+// the pod of the DaemonSet has already been removed from the node
+func nodeMigrated(node *v1.Node, cluster *corev1alpha1.StorageCluster) bool {
+	return cluster.Annotations[util.AnnotationMigrateDaemonSet] == "" ||
+		node.Labels[labelKeyMigration] == migrationDone
+}
+
+func storagePodReady(pods []*v1.Pod) bool {
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && podutil.IsPodReady(pod) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNodeSelectorRequirement(
+	requirements []v1.NodeSelectorRequirement,
+	requirement v1.NodeSelectorRequirement,
+) bool {
+	for _, r := range requirements {
+		if r.Key == requirement.Key && r.Operator == requirement.Operator {
+			return true
+		}
+	}
+	return false
+}
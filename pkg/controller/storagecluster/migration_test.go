@@ -0,0 +1,281 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMigrateDaemonSet(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			UID:       "cluster-uid",
+			Annotations: map[string]string{
+				util.AnnotationMigrateDaemonSet: "portworx",
+			},
+		},
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "portworx",
+			Namespace: cluster.Namespace,
+			UID:       "ds-uid",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Affinity: &v1.Affinity{
+						NodeAffinity: &v1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+								NodeSelectorTerms: []v1.NodeSelectorTerm{
+									{
+										MatchExpressions: []v1.NodeSelectorRequirement{
+											{
+												Key:      "px/enabled",
+												Operator: v1.NodeSelectorOpNotIn,
+												Values:   []string{"false"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	dsPod1 := createDaemonSetPod(ds, "portworx-1", "node1")
+	dsPod2 := createDaemonSetPod(ds, "portworx-2", "node2")
+
+	driver := testutil.MockDriver(mockCtrl)
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return("mock-driver").AnyTimes()
+	k8sClient := testutil.FakeK8sClient(
+		cluster, ds, dsPod1, dsPod2,
+		createK8sNode("node1", 10), createK8sNode("node2", 10),
+	)
+	controller := Controller{
+		client:   k8sClient,
+		Driver:   driver,
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	// The DaemonSet should exclude the migrated nodes without restarting its pods,
+	// and the pod on the first node should be removed
+	err := controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+
+	updatedDS := &appsv1.DaemonSet{}
+	err = testutil.Get(k8sClient, updatedDS, ds.Name, ds.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, appsv1.OnDeleteDaemonSetStrategyType, updatedDS.Spec.UpdateStrategy.Type)
+	requirements := updatedDS.Spec.Template.Spec.Affinity.NodeAffinity.
+		RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions
+	require.Len(t, requirements, 2)
+	require.Equal(t, labelKeyMigration, requirements[1].Key)
+	require.Equal(t, v1.NodeSelectorOpDoesNotExist, requirements[1].Operator)
+
+	require.Equal(t, migrationPending, nodeMigrationLabel(t, k8sClient, "node1"))
+	require.Empty(t, nodeMigrationLabel(t, k8sClient, "node2"))
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
+	require.Equal(t, migrationInProgressReason, condition.Reason)
+	require.Equal(t, "Migrated 0 of 2 nodes from DaemonSet portworx. "+
+		"Removing the pod of the DaemonSet from node node1", condition.Message)
+
+	// The storage pod should not run on the node until the pod of the DaemonSet is removed
+	node1 := &v1.Node{}
+	err = testutil.Get(k8sClient, node1, "node1", "")
+	require.NoError(t, err)
+	require.False(t, nodeMigrated(node1, cluster))
+
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+	require.Equal(t, migrationPending, nodeMigrationLabel(t, k8sClient, "node1"))
+
+	// Once the pod of the DaemonSet is removed, the storage pod can run on the node
+	err = k8sClient.Delete(context.TODO(), dsPod1)
+	require.NoError(t, err)
+
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+	require.Equal(t, migrationDone, nodeMigrationLabel(t, k8sClient, "node1"))
+	require.Empty(t, nodeMigrationLabel(t, k8sClient, "node2"))
+	node1 = &v1.Node{}
+	err = testutil.Get(k8sClient, node1, "node1", "")
+	require.NoError(t, err)
+	require.True(t, nodeMigrated(node1, cluster))
+
+	// The next node should not be migrated until the storage pod is ready
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+	require.Empty(t, nodeMigrationLabel(t, k8sClient, "node2"))
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	require.Equal(t, "Migrated 0 of 2 nodes from DaemonSet portworx. "+
+		"Waiting for the storage pod on node node1 to be ready", condition.Message)
+
+	storagePod1 := createReadyStoragePod(cluster, "storage-1", "node1",
+		controller.storageClusterSelectorLabels(cluster))
+	err = k8sClient.Create(context.TODO(), storagePod1)
+	require.NoError(t, err)
+
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+	require.Equal(t, migrationPending, nodeMigrationLabel(t, k8sClient, "node2"))
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	require.Equal(t, "Migrated 1 of 2 nodes from DaemonSet portworx. "+
+		"Removing the pod of the DaemonSet from node node2", condition.Message)
+
+	// Migrate the last node
+	err = k8sClient.Delete(context.TODO(), dsPod2)
+	require.NoError(t, err)
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+	require.Equal(t, migrationDone, nodeMigrationLabel(t, k8sClient, "node2"))
+
+	storagePod2 := createReadyStoragePod(cluster, "storage-2", "node2",
+		controller.storageClusterSelectorLabels(cluster))
+	err = k8sClient.Create(context.TODO(), storagePod2)
+	require.NoError(t, err)
+
+	// Once all pods are replaced, the DaemonSet should be deleted and the
+	// migration annotation and labels removed
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, &appsv1.DaemonSet{}, ds.Name, ds.Namespace)
+	require.True(t, errors.IsNotFound(err))
+	require.Empty(t, nodeMigrationLabel(t, k8sClient, "node1"))
+	require.Empty(t, nodeMigrationLabel(t, k8sClient, "node2"))
+	require.NotContains(t, cluster.Annotations, util.AnnotationMigrateDaemonSet)
+
+	updatedCluster := &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	require.NotContains(t, updatedCluster.Annotations, util.AnnotationMigrateDaemonSet)
+
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
+	require.Equal(t, migrationCompletedReason, condition.Reason)
+	require.True(t, nodeMigrated(&v1.Node{}, cluster))
+
+	// Nothing should be done once the migration is complete
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+}
+
+func TestMigrateMissingDaemonSet(t *testing.T) {
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				util.AnnotationMigrateDaemonSet: "portworx",
+			},
+		},
+	}
+	node := createK8sNode("node1", 10)
+	node.Labels = map[string]string{labelKeyMigration: migrationDone}
+	k8sClient := testutil.FakeK8sClient(cluster, node)
+	controller := Controller{
+		client:   k8sClient,
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	// If the DaemonSet is already gone, the migration should be marked complete
+	err := controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+
+	require.NotContains(t, cluster.Annotations, util.AnnotationMigrateDaemonSet)
+	require.Empty(t, nodeMigrationLabel(t, k8sClient, "node1"))
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
+}
+
+func TestMigrateNonExistentDaemonSet(t *testing.T) {
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				util.AnnotationMigrateDaemonSet: "portworx",
+			},
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	k8sClient := testutil.FakeK8sClient(cluster, createK8sNode("node1", 10))
+	controller := Controller{
+		client:   k8sClient,
+		recorder: recorder,
+	}
+
+	// If no node was migrated, the DaemonSet never existed and the migration
+	// should fail without removing the annotation
+	err := controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+
+	require.Contains(t, cluster.Annotations, util.AnnotationMigrateDaemonSet)
+	updatedCluster := &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, updatedCluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	require.Contains(t, updatedCluster.Annotations, util.AnnotationMigrateDaemonSet)
+	require.False(t, nodeMigrated(createK8sNode("node1", 10), cluster))
+
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeMigration)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
+	require.Equal(t, migrationFailedReason, condition.Reason)
+	require.Equal(t, "DaemonSet kube-test/portworx to migrate from is not found", condition.Message)
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events,
+		fmt.Sprintf("%v %v", v1.EventTypeWarning, migrationFailedReason))
+
+	// The failure should not be reported again on the next sync
+	err = controller.migrateDaemonSet(cluster)
+	require.NoError(t, err)
+	require.Empty(t, recorder.Events)
+}
+
+func createDaemonSetPod(ds *appsv1.DaemonSet, podName, nodeName string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: ds.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(ds, appsv1.SchemeGroupVersion.WithKind("DaemonSet")),
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+		},
+	}
+}
+
+func nodeMigrationLabel(t *testing.T, k8sClient client.Client, nodeName string) string {
+	node := &v1.Node{}
+	err := testutil.Get(k8sClient, node, nodeName, "")
+	require.NoError(t, err)
+	return node.Labels[labelKeyMigration]
+}
@@ -0,0 +1,248 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/hashicorp/go-version"
+	"github.com/libopenstorage/operator/drivers/storage"
+	"github.com/libopenstorage/operator/pkg/apis"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/portworx/sched-ops/k8s"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1beta1 "k8s.io/api/scheduling/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	fakeextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakek8sclient "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	k8scontroller "k8s.io/kubernetes/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	renderNodeName     = "storage-node"
+	renderEventsBuffer = 1024
+)
+
+// renderedLists are the lists of objects that the controller and the storage
+// driver components may create for a StorageCluster. The rendered objects are
+// ordered by the position of their list, which follows the order in which the
+// objects would have to be applied to a cluster.
+var renderedLists = []func() runtime.Object{
+	func() runtime.Object { return &v1.ServiceAccountList{} },
+	func() runtime.Object { return &rbacv1.ClusterRoleList{} },
+	func() runtime.Object { return &rbacv1.ClusterRoleBindingList{} },
+	func() runtime.Object { return &rbacv1.RoleList{} },
+	func() runtime.Object { return &rbacv1.RoleBindingList{} },
+	func() runtime.Object { return &v1.ConfigMapList{} },
+	func() runtime.Object { return &storagev1.StorageClassList{} },
+	func() runtime.Object { return &storagev1beta1.CSIDriverList{} },
+	func() runtime.Object { return &schedulingv1beta1.PriorityClassList{} },
+	func() runtime.Object { return &v1.ServiceList{} },
+	func() runtime.Object { return &appsv1.DaemonSetList{} },
+	func() runtime.Object { return &appsv1.DeploymentList{} },
+	func() runtime.Object { return &appsv1.StatefulSetList{} },
+	func() runtime.Object { return &monitoringv1.ServiceMonitorList{} },
+	func() runtime.Object { return &monitoringv1.PrometheusRuleList{} },
+}
+
+// Render returns all the objects that would be created for the given
+// StorageCluster, without talking to a Kubernetes cluster. The defaults are set
+// on the cluster, the components of the storage driver and Stork are installed
+// against an in-memory client, and the storage pod is created for a single node
+// with the given labels. The storage driver is initialized with the in-memory
+// client and the Kubernetes client used by the driver is replaced with a fake,
+// so Render should not be used by a running operator.
+func Render(
+	driver storage.Driver,
+	cluster *corev1alpha1.StorageCluster,
+	k8sVersion *version.Version,
+	nodeLabels map[string]string,
+) ([]runtime.Object, error) {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		apiextensionsv1beta1.AddToScheme,
+		apis.AddToScheme,
+		monitoringv1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	versionClient := fakek8sclient.NewSimpleClientset()
+	versionClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &k8sversion.Info{
+		GitVersion: "v" + k8sVersion.String(),
+	}
+	k8s.Instance().SetBaseClient(versionClient)
+	extClient := fakeextclient.NewSimpleClientset()
+	extClient.PrependReactor("create", "customresourcedefinitions", establishCRD)
+	k8s.Instance().SetAPIExtensionsClient(extClient)
+
+	cluster = cluster.DeepCopy()
+	if cluster.Namespace == "" {
+		cluster.Namespace = v1.NamespaceDefault
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   renderNodeName,
+			Labels: nodeLabels,
+		},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(scheme, cluster.DeepCopy(), node)
+	recorder := record.NewFakeRecorder(renderEventsBuffer)
+	defer logRenderEvents(recorder)
+
+	c := &Controller{
+		client:            k8sClient,
+		scheme:            scheme,
+		recorder:          recorder,
+		Driver:            driver,
+		kubernetesVersion: k8sVersion,
+	}
+	if err := c.validateK8sVersion(); err != nil {
+		return nil, err
+	}
+	if err := driver.Init(k8sClient, scheme, recorder); err != nil {
+		return nil, fmt.Errorf("failed to initialize %s driver: %v", driver.String(), err)
+	}
+
+	c.setStorageClusterDefaults(cluster)
+	if err := driver.PreInstall(cluster); err != nil {
+		return nil, fmt.Errorf("failed to install components: %v", err)
+	}
+	if err := c.syncStork(cluster); err != nil {
+		return nil, err
+	}
+
+	var objects []runtime.Object
+	for _, newList := range renderedLists {
+		list := newList()
+		if err := k8sClient.List(context.TODO(), list, &client.ListOptions{}); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(items, func(i, j int) bool {
+			return objectKey(items[i]) < objectKey(items[j])
+		})
+		objects = append(objects, items...)
+	}
+
+	crdList, err := extClient.ApiextensionsV1beta1().CustomResourceDefinitions().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	crds := make([]runtime.Object, 0, len(crdList.Items))
+	for _, crd := range crdList.Items {
+		crd := crd.DeepCopy()
+		crd.Status = apiextensionsv1beta1.CustomResourceDefinitionStatus{}
+		crds = append(crds, crd)
+	}
+	sort.Slice(crds, func(i, j int) bool {
+		return objectKey(crds[i]) < objectKey(crds[j])
+	})
+	objects = append(crds, objects...)
+
+	pod, err := c.renderStoragePod(cluster, node)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, pod)
+
+	for _, obj := range objects {
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return nil, err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		if objMeta, err := meta.Accessor(obj); err == nil {
+			objMeta.SetResourceVersion("")
+		}
+	}
+	return objects, nil
+}
+
+// renderStoragePod creates the storage pod for the given node the same way
+// the controller does, so node specific configuration in the cluster spec
+// that matches the node labels is applied as well
+func (c *Controller) renderStoragePod(
+	cluster *corev1alpha1.StorageCluster,
+	node *v1.Node,
+) (*v1.Pod, error) {
+	hash := computeHash(&cluster.Spec, cluster.Status.CollisionCount)
+	_, podTemplates, err := c.podTemplatesForNodes(cluster, []string{node.Name}, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(podTemplates) != 1 {
+		return nil, fmt.Errorf("failed to create pod template for node %s", node.Name)
+	}
+	pod, err := k8scontroller.GetPodFromTemplate(
+		podTemplates[0], cluster, metav1.NewControllerRef(cluster, controllerKind))
+	if err != nil {
+		return nil, err
+	}
+	pod.Namespace = cluster.Namespace
+	pod.Spec.NodeName = node.Name
+	return pod, nil
+}
+
+// establishCRD marks the created CRDs as established, so the components
+// waiting for their CRDs to be ready do not block
+func establishCRD(action k8stesting.Action) (bool, runtime.Object, error) {
+	createAction, ok := action.(k8stesting.CreateAction)
+	if !ok {
+		return false, nil, nil
+	}
+	if crd, ok := createAction.GetObject().(*apiextensionsv1beta1.CustomResourceDefinition); ok {
+		crd.Status.Conditions = []apiextensionsv1beta1.CustomResourceDefinitionCondition{{
+			Type:   apiextensionsv1beta1.Established,
+			Status: apiextensionsv1beta1.ConditionTrue,
+		}}
+	}
+	return false, nil, nil
+}
+
+// logRenderEvents logs the warnings raised while rendering, like components
+// that failed to install, as their objects will be missing from the output
+func logRenderEvents(recorder *record.FakeRecorder) {
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.HasPrefix(event, v1.EventTypeWarning) {
+				logrus.Warn(strings.TrimPrefix(event, v1.EventTypeWarning+" "))
+			}
+		default:
+			return
+		}
+	}
+}
+
+func objectKey(obj runtime.Object) string {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return objMeta.GetNamespace() + "/" + objMeta.GetName()
+}
@@ -0,0 +1,124 @@
+package storagecluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/go-version"
+	"github.com/libopenstorage/operator/drivers/storage"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRender(t *testing.T) {
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Image: "portworx/oci-monitor:2.1.5",
+			Stork: &corev1alpha1.StorkSpec{
+				Enabled: true,
+				Image:   "osd/stork:test",
+			},
+			Nodes: []corev1alpha1.NodeSpec{
+				{
+					Selector: corev1alpha1.NodeSelector{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"zone": "a"},
+						},
+					},
+					CommonConfig: corev1alpha1.CommonConfig{
+						Network: &corev1alpha1.NetworkSpec{
+							DataInterface: stringPtr("eth1"),
+						},
+					},
+				},
+			},
+		},
+	}
+	k8sVersion, _ := version.NewVersion("1.16.0")
+	driver, err := storage.Get("portworx")
+	require.NoError(t, err)
+
+	objects, err := Render(driver, cluster, k8sVersion, map[string]string{"zone": "a"})
+	require.NoError(t, err)
+
+	kinds := make(map[string][]string)
+	for _, obj := range objects {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		require.NotEmpty(t, kind)
+		objMeta, err := meta.Accessor(obj)
+		require.NoError(t, err)
+		require.Empty(t, objMeta.GetResourceVersion())
+		kinds[kind] = append(kinds[kind], objMeta.GetName())
+	}
+
+	// The CRDs should be rendered first and the storage pod last
+	require.Equal(t, "CustomResourceDefinition", objects[0].GetObjectKind().GroupVersionKind().Kind)
+	require.Contains(t, kinds["CustomResourceDefinition"], "volumeplacementstrategies.portworx.io")
+	require.Contains(t, kinds["ServiceAccount"], "portworx")
+	require.Contains(t, kinds["ClusterRole"], "portworx")
+	require.Contains(t, kinds["Service"], "portworx-service")
+	require.Contains(t, kinds["PriorityClass"], "px-critical")
+	require.Contains(t, kinds["Deployment"], storkDeploymentName)
+	require.Contains(t, kinds["Deployment"], storkSchedDeploymentName)
+
+	// Stork scheduler should use the given kubernetes version
+	for _, obj := range objects {
+		if deployment, ok := obj.(*appsv1.Deployment); ok && deployment.Name == storkSchedDeploymentName {
+			require.Equal(t, "gcr.io/google_containers/kube-scheduler-amd64:v1.16.0",
+				deployment.Spec.Template.Spec.Containers[0].Image)
+		}
+	}
+
+	// The storage pod should use the configuration of the matching node spec
+	pod, ok := objects[len(objects)-1].(*v1.Pod)
+	require.True(t, ok)
+	require.Equal(t, "Pod", pod.Kind)
+	require.Equal(t, cluster.Namespace, pod.Namespace)
+	require.Equal(t, renderNodeName, pod.Spec.NodeName)
+	require.Equal(t, `{"zone":"a"}`, pod.Annotations[annotationNodeLabels])
+	require.Len(t, pod.OwnerReferences, 1)
+	require.Equal(t, cluster.Name, pod.OwnerReferences[0].Name)
+	require.Contains(t, pod.Spec.Containers[0].Args, "eth1")
+
+	// The given cluster should not be modified
+	require.Nil(t, cluster.Spec.RevisionHistoryLimit)
+}
+
+func TestRenderFailures(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "px-cluster",
+		},
+	}
+	driver := testutil.MockDriver(mockCtrl)
+	driver.EXPECT().String().Return("mock").AnyTimes()
+
+	// Unsupported kubernetes version
+	k8sVersion, _ := version.NewVersion("1.10.0")
+	_, err := Render(driver, cluster, k8sVersion, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "minimum supported kubernetes version")
+
+	// Failure to install the components
+	k8sVersion, _ = version.NewVersion("1.16.0")
+	driver.EXPECT().Init(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().SetDefaultsOnStorageCluster(gomock.Any()).AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(fmt.Errorf("preinstall error"))
+
+	_, err = Render(driver, cluster, k8sVersion, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "preinstall error")
+}
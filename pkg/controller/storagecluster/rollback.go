@@ -0,0 +1,171 @@
+package storagecluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	"github.com/sirupsen/logrus"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// annotationRollbackTo is the annotation that can be used instead of
+	// spec.rollbackTo to rollback a StorageCluster to a previous revision
+	annotationRollbackTo = "storagecluster.core.libopenstorage.org/rollback-to"
+	// rolledBackReason is added to an event when a cluster is rolled back
+	rolledBackReason = "RolledBack"
+	// rollbackFailedReason is set on the rollback condition when a rollback fails
+	rollbackFailedReason = "RollbackFailed"
+	// revisionUserSpecKey is the key of the spec applied by the user in the
+	// data of a ControllerRevision
+	revisionUserSpecKey = "userSpec"
+)
+
+// rollbackIfRequested restores the spec of the given StorageCluster from the
+// ControllerRevision requested in spec.rollbackTo or the rollback-to annotation.
+// The restored spec is then rolled out like any other update to the cluster.
+// The request is cleared irrespective of the result, so a bad request is not
+// retried forever, and the outcome is recorded as a rollback condition.
+func (c *Controller) rollbackIfRequested(cluster *corev1alpha1.StorageCluster) error {
+	revision, requested, err := rollbackRevision(cluster)
+	if !requested {
+		return nil
+	}
+
+	toUpdate := cluster.DeepCopy()
+	toUpdate.Spec.RollbackTo = nil
+	delete(toUpdate.Annotations, annotationRollbackTo)
+
+	var history *apps.ControllerRevision
+	if err == nil {
+		history, err = c.revisionToRollback(cluster, revision)
+	}
+	var spec *corev1alpha1.StorageClusterSpec
+	if err == nil {
+		spec, err = userSpecFromRevision(history)
+	}
+	if err == nil {
+		toUpdate.Spec = *spec
+		toUpdate.Spec.RollbackTo = nil
+	}
+
+	if updateErr := c.client.Update(context.TODO(), toUpdate); updateErr != nil {
+		return fmt.Errorf("failed to rollback StorageCluster %v/%v: %v",
+			cluster.Namespace, cluster.Name, updateErr)
+	}
+	toUpdate.DeepCopyInto(cluster)
+
+	condition := corev1alpha1.ClusterCondition{
+		Type: corev1alpha1.ClusterConditionTypeRollback,
+	}
+	if err != nil {
+		condition.Status = corev1alpha1.ClusterOperationFailed
+		condition.Reason = rollbackFailedReason
+		condition.Message = fmt.Sprintf("Failed to rollback: %v", err)
+		c.warningEvent(cluster, util.FailedSyncReason, condition.Message)
+	} else {
+		condition.Status = corev1alpha1.ClusterOperationCompleted
+		condition.Reason = rolledBackReason
+		condition.Message = fmt.Sprintf("Rolled back to revision %d", history.Revision)
+		logrus.Infof("StorageCluster %v/%v: %s", cluster.Namespace, cluster.Name, condition.Message)
+		c.recorder.Event(cluster, v1.EventTypeNormal, rolledBackReason, condition.Message)
+	}
+	setClusterCondition(cluster, condition)
+	return nil
+}
+
+// revisionToRollback returns the ControllerRevision of the cluster with the given
+// revision number. If the revision is 0, it returns the revision before the latest.
+func (c *Controller) revisionToRollback(
+	cluster *corev1alpha1.StorageCluster,
+	revision int64,
+) (*apps.ControllerRevision, error) {
+	histories, err := c.controlledHistories(cluster)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(historiesByRevision(histories))
+
+	if revision == 0 {
+		if len(histories) < 2 {
+			return nil, fmt.Errorf("no previous revision found")
+		}
+		return histories[len(histories)-2], nil
+	}
+	for _, history := range histories {
+		if history.Revision == revision {
+			return history, nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d not found", revision)
+}
+
+// rollbackRevision returns the revision requested for rollback, either from
+// spec.rollbackTo or from the rollback-to annotation, and whether a rollback
+// was requested at all.
+func rollbackRevision(cluster *corev1alpha1.StorageCluster) (int64, bool, error) {
+	if cluster.Spec.RollbackTo != nil {
+		return cluster.Spec.RollbackTo.Revision, true, nil
+	}
+	value, exists := cluster.Annotations[annotationRollbackTo]
+	if !exists {
+		return 0, false, nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, true, fmt.Errorf("invalid revision %q in %s annotation", value, annotationRollbackTo)
+	}
+	return revision, true, nil
+}
+
+// userSpecFromRevision returns the spec applied by the user that is stored in
+// the given revision. Revisions created before the user spec was stored only
+// have the spec with the defaults, which is returned instead.
+func userSpecFromRevision(history *apps.ControllerRevision) (*corev1alpha1.StorageClusterSpec, error) {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(history.Data.Raw, &data); err != nil {
+		return nil, err
+	}
+	rawUserSpec, exists := data[revisionUserSpecKey]
+	if !exists {
+		return specFromRevision(history)
+	}
+	clusterSpec := &corev1alpha1.StorageClusterSpec{}
+	if err := json.Unmarshal(rawUserSpec, clusterSpec); err != nil {
+		return nil, err
+	}
+	return clusterSpec, nil
+}
+
+// specFromRevision returns the StorageCluster spec with the defaults that is
+// stored in the given revision
+func specFromRevision(history *apps.ControllerRevision) (*corev1alpha1.StorageClusterSpec, error) {
+	var raw map[string]interface{}
+	err := json.Unmarshal(history.Data.Raw, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := raw["spec"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec not found in revision %v", history.Name)
+	}
+	delete(spec, "$patch")
+
+	rawSpec, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterSpec := &corev1alpha1.StorageClusterSpec{}
+	if err = json.Unmarshal(rawSpec, clusterSpec); err != nil {
+		return nil, err
+	}
+	return clusterSpec, nil
+}
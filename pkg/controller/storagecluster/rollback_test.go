@@ -0,0 +1,280 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/go-version"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	k8scontroller "k8s.io/kubernetes/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRollbackToRevision(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.Image = "test/image:1.0.0"
+	controller, k8sClient, podControl, recorder := newRollbackTestController(mockCtrl, cluster)
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	// Create the first revision
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+
+	// Create the second revision by updating the image
+	err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	cluster.Spec.Image = "test/image:2.0.0"
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	revisions := &appsv1.ControllerRevisionList{}
+	err = testutil.List(k8sClient, revisions)
+	require.NoError(t, err)
+	require.Len(t, revisions.Items, 2)
+
+	// Create a pod running the second revision
+	clusterRef := metav1.NewControllerRef(cluster, controllerKind)
+	pod, err := k8scontroller.GetPodFromTemplate(&podControl.Templates[1], cluster, clusterRef)
+	require.NoError(t, err)
+	pod.Name = pod.GenerateName + "1"
+	pod.Namespace = cluster.Namespace
+	pod.Spec.NodeName = "k8s-node"
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	err = k8sClient.Create(context.TODO(), pod)
+	require.NoError(t, err)
+	podControl.Templates = nil
+	podControl.DeletePodName = nil
+
+	// Rollback to the first revision
+	err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	cluster.Spec.RollbackTo = &corev1alpha1.RollbackConfig{Revision: 1}
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+	drainEvents(recorder)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	// The spec should be restored from the revision and the request cleared.
+	// The defaults should not be written to the spec of the user.
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "test/image:1.0.0", cluster.Spec.Image)
+	require.Nil(t, cluster.Spec.RollbackTo)
+	require.Nil(t, cluster.Spec.StartPort)
+
+	// The rollback should be recorded in the status
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeRollback)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
+	require.Equal(t, rolledBackReason, condition.Reason)
+	require.Equal(t, "Rolled back to revision 1", condition.Message)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, fmt.Sprintf("%v %v Rolled back to revision 1", v1.EventTypeNormal, rolledBackReason),
+		<-recorder.Events)
+
+	// The old revision should be reused as the latest revision, instead
+	// of creating a new one, and the pod should be rolled back
+	revisions = &appsv1.ControllerRevisionList{}
+	err = testutil.List(k8sClient, revisions)
+	require.NoError(t, err)
+	require.Len(t, revisions.Items, 2)
+	require.Equal(t, []string{pod.Name}, podControl.DeletePodName)
+	for _, revision := range revisions.Items {
+		if revision.Revision == 3 {
+			require.NotEqual(t, pod.Labels[defaultStorageClusterUniqueLabelKey],
+				revision.Labels[defaultStorageClusterUniqueLabelKey])
+		} else {
+			require.Equal(t, int64(2), revision.Revision)
+		}
+	}
+}
+
+func TestRollbackToPreviousRevisionUsingAnnotation(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.Image = "test/image:1.0.0"
+	controller, k8sClient, _, _ := newRollbackTestController(mockCtrl, cluster)
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+
+	for _, image := range []string{"test/image:2.0.0", "test/image:3.0.0"} {
+		err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+		require.NoError(t, err)
+		cluster.Spec.Image = image
+		err = k8sClient.Update(context.TODO(), cluster)
+		require.NoError(t, err)
+		_, err = controller.Reconcile(request)
+		require.NoError(t, err)
+	}
+
+	// Revision 0 should rollback to the revision before the current one
+	err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	cluster.Annotations = map[string]string{annotationRollbackTo: "0"}
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "test/image:2.0.0", cluster.Spec.Image)
+	require.NotContains(t, cluster.Annotations, annotationRollbackTo)
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeRollback)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
+	require.Equal(t, "Rolled back to revision 2", condition.Message)
+}
+
+func TestRollbackFailures(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.Image = "test/image:1.0.0"
+	controller, k8sClient, _, recorder := newRollbackTestController(mockCtrl, cluster)
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+
+	// Rollback to a revision that does not exist
+	err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	cluster.Spec.RollbackTo = &corev1alpha1.RollbackConfig{Revision: 5}
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+	drainEvents(recorder)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	// The spec should not change other than clearing the request
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "test/image:1.0.0", cluster.Spec.Image)
+	require.Nil(t, cluster.Spec.RollbackTo)
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeRollback)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
+	require.Equal(t, rollbackFailedReason, condition.Reason)
+	require.Equal(t, "Failed to rollback: revision 5 not found", condition.Message)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, fmt.Sprintf("%v %v Failed to rollback: revision 5 not found",
+		v1.EventTypeWarning, util.FailedSyncReason), <-recorder.Events)
+
+	// Rollback to the previous revision when there is only one revision
+	cluster.Annotations = map[string]string{annotationRollbackTo: "0"}
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.NotContains(t, cluster.Annotations, annotationRollbackTo)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeRollback)
+	require.Equal(t, "Failed to rollback: no previous revision found", condition.Message)
+
+	// Rollback with an invalid revision in the annotation
+	cluster.Annotations = map[string]string{annotationRollbackTo: "latest"}
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.NotContains(t, cluster.Annotations, annotationRollbackTo)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeRollback)
+	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
+	require.Equal(t, "Failed to rollback: invalid revision \"latest\" in "+
+		annotationRollbackTo+" annotation", condition.Message)
+}
+
+func newRollbackTestController(
+	mockCtrl *gomock.Controller,
+	cluster *corev1alpha1.StorageCluster,
+) (*Controller, client.Client, *k8scontroller.FakePodControl, *record.FakeRecorder) {
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster, createK8sNode("k8s-node", 10))
+	podControl := &k8scontroller.FakePodControl{}
+	recorder := record.NewFakeRecorder(10)
+	controller := &Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		podControl:        podControl,
+		recorder:          recorder,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().
+		SetDefaultsOnStorageCluster(gomock.Any()).
+		Do(func(c *corev1alpha1.StorageCluster) {
+			if c.Spec.StartPort == nil {
+				startPort := uint32(9001)
+				c.Spec.StartPort = &startPort
+			}
+		}).
+		AnyTimes()
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return("mock-driver").AnyTimes()
+	driver.EXPECT().PreInstall(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateDriver(gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().GetStoragePodSpec(gomock.Any(), gomock.Any()).Return(v1.PodSpec{}, nil).AnyTimes()
+	driver.EXPECT().CanUpdateStoragePods(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	driver.EXPECT().UpdateStorageClusterStatus(gomock.Any()).Return(nil).AnyTimes()
+	return controller, k8sClient, podControl, recorder
+}
+
+func drainEvents(recorder *record.FakeRecorder) {
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+}
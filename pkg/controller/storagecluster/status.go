@@ -0,0 +1,88 @@
+package storagecluster
+
+import (
+	"fmt"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+)
+
+const (
+	// storagePodsReadyReason is set on the ready condition when all the storage
+	// pods are running the latest revision and are ready
+	storagePodsReadyReason = "StoragePodsReady"
+	// rolloutInProgressReason is set on the ready and reconciling conditions
+	// while storage pods are being created or updated
+	rolloutInProgressReason = "RolloutInProgress"
+	// reconciledReason is set on the reconciling condition once the cluster
+	// has reached its desired state
+	reconciledReason = "Reconciled"
+	// notStalledReason is set on the stalled condition when the controller is
+	// able to make progress
+	notStalledReason = "NotStalled"
+)
+
+// setStatusConditions sets the Ready, Reconciling and Stalled conditions of
+// the cluster. They follow the kstatus conventions, so that tools like
+// kubectl wait can tell when a StorageCluster has been reconciled. The given
+// nodes are the ones yet to run ready storage pods of the latest revision.
+func setStatusConditions(
+	cluster *corev1alpha1.StorageCluster,
+	pendingNodes []string,
+) {
+	ready := corev1alpha1.ClusterCondition{
+		Type:   corev1alpha1.ClusterConditionTypeReady,
+		Status: corev1alpha1.ClusterConditionFalse,
+	}
+	reconciling := corev1alpha1.ClusterCondition{
+		Type:   corev1alpha1.ClusterConditionTypeReconciling,
+		Status: corev1alpha1.ClusterConditionFalse,
+		Reason: reconciledReason,
+	}
+	stalled := corev1alpha1.ClusterCondition{
+		Type:   corev1alpha1.ClusterConditionTypeStalled,
+		Status: corev1alpha1.ClusterConditionFalse,
+		Reason: notStalledReason,
+	}
+
+	if condition := stalledCondition(cluster, pendingNodes); condition != nil {
+		stalled.Status = corev1alpha1.ClusterConditionTrue
+		stalled.Reason = condition.Reason
+		stalled.Message = condition.Message
+		ready.Reason = condition.Reason
+		ready.Message = condition.Message
+	} else if len(pendingNodes) > 0 {
+		message := fmt.Sprintf("Waiting on nodes %s to run ready storage pods of the latest revision",
+			formatNodeNames(pendingNodes))
+		reconciling.Status = corev1alpha1.ClusterConditionTrue
+		reconciling.Reason = rolloutInProgressReason
+		reconciling.Message = message
+		ready.Reason = rolloutInProgressReason
+		ready.Message = message
+	} else {
+		ready.Status = corev1alpha1.ClusterConditionTrue
+		ready.Reason = storagePodsReadyReason
+		ready.Message = fmt.Sprintf("%d of %d nodes are running ready storage pods of the latest revision",
+			cluster.Status.NumberReady, cluster.Status.DesiredNumberScheduled)
+	}
+
+	setClusterCondition(cluster, ready)
+	setClusterCondition(cluster, reconciling)
+	setClusterCondition(cluster, stalled)
+}
+
+// stalledCondition returns the condition that prevents the controller from
+// making progress without intervention, or nil if there is no such condition
+func stalledCondition(
+	cluster *corev1alpha1.StorageCluster,
+	pendingNodes []string,
+) *corev1alpha1.ClusterCondition {
+	validation := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeValidation)
+	if validation != nil && validation.Status == corev1alpha1.ClusterOperationFailed {
+		return validation
+	}
+	upgrade := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	if len(pendingNodes) > 0 && upgrade != nil && upgrade.Status == corev1alpha1.ClusterOperationTimeout {
+		return upgrade
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package storagecluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestStorageClusterStatus(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Generation = 3
+	cluster.Spec.Placement = &corev1alpha1.PlacementSpec{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{
+								Key:      "storage",
+								Operator: v1.NodeSelectorOpIn,
+								Values:   []string{"true"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	// The default k8s-node is not selected to run storage pods
+	controller, k8sClient, podControl, _ := newRollbackTestController(mockCtrl, cluster)
+	for _, nodeName := range []string{"node-1", "node-2", "node-3", "node-4"} {
+		node := createK8sNode(nodeName, 10)
+		node.Labels = map[string]string{"storage": "true"}
+		err := k8sClient.Create(context.TODO(), node)
+		require.NoError(t, err)
+	}
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Len(t, podControl.Templates, 4)
+	hash := podControl.Templates[0].Labels[defaultStorageClusterUniqueLabelKey]
+
+	// Nothing is running yet, so all the desired nodes are pending
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cluster.Status.ObservedGeneration)
+	require.Equal(t, int32(4), cluster.Status.DesiredNumberScheduled)
+	require.Equal(t, int32(0), cluster.Status.CurrentNumberScheduled)
+	require.Equal(t, int32(4), cluster.Status.NumberUnavailable)
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReady)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+	require.Equal(t, rolloutInProgressReason, condition.Reason)
+	require.Equal(t, "Waiting on nodes [node-1, node-2, node-3, node-4] to run "+
+		"ready storage pods of the latest revision", condition.Message)
+	stalledTransitionTime := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeStalled).LastTransitionTime
+
+	// node-1 runs an updated and ready pod, node-2 runs an outdated and ready pod,
+	// node-3 runs an updated pod that is not ready, node-4 has no pod, and
+	// k8s-node runs a pod even though it should not be running one
+	podLabels := func(podHash string) map[string]string {
+		return map[string]string{
+			labelKeyName:                        cluster.Name,
+			labelKeyDriverName:                  "mock-driver",
+			defaultStorageClusterUniqueLabelKey: podHash,
+		}
+	}
+	readyCondition := []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	pod1 := createStoragePod(cluster, "pod-1", "node-1", podLabels(hash))
+	pod1.Status.Conditions = readyCondition
+	pod2 := createStoragePod(cluster, "pod-2", "node-2", podLabels("old-hash"))
+	pod2.Status.Conditions = readyCondition
+	pod3 := createStoragePod(cluster, "pod-3", "node-3", podLabels(hash))
+	misscheduledPod := createStoragePod(cluster, "pod-k8s-node", "k8s-node", podLabels(hash))
+	misscheduledPod.Status.Conditions = readyCondition
+	for _, pod := range []*v1.Pod{pod1, pod2, pod3, misscheduledPod} {
+		err = k8sClient.Create(context.TODO(), pod)
+		require.NoError(t, err)
+	}
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, int32(4), cluster.Status.DesiredNumberScheduled)
+	require.Equal(t, int32(3), cluster.Status.CurrentNumberScheduled)
+	require.Equal(t, int32(1), cluster.Status.NumberMisscheduled)
+	require.Equal(t, int32(2), cluster.Status.NumberReady)
+	require.Equal(t, int32(2), cluster.Status.UpdatedNumberScheduled)
+	require.Equal(t, int32(2), cluster.Status.NumberAvailable)
+	require.Equal(t, int32(2), cluster.Status.NumberUnavailable)
+
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReady)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+	require.Equal(t, rolloutInProgressReason, condition.Reason)
+	require.Equal(t, "Waiting on nodes [node-2, node-3, node-4] to run "+
+		"ready storage pods of the latest revision", condition.Message)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReconciling)
+	require.Equal(t, corev1alpha1.ClusterConditionTrue, condition.Status)
+	require.Equal(t, rolloutInProgressReason, condition.Reason)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeStalled)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+	require.Equal(t, stalledTransitionTime, condition.LastTransitionTime)
+
+	// Once all the desired nodes run ready pods of the latest revision,
+	// the cluster should be ready
+	err = k8sClient.Delete(context.TODO(), pod2)
+	require.NoError(t, err)
+	pod2 = createStoragePod(cluster, "pod-2-updated", "node-2", podLabels(hash))
+	pod2.Status.Conditions = readyCondition
+	pod3.Status.Conditions = readyCondition
+	pod4 := createStoragePod(cluster, "pod-4", "node-4", podLabels(hash))
+	pod4.Status.Conditions = readyCondition
+	for _, pod := range []*v1.Pod{pod2, pod4} {
+		err = k8sClient.Create(context.TODO(), pod)
+		require.NoError(t, err)
+	}
+	err = k8sClient.Status().Update(context.TODO(), pod3)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, int32(4), cluster.Status.DesiredNumberScheduled)
+	require.Equal(t, int32(4), cluster.Status.CurrentNumberScheduled)
+	require.Equal(t, int32(4), cluster.Status.NumberReady)
+	require.Equal(t, int32(4), cluster.Status.UpdatedNumberScheduled)
+	require.Equal(t, int32(4), cluster.Status.NumberAvailable)
+	require.Equal(t, int32(0), cluster.Status.NumberUnavailable)
+
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReady)
+	require.Equal(t, corev1alpha1.ClusterConditionTrue, condition.Status)
+	require.Equal(t, storagePodsReadyReason, condition.Reason)
+	require.Equal(t, "4 of 4 nodes are running ready storage pods of the latest revision",
+		condition.Message)
+	require.False(t, condition.LastTransitionTime.IsZero())
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReconciling)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+	require.Equal(t, reconciledReason, condition.Reason)
+}
+
+func TestStorageClusterStalledOnUpgradeTimeout(t *testing.T) {
+	cluster := createStorageCluster()
+	setClusterCondition(cluster, corev1alpha1.ClusterCondition{
+		Type:    corev1alpha1.ClusterConditionTypeUpgrade,
+		Status:  corev1alpha1.ClusterOperationTimeout,
+		Reason:  upgradeTimeoutReason,
+		Message: "Upgrade has not progressed",
+	})
+
+	setStatusConditions(cluster, []string{"node-1"})
+
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeStalled)
+	require.Equal(t, corev1alpha1.ClusterConditionTrue, condition.Status)
+	require.Equal(t, upgradeTimeoutReason, condition.Reason)
+	require.Equal(t, "Upgrade has not progressed", condition.Message)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReady)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+	require.Equal(t, upgradeTimeoutReason, condition.Reason)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReconciling)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+
+	// A timed out upgrade that eventually finished should not stall the cluster
+	setStatusConditions(cluster, nil)
+
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeStalled)
+	require.Equal(t, corev1alpha1.ClusterConditionFalse, condition.Status)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeReady)
+	require.Equal(t, corev1alpha1.ClusterConditionTrue, condition.Status)
+}
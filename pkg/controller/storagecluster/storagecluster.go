@@ -22,7 +22,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"path"
-	"reflect"
 	"sort"
 	"strings"
 	"sync"
@@ -32,12 +31,15 @@ import (
 	"github.com/libopenstorage/operator/drivers/storage"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
 	"github.com/libopenstorage/operator/pkg/cloudprovider"
+	"github.com/libopenstorage/operator/pkg/metrics"
 	"github.com/libopenstorage/operator/pkg/util"
 	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
 	"github.com/portworx/sched-ops/k8s"
 	"github.com/sirupsen/logrus"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -83,6 +85,9 @@ const (
 	storageClusterCRDFile               = "core_v1alpha1_storagecluster_crd.yaml"
 	storageNodeCRDFile                  = "core_v1alpha1_storagenode_crd.yaml"
 	minSupportedK8sVersion              = "1.11.0"
+	deleteInProgressReason              = "DeleteInProgress"
+	validationFailedReason              = "ValidationFailed"
+	validatedReason                     = "Validated"
 )
 
 var _ reconcile.Reconciler = &Controller{}
@@ -96,15 +101,17 @@ var (
 type Controller struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client                        client.Client
-	scheme                        *runtime.Scheme
-	recorder                      record.EventRecorder
-	podControl                    k8scontroller.PodControlInterface
-	crControl                     k8scontroller.ControllerRevisionControlInterface
-	Driver                        storage.Driver
-	kubernetesVersion             *version.Version
-	isStorkDeploymentCreated      bool
-	isStorkSchedDeploymentCreated bool
+	client            client.Client
+	scheme            *runtime.Scheme
+	recorder          record.EventRecorder
+	podControl        k8scontroller.PodControlInterface
+	crControl         k8scontroller.ControllerRevisionControlInterface
+	Driver            storage.Driver
+	kubernetesVersion *version.Version
+	// storkDeploymentCreated and storkSchedDeploymentCreated are the clusters
+	// for which the Stork deployments have been created by the controller
+	storkDeploymentCreated      util.ClusterSet
+	storkSchedDeploymentCreated util.ClusterSet
 }
 
 // Init initialize the storage cluster controller
@@ -152,6 +159,68 @@ func (c *Controller) Init(mgr manager.Manager) error {
 		return err
 	}
 
+	// Watch for changes to Nodes, so storage pods are created on new nodes and
+	// nodes whose labels or taints have changed without waiting for a resync
+	err = ctrl.Watch(
+		&source.Kind{Type: &v1.Node{}},
+		&handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(c.storageClustersForNode),
+		},
+		nodePredicate(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to StorageNodes that belong to StorageCluster object
+	err = ctrl.Watch(
+		&source.Kind{Type: &corev1alpha1.StorageNode{}},
+		&handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &corev1alpha1.StorageCluster{},
+		},
+		storageNodePredicate(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the objects created by the components of a
+	// StorageCluster, so they are restored if deleted or changed out of band
+	for _, obj := range []runtime.Object{
+		&apps.Deployment{},
+		&apps.StatefulSet{},
+		&apps.DaemonSet{},
+		&v1.Service{},
+	} {
+		err = ctrl.Watch(
+			&source.Kind{Type: obj},
+			&handler.EnqueueRequestForOwner{
+				IsController: true,
+				OwnerType:    &corev1alpha1.StorageCluster{},
+			},
+			ownedObjectPredicate(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, obj := range []runtime.Object{
+		&rbacv1.ClusterRole{},
+		&storagev1.StorageClass{},
+	} {
+		err = ctrl.Watch(
+			&source.Kind{Type: obj},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(c.storageClustersForOwnedObject),
+			},
+			ownedObjectPredicate(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
 	if err != nil {
 		return fmt.Errorf("error getting kubernetes client: %v", err)
@@ -173,7 +242,15 @@ func (c *Controller) Init(mgr manager.Manager) error {
 		return fmt.Errorf("error setting node name index on pod cache: %v", err)
 	}
 
-	return nil
+	// Clean up the cluster scoped objects of the clusters that were deleted
+	// while the operator was not running, once the operator is the leader
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		if err := c.deleteOrphanedObjects(); err != nil {
+			logrus.Warnf("Failed to delete orphaned cluster scoped objects: %v", err)
+		}
+		<-stop
+		return nil
+	}))
 }
 
 // Reconcile reads that state of the cluster for a StorageCluster object and makes changes based on
@@ -182,6 +259,13 @@ func (c *Controller) Init(mgr manager.Manager) error {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (c *Controller) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	start := time.Now()
+	result, err := c.reconcile(request)
+	metrics.ObserveReconcile(request.Namespace, request.Name, time.Since(start), err)
+	return result, err
+}
+
+func (c *Controller) reconcile(request reconcile.Request) (reconcile.Result, error) {
 	log := logrus.WithFields(map[string]interface{}{
 		"Request.Namespace": request.Namespace,
 		"Request.Name":      request.Name,
@@ -195,6 +279,7 @@ func (c *Controller) Reconcile(request reconcile.Request) (reconcile.Result, err
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected.
+			metrics.DeleteClusterMetrics(request.Namespace, request.Name)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -203,8 +288,16 @@ func (c *Controller) Reconcile(request reconcile.Request) (reconcile.Result, err
 
 	if err := c.validate(cluster); err != nil {
 		c.warningEvent(cluster, util.FailedValidationReason, err.Error())
+		setValidationCondition(cluster, err)
+		setStatusConditions(cluster, nil)
+		cluster.Status.ObservedGeneration = cluster.Generation
+		if updateErr := k8sutil.UpdateStorageClusterStatus(c.client, cluster); updateErr != nil {
+			logrus.Warnf("Failed to update validation status of StorageCluster %v/%v: %v",
+				cluster.Namespace, cluster.Name, updateErr)
+		}
 		return reconcile.Result{}, err
 	}
+	setValidationCondition(cluster, nil)
 
 	if err := c.syncStorageCluster(cluster); err != nil {
 		c.warningEvent(cluster, util.FailedSyncReason, err.Error())
@@ -218,7 +311,11 @@ func (c *Controller) validate(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.validateK8sVersion(); err != nil {
 		return err
 	}
-	if err := c.validateSingleCluster(cluster); err != nil {
+	// Do not block the deletion of a cluster even if it conflicts with other clusters
+	if cluster.DeletionTimestamp != nil {
+		return nil
+	}
+	if err := c.validateClusterPlacement(cluster); err != nil {
 		return err
 	}
 	return nil
@@ -243,31 +340,45 @@ func (c *Controller) validateK8sVersion() error {
 	return nil
 }
 
-func (c *Controller) validateSingleCluster(current *corev1alpha1.StorageCluster) error {
-	// If the current cluster has the delete finalizer then it has been already reconciled
-	for _, finalizer := range current.Finalizers {
-		if finalizer == deleteFinalizerName {
-			return nil
-		}
-	}
-
-	// If the cluster current cluster does not have the finalizer, check if any existing
-	// StorageClusters have the finalizer. If none of them have it, then current just got
-	// lucky and is the only StorageCluster that will get reconciled; else if a cluster
-	// is found with the finalizer then we cannot process current as we support only 1.
+// validateClusterPlacement validates that the given StorageCluster does not conflict
+// with the other StorageClusters in the Kubernetes cluster. Multiple StorageClusters
+// are allowed only if they are in different namespaces and the nodes selected by
+// their placement do not overlap. In case of a conflict, the cluster that has already
+// been reconciled, or else the one that was created first, takes precedence.
+func (c *Controller) validateClusterPlacement(current *corev1alpha1.StorageCluster) error {
 	clusterList := &corev1alpha1.StorageClusterList{}
 	err := c.client.List(context.TODO(), clusterList, &client.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to list storage clusters. %v", err)
 	}
+
+	var nodeList *v1.NodeList
 	for _, cluster := range clusterList.Items {
-		if cluster.Name == current.Name && cluster.Namespace == current.Namespace {
+		if (cluster.Name == current.Name && cluster.Namespace == current.Namespace) ||
+			!hasPrecedence(&cluster, current) {
 			continue
 		}
-		for _, finalizer := range cluster.Finalizers {
-			if finalizer == deleteFinalizerName {
-				return fmt.Errorf("only one StorageCluster is allowed in a Kubernetes cluster. "+
-					"StorageCluster %s/%s already exists", cluster.Namespace, cluster.Name)
+		if cluster.Namespace == current.Namespace {
+			return fmt.Errorf("StorageCluster %s/%s already exists in namespace %s. Multiple "+
+				"StorageClusters should be in different namespaces",
+				cluster.Namespace, cluster.Name, current.Namespace)
+		}
+		if !hasRequiredNodeAffinity(&cluster) && !hasRequiredNodeAffinity(current) {
+			return fmt.Errorf("StorageCluster %s/%s already runs on all the nodes. Multiple "+
+				"StorageClusters should use placement node affinity to select disjoint "+
+				"sets of nodes", cluster.Namespace, cluster.Name)
+		}
+		if nodeList == nil {
+			nodeList = &v1.NodeList{}
+			if err := c.client.List(context.TODO(), nodeList, &client.ListOptions{}); err != nil {
+				return fmt.Errorf("failed to list nodes. %v", err)
+			}
+		}
+		for _, node := range nodeList.Items {
+			if nodeMatchesPlacement(&node, &cluster) && nodeMatchesPlacement(&node, current) {
+				return fmt.Errorf("node %s is selected by StorageCluster %s/%s as well. Multiple "+
+					"StorageClusters should use placement node affinity to select disjoint "+
+					"sets of nodes", node.Name, cluster.Namespace, cluster.Name)
 			}
 		}
 	}
@@ -336,19 +447,41 @@ func (c *Controller) syncStorageCluster(
 		return c.deleteStorageCluster(cluster)
 	}
 
-	// Set defaults in the storage cluster object if not set
-	if err := c.setStorageClusterDefaults(cluster); err != nil {
-		return fmt.Errorf("failed to update StorageCluster %v/%v with default values: %v",
+	// Add the delete finalizer so the storage cluster gets cleaned up properly
+	if err := c.addDeleteFinalizer(cluster); err != nil {
+		return fmt.Errorf("failed to add finalizer to StorageCluster %v/%v: %v",
 			cluster.Namespace, cluster.Name, err)
 	}
 
+	// Restore the spec from a previous revision if a rollback has been requested
+	if err := c.rollbackIfRequested(cluster); err != nil {
+		return err
+	}
+
+	// Replace the pods of an existing DaemonSet that is being migrated to the cluster
+	if err := c.migrateDaemonSet(cluster); err != nil {
+		return err
+	}
+
+	// Compute the effective configuration of the cluster by setting the defaults
+	// on a copy of the cluster. The spec applied by the user is never updated.
+	userCluster := cluster
+	cluster = cluster.DeepCopy()
+	c.setStorageClusterDefaults(cluster)
+
 	// Ensure Stork is deployed with right configuration
 	if err := c.syncStork(cluster); err != nil {
 		return err
 	}
 
+	// Take the storage nodes on cordoned nodes offline, and bring back the
+	// ones on nodes that have been uncordoned
+	if err := c.syncCordonedNodes(cluster); err != nil {
+		return err
+	}
+
 	// Construct histories of the StorageCluster, and get the hash of current history
-	cur, old, err := c.constructHistory(cluster)
+	cur, old, err := c.constructHistory(cluster, &userCluster.Spec)
 	if err != nil {
 		return fmt.Errorf("failed to construct revisions of StorageCluster %v/%v: %v",
 			cluster.Namespace, cluster.Name, err)
@@ -357,7 +490,7 @@ func (c *Controller) syncStorageCluster(
 
 	// TODO: Don't process a storage cluster until all its previous creations and
 	// deletions have been processed.
-	err = c.manage(cluster, hash)
+	desiredNodes, err := c.manage(cluster, hash, old)
 	if err != nil {
 		return err
 	}
@@ -376,8 +509,35 @@ func (c *Controller) syncStorageCluster(
 			cluster.Namespace, cluster.Name, err)
 	}
 
+	previousUpdatedNodes := cluster.Status.UpdatedNodes
+	pendingNodes, err := c.setRolloutStatus(cluster, hash, desiredNodes)
+	if err != nil {
+		return err
+	}
+
+	// Track the progress of the upgrade and rollback if it has exceeded its deadline
+	if rollbackTo := c.setUpgradeCondition(cluster, cur, previousUpdatedNodes, pendingNodes); rollbackTo != nil {
+		if err := c.requestRollback(userCluster, rollbackTo.Revision); err != nil {
+			return err
+		}
+	}
+
+	setStatusConditions(cluster, pendingNodes)
+	cluster.Status.ObservedGeneration = cluster.Generation
+
+	// Keep track of the cluster scoped objects, so they are deleted with the cluster
+	if err := c.setClusterScopedObjects(cluster); err != nil {
+		logrus.Warnf("Failed to get cluster scoped objects of StorageCluster %v/%v: %v",
+			cluster.Namespace, cluster.Name, err)
+	}
+
 	// Update status of the cluster
-	return c.updateStorageClusterStatus(cluster)
+	if err := c.updateStorageClusterStatus(cluster, &userCluster.Spec); err != nil {
+		return err
+	}
+
+	// Report the storage pod running on each node in the StorageNodes
+	return c.updateStorageNodePodStatus(cluster, hash)
 }
 
 func (c *Controller) deleteStorageCluster(
@@ -405,43 +565,44 @@ func (c *Controller) deleteStorageCluster(
 
 	if deleteFinalizerExists(cluster) {
 		toDelete := cluster.DeepCopy()
-		deleteClusterCondition, driverErr := c.Driver.DeleteStorage(toDelete)
+		// The driver needs the effective configuration of the cluster to remove
+		// the storage, but the defaults must not be written back to the cluster
+		effectiveCluster := cluster.DeepCopy()
+		c.setStorageClusterDefaults(effectiveCluster)
+		deleteClusterCondition, driverErr := c.Driver.DeleteStorage(effectiveCluster)
 		if driverErr != nil {
 			msg := fmt.Sprintf("Driver failed to delete storage. %v", driverErr)
 			c.warningEvent(toDelete, util.FailedSyncReason, msg)
 		}
-		// Check if there is an existing delete condition and overwrite it
-		foundIndex := -1
-		for i, deleteCondition := range toDelete.Status.Conditions {
-			if deleteCondition.Type == corev1alpha1.ClusterConditionTypeDelete {
-				foundIndex = i
-				break
+		// Overwrite an existing delete condition only if we have the latest delete condition
+		if deleteClusterCondition != nil {
+			setClusterCondition(toDelete, *deleteClusterCondition)
+		} else if getClusterCondition(toDelete, corev1alpha1.ClusterConditionTypeDelete) == nil {
+			condition := corev1alpha1.ClusterCondition{
+				Type:   corev1alpha1.ClusterConditionTypeDelete,
+				Status: corev1alpha1.ClusterOperationInProgress,
+				Reason: deleteInProgressReason,
 			}
-		}
-		if foundIndex == -1 {
-			if deleteClusterCondition == nil {
-				deleteClusterCondition = &corev1alpha1.ClusterCondition{
-					Type:   corev1alpha1.ClusterConditionTypeDelete,
-					Status: corev1alpha1.ClusterOperationInProgress,
-				}
-				if driverErr != nil {
-					deleteClusterCondition.Reason = err.Error()
-				}
+			if driverErr != nil {
+				condition.Message = driverErr.Error()
 			}
-			foundIndex = len(toDelete.Status.Conditions)
-			toDelete.Status.Conditions = append(toDelete.Status.Conditions, *deleteClusterCondition)
-		} else if deleteClusterCondition != nil {
-			// Update existing delete condition only if we have the latest Delete condition
-			toDelete.Status.Conditions[foundIndex] = *deleteClusterCondition
+			setClusterCondition(toDelete, condition)
 		}
+		deleteCondition := getClusterCondition(toDelete, corev1alpha1.ClusterConditionTypeDelete)
 
-		toDelete.Status.Phase = string(corev1alpha1.ClusterConditionTypeDelete) + string(toDelete.Status.Conditions[foundIndex].Status)
+		toDelete.Status.Phase = string(corev1alpha1.ClusterConditionTypeDelete) + string(deleteCondition.Status)
+		metrics.SetClusterPhase(toDelete.Namespace, toDelete.Name, toDelete.Status.Phase)
 		if err := k8sutil.UpdateStorageClusterStatus(c.client, toDelete); err != nil && !errors.IsNotFound(err) {
 			return fmt.Errorf("error updating delete status for StorageCluster %v/%v: %v",
 				toDelete.Namespace, toDelete.Name, err)
 		}
 
-		if toDelete.Status.Conditions[foundIndex].Status == corev1alpha1.ClusterOperationCompleted {
+		if deleteCondition.Status == corev1alpha1.ClusterOperationCompleted {
+			// Cluster scoped objects are not garbage collected through the
+			// cluster, so they are deleted before removing the finalizer
+			if err := c.deleteClusterScopedObjects(toDelete); err != nil {
+				return err
+			}
 			newFinalizers := removeDeleteFinalizer(toDelete.Finalizers)
 			toDelete.Finalizers = newFinalizers
 			if err := c.client.Update(context.TODO(), toDelete); err != nil && !errors.IsNotFound(err) {
@@ -450,36 +611,47 @@ func (c *Controller) deleteStorageCluster(
 		}
 	}
 
-	c.isStorkDeploymentCreated = false
-	c.isStorkSchedDeploymentCreated = false
+	c.storkDeploymentCreated.Remove(cluster)
+	c.storkSchedDeploymentCreated.Remove(cluster)
 
 	return nil
 }
 
+// updateStorageClusterStatus updates the status of the given cluster which has
+// the effective spec. The user spec is restored before persisting the object,
+// so none of the computed defaults are written back to the spec.
 func (c *Controller) updateStorageClusterStatus(
 	cluster *corev1alpha1.StorageCluster,
+	userSpec *corev1alpha1.StorageClusterSpec,
 ) error {
 	toUpdate := cluster.DeepCopy()
 	if err := c.Driver.UpdateStorageClusterStatus(toUpdate); err != nil {
 		c.warningEvent(cluster, util.FailedSyncReason, err.Error())
 	}
+	toUpdate.Spec = *userSpec.DeepCopy()
+	metrics.SetClusterPhase(toUpdate.Namespace, toUpdate.Name, toUpdate.Status.Phase)
 	return k8sutil.UpdateStorageClusterStatus(c.client, toUpdate)
 }
 
+// manage creates and deletes storage pods so that they run on the nodes
+// they are supposed to run on. Nodes that are not selected by the rolling
+// update yet get the storage pod of the previous revision. It returns the
+// nodes that should be running the storage pod.
 func (c *Controller) manage(
 	cluster *corev1alpha1.StorageCluster,
 	hash string,
-) error {
+	old []*apps.ControllerRevision,
+) (map[string]bool, error) {
 	// Run the pre install hook for the driver to ensure we are ready to create storage pods
 	if err := c.Driver.PreInstall(cluster); err != nil {
-		return fmt.Errorf("failed to run preinstall hooks for %v/%v: %v",
+		return nil, fmt.Errorf("failed to run preinstall hooks for %v/%v: %v",
 			cluster.Namespace, cluster.Name, err)
 	}
 
 	// Find out the pods which are created for the nodes by StorageCluster
 	nodeToStoragePods, err := c.getNodeToStoragePods(cluster)
 	if err != nil {
-		return fmt.Errorf("couldn't get node to storage cluster pods mapping for storage cluster %v: %v",
+		return nil, fmt.Errorf("couldn't get node to storage cluster pods mapping for storage cluster %v: %v",
 			cluster.Name, err)
 	}
 
@@ -488,7 +660,7 @@ func (c *Controller) manage(
 	nodeList := &v1.NodeList{}
 	err = c.client.List(context.TODO(), nodeList, &client.ListOptions{})
 	if err != nil {
-		return fmt.Errorf("couldn't get list of nodes when syncing storage cluster %#v: %v",
+		return nil, fmt.Errorf("couldn't get list of nodes when syncing storage cluster %#v: %v",
 			cluster, err)
 	}
 	var (
@@ -525,22 +697,47 @@ func (c *Controller) manage(
 		logrus.Debugf("Failed to update driver: %v", err)
 	}
 
+	desiredNodes := make(map[string]bool)
 	for _, node := range nodeList.Items {
-		nodesNeedingStoragePodsOnNode, podsToDeleteOnNode, err := c.podsShouldBeOnNode(&node, nodeToStoragePods, cluster)
+		nodesNeedingStoragePodsOnNode, podsToDeleteOnNode, wantToRun, err :=
+			c.podsShouldBeOnNode(&node, nodeToStoragePods, cluster)
 		if err != nil {
 			continue
 		}
+		if wantToRun {
+			desiredNodes[node.Name] = true
+		}
 
 		nodesNeedingStoragePods = append(nodesNeedingStoragePods, nodesNeedingStoragePodsOnNode...)
 		podsToDelete = append(podsToDelete, podsToDeleteOnNode...)
 	}
 
+	var nodesNeedingPreviousStoragePods []string
+	previous := previousRevision(old)
+	if previous != nil {
+		nodesNeedingStoragePods, nodesNeedingPreviousStoragePods, err =
+			c.splitNodesToUpdate(cluster, nodesNeedingStoragePods)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if err := c.syncNodes(cluster, podsToDelete, nodesNeedingStoragePods, hash); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
-	// TODO: Update the status of the pods in the CR (available, running, etc)
+	if len(nodesNeedingPreviousStoragePods) > 0 {
+		previousCluster, err := clusterForRevision(cluster, previous)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get spec of revision %v: %v", previous.Name, err)
+		}
+		previousHash := previous.Labels[defaultStorageClusterUniqueLabelKey]
+		if err := c.syncNodes(previousCluster, []string{}, nodesNeedingPreviousStoragePods, previousHash); err != nil {
+			return nil, err
+		}
+	}
+
+	return desiredNodes, nil
 }
 
 // syncNodes deletes given pods and creates new storage pods on the given nodes
@@ -599,7 +796,9 @@ func (c *Controller) syncNodes(
 				if err != nil {
 					logrus.Warnf("Failed creation of storage pod on node %v: %v", nodesNeedingStoragePods[idx], err)
 					errCh <- err
+					return
 				}
+				metrics.AddPodsCreated(cluster.Namespace, cluster.Name, 1)
 			}(i)
 		}
 		createWait.Wait()
@@ -627,7 +826,9 @@ func (c *Controller) syncNodes(
 			if err != nil {
 				logrus.Warnf("Failed deletion of storage pod %v: %v", podsToDelete[idx], err)
 				errCh <- err
+				return
 			}
+			metrics.AddPodsDeleted(cluster.Namespace, cluster.Name, 1)
 		}(i)
 	}
 	deleteWait.Wait()
@@ -758,7 +959,7 @@ func (c *Controller) podsShouldBeOnNode(
 	node *v1.Node,
 	nodeToStoragePods map[string][]*v1.Pod,
 	cluster *corev1alpha1.StorageCluster,
-) (nodesNeedingStoragePods, podsToDelete []string, err error) {
+) (nodesNeedingStoragePods, podsToDelete []string, wantToRun bool, err error) {
 	wantToRun, shouldSchedule, shouldContinueRunning, err := c.nodeShouldRunStoragePod(node, cluster)
 	if err != nil {
 		return
@@ -804,7 +1005,7 @@ func (c *Controller) podsShouldBeOnNode(
 		}
 	}
 
-	return nodesNeedingStoragePods, podsToDelete, nil
+	return nodesNeedingStoragePods, podsToDelete, wantToRun, nil
 }
 
 // nodeShouldRunStoragePod simulates a storage pod on the given node which helps
@@ -814,6 +1015,18 @@ func (c *Controller) nodeShouldRunStoragePod(
 	node *v1.Node,
 	cluster *corev1alpha1.StorageCluster,
 ) (wantToRun, shouldSchedule, shouldContinueRunning bool, err error) {
+	// The storage pod cannot run on a node until the pod of the DaemonSet that
+	// is being migrated has been removed from the node
+	if !nodeMigrated(node, cluster) {
+		return false, false, false, nil
+	}
+
+	// The storage pod is removed from a cordoned node on which the storage
+	// node has been shut down, until the node is uncordoned
+	if nodeShutdownForCordon(node) {
+		return false, false, false, nil
+	}
+
 	newPod, err := c.newSimulationPod(cluster, node.Name)
 	if err != nil {
 		logrus.Debugf("Failed to create a pod spec for node %v: %v", node.Name, err)
@@ -887,7 +1100,7 @@ func (c *Controller) nodeShouldRunStoragePod(
 			}
 			if emitEvent {
 				c.recorder.Eventf(cluster, v1.EventTypeWarning, util.FailedPlacementReason,
-					"failed to place pod on %q: %s", node.Name, reason.GetReason)
+					"failed to place pod on %q: %s", node.Name, reason.GetReason())
 			}
 		}
 	}
@@ -911,6 +1124,10 @@ func (c *Controller) createPodTemplate(
 		return v1.PodTemplateSpec{}, fmt.Errorf("failed to create pod template: %v", err)
 	}
 	addOrUpdateStoragePodTolerations(&podSpec)
+	addPlacementTolerations(&podSpec, cluster)
+	if cluster.Spec.PriorityClassName != "" {
+		podSpec.PriorityClassName = cluster.Spec.PriorityClassName
+	}
 
 	newTemplate := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -947,15 +1164,15 @@ func (c *Controller) newSimulationPod(
 		},
 	}
 
-	// TODO: Add tolerations to the pod spec when StorageCluster supports it
 	if cluster.Spec.Placement != nil && cluster.Spec.Placement.NodeAffinity != nil {
 		newPod.Spec.Affinity = &v1.Affinity{
 			NodeAffinity: cluster.Spec.Placement.NodeAffinity.DeepCopy(),
 		}
 	}
 
-	// Add default tolerations for StorageCluster pods
+	// Add default and user defined tolerations for StorageCluster pods
 	addOrUpdateStoragePodTolerations(&newPod.Spec)
+	addPlacementTolerations(&newPod.Spec, cluster)
 	return newPod, nil
 }
 
@@ -989,10 +1206,82 @@ func (c *Controller) simulate(
 	return reasons, nodeInfo, err
 }
 
-func (c *Controller) setStorageClusterDefaults(cluster *corev1alpha1.StorageCluster) error {
+// addDeleteFinalizer adds the delete finalizer to the StorageCluster if not
+// already present. Only the metadata is updated, the spec is left as is. As the
+// finalizer is added only when the cluster is reconciled for the first time, the
+// resource suffix for the cluster scoped objects is decided here as well.
+func (c *Controller) addDeleteFinalizer(cluster *corev1alpha1.StorageCluster) error {
+	if deleteFinalizerExists(cluster) {
+		return nil
+	}
 	toUpdate := cluster.DeepCopy()
+	if err := c.setResourceSuffix(toUpdate); err != nil {
+		return err
+	}
+	toUpdate.Finalizers = append(toUpdate.Finalizers, deleteFinalizerName)
+	if err := c.client.Update(context.TODO(), toUpdate); err != nil {
+		return err
+	}
+	cluster.Annotations = toUpdate.Annotations
+	cluster.Finalizers = append([]string{}, toUpdate.Finalizers...)
+	cluster.ResourceVersion = toUpdate.ResourceVersion
+	return nil
+}
+
+// setResourceSuffix sets a resource suffix on the given StorageCluster if other
+// StorageClusters have already been reconciled. The suffix is the namespace of the
+// cluster, as multiple StorageClusters cannot be in the same namespace. The first
+// cluster does not get a suffix, so its cluster scoped objects keep their names.
+func (c *Controller) setResourceSuffix(current *corev1alpha1.StorageCluster) error {
+	if _, exists := current.Annotations[util.AnnotationResourceSuffix]; exists {
+		return nil
+	}
 
-	updateStrategy := &toUpdate.Spec.UpdateStrategy
+	clusterList := &corev1alpha1.StorageClusterList{}
+	err := c.client.List(context.TODO(), clusterList, &client.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list storage clusters. %v", err)
+	}
+	for _, cluster := range clusterList.Items {
+		if cluster.Name == current.Name && cluster.Namespace == current.Namespace {
+			continue
+		}
+		if deleteFinalizerExists(&cluster) {
+			if current.Annotations == nil {
+				current.Annotations = make(map[string]string)
+			}
+			current.Annotations[util.AnnotationResourceSuffix] = current.Namespace
+			return nil
+		}
+	}
+	return nil
+}
+
+// setValidationCondition sets the validation condition on the cluster based on
+// the given validation error. If the validation passed, an existing validation
+// condition is marked as completed, else a new condition is not added.
+func setValidationCondition(cluster *corev1alpha1.StorageCluster, err error) {
+	condition := corev1alpha1.ClusterCondition{
+		Type:   corev1alpha1.ClusterConditionTypeValidation,
+		Status: corev1alpha1.ClusterOperationCompleted,
+		Reason: validatedReason,
+	}
+	if err != nil {
+		condition.Status = corev1alpha1.ClusterOperationFailed
+		condition.Reason = validationFailedReason
+		condition.Message = err.Error()
+	}
+	if err != nil || getClusterCondition(cluster, condition.Type) != nil {
+		setClusterCondition(cluster, condition)
+	}
+}
+
+// setStorageClusterDefaults sets the defaults on the given StorageCluster in
+// memory. The result is the effective spec of the cluster which is used for
+// reconciliation and for computing the revision hash. The defaults are not
+// persisted in the spec, instead the computed images are reported in status.
+func (c *Controller) setStorageClusterDefaults(cluster *corev1alpha1.StorageCluster) {
+	updateStrategy := &cluster.Spec.UpdateStrategy
 	if updateStrategy.Type == "" {
 		updateStrategy.Type = corev1alpha1.RollingUpdateStorageClusterStrategyType
 	}
@@ -1007,38 +1296,30 @@ func (c *Controller) setStorageClusterDefaults(cluster *corev1alpha1.StorageClus
 		}
 	}
 
-	if toUpdate.Spec.RevisionHistoryLimit == nil {
-		toUpdate.Spec.RevisionHistoryLimit = new(int32)
-		*toUpdate.Spec.RevisionHistoryLimit = defaultRevisionHistoryLimit
+	if cluster.Spec.RevisionHistoryLimit == nil {
+		cluster.Spec.RevisionHistoryLimit = new(int32)
+		*cluster.Spec.RevisionHistoryLimit = defaultRevisionHistoryLimit
 	}
 
-	if toUpdate.Spec.ImagePullPolicy == "" {
-		toUpdate.Spec.ImagePullPolicy = v1.PullAlways
+	if cluster.Spec.ImagePullPolicy == "" {
+		cluster.Spec.ImagePullPolicy = v1.PullAlways
 	}
 
-	foundDeleteFinalizer := false
-	for _, finalizer := range toUpdate.Finalizers {
-		if finalizer == deleteFinalizerName {
-			foundDeleteFinalizer = true
-			break
-		}
+	c.Driver.SetDefaultsOnStorageCluster(cluster)
+
+	cluster.Status.Version = cluster.Spec.Version
+	cluster.Status.DesiredImages = &corev1alpha1.ComponentImages{
+		StorageDriver: cluster.Spec.Image,
 	}
-	if !foundDeleteFinalizer {
-		toUpdate.Finalizers = append(toUpdate.Finalizers, deleteFinalizerName)
+	if cluster.Spec.Stork != nil && cluster.Spec.Stork.Enabled {
+		cluster.Status.DesiredImages.Stork = cluster.Spec.Stork.Image
 	}
-
-	c.Driver.SetDefaultsOnStorageCluster(toUpdate)
-
-	// Update the spec only if anything has changed
-	if !reflect.DeepEqual(cluster.Spec, toUpdate.Spec) || !foundDeleteFinalizer {
-		err := c.client.Update(context.TODO(), toUpdate)
-		if err != nil {
-			return err
-		}
-		cluster.Spec = *toUpdate.Spec.DeepCopy()
-		cluster.Finalizers = append([]string{}, toUpdate.Finalizers...)
+	if cluster.Spec.UserInterface != nil && cluster.Spec.UserInterface.Enabled {
+		cluster.Status.DesiredImages.UserInterface = cluster.Spec.UserInterface.Image
+	}
+	if cluster.Spec.Autopilot != nil && cluster.Spec.Autopilot.Enabled {
+		cluster.Status.DesiredImages.Autopilot = cluster.Spec.Autopilot.Image
 	}
-	return nil
 }
 
 func isControlledByStorageCluster(pod *v1.Pod, uid types.UID) bool {
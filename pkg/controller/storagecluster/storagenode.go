@@ -0,0 +1,157 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updateStorageNodePodStatus sets the status of the storage pod running on
+// each node in the StorageNode of that node, so that nodes still running an
+// older revision can be found during an upgrade. StorageNodes are created by
+// the driver, so nodes without a StorageNode are skipped.
+func (c *Controller) updateStorageNodePodStatus(
+	cluster *corev1alpha1.StorageCluster,
+	hash string,
+) error {
+	nodeToStoragePods, err := c.getNodeToStoragePods(cluster)
+	if err != nil {
+		return fmt.Errorf("couldn't get node to storage pod mapping for storage cluster %v: %v",
+			cluster.Name, err)
+	}
+
+	storageNodes, err := c.getStorageNodes(cluster)
+	if err != nil {
+		return err
+	}
+
+	for _, storageNode := range storageNodes {
+		var podStatus *corev1alpha1.StoragePodStatus
+		if pod := currentStoragePod(nodeToStoragePods[storageNode.Name]); pod != nil {
+			podStatus = c.storagePodStatus(cluster, pod, hash)
+		}
+		if equality.Semantic.DeepEqual(podStatus, storageNode.Status.Pod) {
+			continue
+		}
+		toUpdate := storageNode.DeepCopy()
+		toUpdate.Status.Pod = podStatus
+		if err := c.client.Status().Update(context.TODO(), toUpdate); err != nil {
+			msg := fmt.Sprintf("Failed to update pod status of StorageNode %v/%v: %v",
+				toUpdate.Namespace, toUpdate.Name, err)
+			c.warningEvent(cluster, util.FailedSyncReason, msg)
+		}
+	}
+	return nil
+}
+
+// getStorageNodes returns the StorageNodes controlled by the given cluster,
+// keyed by the name of the node
+func (c *Controller) getStorageNodes(
+	cluster *corev1alpha1.StorageCluster,
+) (map[string]*corev1alpha1.StorageNode, error) {
+	storageNodeList := &corev1alpha1.StorageNodeList{}
+	err := c.client.List(context.TODO(), storageNodeList, &client.ListOptions{Namespace: cluster.Namespace})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a list of StorageNode: %v", err)
+	}
+
+	storageNodes := make(map[string]*corev1alpha1.StorageNode)
+	for i := range storageNodeList.Items {
+		storageNode := &storageNodeList.Items[i]
+		if metav1.IsControlledBy(storageNode, cluster) {
+			storageNodes[storageNode.Name] = storageNode
+		}
+	}
+	return storageNodes, nil
+}
+
+// setStorageNodeCondition sets the given condition on the StorageNode, and
+// updates the status of the node if the condition has changed
+func (c *Controller) setStorageNodeCondition(
+	storageNode *corev1alpha1.StorageNode,
+	condition corev1alpha1.NodeCondition,
+) error {
+	existing := getStorageNodeCondition(storageNode, condition.Type)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason {
+		return nil
+	}
+
+	toUpdate := storageNode.DeepCopy()
+	if existing := getStorageNodeCondition(toUpdate, condition.Type); existing != nil {
+		*existing = condition
+	} else {
+		toUpdate.Status.Conditions = append(toUpdate.Status.Conditions, condition)
+	}
+	if err := c.client.Status().Update(context.TODO(), toUpdate); err != nil {
+		return err
+	}
+	storageNode.Status = toUpdate.Status
+	storageNode.ResourceVersion = toUpdate.ResourceVersion
+	return nil
+}
+
+// storagePodStatus returns the status of the given storage pod. The image and
+// restart details are taken from the first container, which runs the storage driver.
+func (c *Controller) storagePodStatus(
+	cluster *corev1alpha1.StorageCluster,
+	pod *v1.Pod,
+	hash string,
+) *corev1alpha1.StoragePodStatus {
+	podStatus := &corev1alpha1.StoragePodStatus{
+		Name:     pod.Name,
+		Revision: pod.Labels[defaultStorageClusterUniqueLabelKey],
+		Updated:  c.isPodUpdated(cluster, pod, hash),
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return podStatus
+	}
+
+	container := pod.Spec.Containers[0]
+	podStatus.Image = container.Image
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.Name != container.Name {
+			continue
+		}
+		if containerStatus.Image != "" {
+			podStatus.Image = containerStatus.Image
+		}
+		podStatus.RestartCount = containerStatus.RestartCount
+		if containerStatus.State.Running != nil {
+			startedAt := containerStatus.State.Running.StartedAt
+			podStatus.LastRestartTime = &startedAt
+		}
+	}
+	return podStatus
+}
+
+// currentStoragePod returns the storage pod that is not being deleted from the
+// given pods of a node. If all of them are being deleted, the first one is returned.
+func currentStoragePod(pods []*v1.Pod) *v1.Pod {
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil {
+			return pod
+		}
+	}
+	if len(pods) > 0 {
+		return pods[0]
+	}
+	return nil
+}
+
+func getStorageNodeCondition(
+	storageNode *corev1alpha1.StorageNode,
+	conditionType corev1alpha1.NodeConditionType,
+) *corev1alpha1.NodeCondition {
+	for i := range storageNode.Status.Conditions {
+		if storageNode.Status.Conditions[i].Type == conditionType {
+			return &storageNode.Status.Conditions[i]
+		}
+	}
+	return nil
+}
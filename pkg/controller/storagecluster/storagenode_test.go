@@ -0,0 +1,152 @@
+package storagecluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestUpdateStorageNodePodStatus(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			UID:       "cluster-uid",
+		},
+	}
+	driver := testutil.MockDriver(mockCtrl)
+	driver.EXPECT().GetSelectorLabels().Return(nil).AnyTimes()
+	driver.EXPECT().String().Return("mock-driver").AnyTimes()
+	controller := Controller{
+		Driver:   driver,
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	startedAt := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	podLabels := controller.storageClusterSelectorLabels(cluster)
+	podLabels[defaultStorageClusterUniqueLabelKey] = "new-hash"
+	updatedPod := createStoragePod(cluster, "pod-1", "node1", podLabels)
+	updatedPod.Spec.Containers = []v1.Container{
+		{Name: "portworx", Image: "portworx/oci-monitor:2.3.2"},
+		{Name: "csi-node-driver-registrar", Image: "csi-registrar:1.0.0"},
+	}
+	updatedPod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{
+			Name:         "csi-node-driver-registrar",
+			Image:        "csi-registrar:1.0.0",
+			RestartCount: 5,
+		},
+		{
+			Name:         "portworx",
+			Image:        "docker.io/portworx/oci-monitor:2.3.2",
+			RestartCount: 2,
+			State: v1.ContainerState{
+				Running: &v1.ContainerStateRunning{StartedAt: startedAt},
+			},
+		},
+	}
+
+	podLabels = controller.storageClusterSelectorLabels(cluster)
+	podLabels[defaultStorageClusterUniqueLabelKey] = "old-hash"
+	outdatedPod := createStoragePod(cluster, "pod-2", "node2", podLabels)
+	outdatedPod.Spec.Containers = []v1.Container{
+		{Name: "portworx", Image: "portworx/oci-monitor:2.3.1"},
+	}
+
+	clusterRef := metav1.NewControllerRef(cluster, controllerKind)
+	createStorageNode := func(name string, ownerRef *metav1.OwnerReference) *corev1alpha1.StorageNode {
+		return &corev1alpha1.StorageNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       cluster.Namespace,
+				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+			},
+		}
+	}
+	otherClusterRef := clusterRef.DeepCopy()
+	otherClusterRef.UID = "other-cluster-uid"
+
+	k8sClient := testutil.FakeK8sClient(
+		cluster, updatedPod, outdatedPod,
+		createK8sNode("node1", 10), createK8sNode("node2", 10), createK8sNode("node3", 10),
+		createStorageNode("node1", clusterRef),
+		createStorageNode("node2", clusterRef),
+		createStorageNode("node3", clusterRef),
+		createStorageNode("node4", otherClusterRef),
+	)
+	controller.client = k8sClient
+
+	err := controller.updateStorageNodePodStatus(cluster, "new-hash")
+	require.NoError(t, err)
+
+	// The status should be taken from the storage container and not the others
+	storageNode := &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node1", cluster.Namespace)
+	require.NoError(t, err)
+	require.NotNil(t, storageNode.Status.Pod)
+	require.Equal(t, "pod-1", storageNode.Status.Pod.Name)
+	require.Equal(t, "new-hash", storageNode.Status.Pod.Revision)
+	require.True(t, storageNode.Status.Pod.Updated)
+	require.Equal(t, "docker.io/portworx/oci-monitor:2.3.2", storageNode.Status.Pod.Image)
+	require.Equal(t, int32(2), storageNode.Status.Pod.RestartCount)
+	require.True(t, startedAt.Equal(storageNode.Status.Pod.LastRestartTime))
+
+	// The image from the pod spec should be used if the container has not started
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node2", cluster.Namespace)
+	require.NoError(t, err)
+	require.NotNil(t, storageNode.Status.Pod)
+	require.Equal(t, "pod-2", storageNode.Status.Pod.Name)
+	require.Equal(t, "old-hash", storageNode.Status.Pod.Revision)
+	require.False(t, storageNode.Status.Pod.Updated)
+	require.Equal(t, "portworx/oci-monitor:2.3.1", storageNode.Status.Pod.Image)
+	require.Nil(t, storageNode.Status.Pod.LastRestartTime)
+
+	// Nodes without storage pods should not have a pod status
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node3", cluster.Namespace)
+	require.NoError(t, err)
+	require.Nil(t, storageNode.Status.Pod)
+
+	// StorageNodes of other clusters should not be updated
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node4", cluster.Namespace)
+	require.NoError(t, err)
+	require.Nil(t, storageNode.Status.Pod)
+
+	// Updates from the driver should not remove the pod status
+	driverNode := createStorageNode("node1", clusterRef)
+	driverNode.Status.Phase = string(corev1alpha1.NodeOnline)
+	err = k8sutil.CreateOrUpdateStorageNode(k8sClient, driverNode, clusterRef)
+	require.NoError(t, err)
+
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node1", cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, string(corev1alpha1.NodeOnline), storageNode.Status.Phase)
+	require.NotNil(t, storageNode.Status.Pod)
+	require.Equal(t, "pod-1", storageNode.Status.Pod.Name)
+
+	// The pod status should be removed once the pod is gone
+	err = testutil.Delete(k8sClient, updatedPod)
+	require.NoError(t, err)
+
+	err = controller.updateStorageNodePodStatus(cluster, "new-hash")
+	require.NoError(t, err)
+
+	storageNode = &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, storageNode, "node1", cluster.Namespace)
+	require.NoError(t, err)
+	require.Nil(t, storageNode.Status.Pod)
+}
@@ -16,8 +16,8 @@ import (
 	"k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -76,10 +76,10 @@ func (c *Controller) setupStork(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.createStorkServiceAccount(cluster.Namespace, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createStorkClusterRole(ownerRef); err != nil {
+	if err := c.createStorkClusterRole(cluster, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createStorkClusterRoleBinding(cluster.Namespace, ownerRef); err != nil {
+	if err := c.createStorkClusterRoleBinding(cluster, ownerRef); err != nil {
 		return err
 	}
 	if err := c.createStorkService(cluster.Namespace, ownerRef); err != nil {
@@ -88,7 +88,7 @@ func (c *Controller) setupStork(cluster *corev1alpha1.StorageCluster) error {
 	if err := c.createStorkDeployment(cluster, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createStorkSnapshotStorageClass(ownerRef); err != nil {
+	if err := c.createStorkSnapshotStorageClass(cluster, ownerRef); err != nil {
 		return err
 	}
 	return c.setupStorkScheduler(cluster)
@@ -99,10 +99,10 @@ func (c *Controller) setupStorkScheduler(cluster *corev1alpha1.StorageCluster) e
 	if err := c.createStorkSchedServiceAccount(cluster.Namespace, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createStorkSchedClusterRole(ownerRef); err != nil {
+	if err := c.createStorkSchedClusterRole(cluster, ownerRef); err != nil {
 		return err
 	}
-	if err := c.createStorkSchedClusterRoleBinding(cluster.Namespace, ownerRef); err != nil {
+	if err := c.createStorkSchedClusterRoleBinding(cluster, ownerRef); err != nil {
 		return err
 	}
 	if err := c.createStorkSchedDeployment(cluster, ownerRef); err != nil {
@@ -120,10 +120,10 @@ func (c *Controller) removeStork(cluster *corev1alpha1.StorageCluster) error {
 	if err := k8sutil.DeleteServiceAccount(c.client, storkServiceAccountName, namespace, *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRole(c.client, storkClusterRoleName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRole(c.client, util.ClusterScopedName(cluster, storkClusterRoleName), *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRoleBinding(c.client, storkClusterRoleBindingName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRoleBinding(c.client, util.ClusterScopedName(cluster, storkClusterRoleBindingName), *ownerRef); err != nil {
 		return err
 	}
 	if err := k8sutil.DeleteService(c.client, storkServiceName, namespace, *ownerRef); err != nil {
@@ -132,30 +132,31 @@ func (c *Controller) removeStork(cluster *corev1alpha1.StorageCluster) error {
 	if err := k8sutil.DeleteDeployment(c.client, storkDeploymentName, namespace, *ownerRef); err != nil {
 		return err
 	}
-	c.isStorkDeploymentCreated = false
-	if err := k8sutil.DeleteStorageClass(c.client, storkSnapshotStorageClassName, *ownerRef); err != nil {
+	c.storkDeploymentCreated.Remove(cluster)
+	if err := k8sutil.DeleteStorageClass(c.client, util.ClusterScopedName(cluster, storkSnapshotStorageClassName), *ownerRef); err != nil {
 		return err
 	}
-	return c.removeStorkScheduler(namespace, ownerRef)
+	return c.removeStorkScheduler(cluster, ownerRef)
 }
 
 func (c *Controller) removeStorkScheduler(
-	namespace string,
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
+	namespace := cluster.Namespace
 	if err := k8sutil.DeleteServiceAccount(c.client, storkSchedServiceAccountName, namespace, *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRole(c.client, storkSchedClusterRoleName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRole(c.client, util.ClusterScopedName(cluster, storkSchedClusterRoleName), *ownerRef); err != nil {
 		return err
 	}
-	if err := k8sutil.DeleteClusterRoleBinding(c.client, storkSchedClusterRoleBindingName, *ownerRef); err != nil {
+	if err := k8sutil.DeleteClusterRoleBinding(c.client, util.ClusterScopedName(cluster, storkSchedClusterRoleBindingName), *ownerRef); err != nil {
 		return err
 	}
 	if err := k8sutil.DeleteDeployment(c.client, storkSchedDeploymentName, namespace, *ownerRef); err != nil {
 		return err
 	}
-	c.isStorkSchedDeploymentCreated = false
+	c.storkSchedDeploymentCreated.Remove(cluster)
 	return nil
 }
 
@@ -187,7 +188,7 @@ func (c *Controller) createStorkConfigMap(
 		return err
 	}
 
-	return k8sutil.CreateOrUpdateConfigMap(
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&v1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
@@ -204,17 +205,18 @@ func (c *Controller) createStorkConfigMap(
 }
 
 func (c *Controller) createStorkSnapshotStorageClass(
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
 	return k8sutil.CreateStorageClass(
 		c.client,
 		&storagev1.StorageClass{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            storkSnapshotStorageClassName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, storkSnapshotStorageClassName),
 			},
 			Provisioner: "stork-snapshot",
 		},
+		ownerRef,
 	)
 }
 
@@ -222,7 +224,7 @@ func (c *Controller) createStorkServiceAccount(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
@@ -239,7 +241,7 @@ func (c *Controller) createStorkSchedServiceAccount(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateServiceAccount(
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
@@ -252,13 +254,15 @@ func (c *Controller) createStorkSchedServiceAccount(
 	)
 }
 
-func (c *Controller) createStorkClusterRole(ownerRef *metav1.OwnerReference) error {
-	return k8sutil.CreateOrUpdateClusterRole(
+func (c *Controller) createStorkClusterRole(
+	cluster *corev1alpha1.StorageCluster,
+	ownerRef *metav1.OwnerReference,
+) error {
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&rbacv1.ClusterRole{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            storkClusterRoleName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, storkClusterRoleName),
 			},
 			Rules: []rbacv1.PolicyRule{
 				{
@@ -272,13 +276,15 @@ func (c *Controller) createStorkClusterRole(ownerRef *metav1.OwnerReference) err
 	)
 }
 
-func (c *Controller) createStorkSchedClusterRole(ownerRef *metav1.OwnerReference) error {
-	return k8sutil.CreateOrUpdateClusterRole(
+func (c *Controller) createStorkSchedClusterRole(
+	cluster *corev1alpha1.StorageCluster,
+	ownerRef *metav1.OwnerReference,
+) error {
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&rbacv1.ClusterRole{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            storkSchedClusterRoleName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, storkSchedClusterRoleName),
 			},
 			Rules: []rbacv1.PolicyRule{
 				{
@@ -359,26 +365,25 @@ func (c *Controller) createStorkSchedClusterRole(ownerRef *metav1.OwnerReference
 }
 
 func (c *Controller) createStorkClusterRoleBinding(
-	clusterNamespace string,
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateClusterRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            storkClusterRoleBindingName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, storkClusterRoleBindingName),
 			},
 			Subjects: []rbacv1.Subject{
 				{
 					Kind:      "ServiceAccount",
 					Name:      storkServiceAccountName,
-					Namespace: clusterNamespace,
+					Namespace: cluster.Namespace,
 				},
 			},
 			RoleRef: rbacv1.RoleRef{
 				Kind:     "ClusterRole",
-				Name:     storkClusterRoleName,
+				Name:     util.ClusterScopedName(cluster, storkClusterRoleName),
 				APIGroup: "rbac.authorization.k8s.io",
 			},
 		},
@@ -387,26 +392,25 @@ func (c *Controller) createStorkClusterRoleBinding(
 }
 
 func (c *Controller) createStorkSchedClusterRoleBinding(
-	clusterNamespace string,
+	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateClusterRoleBinding(
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            storkSchedClusterRoleBindingName,
-				OwnerReferences: []metav1.OwnerReference{*ownerRef},
+				Name: util.ClusterScopedName(cluster, storkSchedClusterRoleBindingName),
 			},
 			Subjects: []rbacv1.Subject{
 				{
 					Kind:      "ServiceAccount",
 					Name:      storkSchedServiceAccountName,
-					Namespace: clusterNamespace,
+					Namespace: cluster.Namespace,
 				},
 			},
 			RoleRef: rbacv1.RoleRef{
 				Kind:     "ClusterRole",
-				Name:     storkSchedClusterRoleName,
+				Name:     util.ClusterScopedName(cluster, storkSchedClusterRoleName),
 				APIGroup: "rbac.authorization.k8s.io",
 			},
 		},
@@ -418,7 +422,7 @@ func (c *Controller) createStorkService(
 	clusterNamespace string,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	return k8sutil.CreateOrUpdateService(
+	return k8sutil.CreateOrUpdate(
 		c.client,
 		&v1.Service{
 			ObjectMeta: metav1.ObjectMeta{
@@ -467,11 +471,8 @@ func (c *Controller) createStorkDeployment(
 		return err
 	}
 
-	targetCPU := defaultStorkCPU
-	if cpuStr, ok := cluster.Annotations[annotationStorkCPU]; ok {
-		targetCPU = cpuStr
-	}
-	targetCPUQuantity, err := resource.ParseQuantity(targetCPU)
+	targetResources, err := util.ResourceRequirements(cluster,
+		cluster.Spec.Stork.Resources, annotationStorkCPU, defaultStorkCPU)
 	if err != nil {
 		return err
 	}
@@ -511,32 +512,45 @@ func (c *Controller) createStorkDeployment(
 	var existingImage string
 	var existingCommand []string
 	var existingEnvs []v1.EnvVar
-	var existingCPUQuantity resource.Quantity
+	var existingResources v1.ResourceRequirements
 	for _, c := range existingDeployment.Spec.Template.Spec.Containers {
 		if c.Name == storkContainerName {
 			existingImage = c.Image
 			existingCommand = c.Command
 			existingEnvs = append([]v1.EnvVar{}, c.Env...)
 			sort.Sort(envByName(existingEnvs))
-			existingCPUQuantity = c.Resources.Requests[v1.ResourceCPU]
+			existingResources = c.Resources
 			break
 		}
 	}
 
-	// Check if image, envs, cpu or args are modified
+	// Check if image, envs, resources or args are modified
 	modified := existingImage != imageName ||
 		!reflect.DeepEqual(existingCommand, command) ||
 		!reflect.DeepEqual(existingEnvs, envVars) ||
-		existingCPUQuantity.Cmp(targetCPUQuantity) != 0
+		!equality.Semantic.DeepEqual(existingResources, targetResources) ||
+		cluster.Spec.PriorityClassName != existingDeployment.Spec.Template.Spec.PriorityClassName
+
+	deployment := c.getStorkDeploymentSpec(cluster, ownerRef, imageName,
+		command, envVars, targetResources)
+	modified = modified ||
+		util.PlacementChanged(&existingDeployment.Spec.Template.Spec, &deployment.Spec.Template.Spec)
+	// Revert the deployment if it was deleted or changed out of band,
+	// unless it is being updated anyway
+	drifted := false
+	if c.storkDeploymentCreated.Contains(cluster) && (!modified || existingDeployment.Name == "") {
+		drifted, err = k8sutil.DetectDrift(c.recorder, cluster, deployment, existingDeployment)
+		if err != nil {
+			return err
+		}
+	}
 
-	if !c.isStorkDeploymentCreated || modified {
-		deployment := c.getStorkDeploymentSpec(cluster, ownerRef, imageName,
-			command, envVars, targetCPUQuantity)
-		if err = k8sutil.CreateOrUpdateDeployment(c.client, deployment, ownerRef); err != nil {
+	if !c.storkDeploymentCreated.Contains(cluster) || modified || drifted {
+		if err = k8sutil.CreateOrUpdate(c.client, deployment, ownerRef); err != nil {
 			return err
 		}
 	}
-	c.isStorkDeploymentCreated = true
+	c.storkDeploymentCreated.Add(cluster)
 	return nil
 }
 
@@ -546,7 +560,7 @@ func (c *Controller) getStorkDeploymentSpec(
 	imageName string,
 	command []string,
 	envVars []v1.EnvVar,
-	cpuQuantity resource.Quantity,
+	resources v1.ResourceRequirements,
 ) *apps.Deployment {
 	imagePullPolicy := v1.PullAlways
 	if cluster.Spec.ImagePullPolicy == v1.PullNever ||
@@ -597,6 +611,7 @@ func (c *Controller) getStorkDeploymentSpec(
 				},
 				Spec: v1.PodSpec{
 					ServiceAccountName: storkServiceAccountName,
+					PriorityClassName:  cluster.Spec.PriorityClassName,
 					Containers: []v1.Container{
 						{
 							Name:            storkContainerName,
@@ -604,11 +619,7 @@ func (c *Controller) getStorkDeploymentSpec(
 							ImagePullPolicy: imagePullPolicy,
 							Command:         command,
 							Env:             envVars,
-							Resources: v1.ResourceRequirements{
-								Requests: map[v1.ResourceName]resource.Quantity{
-									v1.ResourceCPU: cpuQuantity,
-								},
-							},
+							Resources:       resources,
 						},
 					},
 					Affinity: &v1.Affinity{
@@ -645,6 +656,7 @@ func (c *Controller) getStorkDeploymentSpec(
 		)
 	}
 
+	util.ApplyPlacement(&deployment.Spec.Template.Spec, cluster.Spec.Stork.Placement)
 	return deployment
 }
 
@@ -652,11 +664,8 @@ func (c *Controller) createStorkSchedDeployment(
 	cluster *corev1alpha1.StorageCluster,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	targetCPU := defaultStorkCPU
-	if cpuStr, ok := cluster.Annotations[annotationStorkSchedCPU]; ok {
-		targetCPU = cpuStr
-	}
-	targetCPUQuantity, err := resource.ParseQuantity(targetCPU)
+	targetResources, err := util.ResourceRequirements(cluster,
+		cluster.Spec.Stork.SchedulerResources, annotationStorkSchedCPU, defaultStorkCPU)
 	if err != nil {
 		return err
 	}
@@ -689,26 +698,39 @@ func (c *Controller) createStorkSchedDeployment(
 
 	var existingImage string
 	var existingCommand []string
-	var existingCPUQuantity resource.Quantity
+	var existingResources v1.ResourceRequirements
 	for _, c := range existingDeployment.Spec.Template.Spec.Containers {
 		if c.Name == storkSchedContainerName {
 			existingImage = c.Image
 			existingCommand = c.Command
-			existingCPUQuantity = c.Resources.Requests[v1.ResourceCPU]
+			existingResources = c.Resources
 		}
 	}
 
 	modified := existingImage != imageName ||
 		!reflect.DeepEqual(existingCommand, command) ||
-		existingCPUQuantity.Cmp(targetCPUQuantity) != 0
+		!equality.Semantic.DeepEqual(existingResources, targetResources) ||
+		cluster.Spec.PriorityClassName != existingDeployment.Spec.Template.Spec.PriorityClassName
+
+	deployment := getStorkSchedDeploymentSpec(cluster, ownerRef, imageName, command, targetResources)
+	modified = modified ||
+		util.PlacementChanged(&existingDeployment.Spec.Template.Spec, &deployment.Spec.Template.Spec)
+	// Revert the deployment if it was deleted or changed out of band,
+	// unless it is being updated anyway
+	drifted := false
+	if c.storkSchedDeploymentCreated.Contains(cluster) && (!modified || existingDeployment.Name == "") {
+		drifted, err = k8sutil.DetectDrift(c.recorder, cluster, deployment, existingDeployment)
+		if err != nil {
+			return err
+		}
+	}
 
-	if !c.isStorkSchedDeploymentCreated || modified {
-		deployment := getStorkSchedDeploymentSpec(cluster, ownerRef, imageName, command, targetCPUQuantity)
-		if err = k8sutil.CreateOrUpdateDeployment(c.client, deployment, ownerRef); err != nil {
+	if !c.storkSchedDeploymentCreated.Contains(cluster) || modified || drifted {
+		if err = k8sutil.CreateOrUpdate(c.client, deployment, ownerRef); err != nil {
 			return err
 		}
 	}
-	c.isStorkSchedDeploymentCreated = true
+	c.storkSchedDeploymentCreated.Add(cluster)
 	return nil
 }
 
@@ -717,7 +739,7 @@ func getStorkSchedDeploymentSpec(
 	ownerRef *metav1.OwnerReference,
 	imageName string,
 	command []string,
-	cpuQuantity resource.Quantity,
+	resources v1.ResourceRequirements,
 ) *apps.Deployment {
 	templateLabels := map[string]string{
 		"tier":      "control-plane",
@@ -751,6 +773,7 @@ func getStorkSchedDeploymentSpec(
 				},
 				Spec: v1.PodSpec{
 					ServiceAccountName: storkSchedServiceAccountName,
+					PriorityClassName:  cluster.Spec.PriorityClassName,
 					Containers: []v1.Container{
 						{
 							Name:    storkSchedDeploymentName,
@@ -773,11 +796,7 @@ func getStorkSchedDeploymentSpec(
 									},
 								},
 							},
-							Resources: v1.ResourceRequirements{
-								Requests: map[v1.ResourceName]resource.Quantity{
-									v1.ResourceCPU: cpuQuantity,
-								},
-							},
+							Resources: resources,
 						},
 					},
 					Affinity: &v1.Affinity{
@@ -814,6 +833,7 @@ func getStorkSchedDeploymentSpec(
 		)
 	}
 
+	util.ApplyPlacement(&deployment.Spec.Template.Spec, cluster.Spec.Stork.Placement)
 	return deployment
 }
 
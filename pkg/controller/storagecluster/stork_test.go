@@ -11,6 +11,7 @@ import (
 	_ "github.com/libopenstorage/operator/drivers/storage/portworx"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
 	"github.com/libopenstorage/operator/pkg/util"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
 	testutil "github.com/libopenstorage/operator/pkg/util/test"
 	"github.com/portworx/sched-ops/k8s"
 	"github.com/stretchr/testify/require"
@@ -21,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	fakek8sclient "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/record"
 	schedulerv1 "k8s.io/kubernetes/pkg/scheduler/api/v1"
@@ -139,8 +141,8 @@ func TestStorkInstallation(t *testing.T) {
 	err = testutil.Get(k8sClient, storkCR, storkClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedStorkCR.Name, storkCR.Name)
-	require.Len(t, storkCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, storkCR.OwnerReferences[0].Name)
+	require.Empty(t, storkCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(storkCR))
 	require.ElementsMatch(t, expectedStorkCR.Rules, storkCR.Rules)
 
 	// Stork Scheduler ClusterRole
@@ -149,8 +151,8 @@ func TestStorkInstallation(t *testing.T) {
 	err = testutil.Get(k8sClient, schedCR, storkSchedClusterRoleName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSchedCR.Name, schedCR.Name)
-	require.Len(t, schedCR.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, schedCR.OwnerReferences[0].Name)
+	require.Empty(t, schedCR.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(schedCR))
 	require.ElementsMatch(t, expectedSchedCR.Rules, schedCR.Rules)
 
 	// ClusterRoleBindings
@@ -165,8 +167,8 @@ func TestStorkInstallation(t *testing.T) {
 	err = testutil.Get(k8sClient, storkCRB, storkClusterRoleBindingName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedStorkCRB.Name, storkCRB.Name)
-	require.Len(t, storkCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, storkCRB.OwnerReferences[0].Name)
+	require.Empty(t, storkCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(storkCRB))
 	require.ElementsMatch(t, expectedStorkCRB.Subjects, storkCRB.Subjects)
 	require.Equal(t, expectedStorkCRB.RoleRef, storkCRB.RoleRef)
 
@@ -176,8 +178,8 @@ func TestStorkInstallation(t *testing.T) {
 	err = testutil.Get(k8sClient, schedCRB, storkSchedClusterRoleBindingName, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedSchedCRB.Name, schedCRB.Name)
-	require.Len(t, schedCRB.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, schedCRB.OwnerReferences[0].Name)
+	require.Empty(t, schedCRB.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(schedCRB))
 	require.ElementsMatch(t, expectedSchedCRB.Subjects, schedCRB.Subjects)
 	require.Equal(t, expectedSchedCRB.RoleRef, schedCRB.RoleRef)
 
@@ -217,6 +219,9 @@ func TestStorkInstallation(t *testing.T) {
 	expectedStorkDeployment.Spec.Template.Spec.Containers[0].Resources.Requests = nil
 	storkDeployment.Spec.Template.Spec.Containers[0].Resources.Requests = nil
 	require.Equal(t, expectedStorkDeployment.Labels, storkDeployment.Labels)
+	require.Contains(t, storkDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(storkDeployment.Annotations, util.AnnotationLastAppliedHash)
+	delete(storkDeployment.Annotations, util.AnnotationLastAppliedLabels)
 	require.Equal(t, expectedStorkDeployment.Annotations, storkDeployment.Annotations)
 	require.Equal(t, expectedStorkDeployment.Spec, storkDeployment.Spec)
 
@@ -240,11 +245,87 @@ func TestStorkInstallation(t *testing.T) {
 	err = testutil.Get(k8sClient, storkStorageClass, storkSnapshotStorageClassName, "")
 	require.NoError(t, err)
 	require.Equal(t, storkSnapshotStorageClassName, storkStorageClass.Name)
-	require.Len(t, storkStorageClass.OwnerReferences, 1)
-	require.Equal(t, cluster.Name, storkStorageClass.OwnerReferences[0].Name)
+	require.Empty(t, storkStorageClass.OwnerReferences)
+	require.Equal(t, []types.UID{cluster.UID}, k8sutil.StorageClusterOwners(storkStorageClass))
 	require.Equal(t, "stork-snapshot", storkStorageClass.Provisioner)
 }
 
+func TestStorkInstallationWithResourceSuffix(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				util.AnnotationResourceSuffix: "kube-test",
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Stork: &corev1alpha1.StorkSpec{
+				Enabled: true,
+				Image:   "osd/stork:test",
+			},
+		},
+	}
+
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().GetStorkDriverName().Return("pxd", nil).AnyTimes()
+	driver.EXPECT().GetStorkEnvList(cluster).Return(nil).AnyTimes()
+
+	err := controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	// Cluster scoped objects should have the resource suffix
+	storkCR := &rbacv1.ClusterRole{}
+	err = testutil.Get(k8sClient, storkCR, "stork-kube-test", "")
+	require.NoError(t, err)
+
+	storkCRB := &rbacv1.ClusterRoleBinding{}
+	err = testutil.Get(k8sClient, storkCRB, "stork-kube-test", "")
+	require.NoError(t, err)
+	require.Equal(t, "stork-kube-test", storkCRB.RoleRef.Name)
+
+	storkSchedCR := &rbacv1.ClusterRole{}
+	err = testutil.Get(k8sClient, storkSchedCR, "stork-scheduler-kube-test", "")
+	require.NoError(t, err)
+
+	storkSchedCRB := &rbacv1.ClusterRoleBinding{}
+	err = testutil.Get(k8sClient, storkSchedCRB, "stork-scheduler-kube-test", "")
+	require.NoError(t, err)
+	require.Equal(t, "stork-scheduler-kube-test", storkSchedCRB.RoleRef.Name)
+
+	storkSnapshotSC := &storagev1.StorageClass{}
+	err = testutil.Get(k8sClient, storkSnapshotSC, "stork-snapshot-sc-kube-test", "")
+	require.NoError(t, err)
+
+	// Namespaced objects should not have the resource suffix
+	storkDeployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, storkDeployment, storkDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+
+	// Cluster scoped objects with the resource suffix should be removed
+	err = controller.removeStork(cluster)
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, storkCR, "stork-kube-test", "")
+	require.True(t, errors.IsNotFound(err))
+	err = testutil.Get(k8sClient, storkSchedCRB, "stork-scheduler-kube-test", "")
+	require.True(t, errors.IsNotFound(err))
+	err = testutil.Get(k8sClient, storkSnapshotSC, "stork-snapshot-sc-kube-test", "")
+	require.True(t, errors.IsNotFound(err))
+}
+
 func TestStorkWithoutImage(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -536,6 +617,288 @@ func TestStorkCPUChange(t *testing.T) {
 	require.Zero(t, expectedCPUQuantity.Cmp(deployment.Spec.Template.Spec.Containers[0].Resources.Requests[v1.ResourceCPU]))
 }
 
+func TestStorkResourcesChange(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			Annotations: map[string]string{
+				annotationStorkCPU: "0.2",
+			},
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Stork: &corev1alpha1.StorkSpec{
+				Enabled: true,
+				Image:   "osd/stork:test",
+			},
+		},
+	}
+
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().GetStorkDriverName().Return("pxd", nil).AnyTimes()
+	driver.EXPECT().GetStorkEnvList(cluster).
+		Return([]v1.EnvVar{{Name: "PX_NAMESPACE", Value: cluster.Namespace}}).
+		AnyTimes()
+
+	err := controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	// Resources from the spec should take precedence over the annotation
+	storkResources := v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+	}
+	schedulerResources := v1.ResourceRequirements{
+		Limits: v1.ResourceList{
+			v1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+	}
+	cluster.Spec.Stork.Resources = storkResources.DeepCopy()
+	cluster.Spec.Stork.SchedulerResources = schedulerResources.DeepCopy()
+
+	err = controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	deployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, storkResources, deployment.Spec.Template.Spec.Containers[0].Resources)
+
+	deployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkSchedDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, schedulerResources, deployment.Spec.Template.Spec.Containers[0].Resources)
+}
+
+func TestStorkPriorityClassChange(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Stork: &corev1alpha1.StorkSpec{
+				Enabled: true,
+				Image:   "osd/stork:test",
+			},
+		},
+	}
+
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().GetStorkDriverName().Return("pxd", nil).AnyTimes()
+	driver.EXPECT().GetStorkEnvList(cluster).Return(nil).AnyTimes()
+
+	err := controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	deployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Empty(t, deployment.Spec.Template.Spec.PriorityClassName)
+
+	// Stork and the stork scheduler should use the priority class of the cluster
+	cluster.Spec.PriorityClassName = "px-critical"
+
+	err = controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	deployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "px-critical", deployment.Spec.Template.Spec.PriorityClassName)
+
+	deployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkSchedDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "px-critical", deployment.Spec.Template.Spec.PriorityClassName)
+}
+
+func TestStorkPlacementChange(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Stork: &corev1alpha1.StorkSpec{
+				Enabled: true,
+				Image:   "osd/stork:test",
+			},
+		},
+	}
+
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		kubernetesVersion: k8sVersion,
+	}
+
+	driver.EXPECT().GetStorkDriverName().Return("pxd", nil).AnyTimes()
+	driver.EXPECT().GetStorkEnvList(cluster).Return(nil).AnyTimes()
+
+	err := controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	deployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Nil(t, deployment.Spec.Template.Spec.Affinity.NodeAffinity)
+	require.Empty(t, deployment.Spec.Template.Spec.Tolerations)
+
+	// Stork and the stork scheduler should run with the stork placement,
+	// without losing their pod anti affinity
+	nodeAffinity := &v1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{
+				{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{
+							Key:      "control-plane",
+							Operator: v1.NodeSelectorOpExists,
+						},
+					},
+				},
+			},
+		},
+	}
+	tolerations := []v1.Toleration{
+		{
+			Key:      "control-plane",
+			Operator: v1.TolerationOpExists,
+			Effect:   v1.TaintEffectNoSchedule,
+		},
+	}
+	cluster.Spec.Stork.Placement = &corev1alpha1.PlacementSpec{
+		NodeAffinity: nodeAffinity,
+		Tolerations:  tolerations,
+	}
+
+	err = controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	for _, name := range []string{storkDeploymentName, storkSchedDeploymentName} {
+		deployment = &appsv1.Deployment{}
+		err = testutil.Get(k8sClient, deployment, name, cluster.Namespace)
+		require.NoError(t, err)
+		require.Equal(t, nodeAffinity, deployment.Spec.Template.Spec.Affinity.NodeAffinity)
+		require.NotNil(t, deployment.Spec.Template.Spec.Affinity.PodAntiAffinity)
+		require.Equal(t, tolerations, deployment.Spec.Template.Spec.Tolerations)
+	}
+}
+
+func TestStorkSchedulerDrift(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1alpha1.StorageClusterSpec{
+			Stork: &corev1alpha1.StorkSpec{
+				Enabled: true,
+				Image:   "osd/stork:test",
+			},
+		},
+	}
+
+	k8s.Instance().SetBaseClient(fakek8sclient.NewSimpleClientset())
+	k8sVersion, _ := version.NewVersion("1.11.0")
+	driver := testutil.MockDriver(mockCtrl)
+	k8sClient := testutil.FakeK8sClient(cluster)
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:            k8sClient,
+		Driver:            driver,
+		kubernetesVersion: k8sVersion,
+		recorder:          recorder,
+	}
+
+	driver.EXPECT().GetStorkDriverName().Return("pxd", nil).AnyTimes()
+	driver.EXPECT().GetStorkEnvList(cluster).Return(nil).AnyTimes()
+
+	err := controller.syncStork(cluster)
+	require.NoError(t, err)
+	require.Empty(t, recorder.Events)
+
+	// Changes to the deployment made out of band should be reverted
+	deployment := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkSchedDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	expectedReplicas := *deployment.Spec.Replicas
+	replicas := expectedReplicas + 2
+	deployment.Spec.Replicas = &replicas
+	err = k8sClient.Update(context.TODO(), deployment)
+	require.NoError(t, err)
+
+	err = controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	deployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkSchedDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, expectedReplicas, *deployment.Spec.Replicas)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t,
+		fmt.Sprintf("%v %v Reverting changes to Deployment kube-test/%s: spec.replicas",
+			v1.EventTypeWarning, util.DriftedReason, storkSchedDeploymentName),
+		<-recorder.Events)
+
+	// Deployment deleted out of band should be recreated
+	err = k8sClient.Delete(context.TODO(), deployment)
+	require.NoError(t, err)
+
+	err = controller.syncStork(cluster)
+	require.NoError(t, err)
+
+	deployment = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, deployment, storkSchedDeploymentName, cluster.Namespace)
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t,
+		fmt.Sprintf("%v %v Deployment kube-test/%s was deleted, recreating it",
+			v1.EventTypeWarning, util.DriftedReason, storkSchedDeploymentName),
+		<-recorder.Events)
+}
+
 func TestStorkSchedulerCPUChange(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -26,10 +26,12 @@ import (
 	"sort"
 
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/metrics"
 	"github.com/portworx/sched-ops/k8s"
 	"github.com/sirupsen/logrus"
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -42,7 +44,10 @@ import (
 )
 
 // rollingUpdate deletes old storage cluster pods making sure that no more than
-// cluster.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable pods are unavailable
+// cluster.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable pods are unavailable.
+// If the rolling update has a partition or canary selector, only the old pods
+// on the selected nodes are deleted. Available old pods are deleted only if the
+// storage driver confirms that the storage cluster can tolerate it.
 func (c *Controller) rollingUpdate(cluster *corev1alpha1.StorageCluster, hash string) error {
 	nodeToStoragePods, err := c.getNodeToStoragePods(cluster)
 	if err != nil {
@@ -50,7 +55,25 @@ func (c *Controller) rollingUpdate(cluster *corev1alpha1.StorageCluster, hash st
 			cluster.Name, err)
 	}
 
-	_, oldPods := c.getAllStorageClusterPods(cluster, nodeToStoragePods, hash)
+	nodesToUpdate, err := c.getNodesToUpdate(cluster)
+	if err != nil {
+		return fmt.Errorf("couldn't get nodes to update: %v", err)
+	}
+
+	var updatedNodes []string
+	var oldPods []*v1.Pod
+	for nodeName, pods := range nodeToStoragePods {
+		newPodsOnNode, oldPodsOnNode := c.getAllStorageClusterPods(
+			cluster, map[string][]*v1.Pod{nodeName: pods}, hash)
+		if len(newPodsOnNode) > 0 {
+			updatedNodes = append(updatedNodes, nodeName)
+		}
+		if nodesToUpdate == nil || nodesToUpdate[nodeName] {
+			oldPods = append(oldPods, oldPodsOnNode...)
+		}
+	}
+	sort.Strings(updatedNodes)
+
 	maxUnavailable, numUnavailable, err := c.getUnavailableNumbers(cluster, nodeToStoragePods)
 	if err != nil {
 		return fmt.Errorf("couldn't get unavailable numbers: %v", err)
@@ -69,6 +92,16 @@ func (c *Controller) rollingUpdate(cluster *corev1alpha1.StorageCluster, hash st
 		oldPodsToDelete = append(oldPodsToDelete, pod.Name)
 	}
 
+	// Before taking down more storage pods, ensure the storage cluster is healthy
+	// and the storage service on the already updated nodes is back up
+	if len(oldAvailablePods) > 0 && numUnavailable < maxUnavailable {
+		if err := c.Driver.CanUpdateStoragePods(cluster.DeepCopy(), updatedNodes); err != nil {
+			logrus.Infof("Waiting to update more storage pods of StorageCluster %v/%v: %v",
+				cluster.Namespace, cluster.Name, err)
+			oldAvailablePods = nil
+		}
+	}
+
 	logrus.Debugf("Marking old pods for deletion")
 	for _, pod := range oldAvailablePods {
 		if numUnavailable >= maxUnavailable {
@@ -83,11 +116,201 @@ func (c *Controller) rollingUpdate(cluster *corev1alpha1.StorageCluster, hash st
 	return c.syncNodes(cluster, oldPodsToDelete, []string{}, hash)
 }
 
+// getNodesToUpdate returns the names of the nodes whose storage pods can be
+// updated to the latest revision, as limited by the partition and the canary
+// selector of the rolling update. Nodes are picked in the order of their names,
+// so the same nodes are selected every time. It returns nil if the rolling
+// update is not limited to a subset of nodes.
+func (c *Controller) getNodesToUpdate(
+	cluster *corev1alpha1.StorageCluster,
+) (map[string]bool, error) {
+	rollingUpdate := cluster.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil ||
+		(rollingUpdate.Partition == nil && rollingUpdate.CanarySelector == nil) {
+		return nil, nil
+	}
+
+	var canarySelector labels.Selector
+	if rollingUpdate.CanarySelector != nil {
+		var err error
+		canarySelector, err = metav1.LabelSelectorAsSelector(rollingUpdate.CanarySelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid canary selector: %v", err)
+		}
+	}
+
+	nodeList := &v1.NodeList{}
+	err := c.client.List(context.TODO(), nodeList, &client.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get list of nodes during rolling "+
+			"update of storage cluster %v/%v: %v", cluster.Namespace, cluster.Name, err)
+	}
+	sort.Slice(nodeList.Items, func(i, j int) bool {
+		return nodeList.Items[i].Name < nodeList.Items[j].Name
+	})
+
+	nodesToUpdate := make(map[string]bool)
+	for _, node := range nodeList.Items {
+		if rollingUpdate.Partition != nil && len(nodesToUpdate) >= int(*rollingUpdate.Partition) {
+			break
+		}
+		if canarySelector != nil && !canarySelector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		wantToRun, _, _, err := c.nodeShouldRunStoragePod(&node, cluster)
+		if err != nil {
+			return nil, err
+		}
+		if wantToRun {
+			nodesToUpdate[node.Name] = true
+		}
+	}
+	return nodesToUpdate, nil
+}
+
+// splitNodesToUpdate splits the given nodes that need a storage pod into the
+// nodes that can get the storage pod of the latest revision, and the nodes that
+// are not selected by the partition or the canary selector of the rolling
+// update. The latter should get the storage pod of the previous revision, so
+// a storage pod recreated on them does not bypass the rolling update.
+func (c *Controller) splitNodesToUpdate(
+	cluster *corev1alpha1.StorageCluster,
+	nodes []string,
+) ([]string, []string, error) {
+	if cluster.Spec.UpdateStrategy.Type != corev1alpha1.RollingUpdateStorageClusterStrategyType {
+		return nodes, nil, nil
+	}
+	nodesToUpdate, err := c.getNodesToUpdate(cluster)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get nodes to update: %v", err)
+	} else if nodesToUpdate == nil {
+		return nodes, nil, nil
+	}
+
+	var latestNodes, previousNodes []string
+	for _, nodeName := range nodes {
+		if nodesToUpdate[nodeName] {
+			latestNodes = append(latestNodes, nodeName)
+		} else {
+			previousNodes = append(previousNodes, nodeName)
+		}
+	}
+	return latestNodes, previousNodes, nil
+}
+
+// previousRevision returns the latest of the given old revisions, which is the
+// revision the cluster is being updated from. It returns nil if there are no
+// old revisions.
+func previousRevision(old []*apps.ControllerRevision) *apps.ControllerRevision {
+	var previous *apps.ControllerRevision
+	for _, history := range old {
+		if previous == nil || history.Revision > previous.Revision {
+			previous = history
+		}
+	}
+	return previous
+}
+
+// clusterForRevision returns a copy of the given cluster with the spec that
+// is stored in the given revision
+func clusterForRevision(
+	cluster *corev1alpha1.StorageCluster,
+	history *apps.ControllerRevision,
+) (*corev1alpha1.StorageCluster, error) {
+	spec, err := specFromRevision(history)
+	if err != nil {
+		return nil, err
+	}
+	clusterCopy := cluster.DeepCopy()
+	clusterCopy.Spec = *spec
+	return clusterCopy, nil
+}
+
+// setRolloutStatus sets the number of nodes running storage pods in the status
+// of the cluster, like the status of a DaemonSet. It also sets the number of
+// nodes running storage pods of the latest revision and of older revisions;
+// a node with storage pods of both the revisions is counted as outdated.
+// It returns the nodes that should be running the storage pod, but are yet
+// to run a ready storage pod of the latest revision, sorted by name.
+func (c *Controller) setRolloutStatus(
+	cluster *corev1alpha1.StorageCluster,
+	hash string,
+	desiredNodes map[string]bool,
+) ([]string, error) {
+	nodeToStoragePods, err := c.getNodeToStoragePods(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get node to storage pod mapping for storage cluster %v: %v",
+			cluster.Name, err)
+	}
+
+	var updatedNodes, outdatedNodes int32
+	var currentNumber, misscheduledNumber, readyNumber, updatedNumber, availableNumber int32
+	var pendingNodes []string
+	for nodeName, pods := range nodeToStoragePods {
+		updated, allReady := true, true
+		anyUpdated, anyReady, anyAvailable := false, false, false
+		for _, pod := range pods {
+			podUpdated := c.isPodUpdated(cluster, pod, hash)
+			podReady := podutil.IsPodReady(pod)
+			updated = updated && podUpdated
+			allReady = allReady && podReady
+			anyUpdated = anyUpdated || podUpdated
+			anyReady = anyReady || podReady
+			anyAvailable = anyAvailable || (podReady && pod.DeletionTimestamp == nil)
+		}
+		if updated {
+			updatedNodes++
+		} else {
+			outdatedNodes++
+		}
+
+		if !desiredNodes[nodeName] {
+			misscheduledNumber++
+			continue
+		}
+		currentNumber++
+		if anyUpdated {
+			updatedNumber++
+		}
+		if anyReady {
+			readyNumber++
+		}
+		if anyAvailable {
+			availableNumber++
+		}
+		if !updated || !allReady {
+			pendingNodes = append(pendingNodes, nodeName)
+		}
+	}
+	for nodeName := range desiredNodes {
+		if _, exists := nodeToStoragePods[nodeName]; !exists {
+			pendingNodes = append(pendingNodes, nodeName)
+		}
+	}
+	sort.Strings(pendingNodes)
+
+	cluster.Status.DesiredNumberScheduled = int32(len(desiredNodes))
+	cluster.Status.CurrentNumberScheduled = currentNumber
+	cluster.Status.NumberMisscheduled = misscheduledNumber
+	cluster.Status.NumberReady = readyNumber
+	cluster.Status.UpdatedNumberScheduled = updatedNumber
+	cluster.Status.NumberAvailable = availableNumber
+	cluster.Status.NumberUnavailable = cluster.Status.DesiredNumberScheduled - availableNumber
+	cluster.Status.UpdatedNodes = updatedNodes
+	cluster.Status.OutdatedNodes = outdatedNodes
+	metrics.SetRollingUpdateProgress(cluster.Namespace, cluster.Name,
+		int(updatedNumber), len(pendingNodes))
+	return pendingNodes, nil
+}
+
 // constructHistory finds all histories controlled by the given StorageCluster, and
 // update current history revision number, or create current history if needed to.
 // It also deduplicates current history, and adds missing unique labels to existing histories.
+// The given cluster has the defaults set, while userSpec is the spec applied by
+// the user, which is stored in a new history so a rollback can restore it.
 func (c *Controller) constructHistory(
 	cluster *corev1alpha1.StorageCluster,
+	userSpec *corev1alpha1.StorageClusterSpec,
 ) (cur *apps.ControllerRevision, old []*apps.ControllerRevision, err error) {
 	var histories []*apps.ControllerRevision
 	var currentHistories []*apps.ControllerRevision
@@ -127,7 +350,7 @@ func (c *Controller) constructHistory(
 	switch len(currentHistories) {
 	case 0:
 		// Create a new history if the current one isn't found
-		cur, err = c.snapshot(cluster, currRevision)
+		cur, err = c.snapshot(cluster, userSpec, currRevision)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -198,9 +421,10 @@ func (c *Controller) controlledHistories(
 
 func (c *Controller) snapshot(
 	cluster *corev1alpha1.StorageCluster,
+	userSpec *corev1alpha1.StorageClusterSpec,
 	revision int64,
 ) (*apps.ControllerRevision, error) {
-	patch, err := getPatch(cluster)
+	data, err := getRevisionData(cluster, userSpec)
 	if err != nil {
 		return nil, err
 	}
@@ -218,7 +442,7 @@ func (c *Controller) snapshot(
 			Annotations:     cluster.Annotations,
 			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cluster, controllerKind)},
 		},
-		Data:     runtime.RawExtension{Raw: patch},
+		Data:     runtime.RawExtension{Raw: data},
 		Revision: revision,
 	}
 
@@ -534,25 +758,7 @@ func matchSelectedFields(
 	node *v1.Node,
 	oldNodeLabels map[string]string,
 ) (bool, error) {
-	var raw map[string]interface{}
-	err := json.Unmarshal(history.Data.Raw, &raw)
-	if err != nil {
-		return false, err
-	}
-
-	spec, ok := raw["spec"].(map[string]interface{})
-	if !ok {
-		return false, nil
-	}
-	delete(spec, "$patch")
-
-	rawHistory, err := json.Marshal(spec)
-	if err != nil {
-		return false, err
-	}
-
-	oldSpec := &corev1alpha1.StorageClusterSpec{}
-	err = json.Unmarshal(rawHistory, oldSpec)
+	oldSpec, err := specFromRevision(history)
 	if err != nil {
 		return false, err
 	}
@@ -582,6 +788,16 @@ func matchSelectedFields(
 		return false, nil
 	} else if !isEnvEqual(oldSpec.Env, currentSpec.Env) {
 		return false, nil
+	} else if !equality.Semantic.DeepEqual(oldSpec.Resources, currentSpec.Resources) {
+		return false, nil
+	} else if oldSpec.LogFile != currentSpec.LogFile {
+		return false, nil
+	} else if !reflect.DeepEqual(oldSpec.ExtraArgs, currentSpec.ExtraArgs) {
+		return false, nil
+	} else if oldSpec.PriorityClassName != currentSpec.PriorityClassName {
+		return false, nil
+	} else if !reflect.DeepEqual(oldSpec.Placement, currentSpec.Placement) {
+		return false, nil
 	}
 	return true, nil
 }
@@ -655,6 +871,15 @@ func overwriteClusterSpecWithNodeSpec(
 			clusterSpec.RuntimeOpts[k] = v
 		}
 	}
+	if nodeSpec.Resources != nil {
+		clusterSpec.Resources = nodeSpec.Resources.DeepCopy()
+	}
+	if nodeSpec.LogFile != "" {
+		clusterSpec.LogFile = nodeSpec.LogFile
+	}
+	if len(nodeSpec.ExtraArgs) > 0 {
+		clusterSpec.ExtraArgs = append([]string(nil), nodeSpec.ExtraArgs...)
+	}
 }
 
 // splitByAvailablePods splits provided storage cluster pods by availability
@@ -692,7 +917,11 @@ func match(
 	if err != nil {
 		return false, err
 	}
-	return bytes.Equal(patch, history.Data.Raw), nil
+	historyPatch, err := patchFromRevision(history)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(patch, historyPatch), nil
 }
 
 // getPatch returns a strategic merge patch that can be applied to restore a StorageCluster
@@ -716,6 +945,44 @@ func getPatch(cluster *corev1alpha1.StorageCluster) ([]byte, error) {
 	return json.Marshal(objCopy)
 }
 
+// getRevisionData returns the data stored in a ControllerRevision of the given
+// StorageCluster. It contains the patch with the spec after setting the defaults,
+// which is used to match the cluster with its revisions, and the spec applied
+// by the user, which is restored when the cluster is rolled back.
+func getRevisionData(
+	cluster *corev1alpha1.StorageCluster,
+	userSpec *corev1alpha1.StorageClusterSpec,
+) ([]byte, error) {
+	patch, err := getPatch(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &data); err != nil {
+		return nil, err
+	}
+	rawUserSpec, err := json.Marshal(userSpec)
+	if err != nil {
+		return nil, err
+	}
+	data[revisionUserSpecKey] = rawUserSpec
+	return json.Marshal(data)
+}
+
+// patchFromRevision returns the patch stored in the given revision, without
+// the spec applied by the user
+func patchFromRevision(history *apps.ControllerRevision) ([]byte, error) {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(history.Data.Raw, &data); err != nil {
+		return nil, err
+	}
+	if _, exists := data[revisionUserSpecKey]; !exists {
+		return history.Data.Raw, nil
+	}
+	delete(data, revisionUserSpecKey)
+	return json.Marshal(data)
+}
+
 type historiesByRevision []*apps.ControllerRevision
 
 func (h historiesByRevision) Len() int      { return len(h) }
@@ -0,0 +1,207 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/sirupsen/logrus"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// upgradeInProgressReason is set on the upgrade condition while storage
+	// pods are being updated to the latest revision
+	upgradeInProgressReason = "UpgradeInProgress"
+	// upgradePausedReason is set on the upgrade condition when none of the
+	// remaining nodes can be updated because of the partition or canary selector
+	upgradePausedReason = "UpgradePaused"
+	// upgradeCompletedReason is set on the upgrade condition once all storage
+	// pods are running the latest revision
+	upgradeCompletedReason = "UpgradeCompleted"
+	// upgradeTimeoutReason is added to an event and set on the upgrade
+	// condition when an upgrade exceeds its progress deadline
+	upgradeTimeoutReason = "UpgradeTimeout"
+	// maxPendingNodesInCondition is the maximum number of pending nodes listed
+	// in the upgrade condition, so the condition stays readable in large clusters
+	maxPendingNodesInCondition = 5
+)
+
+// setUpgradeCondition tracks the rollout of the latest revision of the cluster
+// in the upgrade condition. The upgrade starts when the latest revision differs
+// from the revision all the storage pods were running, and completes once every
+// node runs a ready storage pod of the latest revision. If the upgrade does not
+// progress within the progress deadline, it is marked as timed out. If automatic
+// rollback is enabled, the revision the cluster was running before the upgrade
+// is returned, so the caller can roll back to it.
+func (c *Controller) setUpgradeCondition(
+	cluster *corev1alpha1.StorageCluster,
+	cur *apps.ControllerRevision,
+	previousUpdatedNodes int32,
+	pendingNodes []string,
+) *apps.ControllerRevision {
+	hash := cur.Labels[defaultStorageClusterUniqueLabelKey]
+	newRevision := cluster.Status.UpdateRevision != hash
+	cluster.Status.UpdateRevision = hash
+	now := metav1.Now()
+
+	if len(pendingNodes) == 0 {
+		if cluster.Status.CurrentRevision != "" && cluster.Status.CurrentRevision != hash {
+			message := fmt.Sprintf("Updated %d nodes to revision %d",
+				cluster.Status.UpdatedNodes, cur.Revision)
+			logrus.Infof("StorageCluster %v/%v: %s", cluster.Namespace, cluster.Name, message)
+			setClusterCondition(cluster, corev1alpha1.ClusterCondition{
+				Type:           corev1alpha1.ClusterConditionTypeUpgrade,
+				Status:         corev1alpha1.ClusterOperationCompleted,
+				Reason:         upgradeCompletedReason,
+				Message:        message,
+				LastUpdateTime: now,
+			})
+		}
+		cluster.Status.CurrentRevision = hash
+		return nil
+	} else if cluster.Status.CurrentRevision == "" || cluster.Status.CurrentRevision == hash {
+		// The storage pods are still being installed, or are being restored to
+		// the revision they were running, so there is no upgrade to track
+		return nil
+	}
+
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	inProgress := condition != nil &&
+		(condition.Status == corev1alpha1.ClusterOperationInProgress ||
+			condition.Status == corev1alpha1.ClusterOperationTimeout)
+	paused := c.isUpgradePaused(cluster, pendingNodes)
+
+	newCondition := corev1alpha1.ClusterCondition{
+		Type:           corev1alpha1.ClusterConditionTypeUpgrade,
+		Status:         corev1alpha1.ClusterOperationInProgress,
+		LastUpdateTime: now,
+	}
+	if inProgress && !newRevision && !paused && cluster.Status.UpdatedNodes <= previousUpdatedNodes {
+		newCondition.LastUpdateTime = condition.LastUpdateTime
+	}
+
+	progress := fmt.Sprintf("Updated %d nodes to revision %d",
+		cluster.Status.UpdatedNodes, cur.Revision)
+	if paused {
+		newCondition.Reason = upgradePausedReason
+		newCondition.Message = progress + ", remaining nodes are not selected " +
+			"by the partition or canary selector"
+	} else {
+		newCondition.Reason = upgradeInProgressReason
+		newCondition.Message = fmt.Sprintf("%s, waiting on nodes %s",
+			progress, formatNodeNames(pendingNodes))
+	}
+
+	var rollbackTo *apps.ControllerRevision
+	deadline := progressDeadline(cluster)
+	if deadline > 0 && now.Sub(newCondition.LastUpdateTime.Time) > deadline {
+		newCondition.Status = corev1alpha1.ClusterOperationTimeout
+		newCondition.Reason = upgradeTimeoutReason
+		newCondition.Message = fmt.Sprintf("Upgrade has not progressed for %v. %s",
+			deadline, newCondition.Message)
+		if cluster.Spec.UpdateStrategy.RollingUpdate.AutoRollback {
+			history, err := c.revisionWithHash(cluster, cluster.Status.CurrentRevision)
+			if err != nil {
+				logrus.Warnf("Failed to get revision to rollback StorageCluster %v/%v: %v",
+					cluster.Namespace, cluster.Name, err)
+			} else {
+				rollbackTo = history
+				newCondition.Status = corev1alpha1.ClusterOperationFailed
+				newCondition.Message = fmt.Sprintf("%s. Rolling back to revision %d",
+					newCondition.Message, history.Revision)
+			}
+		}
+		if condition == nil || condition.Status != newCondition.Status {
+			c.warningEvent(cluster, upgradeTimeoutReason, newCondition.Message)
+		}
+	}
+
+	setClusterCondition(cluster, newCondition)
+	return rollbackTo
+}
+
+// isUpgradePaused returns true if none of the pending nodes can be updated
+// because of the partition or canary selector of the rolling update
+func (c *Controller) isUpgradePaused(
+	cluster *corev1alpha1.StorageCluster,
+	pendingNodes []string,
+) bool {
+	if cluster.Spec.UpdateStrategy.Type != corev1alpha1.RollingUpdateStorageClusterStrategyType {
+		return false
+	}
+	nodesToUpdate, err := c.getNodesToUpdate(cluster)
+	if err != nil || nodesToUpdate == nil {
+		return false
+	}
+	for _, nodeName := range pendingNodes {
+		if nodesToUpdate[nodeName] {
+			return false
+		}
+	}
+	return true
+}
+
+// requestRollback requests a rollback of the given cluster to the given
+// revision. The rollback is done in the next reconcile like a rollback
+// requested by the user, so it is recorded in the rollback condition.
+func (c *Controller) requestRollback(
+	cluster *corev1alpha1.StorageCluster,
+	revision int64,
+) error {
+	toUpdate := cluster.DeepCopy()
+	toUpdate.Spec.RollbackTo = &corev1alpha1.RollbackConfig{Revision: revision}
+	if err := c.client.Update(context.TODO(), toUpdate); err != nil {
+		return fmt.Errorf("failed to request rollback of StorageCluster %v/%v: %v",
+			cluster.Namespace, cluster.Name, err)
+	}
+	logrus.Infof("StorageCluster %v/%v: Requested rollback to revision %d",
+		cluster.Namespace, cluster.Name, revision)
+	c.recorder.Event(cluster, v1.EventTypeNormal, rolledBackReason,
+		fmt.Sprintf("Rolling back to revision %d as the upgrade exceeded its progress deadline", revision))
+	toUpdate.DeepCopyInto(cluster)
+	return nil
+}
+
+// revisionWithHash returns the ControllerRevision of the cluster with the given hash
+func (c *Controller) revisionWithHash(
+	cluster *corev1alpha1.StorageCluster,
+	hash string,
+) (*apps.ControllerRevision, error) {
+	histories, err := c.controlledHistories(cluster)
+	if err != nil {
+		return nil, err
+	}
+	for _, history := range histories {
+		if history.Labels[defaultStorageClusterUniqueLabelKey] == hash {
+			return history, nil
+		}
+	}
+	return nil, fmt.Errorf("revision with hash %s not found", hash)
+}
+
+// progressDeadline returns the progress deadline of the rolling update of
+// the cluster, or 0 if the upgrade should never time out
+func progressDeadline(cluster *corev1alpha1.StorageCluster) time.Duration {
+	if cluster.Spec.UpdateStrategy.Type != corev1alpha1.RollingUpdateStorageClusterStrategyType ||
+		cluster.Spec.UpdateStrategy.RollingUpdate == nil ||
+		cluster.Spec.UpdateStrategy.RollingUpdate.ProgressDeadlineSeconds == nil {
+		return 0
+	}
+	return time.Duration(*cluster.Spec.UpdateStrategy.RollingUpdate.ProgressDeadlineSeconds) * time.Second
+}
+
+// formatNodeNames returns the given node names as a readable list, truncated
+// to at most maxPendingNodesInCondition names
+func formatNodeNames(nodeNames []string) string {
+	if len(nodeNames) <= maxPendingNodesInCondition {
+		return "[" + strings.Join(nodeNames, ", ") + "]"
+	}
+	return fmt.Sprintf("[%s and %d more]",
+		strings.Join(nodeNames[:maxPendingNodesInCondition], ", "),
+		len(nodeNames)-maxPendingNodesInCondition)
+}
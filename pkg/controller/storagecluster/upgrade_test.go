@@ -0,0 +1,371 @@
+package storagecluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestUpgradeProgress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.Image = "test/image:1.0.0"
+	controller, k8sClient, podControl, _ := newRollbackTestController(mockCtrl, cluster)
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	// Install the cluster with a ready storage pod of the first revision
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	oldPod := createReadyPodFromTemplate(t, k8sClient, cluster, &podControl.Templates[0])
+	podControl.Templates = nil
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	oldHash := oldPod.Labels[defaultStorageClusterUniqueLabelKey]
+	require.Equal(t, oldHash, cluster.Status.CurrentRevision)
+	require.Equal(t, oldHash, cluster.Status.UpdateRevision)
+	require.Nil(t, getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade))
+
+	// Upgrade the cluster. The upgrade should be in progress until
+	// the node runs a ready storage pod of the new revision.
+	cluster.Spec.Image = "test/image:2.0.0"
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, oldHash, cluster.Status.CurrentRevision)
+	require.NotEqual(t, oldHash, cluster.Status.UpdateRevision)
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
+	require.Equal(t, upgradeInProgressReason, condition.Reason)
+	require.Equal(t, "Updated 0 nodes to revision 2, waiting on nodes [k8s-node]",
+		condition.Message)
+	require.False(t, condition.LastUpdateTime.IsZero())
+
+	// The node should remain pending while its storage pod is recreated
+	err = k8sClient.Delete(context.TODO(), oldPod)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
+	require.Equal(t, "Updated 0 nodes to revision 2, waiting on nodes [k8s-node]",
+		condition.Message)
+
+	// The new pod is running but not ready yet
+	require.Len(t, podControl.Templates, 1)
+	newPod := createReadyPodFromTemplate(t, k8sClient, cluster, &podControl.Templates[0])
+	newPod.Status.Conditions = nil
+	err = k8sClient.Status().Update(context.TODO(), newPod)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
+	require.Equal(t, "Updated 1 nodes to revision 2, waiting on nodes [k8s-node]",
+		condition.Message)
+
+	// The upgrade should complete once the new pod is ready
+	newPod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	err = k8sClient.Status().Update(context.TODO(), newPod)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	newHash := newPod.Labels[defaultStorageClusterUniqueLabelKey]
+	require.Equal(t, newHash, cluster.Status.CurrentRevision)
+	require.Equal(t, newHash, cluster.Status.UpdateRevision)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
+	require.Equal(t, upgradeCompletedReason, condition.Reason)
+	require.Equal(t, "Updated 1 nodes to revision 2", condition.Message)
+}
+
+func TestUpgradeTimeout(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.Image = "test/image:1.0.0"
+	progressDeadline := int32(60)
+	cluster.Spec.UpdateStrategy.RollingUpdate.ProgressDeadlineSeconds = &progressDeadline
+	controller, k8sClient, podControl, recorder := newRollbackTestController(mockCtrl, cluster)
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	createReadyPodFromTemplate(t, k8sClient, cluster, &podControl.Templates[0])
+	podControl.Templates = nil
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	cluster.Spec.Image = "test/image:2.0.0"
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
+
+	// The upgrade should not time out if it has not exceeded the deadline
+	lastUpdateTime := condition.LastUpdateTime
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
+	require.Equal(t, lastUpdateTime.Unix(), condition.LastUpdateTime.Unix())
+
+	// The upgrade should time out once it has not progressed for the deadline
+	setUpgradeLastUpdateTime(t, k8sClient, cluster, time.Now().Add(-2*time.Minute))
+	drainEvents(recorder)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	expectedMessage := "Upgrade has not progressed for 1m0s. " +
+		"Updated 0 nodes to revision 2, waiting on nodes [k8s-node]"
+	require.Equal(t, corev1alpha1.ClusterOperationTimeout, condition.Status)
+	require.Equal(t, upgradeTimeoutReason, condition.Reason)
+	require.Equal(t, expectedMessage, condition.Message)
+	require.Nil(t, cluster.Spec.RollbackTo)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, fmt.Sprintf("%v %v %s", v1.EventTypeWarning, upgradeTimeoutReason, expectedMessage),
+		<-recorder.Events)
+
+	// The timeout event should not be raised again on the next reconcile
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationTimeout, condition.Status)
+	require.Empty(t, recorder.Events)
+}
+
+func TestUpgradeTimeoutWithAutoRollback(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.Image = "test/image:1.0.0"
+	progressDeadline := int32(60)
+	cluster.Spec.UpdateStrategy.RollingUpdate.ProgressDeadlineSeconds = &progressDeadline
+	cluster.Spec.UpdateStrategy.RollingUpdate.AutoRollback = true
+	controller, k8sClient, podControl, recorder := newRollbackTestController(mockCtrl, cluster)
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	oldPod := createReadyPodFromTemplate(t, k8sClient, cluster, &podControl.Templates[0])
+	podControl.Templates = nil
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	cluster.Spec.Image = "test/image:2.0.0"
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	// The cluster should be rolled back once the upgrade exceeds the deadline
+	setUpgradeLastUpdateTime(t, k8sClient, cluster, time.Now().Add(-2*time.Minute))
+	drainEvents(recorder)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	expectedMessage := "Upgrade has not progressed for 1m0s. " +
+		"Updated 0 nodes to revision 2, waiting on nodes [k8s-node]. " +
+		"Rolling back to revision 1"
+	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
+	require.Equal(t, expectedMessage, condition.Message)
+	require.Equal(t, &corev1alpha1.RollbackConfig{Revision: 1}, cluster.Spec.RollbackTo)
+	require.Len(t, recorder.Events, 2)
+	require.Equal(t, fmt.Sprintf("%v %v %s", v1.EventTypeWarning, upgradeTimeoutReason, expectedMessage),
+		<-recorder.Events)
+	require.Equal(t, fmt.Sprintf("%v %v Rolling back to revision 1 as the upgrade "+
+		"exceeded its progress deadline", v1.EventTypeNormal, rolledBackReason), <-recorder.Events)
+
+	// The rollback should restore the spec of the previous revision, and
+	// as the storage pods already run that revision, nothing is pending
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, "test/image:1.0.0", cluster.Spec.Image)
+	require.Nil(t, cluster.Spec.RollbackTo)
+	oldHash := oldPod.Labels[defaultStorageClusterUniqueLabelKey]
+	require.Equal(t, oldHash, cluster.Status.CurrentRevision)
+	require.Equal(t, oldHash, cluster.Status.UpdateRevision)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeRollback)
+	require.Equal(t, corev1alpha1.ClusterOperationCompleted, condition.Status)
+	condition = getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationFailed, condition.Status)
+}
+
+func TestUpgradePausedByPartition(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	cluster.Spec.Image = "test/image:1.0.0"
+	progressDeadline := int32(60)
+	cluster.Spec.UpdateStrategy.RollingUpdate.ProgressDeadlineSeconds = &progressDeadline
+	controller, k8sClient, podControl, _ := newRollbackTestController(mockCtrl, cluster)
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	_, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	createReadyPodFromTemplate(t, k8sClient, cluster, &podControl.Templates[0])
+	podControl.Templates = nil
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	// Upgrade the cluster without selecting any nodes to update
+	err = testutil.Get(k8sClient, cluster, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	cluster.Spec.Image = "test/image:2.0.0"
+	cluster.Spec.UpdateStrategy.RollingUpdate.Partition = new(int32)
+	err = k8sClient.Update(context.TODO(), cluster)
+	require.NoError(t, err)
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	// A paused upgrade should never time out
+	setUpgradeLastUpdateTime(t, k8sClient, cluster, time.Now().Add(-2*time.Minute))
+
+	_, err = controller.Reconcile(request)
+	require.NoError(t, err)
+
+	cluster = &corev1alpha1.StorageCluster{}
+	err = testutil.Get(k8sClient, cluster, request.Name, request.Namespace)
+	require.NoError(t, err)
+	condition := getClusterCondition(cluster, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.Equal(t, corev1alpha1.ClusterOperationInProgress, condition.Status)
+	require.Equal(t, upgradePausedReason, condition.Reason)
+	require.Equal(t, "Updated 0 nodes to revision 2, remaining nodes are not "+
+		"selected by the partition or canary selector", condition.Message)
+	require.True(t, time.Since(condition.LastUpdateTime.Time) < time.Minute)
+}
+
+func TestFormatNodeNames(t *testing.T) {
+	require.Equal(t, "[node1]", formatNodeNames([]string{"node1"}))
+	require.Equal(t, "[node1, node2, node3, node4, node5]",
+		formatNodeNames([]string{"node1", "node2", "node3", "node4", "node5"}))
+	require.Equal(t, "[node1, node2, node3, node4, node5 and 2 more]",
+		formatNodeNames([]string{"node1", "node2", "node3", "node4", "node5", "node6", "node7"}))
+}
+
+func createReadyPodFromTemplate(
+	t *testing.T,
+	k8sClient client.Client,
+	cluster *corev1alpha1.StorageCluster,
+	template *v1.PodTemplateSpec,
+) *v1.Pod {
+	hash := template.Labels[defaultStorageClusterUniqueLabelKey]
+	pod := createReadyStoragePod(cluster, cluster.Name+"-"+hash, "k8s-node", template.Labels)
+	err := k8sClient.Create(context.TODO(), pod)
+	require.NoError(t, err)
+	return pod
+}
+
+func setUpgradeLastUpdateTime(
+	t *testing.T,
+	k8sClient client.Client,
+	cluster *corev1alpha1.StorageCluster,
+	lastUpdateTime time.Time,
+) {
+	current := &corev1alpha1.StorageCluster{}
+	err := testutil.Get(k8sClient, current, cluster.Name, cluster.Namespace)
+	require.NoError(t, err)
+	condition := getClusterCondition(current, corev1alpha1.ClusterConditionTypeUpgrade)
+	require.NotNil(t, condition)
+	condition.LastUpdateTime = metav1.NewTime(lastUpdateTime)
+	setClusterCondition(current, *condition)
+	err = k8sClient.Status().Update(context.TODO(), current)
+	require.NoError(t, err)
+}
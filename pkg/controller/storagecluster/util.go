@@ -8,6 +8,9 @@ import (
 
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/rand"
 	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
@@ -15,7 +18,6 @@ import (
 	hashutil "k8s.io/kubernetes/pkg/util/hash"
 )
 
-// TODO: make the storage cluster pod a critical pod to guarantee scheduling
 func addOrUpdateStoragePodTolerations(podSpec *v1.PodSpec) {
 	// StorageCluster pods shouldn't be deleted by NodeController in case of node problems.
 	// Add infinite toleration for taint notReady:NoExecute here to survive taint-based
@@ -68,6 +70,17 @@ func addOrUpdateStoragePodTolerations(podSpec *v1.PodSpec) {
 	})
 }
 
+// addPlacementTolerations adds the tolerations from the placement of the
+// cluster to the storage pod, so it can run on nodes with matching taints
+func addPlacementTolerations(podSpec *v1.PodSpec, cluster *corev1alpha1.StorageCluster) {
+	if cluster.Spec.Placement == nil {
+		return
+	}
+	for i := range cluster.Spec.Placement.Tolerations {
+		v1helper.AddOrUpdateTolerationInPodSpec(podSpec, cluster.Spec.Placement.Tolerations[i].DeepCopy())
+	}
+}
+
 // computeHash returns a hash value calculated from StorageClusterSpec and
 // a collisionCount to avoid hash collision. The hash will be safe encoded to
 // avoid bad words.
@@ -112,6 +125,78 @@ func deleteFinalizerExists(cluster *corev1alpha1.StorageCluster) bool {
 	return false
 }
 
+// hasPrecedence returns true if the first cluster takes precedence over the second
+// one in case they conflict. A cluster that has already been reconciled takes
+// precedence, else the older cluster is preferred.
+func hasPrecedence(first, second *corev1alpha1.StorageCluster) bool {
+	firstReconciled, secondReconciled := deleteFinalizerExists(first), deleteFinalizerExists(second)
+	if firstReconciled != secondReconciled {
+		return firstReconciled
+	}
+	if !first.CreationTimestamp.Equal(&second.CreationTimestamp) {
+		return first.CreationTimestamp.Before(&second.CreationTimestamp)
+	}
+	if first.Namespace != second.Namespace {
+		return first.Namespace < second.Namespace
+	}
+	return first.Name < second.Name
+}
+
+func hasRequiredNodeAffinity(cluster *corev1alpha1.StorageCluster) bool {
+	return cluster.Spec.Placement != nil &&
+		cluster.Spec.Placement.NodeAffinity != nil &&
+		cluster.Spec.Placement.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil
+}
+
+// nodeMatchesPlacement returns true if the given node is selected by the required
+// node affinity of the cluster. A cluster without node affinity selects all nodes.
+func nodeMatchesPlacement(node *v1.Node, cluster *corev1alpha1.StorageCluster) bool {
+	if !hasRequiredNodeAffinity(cluster) {
+		return true
+	}
+	nodeSelector := cluster.Spec.Placement.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	return v1helper.MatchNodeSelectorTerms(
+		nodeSelector.NodeSelectorTerms,
+		labels.Set(node.Labels),
+		fields.Set{"metadata.name": node.Name},
+	)
+}
+
+// getClusterCondition returns the condition of the given type from the cluster
+// status, or nil if the condition is not present
+func getClusterCondition(
+	cluster *corev1alpha1.StorageCluster,
+	conditionType corev1alpha1.ClusterConditionType,
+) *corev1alpha1.ClusterCondition {
+	for i := range cluster.Status.Conditions {
+		if cluster.Status.Conditions[i].Type == conditionType {
+			return &cluster.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setClusterCondition overwrites the condition of the same type in the cluster
+// status, or adds the condition if one is not already present. The last
+// transition time is carried over from the existing condition, unless the
+// status of the condition has changed.
+func setClusterCondition(
+	cluster *corev1alpha1.StorageCluster,
+	condition corev1alpha1.ClusterCondition,
+) {
+	existing := getClusterCondition(cluster, condition.Type)
+	if existing != nil && existing.Status == condition.Status && !existing.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	} else if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	if existing != nil {
+		*existing = condition
+		return
+	}
+	cluster.Status.Conditions = append(cluster.Status.Conditions, condition)
+}
+
 func removeDeleteFinalizer(finalizers []string) []string {
 	newFinalizers := []string{}
 	for _, finalizer := range finalizers {
@@ -0,0 +1,177 @@
+package storagecluster
+
+import (
+	"context"
+	"reflect"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// nodePredicate filters out node updates that cannot change whether a storage
+// pod should run on the node, like the periodic heartbeats of the kubelet
+func nodePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*v1.Node)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*v1.Node)
+			if !ok {
+				return false
+			}
+			return nodeChanged(oldNode, newNode)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// nodeChanged returns true if the labels, taints, schedulability or the
+// status of the conditions of the node have changed
+func nodeChanged(oldNode, newNode *v1.Node) bool {
+	if !reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+		!reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints) ||
+		oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable ||
+		len(oldNode.Status.Conditions) != len(newNode.Status.Conditions) {
+		return true
+	}
+	oldConditions := make(map[v1.NodeConditionType]v1.ConditionStatus)
+	for _, condition := range oldNode.Status.Conditions {
+		oldConditions[condition.Type] = condition.Status
+	}
+	for _, condition := range newNode.Status.Conditions {
+		if status, exists := oldConditions[condition.Type]; !exists || status != condition.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// storageNodePredicate filters out updates to storage nodes that do not
+// change their spec or phase, so the controller does not react to the status
+// updates it makes to the storage nodes itself on every reconcile
+func storageNodePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1alpha1.StorageNode)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1alpha1.StorageNode)
+			if !ok {
+				return false
+			}
+			return oldNode.Status.Phase != newNode.Status.Phase ||
+				!reflect.DeepEqual(oldNode.Spec, newNode.Spec)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// storageClustersForNode maps a Kubernetes node to the StorageClusters whose
+// placement selects the node, so a storage pod can be created on a new node,
+// or removed from a node that is no longer selected, without waiting for the
+// next periodic sync. For node updates, both the old and the new node are
+// mapped, so a cluster that no longer selects a relabelled node is notified.
+func (c *Controller) storageClustersForNode(obj handler.MapObject) []reconcile.Request {
+	node, ok := obj.Object.(*v1.Node)
+	if !ok {
+		return nil
+	}
+
+	clusterList := &corev1alpha1.StorageClusterList{}
+	if err := c.client.List(context.TODO(), clusterList, &client.ListOptions{}); err != nil {
+		logrus.Warnf("Failed to list storage clusters for node %s: %v", node.Name, err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, cluster := range clusterList.Items {
+		if nodeMatchesPlacement(node, &cluster) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      cluster.Name,
+					Namespace: cluster.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+// ownedObjectPredicate filters out the events of the objects created by the
+// components of a StorageCluster that do not need to be reverted, like their
+// creation by the operator itself or updates to their status. Updates to
+// objects without a generation, like services and cluster roles, are always
+// passed through as they do not have a status that changes frequently.
+func ownedObjectPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.MetaOld == nil || e.MetaNew == nil {
+				return false
+			}
+			if !reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels()) {
+				return true
+			}
+			if e.MetaNew.GetGeneration() != 0 {
+				return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration()
+			}
+			return e.MetaOld.GetResourceVersion() != e.MetaNew.GetResourceVersion()
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// storageClustersForOwnedObject maps a cluster scoped object, like a cluster
+// role or a storage class, to the StorageClusters that own it. Cluster scoped
+// objects cannot have owner references to namespaced objects, so the owners
+// are looked up by the UIDs in the owner labels of the object instead.
+func (c *Controller) storageClustersForOwnedObject(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta == nil {
+		return nil
+	}
+	owners := make(map[types.UID]bool)
+	for _, uid := range k8sutil.StorageClusterOwners(obj.Meta) {
+		owners[uid] = true
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+
+	clusterList := &corev1alpha1.StorageClusterList{}
+	if err := c.client.List(context.TODO(), clusterList, &client.ListOptions{}); err != nil {
+		logrus.Warnf("Failed to list storage clusters for %s: %v", obj.Meta.GetName(), err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, cluster := range clusterList.Items {
+		if owners[cluster.UID] {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      cluster.Name,
+					Namespace: cluster.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
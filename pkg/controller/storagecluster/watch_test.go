@@ -0,0 +1,273 @@
+package storagecluster
+
+import (
+	"testing"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestNodePredicate(t *testing.T) {
+	nodePredicate := nodePredicate()
+	oldNode := createK8sNode("node1", 10)
+	oldNode.Status.Conditions = []v1.NodeCondition{
+		{
+			Type:              v1.NodeReady,
+			Status:            v1.ConditionTrue,
+			LastHeartbeatTime: metav1.Now(),
+		},
+	}
+
+	// Node creation and deletion should always trigger a reconcile
+	require.True(t, nodePredicate.Create(event.CreateEvent{Object: oldNode}))
+	require.True(t, nodePredicate.Delete(event.DeleteEvent{Object: oldNode}))
+	require.False(t, nodePredicate.Generic(event.GenericEvent{Object: oldNode}))
+
+	// Heartbeats should not trigger a reconcile
+	newNode := oldNode.DeepCopy()
+	newNode.ResourceVersion = "2"
+	newNode.Status.Conditions[0].LastHeartbeatTime = metav1.NewTime(metav1.Now().Add(10))
+	require.False(t, nodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// Label changes
+	newNode = oldNode.DeepCopy()
+	newNode.Labels = map[string]string{"px/enabled": "false"}
+	require.True(t, nodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// Taint changes
+	newNode = oldNode.DeepCopy()
+	newNode.Spec.Taints = []v1.Taint{{Key: "key", Effect: v1.TaintEffectNoSchedule}}
+	require.True(t, nodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// Schedulability changes
+	newNode = oldNode.DeepCopy()
+	newNode.Spec.Unschedulable = true
+	require.True(t, nodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// Condition status changes
+	newNode = oldNode.DeepCopy()
+	newNode.Status.Conditions[0].Status = v1.ConditionFalse
+	require.True(t, nodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// New conditions
+	newNode = oldNode.DeepCopy()
+	newNode.Status.Conditions = append(newNode.Status.Conditions, v1.NodeCondition{
+		Type:   v1.NodeMemoryPressure,
+		Status: v1.ConditionTrue,
+	})
+	require.True(t, nodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// Objects other than nodes should be ignored
+	require.False(t, nodePredicate.Update(event.UpdateEvent{ObjectOld: &v1.Pod{}, ObjectNew: &v1.Pod{}}))
+}
+
+func TestStorageNodePredicate(t *testing.T) {
+	storageNodePredicate := storageNodePredicate()
+	oldNode := &corev1alpha1.StorageNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "node1",
+			Namespace: "test-ns",
+		},
+		Status: corev1alpha1.NodeStatus{
+			Phase: string(corev1alpha1.NodeInit),
+		},
+	}
+
+	require.True(t, storageNodePredicate.Create(event.CreateEvent{Object: oldNode}))
+	require.True(t, storageNodePredicate.Delete(event.DeleteEvent{Object: oldNode}))
+
+	// Unchanged phase and spec should not trigger a reconcile
+	newNode := oldNode.DeepCopy()
+	newNode.Status.Network.DataIP = "10.0.0.1"
+	require.False(t, storageNodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// Phase changes
+	newNode = oldNode.DeepCopy()
+	newNode.Status.Phase = string(corev1alpha1.NodeOnline)
+	require.True(t, storageNodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	// Spec changes
+	newNode = oldNode.DeepCopy()
+	newNode.Spec.Version = "2.1.0"
+	require.True(t, storageNodePredicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+}
+
+func TestStorageClustersForNode(t *testing.T) {
+	placement := func(pool string) *corev1alpha1.PlacementSpec {
+		return &corev1alpha1.PlacementSpec{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{
+									Key:      "pool",
+									Operator: v1.NodeSelectorOpIn,
+									Values:   []string{pool},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	clusterA := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", Namespace: "ns-a"},
+		Spec:       corev1alpha1.StorageClusterSpec{Placement: placement("a")},
+	}
+	clusterB := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-b", Namespace: "ns-b"},
+		Spec:       corev1alpha1.StorageClusterSpec{Placement: placement("b")},
+	}
+	controller := Controller{
+		client: testutil.FakeK8sClient(clusterA, clusterB),
+	}
+
+	// Node should be mapped only to the clusters that select it
+	node := createK8sNode("node1", 10)
+	node.Labels = map[string]string{"pool": "a"}
+	requests := controller.storageClustersForNode(handler.MapObject{Meta: node, Object: node})
+	require.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "cluster-a", Namespace: "ns-a"}},
+	}, requests)
+
+	// Node not selected by any cluster
+	node.Labels = map[string]string{"pool": "c"}
+	requests = controller.storageClustersForNode(handler.MapObject{Meta: node, Object: node})
+	require.Empty(t, requests)
+
+	// Cluster without placement selects all nodes
+	clusterC := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-c", Namespace: "ns-c"},
+	}
+	controller.client = testutil.FakeK8sClient(clusterA, clusterB, clusterC)
+	requests = controller.storageClustersForNode(handler.MapObject{Meta: node, Object: node})
+	require.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "cluster-c", Namespace: "ns-c"}},
+	}, requests)
+
+	// Objects other than nodes should be ignored
+	pod := &v1.Pod{}
+	requests = controller.storageClustersForNode(handler.MapObject{Meta: pod, Object: pod})
+	require.Empty(t, requests)
+}
+
+func TestOwnedObjectPredicate(t *testing.T) {
+	ownedObjectPredicate := ownedObjectPredicate()
+	oldDeployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "stork",
+			Namespace:       "test-ns",
+			Generation:      1,
+			ResourceVersion: "1",
+		},
+	}
+
+	// Objects are created by the operator itself, so only deletions
+	// should trigger a reconcile
+	require.False(t, ownedObjectPredicate.Create(event.CreateEvent{Meta: oldDeployment, Object: oldDeployment}))
+	require.True(t, ownedObjectPredicate.Delete(event.DeleteEvent{Meta: oldDeployment, Object: oldDeployment}))
+	require.False(t, ownedObjectPredicate.Generic(event.GenericEvent{Meta: oldDeployment, Object: oldDeployment}))
+
+	// Status updates should not trigger a reconcile
+	newDeployment := oldDeployment.DeepCopy()
+	newDeployment.ResourceVersion = "2"
+	newDeployment.Status.ReadyReplicas = 1
+	require.False(t, ownedObjectPredicate.Update(event.UpdateEvent{
+		MetaOld: oldDeployment, ObjectOld: oldDeployment,
+		MetaNew: newDeployment, ObjectNew: newDeployment,
+	}))
+
+	// Spec changes
+	newDeployment = oldDeployment.DeepCopy()
+	newDeployment.ResourceVersion = "2"
+	newDeployment.Generation = 2
+	require.True(t, ownedObjectPredicate.Update(event.UpdateEvent{
+		MetaOld: oldDeployment, ObjectOld: oldDeployment,
+		MetaNew: newDeployment, ObjectNew: newDeployment,
+	}))
+
+	// Label changes
+	newDeployment = oldDeployment.DeepCopy()
+	newDeployment.ResourceVersion = "2"
+	newDeployment.Labels = map[string]string{"key": "value"}
+	require.True(t, ownedObjectPredicate.Update(event.UpdateEvent{
+		MetaOld: oldDeployment, ObjectOld: oldDeployment,
+		MetaNew: newDeployment, ObjectNew: newDeployment,
+	}))
+
+	// Any change to objects without a generation
+	oldClusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "stork",
+			ResourceVersion: "1",
+		},
+	}
+	newClusterRole := oldClusterRole.DeepCopy()
+	require.False(t, ownedObjectPredicate.Update(event.UpdateEvent{
+		MetaOld: oldClusterRole, ObjectOld: oldClusterRole,
+		MetaNew: newClusterRole, ObjectNew: newClusterRole,
+	}))
+	newClusterRole.ResourceVersion = "2"
+	newClusterRole.Rules = []rbacv1.PolicyRule{{Verbs: []string{"*"}}}
+	require.True(t, ownedObjectPredicate.Update(event.UpdateEvent{
+		MetaOld: oldClusterRole, ObjectOld: oldClusterRole,
+		MetaNew: newClusterRole, ObjectNew: newClusterRole,
+	}))
+}
+
+func TestStorageClustersForOwnedObject(t *testing.T) {
+	cluster := createStorageCluster()
+	otherCluster := createStorageCluster()
+	otherCluster.Name = "other-cluster"
+	otherCluster.UID = "other-uid"
+	controller := Controller{
+		client: testutil.FakeK8sClient(cluster, otherCluster),
+	}
+
+	// Cluster scoped object should be mapped to the cluster in its owner labels
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "stork",
+			Labels: map[string]string{
+				util.LabelManaged: "true",
+				util.LabelOwnerPrefix + string(cluster.UID): "true",
+			},
+		},
+	}
+	requests := controller.storageClustersForOwnedObject(handler.MapObject{Meta: clusterRole, Object: clusterRole})
+	require.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}},
+	}, requests)
+
+	// Object shared by multiple clusters should be mapped to all of them
+	clusterRole.Labels[util.LabelOwnerPrefix+string(otherCluster.UID)] = "true"
+	requests = controller.storageClustersForOwnedObject(handler.MapObject{Meta: clusterRole, Object: clusterRole})
+	require.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}},
+		{NamespacedName: types.NamespacedName{Name: otherCluster.Name, Namespace: otherCluster.Namespace}},
+	}, requests)
+
+	// Owner references to StorageClusters are not supported on cluster scoped objects
+	clusterRole.Labels = nil
+	clusterRole.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(cluster, controllerKind)}
+	requests = controller.storageClustersForOwnedObject(handler.MapObject{Meta: clusterRole, Object: clusterRole})
+	require.Empty(t, requests)
+
+	// Owner that does not exist anymore
+	clusterRole.OwnerReferences = nil
+	clusterRole.Labels = map[string]string{util.LabelOwnerPrefix + "deleted-uid": "true"}
+	requests = controller.storageClustersForOwnedObject(handler.MapObject{Meta: clusterRole, Object: clusterRole})
+	require.Empty(t, requests)
+}
@@ -0,0 +1,215 @@
+package storagecluster
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/libopenstorage/operator/drivers/storage"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// ValidatingWebhookPath is the path on which the StorageCluster
+	// validating admission webhook is served
+	ValidatingWebhookPath = "/validate-storagecluster"
+)
+
+// storageClusterValidator is an admission handler that rejects StorageCluster
+// objects with an invalid spec before they are persisted
+type storageClusterValidator struct {
+	driver  storage.Driver
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &storageClusterValidator{}
+
+// RegisterWebhook registers the StorageCluster validating admission webhook
+// with the webhook server of the given manager
+func (c *Controller) RegisterWebhook(mgr manager.Manager) {
+	mgr.GetWebhookServer().Register(
+		ValidatingWebhookPath,
+		&webhook.Admission{
+			Handler: &storageClusterValidator{driver: c.Driver},
+		},
+	)
+}
+
+// Handle validates the StorageCluster present in the admission request
+func (v *storageClusterValidator) Handle(
+	ctx context.Context,
+	req admission.Request,
+) admission.Response {
+	cluster := &corev1alpha1.StorageCluster{}
+	if err := v.decoder.Decode(req, cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// Do not block updates to a cluster that is being deleted, as the
+	// controller still needs to update its status and remove the finalizers
+	if cluster.DeletionTimestamp != nil {
+		return admission.Allowed("")
+	}
+
+	if err := validateStorageCluster(cluster, v.driver); err != nil {
+		logrus.Debugf("Rejecting StorageCluster %v/%v: %v", cluster.Namespace, cluster.Name, err)
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder used to decode the admission requests
+func (v *storageClusterValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// validateStorageCluster validates the given StorageCluster spec. It runs the
+// driver agnostic validations and then the validations from the storage driver.
+func validateStorageCluster(
+	cluster *corev1alpha1.StorageCluster,
+	driver storage.Driver,
+) error {
+	var errs []error
+	if err := validateStorageClusterSpec(cluster).ToAggregate(); err != nil {
+		errs = append(errs, err.Errors()...)
+	}
+	if driver != nil {
+		if err := driver.ValidateStorageCluster(cluster.DeepCopy()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateStorageClusterSpec validates the fields in the StorageCluster spec
+// that are managed by the controller and not by the storage driver.
+func validateStorageClusterSpec(cluster *corev1alpha1.StorageCluster) field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateUpdateStrategy(
+		&cluster.Spec.UpdateStrategy, specPath.Child("updateStrategy"))...)
+
+	if cluster.Spec.DeleteStrategy != nil {
+		switch cluster.Spec.DeleteStrategy.Type {
+		case corev1alpha1.UninstallStorageClusterStrategyType,
+			corev1alpha1.UninstallAndWipeStorageClusterStrategyType:
+		default:
+			allErrs = append(allErrs, field.NotSupported(
+				specPath.Child("deleteStrategy", "type"),
+				cluster.Spec.DeleteStrategy.Type,
+				[]string{
+					string(corev1alpha1.UninstallStorageClusterStrategyType),
+					string(corev1alpha1.UninstallAndWipeStorageClusterStrategyType),
+				},
+			))
+		}
+	}
+
+	if cluster.Spec.RevisionHistoryLimit != nil && *cluster.Spec.RevisionHistoryLimit < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("revisionHistoryLimit"),
+			*cluster.Spec.RevisionHistoryLimit, "cannot be negative"))
+	}
+
+	if cluster.Spec.RollbackTo != nil && cluster.Spec.RollbackTo.Revision < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("rollbackTo", "revision"),
+			cluster.Spec.RollbackTo.Revision, "cannot be negative"))
+	}
+	if value, exists := cluster.Annotations[annotationRollbackTo]; exists {
+		if revision, err := strconv.ParseInt(value, 10, 64); err != nil || revision < 0 {
+			allErrs = append(allErrs, field.Invalid(
+				field.NewPath("metadata", "annotations").Key(annotationRollbackTo),
+				value, "must be a non-negative integer"))
+		}
+	}
+
+	for i, nodeSpec := range cluster.Spec.Nodes {
+		selectorPath := specPath.Child("nodes").Index(i).Child("selector")
+		if nodeSpec.Selector.NodeName != "" && nodeSpec.Selector.LabelSelector != nil {
+			allErrs = append(allErrs, field.Invalid(selectorPath, nodeSpec.Selector.NodeName,
+				"only one of nodeName or labelSelector can be specified"))
+		} else if nodeSpec.Selector.NodeName == "" && nodeSpec.Selector.LabelSelector == nil {
+			allErrs = append(allErrs, field.Required(selectorPath,
+				"either nodeName or labelSelector must be specified"))
+		} else if nodeSpec.Selector.LabelSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(nodeSpec.Selector.LabelSelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(selectorPath.Child("labelSelector"),
+					nodeSpec.Selector.LabelSelector, err.Error()))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validateUpdateStrategy(
+	strategy *corev1alpha1.StorageClusterUpdateStrategy,
+	fldPath *field.Path,
+) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	switch strategy.Type {
+	case "", corev1alpha1.OnDeleteStorageClusterStrategyType:
+	case corev1alpha1.RollingUpdateStorageClusterStrategyType:
+		if strategy.RollingUpdate == nil {
+			break
+		}
+		rollingUpdatePath := fldPath.Child("rollingUpdate")
+		if strategy.RollingUpdate.Partition != nil && *strategy.RollingUpdate.Partition < 0 {
+			allErrs = append(allErrs, field.Invalid(rollingUpdatePath.Child("partition"),
+				*strategy.RollingUpdate.Partition, "cannot be negative"))
+		}
+		if strategy.RollingUpdate.CanarySelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(strategy.RollingUpdate.CanarySelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(rollingUpdatePath.Child("canarySelector"),
+					strategy.RollingUpdate.CanarySelector, err.Error()))
+			}
+		}
+		progressDeadline := strategy.RollingUpdate.ProgressDeadlineSeconds
+		if progressDeadline != nil && *progressDeadline <= 0 {
+			allErrs = append(allErrs, field.Invalid(rollingUpdatePath.Child("progressDeadlineSeconds"),
+				*progressDeadline, "must be greater than 0"))
+		}
+		if strategy.RollingUpdate.AutoRollback && progressDeadline == nil {
+			allErrs = append(allErrs, field.Required(rollingUpdatePath.Child("progressDeadlineSeconds"),
+				"progressDeadlineSeconds is required when autoRollback is enabled"))
+		}
+		if strategy.RollingUpdate.MaxUnavailable == nil {
+			break
+		}
+		maxUnavailablePath := rollingUpdatePath.Child("maxUnavailable")
+		maxUnavailable := strategy.RollingUpdate.MaxUnavailable
+		// Use a large total so that any non-zero percentage does not get rounded down to 0
+		value, err := intstr.GetValueFromIntOrPercent(maxUnavailable, 100, true)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(maxUnavailablePath,
+				maxUnavailable.String(), err.Error()))
+		} else if value <= 0 {
+			allErrs = append(allErrs, field.Invalid(maxUnavailablePath,
+				maxUnavailable.String(), "must be greater than 0"))
+		} else if maxUnavailable.Type == intstr.String && value > 100 {
+			allErrs = append(allErrs, field.Invalid(maxUnavailablePath,
+				maxUnavailable.String(), "must not be greater than 100%"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(
+			fldPath.Child("type"),
+			strategy.Type,
+			[]string{
+				string(corev1alpha1.RollingUpdateStorageClusterStrategyType),
+				string(corev1alpha1.OnDeleteStorageClusterStrategyType),
+			},
+		))
+	}
+
+	return allErrs
+}
@@ -0,0 +1,247 @@
+package storagecluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/mock"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidatingWebhookAllowsValidCluster(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driver := testutil.MockDriver(mockCtrl)
+	validator := newTestValidator(t, driver)
+	cluster := createStorageCluster()
+
+	driver.EXPECT().ValidateStorageCluster(gomock.Any()).Return(nil)
+
+	response := validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.True(t, response.Allowed)
+}
+
+func TestValidatingWebhookRejectsInvalidDriverSpec(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driver := testutil.MockDriver(mockCtrl)
+	validator := newTestValidator(t, driver)
+	cluster := createStorageCluster()
+
+	driver.EXPECT().ValidateStorageCluster(gomock.Any()).
+		Return(fmt.Errorf("spec.storage: invalid"))
+
+	response := validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.storage: invalid", string(response.Result.Reason))
+}
+
+func TestValidatingWebhookRejectsInvalidSpec(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driver := testutil.MockDriver(mockCtrl)
+	validator := newTestValidator(t, driver)
+	driver.EXPECT().ValidateStorageCluster(gomock.Any()).Return(nil).AnyTimes()
+
+	// MaxUnavailable cannot be 0
+	cluster := createStorageCluster()
+	maxUnavailable := intstr.FromInt(0)
+	cluster.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable = &maxUnavailable
+	response := validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.updateStrategy.rollingUpdate.maxUnavailable: "+
+		"Invalid value: \"0\": must be greater than 0", string(response.Result.Reason))
+
+	// MaxUnavailable cannot be 0%
+	maxUnavailable = intstr.FromString("0%")
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.updateStrategy.rollingUpdate.maxUnavailable: "+
+		"Invalid value: \"0%\": must be greater than 0", string(response.Result.Reason))
+
+	// MaxUnavailable should be a valid percentage
+	maxUnavailable = intstr.FromString("ten")
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Contains(t, string(response.Result.Reason),
+		"spec.updateStrategy.rollingUpdate.maxUnavailable: Invalid value: \"ten\"")
+
+	// Partition cannot be negative
+	cluster = createStorageCluster()
+	partition := int32(-1)
+	cluster.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.updateStrategy.rollingUpdate.partition: "+
+		"Invalid value: -1: cannot be negative", string(response.Result.Reason))
+
+	// Canary selector should be a valid label selector
+	cluster = createStorageCluster()
+	cluster.Spec.UpdateStrategy.RollingUpdate.CanarySelector = &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      "canary",
+				Operator: "Equals",
+			},
+		},
+	}
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Contains(t, string(response.Result.Reason),
+		"spec.updateStrategy.rollingUpdate.canarySelector: Invalid value")
+
+	// Progress deadline should be positive
+	cluster = createStorageCluster()
+	progressDeadline := int32(0)
+	cluster.Spec.UpdateStrategy.RollingUpdate.ProgressDeadlineSeconds = &progressDeadline
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.updateStrategy.rollingUpdate.progressDeadlineSeconds: "+
+		"Invalid value: 0: must be greater than 0", string(response.Result.Reason))
+
+	// Automatic rollback needs a progress deadline
+	cluster = createStorageCluster()
+	cluster.Spec.UpdateStrategy.RollingUpdate.AutoRollback = true
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.updateStrategy.rollingUpdate.progressDeadlineSeconds: Required value: "+
+		"progressDeadlineSeconds is required when autoRollback is enabled", string(response.Result.Reason))
+
+	// Unknown update strategy
+	cluster = createStorageCluster()
+	cluster.Spec.UpdateStrategy.Type = "Recreate"
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.updateStrategy.type: Unsupported value: \"Recreate\": "+
+		"supported values: \"RollingUpdate\", \"OnDelete\"", string(response.Result.Reason))
+
+	// Unknown delete strategy
+	cluster = createStorageCluster()
+	cluster.Spec.DeleteStrategy = &corev1alpha1.StorageClusterDeleteStrategy{
+		Type: "Wipe",
+	}
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.deleteStrategy.type: Unsupported value: \"Wipe\": "+
+		"supported values: \"Uninstall\", \"UninstallAndWipe\"", string(response.Result.Reason))
+
+	// Rollback revision cannot be negative
+	cluster = createStorageCluster()
+	cluster.Spec.RollbackTo = &corev1alpha1.RollbackConfig{Revision: -1}
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.rollbackTo.revision: Invalid value: -1: cannot be negative",
+		string(response.Result.Reason))
+
+	// Rollback annotation should be a valid revision
+	cluster = createStorageCluster()
+	cluster.Annotations = map[string]string{annotationRollbackTo: "latest"}
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "metadata.annotations[storagecluster.core.libopenstorage.org/rollback-to]: "+
+		"Invalid value: \"latest\": must be a non-negative integer", string(response.Result.Reason))
+
+	// Node selector with both node name and label selector
+	cluster = createStorageCluster()
+	cluster.Spec.Nodes = []corev1alpha1.NodeSpec{
+		{
+			Selector: corev1alpha1.NodeSelector{
+				NodeName: "node1",
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"key": "value"},
+				},
+			},
+		},
+	}
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.nodes[0].selector: Invalid value: \"node1\": "+
+		"only one of nodeName or labelSelector can be specified", string(response.Result.Reason))
+
+	// Node selector without node name or label selector
+	cluster.Spec.Nodes[0].Selector = corev1alpha1.NodeSelector{}
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Equal(t, "spec.nodes[0].selector: Required value: "+
+		"either nodeName or labelSelector must be specified", string(response.Result.Reason))
+
+	// Multiple errors should all be reported
+	cluster.Spec.UpdateStrategy.Type = "Recreate"
+	response = validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.False(t, response.Allowed)
+	require.Contains(t, string(response.Result.Reason), "spec.updateStrategy.type")
+	require.Contains(t, string(response.Result.Reason), "spec.nodes[0].selector")
+}
+
+func TestValidatingWebhookAllowsClusterBeingDeleted(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driver := testutil.MockDriver(mockCtrl)
+	validator := newTestValidator(t, driver)
+
+	cluster := createStorageCluster()
+	cluster.Spec.UpdateStrategy.Type = "Recreate"
+	deletionTimestamp := metav1.Now()
+	cluster.DeletionTimestamp = &deletionTimestamp
+
+	response := validator.Handle(context.TODO(), admissionRequest(t, cluster))
+	require.True(t, response.Allowed)
+}
+
+func TestValidatingWebhookInvalidRequest(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	driver := testutil.MockDriver(mockCtrl)
+	validator := newTestValidator(t, driver)
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Object: runtime.RawExtension{Raw: []byte("invalid")},
+		},
+	}
+	response := validator.Handle(context.TODO(), request)
+	require.False(t, response.Allowed)
+	require.Equal(t, int32(http.StatusBadRequest), response.Result.Code)
+}
+
+func newTestValidator(t *testing.T, driver *mock.MockDriver) *storageClusterValidator {
+	// Ensure the StorageCluster types are registered in the scheme
+	testutil.FakeK8sClient()
+	decoder, err := admission.NewDecoder(scheme.Scheme)
+	require.NoError(t, err)
+	validator := &storageClusterValidator{driver: driver}
+	err = validator.InjectDecoder(decoder)
+	require.NoError(t, err)
+	return validator
+}
+
+func admissionRequest(t *testing.T, cluster *corev1alpha1.StorageCluster) admission.Request {
+	cluster.TypeMeta = metav1.TypeMeta{
+		Kind:       controllerKind.Kind,
+		APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+	}
+	raw, err := json.Marshal(cluster)
+	require.NoError(t, err)
+	return admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Object: runtime.RawExtension{Raw: raw},
+		},
+	}
+}
@@ -0,0 +1,272 @@
+package storagenode
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libopenstorage/operator/drivers/storage"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// ControllerName is the name of the controller
+	ControllerName = "storagenode-controller"
+	// maintenanceCheckInterval is the interval at which a storage node is
+	// checked while it is moving in or out of maintenance
+	maintenanceCheckInterval = 15 * time.Second
+	// maintenanceTimeout is the time after which a storage node that is still
+	// moving in or out of maintenance is marked as failed, so that the driver
+	// is asked to move it again
+	maintenanceTimeout = 10 * time.Minute
+)
+
+var _ reconcile.Reconciler = &Controller{}
+
+var (
+	clusterKind = corev1alpha1.SchemeGroupVersion.WithKind("StorageCluster")
+)
+
+// Controller reconciles a StorageNode object
+type Controller struct {
+	client   client.Client
+	recorder record.EventRecorder
+	Driver   storage.Driver
+}
+
+// Init initialize the storage node controller
+func (c *Controller) Init(mgr manager.Manager) error {
+	c.client = mgr.GetClient()
+	c.recorder = mgr.GetEventRecorderFor(ControllerName)
+
+	// Create a new controller
+	ctrl, err := controller.New(ControllerName, mgr, controller.Options{Reconciler: c})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource StorageNode
+	return ctrl.Watch(
+		&source.Kind{Type: &corev1alpha1.StorageNode{}},
+		&handler.EnqueueRequestForObject{},
+		storageNodePredicate(),
+	)
+}
+
+// Reconcile moves a storage node in or out of maintenance, as requested in
+// the spec of the StorageNode, and reports the progress in its conditions
+func (c *Controller) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log := logrus.WithFields(map[string]interface{}{
+		"Request.Namespace": request.Namespace,
+		"Request.Name":      request.Name,
+	})
+	log.Debugf("Reconciling StorageNode")
+
+	node := &corev1alpha1.StorageNode{}
+	err := c.client.Get(context.TODO(), request.NamespacedName, node)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	return c.syncMaintenance(node)
+}
+
+func (c *Controller) syncMaintenance(
+	node *corev1alpha1.StorageNode,
+) (reconcile.Result, error) {
+	if node.Spec.Maintenance == nil {
+		return reconcile.Result{}, nil
+	}
+
+	cluster, err := c.getStorageCluster(node)
+	if err != nil {
+		return reconcile.Result{}, err
+	} else if cluster == nil {
+		logrus.Debugf("StorageNode %s/%s does not belong to a StorageCluster",
+			node.Namespace, node.Name)
+		return reconcile.Result{}, nil
+	}
+
+	enabled := *node.Spec.Maintenance
+	inMaintenance := node.Status.Phase == string(corev1alpha1.NodeMaintenance)
+	if enabled == inMaintenance {
+		reason := "Node is out of maintenance"
+		if enabled {
+			reason = "Node is in maintenance"
+		}
+		return reconcile.Result{}, c.setMaintenanceCondition(node, corev1alpha1.NodeOperationCompleted, reason)
+	}
+
+	operation := "exit"
+	reason := "Exiting maintenance"
+	if enabled {
+		operation = "enter"
+		reason = "Entering maintenance"
+	}
+
+	// Wait for the transition that has already been started, unless it is
+	// taking too long, in which case it is tried again in the next sync
+	condition := getMaintenanceCondition(node)
+	if condition != nil && condition.Status == corev1alpha1.NodeOperationInProgress &&
+		condition.Reason == reason {
+		if time.Since(condition.LastTransitionTime.Time) < maintenanceTimeout {
+			return reconcile.Result{RequeueAfter: maintenanceCheckInterval}, nil
+		}
+		msg := fmt.Sprintf("Timed out after %v waiting to %s maintenance", maintenanceTimeout, operation)
+		c.warningEvent(node, util.FailedMaintenanceReason, msg)
+		err := c.setMaintenanceCondition(node, corev1alpha1.NodeOperationFailed, msg)
+		return reconcile.Result{RequeueAfter: maintenanceCheckInterval}, err
+	}
+
+	if err := c.Driver.SetNodeMaintenance(cluster, node, enabled); err != nil {
+		msg := fmt.Sprintf("Failed to %s maintenance: %v", operation, err)
+		c.warningEvent(node, util.FailedMaintenanceReason, msg)
+		if updateErr := c.setMaintenanceCondition(node, corev1alpha1.NodeOperationFailed, msg); updateErr != nil {
+			logrus.Warnf("Failed to update maintenance status of StorageNode %s/%s: %v",
+				node.Namespace, node.Name, updateErr)
+		}
+		return reconcile.Result{}, err
+	}
+
+	c.recorder.Event(node, v1.EventTypeNormal, util.MaintenanceReason, reason)
+	if err := c.setMaintenanceCondition(node, corev1alpha1.NodeOperationInProgress, reason); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: maintenanceCheckInterval}, nil
+}
+
+// getStorageCluster returns the StorageCluster that controls the given
+// node, or nil if the node is not controlled by a StorageCluster
+func (c *Controller) getStorageCluster(
+	node *corev1alpha1.StorageNode,
+) (*corev1alpha1.StorageCluster, error) {
+	ownerRef := metav1.GetControllerOf(node)
+	if ownerRef == nil || ownerRef.Kind != clusterKind.Kind {
+		return nil, nil
+	}
+
+	cluster := &corev1alpha1.StorageCluster{}
+	err := c.client.Get(
+		context.TODO(),
+		types.NamespacedName{
+			Name:      ownerRef.Name,
+			Namespace: node.Namespace,
+		},
+		cluster,
+	)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if cluster.UID != ownerRef.UID {
+		return nil, nil
+	}
+	return cluster, nil
+}
+
+// setMaintenanceCondition sets the maintenance condition of the given node,
+// and updates the status of the node if the condition has changed. The last
+// transition time is set to the current time when the condition changes.
+func (c *Controller) setMaintenanceCondition(
+	node *corev1alpha1.StorageNode,
+	status corev1alpha1.ConditionStatus,
+	reason string,
+) error {
+	condition := getMaintenanceCondition(node)
+	if condition != nil && condition.Status == status && condition.Reason == reason {
+		return nil
+	}
+
+	toUpdate := node.DeepCopy()
+	newCondition := corev1alpha1.NodeCondition{
+		Type:               corev1alpha1.MaintenanceState,
+		Status:             status,
+		Reason:             reason,
+		LastTransitionTime: metav1.Now(),
+	}
+	updated := false
+	for i := range toUpdate.Status.Conditions {
+		if toUpdate.Status.Conditions[i].Type == corev1alpha1.MaintenanceState {
+			toUpdate.Status.Conditions[i] = newCondition
+			updated = true
+		}
+	}
+	if !updated {
+		toUpdate.Status.Conditions = append(toUpdate.Status.Conditions, newCondition)
+	}
+	if err := c.client.Status().Update(context.TODO(), toUpdate); err != nil {
+		return err
+	}
+	node.Status = toUpdate.Status
+	node.ResourceVersion = toUpdate.ResourceVersion
+	return nil
+}
+
+func (c *Controller) warningEvent(
+	node *corev1alpha1.StorageNode,
+	reason, message string,
+) {
+	logrus.Warn(message)
+	c.recorder.Event(node, v1.EventTypeWarning, reason, message)
+}
+
+func getMaintenanceCondition(
+	node *corev1alpha1.StorageNode,
+) *corev1alpha1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == corev1alpha1.MaintenanceState {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// storageNodePredicate filters out updates to storage nodes that change
+// neither the requested maintenance state nor the phase of the node
+func storageNodePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1alpha1.StorageNode)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1alpha1.StorageNode)
+			if !ok {
+				return false
+			}
+			return oldNode.Status.Phase != newNode.Status.Phase ||
+				!boolPtrEqual(oldNode.Spec.Maintenance, newNode.Spec.Maintenance)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func boolPtrEqual(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,352 @@
+package storagenode
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestEnterAndExitMaintenance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	node := createStorageNode(cluster, "node1")
+	node.Status.Phase = string(corev1alpha1.NodeOnline)
+	k8sClient := testutil.FakeK8sClient(cluster, node)
+	driver := testutil.MockDriver(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:   k8sClient,
+		recorder: recorder,
+		Driver:   driver,
+	}
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      node.Name,
+			Namespace: node.Namespace,
+		},
+	}
+
+	// Nothing should be done if the maintenance is not requested
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	require.Empty(t, recorder.Events)
+
+	// The driver should be asked to put the node in maintenance
+	setMaintenance(t, k8sClient, node.Name, true)
+	driver.EXPECT().
+		SetNodeMaintenance(gomock.Any(), gomock.Any(), true).
+		DoAndReturn(func(c *corev1alpha1.StorageCluster, n *corev1alpha1.StorageNode, _ bool) error {
+			require.Equal(t, cluster.Name, c.Name)
+			require.Equal(t, node.Name, n.Name)
+			return nil
+		})
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, maintenanceCheckInterval, result.RequeueAfter)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, fmt.Sprintf("%v %v Entering maintenance", v1.EventTypeNormal, util.MaintenanceReason),
+		<-recorder.Events)
+	condition := maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+	require.Equal(t, "Entering maintenance", condition.Reason)
+
+	// The driver should not be called again while waiting for the transition
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, maintenanceCheckInterval, result.RequeueAfter)
+	require.Empty(t, recorder.Events)
+
+	// The condition should be completed once the node is in maintenance.
+	// Updates from the driver should not remove the condition.
+	setPhase(t, k8sClient, cluster, node.Name, corev1alpha1.NodeMaintenance)
+	condition = maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	condition = maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationCompleted, condition.Status)
+	require.Equal(t, "Node is in maintenance", condition.Reason)
+
+	// The driver should be asked to take the node out of maintenance
+	setMaintenance(t, k8sClient, node.Name, false)
+	driver.EXPECT().
+		SetNodeMaintenance(gomock.Any(), gomock.Any(), false).
+		Return(nil)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, maintenanceCheckInterval, result.RequeueAfter)
+	require.Equal(t, fmt.Sprintf("%v %v Exiting maintenance", v1.EventTypeNormal, util.MaintenanceReason),
+		<-recorder.Events)
+	condition = maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+	require.Equal(t, "Exiting maintenance", condition.Reason)
+
+	setPhase(t, k8sClient, cluster, node.Name, corev1alpha1.NodeOnline)
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Empty(t, result)
+	condition = maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationCompleted, condition.Status)
+	require.Equal(t, "Node is out of maintenance", condition.Reason)
+
+	updatedNode := &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, updatedNode, node.Name, node.Namespace)
+	require.NoError(t, err)
+	require.Len(t, updatedNode.Status.Conditions, 2)
+	require.False(t, *updatedNode.Spec.Maintenance)
+}
+
+func TestMaintenanceFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	node := createStorageNode(cluster, "node1")
+	node.Status.Phase = string(corev1alpha1.NodeOnline)
+	node.Spec.Maintenance = pointer.BoolPtr(true)
+	k8sClient := testutil.FakeK8sClient(cluster, node)
+	driver := testutil.MockDriver(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:   k8sClient,
+		recorder: recorder,
+		Driver:   driver,
+	}
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      node.Name,
+			Namespace: node.Namespace,
+		},
+	}
+
+	driver.EXPECT().
+		SetNodeMaintenance(gomock.Any(), gomock.Any(), true).
+		Return(fmt.Errorf("pod not running"))
+
+	_, err := controller.Reconcile(request)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pod not running")
+	require.Equal(t, fmt.Sprintf("%v %v Failed to enter maintenance: pod not running",
+		v1.EventTypeWarning, util.FailedMaintenanceReason), <-recorder.Events)
+	condition := maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationFailed, condition.Status)
+	require.Equal(t, "Failed to enter maintenance: pod not running", condition.Reason)
+
+	// The driver should be called again after a failure
+	driver.EXPECT().
+		SetNodeMaintenance(gomock.Any(), gomock.Any(), true).
+		Return(nil)
+
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, maintenanceCheckInterval, result.RequeueAfter)
+	condition = maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+}
+
+func TestMaintenanceTimeout(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	node := createStorageNode(cluster, "node1")
+	node.Status.Phase = string(corev1alpha1.NodeOnline)
+	node.Spec.Maintenance = pointer.BoolPtr(true)
+	node.Status.Conditions = []corev1alpha1.NodeCondition{
+		{
+			Type:               corev1alpha1.MaintenanceState,
+			Status:             corev1alpha1.NodeOperationInProgress,
+			Reason:             "Entering maintenance",
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-maintenanceTimeout / 2)),
+		},
+	}
+	k8sClient := testutil.FakeK8sClient(cluster, node)
+	driver := testutil.MockDriver(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	controller := Controller{
+		client:   k8sClient,
+		recorder: recorder,
+		Driver:   driver,
+	}
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      node.Name,
+			Namespace: node.Namespace,
+		},
+	}
+
+	// The driver should not be called again before the timeout
+	result, err := controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, maintenanceCheckInterval, result.RequeueAfter)
+	require.Empty(t, recorder.Events)
+
+	// The transition should be marked as failed once it times out
+	updatedNode := &corev1alpha1.StorageNode{}
+	err = testutil.Get(k8sClient, updatedNode, node.Name, node.Namespace)
+	require.NoError(t, err)
+	updatedNode.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-2 * maintenanceTimeout))
+	err = k8sClient.Status().Update(context.TODO(), updatedNode)
+	require.NoError(t, err)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, maintenanceCheckInterval, result.RequeueAfter)
+	require.Equal(t, fmt.Sprintf("%v %v Timed out after %v waiting to enter maintenance",
+		v1.EventTypeWarning, util.FailedMaintenanceReason, maintenanceTimeout), <-recorder.Events)
+	condition := maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationFailed, condition.Status)
+	require.Equal(t, fmt.Sprintf("Timed out after %v waiting to enter maintenance", maintenanceTimeout),
+		condition.Reason)
+
+	// The driver should be asked again to put the node in maintenance
+	driver.EXPECT().
+		SetNodeMaintenance(gomock.Any(), gomock.Any(), true).
+		Return(nil)
+
+	result, err = controller.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, maintenanceCheckInterval, result.RequeueAfter)
+	condition = maintenanceCondition(t, k8sClient, node.Name)
+	require.Equal(t, corev1alpha1.NodeOperationInProgress, condition.Status)
+	require.Equal(t, "Entering maintenance", condition.Reason)
+	require.WithinDuration(t, time.Now(), condition.LastTransitionTime.Time, time.Minute)
+}
+
+func TestMaintenanceWithoutStorageCluster(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cluster := createStorageCluster()
+	ownedNode := createStorageNode(cluster, "node1")
+	ownedNode.Spec.Maintenance = pointer.BoolPtr(true)
+	orphanNode := ownedNode.DeepCopy()
+	orphanNode.Name = "node2"
+	orphanNode.OwnerReferences = nil
+
+	// Driver should not be called as no StorageCluster controls the nodes
+	k8sClient := testutil.FakeK8sClient(ownedNode, orphanNode)
+	controller := Controller{
+		client:   k8sClient,
+		recorder: record.NewFakeRecorder(10),
+		Driver:   testutil.MockDriver(mockCtrl),
+	}
+
+	for _, name := range []string{"node1", "node2", "missing-node"} {
+		result, err := controller.Reconcile(reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      name,
+				Namespace: cluster.Namespace,
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, result)
+	}
+}
+
+func TestStorageNodePredicate(t *testing.T) {
+	cluster := createStorageCluster()
+	oldNode := createStorageNode(cluster, "node1")
+	predicate := storageNodePredicate()
+
+	newNode := oldNode.DeepCopy()
+	newNode.Status.Network.DataIP = "10.0.0.1"
+	require.False(t, predicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	newNode = oldNode.DeepCopy()
+	newNode.Spec.Maintenance = pointer.BoolPtr(false)
+	require.True(t, predicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	newNode = oldNode.DeepCopy()
+	newNode.Status.Phase = string(corev1alpha1.NodeMaintenance)
+	require.True(t, predicate.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}))
+
+	require.True(t, predicate.Create(event.CreateEvent{Object: oldNode}))
+	require.False(t, predicate.Delete(event.DeleteEvent{Object: oldNode}))
+}
+
+func createStorageCluster() *corev1alpha1.StorageCluster {
+	return &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			UID:       "cluster-uid",
+		},
+	}
+}
+
+func createStorageNode(
+	cluster *corev1alpha1.StorageCluster,
+	name string,
+) *corev1alpha1.StorageNode {
+	return &corev1alpha1.StorageNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cluster, clusterKind),
+			},
+		},
+	}
+}
+
+func setMaintenance(t *testing.T, k8sClient client.Client, name string, enabled bool) {
+	node := &corev1alpha1.StorageNode{}
+	err := testutil.Get(k8sClient, node, name, "kube-test")
+	require.NoError(t, err)
+	node.Spec.Maintenance = pointer.BoolPtr(enabled)
+	err = k8sClient.Update(context.TODO(), node)
+	require.NoError(t, err)
+}
+
+// setPhase updates the phase of the node the same way the driver does
+func setPhase(
+	t *testing.T,
+	k8sClient client.Client,
+	cluster *corev1alpha1.StorageCluster,
+	name string,
+	phase corev1alpha1.ConditionStatus,
+) {
+	node := createStorageNode(cluster, name)
+	node.Status.Phase = string(phase)
+	node.Status.Conditions = []corev1alpha1.NodeCondition{
+		{
+			Type:   corev1alpha1.NodeState,
+			Status: phase,
+		},
+	}
+	err := k8sutil.CreateOrUpdateStorageNode(k8sClient, node, metav1.NewControllerRef(cluster, clusterKind))
+	require.NoError(t, err)
+}
+
+func maintenanceCondition(t *testing.T, k8sClient client.Client, name string) *corev1alpha1.NodeCondition {
+	node := &corev1alpha1.StorageNode{}
+	err := testutil.Get(k8sClient, node, name, "kube-test")
+	require.NoError(t, err)
+	condition := getMaintenanceCondition(node)
+	require.NotNil(t, condition)
+	return condition
+}
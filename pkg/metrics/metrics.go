@@ -0,0 +1,262 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "openstorage_operator"
+
+	labelNamespace = "namespace"
+	labelCluster   = "cluster"
+
+	stateUpdated    = "updated"
+	stateCompleted  = "completed"
+	stateInProgress = "in_progress"
+	statePending    = "pending"
+)
+
+var (
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Time taken to reconcile a StorageCluster",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+		[]string{labelNamespace, labelCluster},
+	)
+	reconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Number of failed reconciles of a StorageCluster",
+		},
+		[]string{labelNamespace, labelCluster},
+	)
+	clusterPhase = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cluster_phase",
+			Help:      "Current phase of a StorageCluster. The series of the current phase is set to 1.",
+		},
+		[]string{labelNamespace, labelCluster, "phase"},
+	)
+	storageNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "storage_nodes",
+			Help:      "Number of StorageNodes of a StorageCluster by their status",
+		},
+		[]string{labelNamespace, labelCluster, "status"},
+	)
+	podsCreated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "pods_created_total",
+			Help:      "Number of storage pods created for a StorageCluster",
+		},
+		[]string{labelNamespace, labelCluster},
+	)
+	podsDeleted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "pods_deleted_total",
+			Help:      "Number of storage pods deleted for a StorageCluster",
+		},
+		[]string{labelNamespace, labelCluster},
+	)
+	rollingUpdateNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "rolling_update_nodes",
+			Help:      "Number of nodes of a StorageCluster that are updated or pending an update",
+		},
+		[]string{labelNamespace, labelCluster, "state"},
+	)
+	nodeWiperNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "node_wiper_nodes",
+			Help:      "Number of nodes of a StorageCluster by the state of the node wiper",
+		},
+		[]string{labelNamespace, labelCluster, "state"},
+	)
+	sdkCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "sdk_call_duration_seconds",
+			Help:      "Time taken by the calls to the storage driver SDK",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		},
+		[]string{"method"},
+	)
+	sdkCallFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "sdk_call_failures_total",
+			Help:      "Number of failed calls to the storage driver SDK",
+		},
+		[]string{"method", "code"},
+	)
+	componentFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "component_reconcile_failures_total",
+			Help:      "Number of failed reconciles of a component of a StorageCluster",
+		},
+		[]string{labelNamespace, labelCluster, "component"},
+	)
+
+	// nodeStatuses are the statuses for which the StorageNode counts are reported
+	nodeStatuses = []corev1alpha1.ConditionStatus{
+		corev1alpha1.NodeOnline,
+		corev1alpha1.NodeInit,
+		corev1alpha1.NodeNotInQuorum,
+		corev1alpha1.NodeMaintenance,
+		corev1alpha1.NodeDecommissioned,
+		corev1alpha1.NodeDegraded,
+		corev1alpha1.NodeOffline,
+		corev1alpha1.NodeUnknown,
+	}
+
+	// currentPhases keeps the last reported phase of every cluster, so the
+	// series of the previous phase can be removed when the phase changes
+	currentPhases     = make(map[string]string)
+	currentPhasesLock sync.Mutex
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		reconcileDuration,
+		reconcileErrors,
+		clusterPhase,
+		storageNodes,
+		podsCreated,
+		podsDeleted,
+		rollingUpdateNodes,
+		nodeWiperNodes,
+		sdkCallDuration,
+		sdkCallFailures,
+		componentFailures,
+	)
+}
+
+// ObserveReconcile records the duration of a reconcile of the given
+// StorageCluster, and counts it as failed if it returned an error
+func ObserveReconcile(namespace, name string, duration time.Duration, err error) {
+	reconcileDuration.WithLabelValues(namespace, name).Observe(duration.Seconds())
+	if err != nil {
+		reconcileErrors.WithLabelValues(namespace, name).Inc()
+	}
+}
+
+// SetClusterPhase sets the current phase of the given StorageCluster
+func SetClusterPhase(namespace, name, phase string) {
+	currentPhasesLock.Lock()
+	defer currentPhasesLock.Unlock()
+
+	key := namespace + "/" + name
+	if previous, exists := currentPhases[key]; exists && previous != phase {
+		clusterPhase.DeleteLabelValues(namespace, name, previous)
+	}
+	currentPhases[key] = phase
+	clusterPhase.WithLabelValues(namespace, name, phase).Set(1)
+}
+
+// SetStorageNodeCounts sets the number of StorageNodes of the given
+// StorageCluster for every node status. Statuses missing from the given
+// counts are reported as zero.
+func SetStorageNodeCounts(
+	namespace, name string,
+	counts map[corev1alpha1.ConditionStatus]int,
+) {
+	for _, status := range nodeStatuses {
+		storageNodes.WithLabelValues(namespace, name, string(status)).Set(float64(counts[status]))
+	}
+}
+
+// AddPodsCreated adds to the number of storage pods created for the given StorageCluster
+func AddPodsCreated(namespace, name string, count int) {
+	podsCreated.WithLabelValues(namespace, name).Add(float64(count))
+}
+
+// AddPodsDeleted adds to the number of storage pods deleted for the given StorageCluster
+func AddPodsDeleted(namespace, name string, count int) {
+	podsDeleted.WithLabelValues(namespace, name).Add(float64(count))
+}
+
+// SetRollingUpdateProgress sets the number of nodes of the given StorageCluster
+// that run the latest revision, and the ones that are yet to be updated
+func SetRollingUpdateProgress(namespace, name string, updated, pending int) {
+	rollingUpdateNodes.WithLabelValues(namespace, name, stateUpdated).Set(float64(updated))
+	rollingUpdateNodes.WithLabelValues(namespace, name, statePending).Set(float64(pending))
+}
+
+// SetNodeWiperProgress sets the number of nodes of the given StorageCluster
+// that have been wiped, are being wiped and are yet to be wiped
+func SetNodeWiperProgress(namespace, name string, completed, inProgress, total int) {
+	pending := total - completed - inProgress
+	if pending < 0 {
+		pending = 0
+	}
+	nodeWiperNodes.WithLabelValues(namespace, name, stateCompleted).Set(float64(completed))
+	nodeWiperNodes.WithLabelValues(namespace, name, stateInProgress).Set(float64(inProgress))
+	nodeWiperNodes.WithLabelValues(namespace, name, statePending).Set(float64(pending))
+}
+
+// IncComponentFailures counts a failed reconcile of a component of the given StorageCluster
+func IncComponentFailures(namespace, name, component string) {
+	componentFailures.WithLabelValues(namespace, name, component).Inc()
+}
+
+// SDKUnaryClientInterceptor returns a gRPC interceptor that records the
+// latency and the failures of the unary calls made to the storage driver SDK
+func SDKUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		sdkCallDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			sdkCallFailures.WithLabelValues(method, status.Code(err).String()).Inc()
+		}
+		return err
+	}
+}
+
+// DeleteClusterMetrics removes the gauges of a StorageCluster that has been
+// deleted, so it does not keep reporting its last state
+func DeleteClusterMetrics(namespace, name string) {
+	currentPhasesLock.Lock()
+	key := namespace + "/" + name
+	if phase, exists := currentPhases[key]; exists {
+		clusterPhase.DeleteLabelValues(namespace, name, phase)
+		delete(currentPhases, key)
+	}
+	currentPhasesLock.Unlock()
+
+	for _, status := range nodeStatuses {
+		storageNodes.DeleteLabelValues(namespace, name, string(status))
+	}
+	for _, state := range []string{stateUpdated, statePending} {
+		rollingUpdateNodes.DeleteLabelValues(namespace, name, state)
+	}
+	for _, state := range []string{stateCompleted, stateInProgress, statePending} {
+		nodeWiperNodes.DeleteLabelValues(namespace, name, state)
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestObserveReconcile(t *testing.T) {
+	ObserveReconcile("reconcile-ns", "px-cluster", time.Second, nil)
+	ObserveReconcile("reconcile-ns", "px-cluster", 2*time.Second, fmt.Errorf("reconcile failed"))
+
+	histogram := getMetric(t, reconcileDuration.WithLabelValues("reconcile-ns", "px-cluster").(prometheus.Metric))
+	require.Equal(t, uint64(2), histogram.GetHistogram().GetSampleCount())
+	require.Equal(t, float64(3), histogram.GetHistogram().GetSampleSum())
+	require.Equal(t, float64(1), counterValue(t, reconcileErrors, "reconcile-ns", "px-cluster"))
+}
+
+func TestSetClusterPhase(t *testing.T) {
+	SetClusterPhase("phase-ns", "px-cluster", string(corev1alpha1.ClusterInit))
+	require.Equal(t, 1, seriesCount(t, clusterPhase))
+	require.Equal(t, float64(1), gaugeValue(t, clusterPhase,
+		"phase-ns", "px-cluster", string(corev1alpha1.ClusterInit)))
+
+	// Only the series of the current phase should be reported
+	SetClusterPhase("phase-ns", "px-cluster", string(corev1alpha1.ClusterOnline))
+	require.Equal(t, 1, seriesCount(t, clusterPhase))
+	require.Equal(t, float64(1), gaugeValue(t, clusterPhase,
+		"phase-ns", "px-cluster", string(corev1alpha1.ClusterOnline)))
+
+	// Series of deleted clusters should be removed
+	DeleteClusterMetrics("phase-ns", "px-cluster")
+	require.Zero(t, seriesCount(t, clusterPhase))
+}
+
+func TestSetStorageNodeCounts(t *testing.T) {
+	SetStorageNodeCounts("nodes-ns", "px-cluster", map[corev1alpha1.ConditionStatus]int{
+		corev1alpha1.NodeOnline:  2,
+		corev1alpha1.NodeOffline: 1,
+	})
+
+	require.Equal(t, float64(2), gaugeValue(t, storageNodes,
+		"nodes-ns", "px-cluster", string(corev1alpha1.NodeOnline)))
+	require.Equal(t, float64(1), gaugeValue(t, storageNodes,
+		"nodes-ns", "px-cluster", string(corev1alpha1.NodeOffline)))
+	require.Zero(t, gaugeValue(t, storageNodes,
+		"nodes-ns", "px-cluster", string(corev1alpha1.NodeInit)))
+
+	// Node counts should be reset when the statuses change
+	SetStorageNodeCounts("nodes-ns", "px-cluster", map[corev1alpha1.ConditionStatus]int{
+		corev1alpha1.NodeOnline: 3,
+	})
+
+	require.Equal(t, float64(3), gaugeValue(t, storageNodes,
+		"nodes-ns", "px-cluster", string(corev1alpha1.NodeOnline)))
+	require.Zero(t, gaugeValue(t, storageNodes,
+		"nodes-ns", "px-cluster", string(corev1alpha1.NodeOffline)))
+}
+
+func TestPodCounts(t *testing.T) {
+	AddPodsCreated("pods-ns", "px-cluster", 1)
+	AddPodsCreated("pods-ns", "px-cluster", 2)
+	AddPodsDeleted("pods-ns", "px-cluster", 1)
+
+	require.Equal(t, float64(3), counterValue(t, podsCreated, "pods-ns", "px-cluster"))
+	require.Equal(t, float64(1), counterValue(t, podsDeleted, "pods-ns", "px-cluster"))
+}
+
+func TestRollingUpdateAndNodeWiperProgress(t *testing.T) {
+	SetRollingUpdateProgress("progress-ns", "px-cluster", 2, 3)
+
+	require.Equal(t, float64(2), gaugeValue(t, rollingUpdateNodes, "progress-ns", "px-cluster", stateUpdated))
+	require.Equal(t, float64(3), gaugeValue(t, rollingUpdateNodes, "progress-ns", "px-cluster", statePending))
+
+	SetNodeWiperProgress("progress-ns", "px-cluster", 1, 2, 5)
+
+	require.Equal(t, float64(1), gaugeValue(t, nodeWiperNodes, "progress-ns", "px-cluster", stateCompleted))
+	require.Equal(t, float64(2), gaugeValue(t, nodeWiperNodes, "progress-ns", "px-cluster", stateInProgress))
+	require.Equal(t, float64(2), gaugeValue(t, nodeWiperNodes, "progress-ns", "px-cluster", statePending))
+
+	DeleteClusterMetrics("progress-ns", "px-cluster")
+	require.Zero(t, seriesCount(t, rollingUpdateNodes))
+	require.Zero(t, seriesCount(t, nodeWiperNodes))
+}
+
+func TestComponentFailures(t *testing.T) {
+	IncComponentFailures("component-ns", "px-cluster", "Autopilot")
+	IncComponentFailures("component-ns", "px-cluster", "Autopilot")
+
+	require.Equal(t, float64(2), counterValue(t, componentFailures, "component-ns", "px-cluster", "Autopilot"))
+}
+
+func TestSDKUnaryClientInterceptor(t *testing.T) {
+	interceptor := SDKUnaryClientInterceptor()
+	method := "/openstorage.api.OpenStorageCluster/InspectCurrent"
+
+	err := interceptor(context.TODO(), method, nil, nil, nil,
+		func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	expectedErr := status.Error(codes.Unavailable, "connection refused")
+	err = interceptor(context.TODO(), method, nil, nil, nil,
+		func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			return expectedErr
+		},
+	)
+	require.Equal(t, expectedErr, err)
+
+	histogram := getMetric(t, sdkCallDuration.WithLabelValues(method).(prometheus.Metric))
+	require.Equal(t, uint64(2), histogram.GetHistogram().GetSampleCount())
+	require.Equal(t, float64(1), counterValue(t, sdkCallFailures, method, codes.Unavailable.String()))
+}
+
+func getMetric(t *testing.T, metric prometheus.Metric) *dto.Metric {
+	m := &dto.Metric{}
+	require.NoError(t, metric.Write(m))
+	return m
+}
+
+func counterValue(t *testing.T, counter *prometheus.CounterVec, labels ...string) float64 {
+	return getMetric(t, counter.WithLabelValues(labels...)).GetCounter().GetValue()
+}
+
+func gaugeValue(t *testing.T, gauge *prometheus.GaugeVec, labels ...string) float64 {
+	return getMetric(t, gauge.WithLabelValues(labels...)).GetGauge().GetValue()
+}
+
+func seriesCount(t *testing.T, collector prometheus.Collector) int {
+	ch := make(chan prometheus.Metric, 100)
+	collector.Collect(ch)
+	close(ch)
+	return len(ch)
+}
@@ -16,6 +16,7 @@ import (
 type SdkServers struct {
 	Cluster *MockOpenStorageClusterServer
 	Node    *MockOpenStorageNodeServer
+	Volume  *MockOpenStorageVolumeServer
 }
 
 // SdkServer can be used to create a sdk server which implements mock server
@@ -51,6 +52,9 @@ func (m *SdkServer) StartOnAddress(ip, port string) error {
 	if m.servers.Node != nil {
 		api.RegisterOpenStorageNodeServer(m.server, m.servers.Node)
 	}
+	if m.servers.Volume != nil {
+		api.RegisterOpenStorageVolumeServer(m.server, m.servers.Volume)
+	}
 
 	reflection.Register(m.server)
 	waitForServer := make(chan bool)
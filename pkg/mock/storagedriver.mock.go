@@ -38,6 +38,34 @@ func (m *MockDriver) EXPECT() *MockDriverMockRecorder {
 	return m.recorder
 }
 
+// CanTakeNodeOffline mocks base method
+func (m *MockDriver) CanTakeNodeOffline(arg0 *v1alpha1.StorageCluster, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CanTakeNodeOffline", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CanTakeNodeOffline indicates an expected call of CanTakeNodeOffline
+func (mr *MockDriverMockRecorder) CanTakeNodeOffline(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanTakeNodeOffline", reflect.TypeOf((*MockDriver)(nil).CanTakeNodeOffline), arg0, arg1)
+}
+
+// CanUpdateStoragePods mocks base method
+func (m *MockDriver) CanUpdateStoragePods(arg0 *v1alpha1.StorageCluster, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CanUpdateStoragePods", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CanUpdateStoragePods indicates an expected call of CanUpdateStoragePods
+func (mr *MockDriverMockRecorder) CanUpdateStoragePods(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanUpdateStoragePods", reflect.TypeOf((*MockDriver)(nil).CanUpdateStoragePods), arg0, arg1)
+}
+
 // DeleteStorage mocks base method
 func (m *MockDriver) DeleteStorage(arg0 *v1alpha1.StorageCluster) (*v1alpha1.ClusterCondition, error) {
 	m.ctrl.T.Helper()
@@ -151,6 +179,20 @@ func (mr *MockDriverMockRecorder) SetDefaultsOnStorageCluster(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDefaultsOnStorageCluster", reflect.TypeOf((*MockDriver)(nil).SetDefaultsOnStorageCluster), arg0)
 }
 
+// SetNodeMaintenance mocks base method
+func (m *MockDriver) SetNodeMaintenance(arg0 *v1alpha1.StorageCluster, arg1 *v1alpha1.StorageNode, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNodeMaintenance", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetNodeMaintenance indicates an expected call of SetNodeMaintenance
+func (mr *MockDriverMockRecorder) SetNodeMaintenance(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNodeMaintenance", reflect.TypeOf((*MockDriver)(nil).SetNodeMaintenance), arg0, arg1, arg2)
+}
+
 // String mocks base method
 func (m *MockDriver) String() string {
 	m.ctrl.T.Helper()
@@ -192,3 +234,17 @@ func (mr *MockDriverMockRecorder) UpdateStorageClusterStatus(arg0 interface{}) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStorageClusterStatus", reflect.TypeOf((*MockDriver)(nil).UpdateStorageClusterStatus), arg0)
 }
+
+// ValidateStorageCluster mocks base method
+func (m *MockDriver) ValidateStorageCluster(arg0 *v1alpha1.StorageCluster) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateStorageCluster", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateStorageCluster indicates an expected call of ValidateStorageCluster
+func (mr *MockDriverMockRecorder) ValidateStorageCluster(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateStorageCluster", reflect.TypeOf((*MockDriver)(nil).ValidateStorageCluster), arg0)
+}
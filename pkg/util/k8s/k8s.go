@@ -3,30 +3,42 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"reflect"
 	"regexp"
+	"sort"
+	"strings"
 
 	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/hashicorp/go-version"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
 	"github.com/portworx/sched-ops/k8s"
 	"github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1beta1 "k8s.io/api/scheduling/v1beta1"
 	storagev1 "k8s.io/api/storage/v1"
 	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/tools/record"
+	hashutil "k8s.io/kubernetes/pkg/util/hash"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
-	kbVerRegex = regexp.MustCompile(`^(v\d+\.\d+\.\d+).*`)
+	kbVerRegex         = regexp.MustCompile(`^(v\d+\.\d+\.\d+).*`)
+	storageClusterKind = corev1alpha1.SchemeGroupVersion.WithKind("StorageCluster")
 )
 
 // GetVersion returns the kubernetes server version
@@ -57,422 +69,441 @@ func ParseObjectFromFile(
 	return err
 }
 
-// CreateOrUpdateServiceAccount creates a service account if not present,
-// else updates it if it has changed
-func CreateOrUpdateServiceAccount(
+// CreateOrUpdate creates the given object if not present, else updates it if
+// it has changed. The hash of the desired object is stored in an annotation,
+// so a change in the desired object is detected even if it only removes a
+// field. A change made out of band is detected by comparing the fields that
+// are set in the desired object with the existing object, so fields defaulted
+// by the API server or set by other controllers do not cause an update. The
+// object is written only if one of these has changed, or if the given owner
+// is missing. The owners of cluster scoped objects are recorded in labels
+// instead of owner references, along with the managed label, so the objects
+// can be found and deleted along with their StorageClusters. On update,
+// the labels, annotations, owners and finalizers added by others are retained,
+// while the labels last applied by the operator that are no longer desired are
+// removed. The fields allocated by the API server are retained too, like the
+// cluster IP and node ports of a service. Objects whose immutable fields have
+// changed, like the value of a PriorityClass, are deleted and created again.
+func CreateOrUpdate(
 	k8sClient client.Client,
-	sa *v1.ServiceAccount,
+	desired runtime.Object,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	existingSA := &v1.ServiceAccount{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      sa.Name,
-			Namespace: sa.Namespace,
-		},
-		existingSA,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s service account", sa.Name)
-		return k8sClient.Create(context.TODO(), sa)
-	} else if err != nil {
+	desiredMeta, err := meta.Accessor(desired)
+	if err != nil {
 		return err
 	}
+	kind, name := kindAndName(desired)
 
-	for _, o := range existingSA.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			sa.OwnerReferences = append(sa.OwnerReferences, o)
-		}
-	}
-
-	if len(sa.OwnerReferences) > len(existingSA.OwnerReferences) {
-		logrus.Debugf("Updating %s service account", sa.Name)
-		return k8sClient.Update(context.TODO(), sa)
-	}
-	return nil
-}
-
-// DeleteServiceAccount deletes a service account if present and owned
-func DeleteServiceAccount(
-	k8sClient client.Client,
-	name, namespace string,
-	owners ...metav1.OwnerReference,
-) error {
-	resource := types.NamespacedName{
-		Name:      name,
-		Namespace: namespace,
-	}
-
-	serviceAccount := &v1.ServiceAccount{}
-	err := k8sClient.Get(context.TODO(), resource, serviceAccount)
-	if errors.IsNotFound(err) {
-		return nil
-	} else if err != nil {
-		return err
+	if ownerRef != nil {
+		desiredMeta.SetOwnerReferences(mergeOwners(desiredMeta.GetOwnerReferences(), *ownerRef))
 	}
-
-	newOwners := removeOwners(serviceAccount.OwnerReferences, owners)
-
-	// Do not delete the object if it does not have the owner that was passed;
-	// even if the object has no owner
-	if (len(serviceAccount.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(serviceAccount.OwnerReferences) > 0 && len(serviceAccount.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete ServiceAccount %s/%s as it is not owned",
-			namespace, name)
-		return nil
+	setOwnerLabels(desiredMeta)
+	hash := computeObjectHash(withServerDefaults(desired))
+	annotations := make(map[string]string)
+	for key, value := range desiredMeta.GetAnnotations() {
+		annotations[key] = value
 	}
-
-	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s/%s ServiceAccount", namespace, name)
-		return k8sClient.Delete(context.TODO(), serviceAccount)
+	annotations[util.AnnotationLastAppliedHash] = hash
+	appliedLabels := appliedLabelKeys(desiredMeta.GetLabels())
+	if appliedLabels != "" {
+		annotations[util.AnnotationLastAppliedLabels] = appliedLabels
 	}
-	serviceAccount.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s/%s ServiceAccount", namespace, name)
-	return k8sClient.Update(context.TODO(), serviceAccount)
-}
+	desiredMeta.SetAnnotations(annotations)
 
-// CreateOrUpdateRole creates a role if not present,
-// else updates it if it has changed
-func CreateOrUpdateRole(
-	k8sClient client.Client,
-	role *rbacv1.Role,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingRole := &rbacv1.Role{}
-	err := k8sClient.Get(
+	existing := reflect.New(reflect.Indirect(reflect.ValueOf(desired)).Type()).Interface().(runtime.Object)
+	err = k8sClient.Get(
 		context.TODO(),
 		types.NamespacedName{
-			Name:      role.Name,
-			Namespace: role.Namespace,
+			Name:      desiredMeta.GetName(),
+			Namespace: desiredMeta.GetNamespace(),
 		},
-		existingRole,
+		existing,
 	)
 	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s role", role.Name)
-		return k8sClient.Create(context.TODO(), role)
+		logrus.Debugf("Creating %s %s", kind, name)
+		return k8sClient.Create(context.TODO(), desired)
 	} else if err != nil {
 		return err
 	}
 
-	modified := !reflect.DeepEqual(role.Rules, existingRole.Rules)
+	existingMeta, err := meta.Accessor(existing)
+	if err != nil {
+		return err
+	}
+	drifted, err := DriftedFields(withServerDefaults(desired), withServerDefaults(existing))
+	if err != nil {
+		return err
+	}
+	desiredMeta.SetLabels(mergeLabels(existingMeta, desiredMeta.GetLabels()))
+	desiredMeta.SetOwnerReferences(mergeOwners(existingMeta.GetOwnerReferences(), desiredMeta.GetOwnerReferences()...))
+	setOwnerLabels(desiredMeta)
+	if len(drifted) == 0 &&
+		equality.Semantic.DeepEqual(desiredMeta.GetLabels(), existingMeta.GetLabels()) &&
+		equality.Semantic.DeepEqual(desiredMeta.GetOwnerReferences(), existingMeta.GetOwnerReferences()) &&
+		existingMeta.GetAnnotations()[util.AnnotationLastAppliedHash] == hash &&
+		existingMeta.GetAnnotations()[util.AnnotationLastAppliedLabels] == appliedLabels {
+		return nil
+	}
 
-	for _, o := range existingRole.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			role.OwnerReferences = append(role.OwnerReferences, o)
+	annotations = mergeMaps(existingMeta.GetAnnotations(), annotations)
+	if appliedLabels == "" {
+		delete(annotations, util.AnnotationLastAppliedLabels)
+	}
+	desiredMeta.SetAnnotations(annotations)
+	desiredMeta.SetFinalizers(existingMeta.GetFinalizers())
+	if immutableFieldsChanged(desired, existing) {
+		logrus.Debugf("Recreating %s %s", kind, name)
+		if err := k8sClient.Delete(context.TODO(), existing); err != nil && !errors.IsNotFound(err) {
+			return err
 		}
+		return k8sClient.Create(context.TODO(), desired)
 	}
+	desiredMeta.SetResourceVersion(existingMeta.GetResourceVersion())
+	retainAllocatedFields(desired, existing)
 
-	if modified || len(role.OwnerReferences) > len(existingRole.OwnerReferences) {
-		logrus.Debugf("Updating %s role", role.Name)
-		return k8sClient.Update(context.TODO(), role)
-	}
-	return nil
+	logrus.Debugf("Updating %s %s", kind, name)
+	return k8sClient.Update(context.TODO(), desired)
 }
 
-// DeleteRole deletes a role if present and owned
-func DeleteRole(
+// Delete deletes the given object if present and owned. The object is only
+// used to get the type and name of the object to be deleted. If the object
+// has other owners too, the given owners are removed from it instead. The
+// owners of cluster scoped objects are looked up in their owner labels.
+func Delete(
 	k8sClient client.Client,
-	name, namespace string,
+	obj runtime.Object,
 	owners ...metav1.OwnerReference,
 ) error {
-	resource := types.NamespacedName{
-		Name:      name,
-		Namespace: namespace,
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return err
 	}
+	kind, name := kindAndName(obj)
 
-	role := &rbacv1.Role{}
-	err := k8sClient.Get(context.TODO(), resource, role)
+	err = k8sClient.Get(
+		context.TODO(),
+		types.NamespacedName{
+			Name:      objMeta.GetName(),
+			Namespace: objMeta.GetNamespace(),
+		},
+		obj,
+	)
 	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	newOwners := removeOwners(role.OwnerReferences, owners)
+	// Owner references set on cluster scoped objects by earlier versions are
+	// handled like the owner labels that replace them
+	setOwnerLabels(objMeta)
+	currentOwners := objMeta.GetOwnerReferences()
+	newOwners := removeOwners(currentOwners, owners)
+	currentOwnerUIDs := StorageClusterOwners(objMeta)
+	labels := removeOwnerLabels(objMeta.GetLabels(), owners)
+	newOwnerUIDs := StorageClusterOwners(&metav1.ObjectMeta{Labels: labels})
 
 	// Do not delete the object if it does not have the owner that was passed;
 	// even if the object has no owner
-	if (len(role.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(role.OwnerReferences) > 0 && len(role.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete Role %s/%s as it is not owned",
-			namespace, name)
+	hasOwners := len(currentOwners) > 0 || len(currentOwnerUIDs) > 0
+	owned := len(currentOwners) != len(newOwners) || len(currentOwnerUIDs) != len(newOwnerUIDs)
+	if (!hasOwners && len(owners) > 0) || (hasOwners && !owned) {
+		logrus.Debugf("Cannot delete %s %s as it is not owned", kind, name)
 		return nil
 	}
 
-	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s/%s Role", namespace, name)
-		return k8sClient.Delete(context.TODO(), role)
+	if len(newOwners) == 0 && len(newOwnerUIDs) == 0 {
+		logrus.Debugf("Deleting %s %s", kind, name)
+		return k8sClient.Delete(context.TODO(), obj)
 	}
-	role.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s/%s Role", namespace, name)
-	return k8sClient.Update(context.TODO(), role)
+	objMeta.SetOwnerReferences(newOwners)
+	objMeta.SetLabels(labels)
+	logrus.Debugf("Disowning %s %s", kind, name)
+	return k8sClient.Update(context.TODO(), obj)
 }
 
-// CreateOrUpdateRoleBinding creates a role binding if not present,
-// else updates it if it has changed
-func CreateOrUpdateRoleBinding(
-	k8sClient client.Client,
-	rb *rbacv1.RoleBinding,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingRB := &rbacv1.RoleBinding{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      rb.Name,
-			Namespace: rb.Namespace,
-		},
-		existingRB,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s role binding", rb.Name)
-		return k8sClient.Create(context.TODO(), rb)
-	} else if err != nil {
-		return err
+// StorageClusterOwners returns the UIDs of the StorageClusters that own the
+// given cluster scoped object, as recorded in its owner labels
+func StorageClusterOwners(objMeta metav1.Object) []types.UID {
+	var owners []types.UID
+	for key := range objMeta.GetLabels() {
+		if strings.HasPrefix(key, util.LabelOwnerPrefix) {
+			owners = append(owners, types.UID(strings.TrimPrefix(key, util.LabelOwnerPrefix)))
+		}
 	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+	return owners
+}
 
-	modified := !reflect.DeepEqual(rb.Subjects, existingRB.Subjects) ||
-		!reflect.DeepEqual(rb.RoleRef, existingRB.RoleRef)
-
-	for _, o := range existingRB.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			rb.OwnerReferences = append(rb.OwnerReferences, o)
+// setOwnerLabels replaces the StorageCluster owner references of the given
+// cluster scoped object with owner labels, and adds the managed label if the
+// object is owned by a StorageCluster. Namespaced objects are not changed.
+func setOwnerLabels(objMeta metav1.Object) {
+	if objMeta.GetNamespace() != "" {
+		return
+	}
+	labels := make(map[string]string)
+	var owners []metav1.OwnerReference
+	for _, owner := range objMeta.GetOwnerReferences() {
+		if owner.Kind == storageClusterKind.Kind &&
+			owner.APIVersion == storageClusterKind.GroupVersion().String() {
+			labels[util.LabelOwnerPrefix+string(owner.UID)] = "true"
+		} else {
+			owners = append(owners, owner)
 		}
 	}
-
-	if modified || len(rb.OwnerReferences) > len(existingRB.OwnerReferences) {
-		logrus.Debugf("Updating %s role binding", rb.Name)
-		return k8sClient.Update(context.TODO(), rb)
+	if len(labels) == 0 {
+		return
 	}
-	return nil
+	labels[util.LabelManaged] = "true"
+	objMeta.SetLabels(mergeMaps(objMeta.GetLabels(), labels))
+	objMeta.SetOwnerReferences(owners)
 }
 
-// DeleteRoleBinding deletes a role binding if present and owned
-func DeleteRoleBinding(
-	k8sClient client.Client,
-	name, namespace string,
-	owners ...metav1.OwnerReference,
-) error {
-	resource := types.NamespacedName{
-		Name:      name,
-		Namespace: namespace,
+// removeOwnerLabels returns a copy of the given labels without the owner
+// labels of the given owners
+func removeOwnerLabels(labels map[string]string, owners []metav1.OwnerReference) map[string]string {
+	newLabels := make(map[string]string)
+	for key, value := range labels {
+		newLabels[key] = value
 	}
-
-	roleBinding := &rbacv1.RoleBinding{}
-	err := k8sClient.Get(context.TODO(), resource, roleBinding)
-	if errors.IsNotFound(err) {
-		return nil
-	} else if err != nil {
-		return err
+	for _, owner := range owners {
+		delete(newLabels, util.LabelOwnerPrefix+string(owner.UID))
 	}
+	return newLabels
+}
 
-	newOwners := removeOwners(roleBinding.OwnerReferences, owners)
-
-	// Do not delete the object if it does not have the owner that was passed;
-	// even if the object has no owner
-	if (len(roleBinding.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(roleBinding.OwnerReferences) > 0 && len(roleBinding.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete RoleBinding %s/%s as it is not owned",
-			namespace, name)
-		return nil
+// appliedLabelKeys returns the sorted and comma separated keys of the given
+// labels, leaving out the owner and managed labels as they are never removed
+// by an update
+func appliedLabelKeys(labels map[string]string) string {
+	var keys []string
+	for key := range labels {
+		if key != util.LabelManaged && !strings.HasPrefix(key, util.LabelOwnerPrefix) {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
 
-	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s/%s RoleBinding", namespace, name)
-		return k8sClient.Delete(context.TODO(), roleBinding)
+// mergeLabels returns the labels of the existing object merged with the
+// desired labels. The labels last applied by the operator that are not in
+// the desired labels anymore are removed, while the labels added by others
+// are retained.
+func mergeLabels(existingMeta metav1.Object, desired map[string]string) map[string]string {
+	labels := make(map[string]string)
+	for key, value := range existingMeta.GetLabels() {
+		labels[key] = value
+	}
+	appliedLabels := existingMeta.GetAnnotations()[util.AnnotationLastAppliedLabels]
+	for _, key := range strings.Split(appliedLabels, ",") {
+		if _, ok := desired[key]; !ok {
+			delete(labels, key)
+		}
 	}
-	roleBinding.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s/%s RoleBinding", namespace, name)
-	return k8sClient.Update(context.TODO(), roleBinding)
+	return mergeMaps(labels, desired)
 }
 
-// CreateOrUpdateClusterRole creates a cluster role if not present,
-// else updates it if it has changed
-func CreateOrUpdateClusterRole(
-	k8sClient client.Client,
-	cr *rbacv1.ClusterRole,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingCR := &rbacv1.ClusterRole{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{Name: cr.Name},
-		existingCR,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s cluster role", cr.Name)
-		return k8sClient.Create(context.TODO(), cr)
-	} else if err != nil {
-		return err
+// withServerDefaults returns a copy of the given object with the defaults set
+// that the API server would set, so an object is not considered changed just
+// because it does not set them
+func withServerDefaults(obj runtime.Object) runtime.Object {
+	objCopy := obj.DeepCopyObject()
+	service, ok := objCopy.(*v1.Service)
+	if !ok {
+		return objCopy
 	}
+	if service.Spec.Type == "" {
+		service.Spec.Type = v1.ServiceTypeClusterIP
+	}
+	for i := range service.Spec.Ports {
+		if service.Spec.Ports[i].Protocol == "" {
+			service.Spec.Ports[i].Protocol = v1.ProtocolTCP
+		}
+		if !hasNodePorts(service) {
+			service.Spec.Ports[i].NodePort = int32(0)
+		}
+	}
+	return service
+}
 
-	modified := !reflect.DeepEqual(cr.Rules, existingCR.Rules)
+// immutableFieldsChanged returns true if the desired object has changed fields
+// of the existing object that cannot be updated. The spec of a
+// PodDisruptionBudget cannot be updated before Kubernetes 1.15, and the value
+// of a PriorityClass cannot be updated at all.
+func immutableFieldsChanged(desired, existing runtime.Object) bool {
+	switch desiredObj := desired.(type) {
+	case *policyv1beta1.PodDisruptionBudget:
+		existingObj := existing.(*policyv1beta1.PodDisruptionBudget)
+		return !equality.Semantic.DeepEqual(desiredObj.Spec, existingObj.Spec)
+	case *schedulingv1beta1.PriorityClass:
+		existingObj := existing.(*schedulingv1beta1.PriorityClass)
+		return desiredObj.Value != existingObj.Value
+	}
+	return false
+}
 
-	for _, o := range existingCR.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			cr.OwnerReferences = append(cr.OwnerReferences, o)
+// retainAllocatedFields copies the fields that are allocated by the API server
+// or other controllers from the existing object to the desired object, if the
+// desired object does not set them
+func retainAllocatedFields(desired, existing runtime.Object) {
+	switch desiredObj := desired.(type) {
+	case *v1.Service:
+		existingObj := existing.(*v1.Service)
+		if desiredObj.Spec.Type == "" {
+			desiredObj.Spec.Type = v1.ServiceTypeClusterIP
+		}
+		if desiredObj.Spec.ClusterIP == "" {
+			desiredObj.Spec.ClusterIP = existingObj.Spec.ClusterIP
+		}
+		nodePorts := make(map[string]int32)
+		for _, port := range existingObj.Spec.Ports {
+			nodePorts[port.Name] = port.NodePort
+		}
+		ports := make([]v1.ServicePort, len(desiredObj.Spec.Ports))
+		for i, port := range desiredObj.Spec.Ports {
+			if !hasNodePorts(desiredObj) {
+				port.NodePort = int32(0)
+			} else if port.NodePort == 0 {
+				port.NodePort = nodePorts[port.Name]
+			}
+			ports[i] = port
+		}
+		desiredObj.Spec.Ports = ports
+	case *v1.ServiceAccount:
+		existingObj := existing.(*v1.ServiceAccount)
+		if len(desiredObj.Secrets) == 0 {
+			desiredObj.Secrets = existingObj.Secrets
 		}
 	}
+}
 
-	if modified || len(cr.OwnerReferences) > len(existingCR.OwnerReferences) {
-		logrus.Debugf("Updating %s cluster role", cr.Name)
-		return k8sClient.Update(context.TODO(), cr)
-	}
-	return nil
+func hasNodePorts(service *v1.Service) bool {
+	return service.Spec.Type == v1.ServiceTypeLoadBalancer ||
+		service.Spec.Type == v1.ServiceTypeNodePort
 }
 
-// DeleteClusterRole deletes a cluster role if present and owned
-func DeleteClusterRole(
-	k8sClient client.Client,
-	name string,
-	owners ...metav1.OwnerReference,
-) error {
-	resource := types.NamespacedName{
-		Name: name,
+// computeObjectHash returns a hash of the given object, ignoring the metadata
+// that is not set by the operator, and the owners of the object, so objects
+// shared by multiple StorageClusters are not updated by each of them in turn
+func computeObjectHash(obj runtime.Object) string {
+	objCopy := obj.DeepCopyObject()
+	objMeta, _ := meta.Accessor(objCopy)
+	annotations := make(map[string]string)
+	for key, value := range objMeta.GetAnnotations() {
+		if key != util.AnnotationLastAppliedHash && key != util.AnnotationLastAppliedLabels {
+			annotations[key] = value
+		}
 	}
-
-	clusterRole := &rbacv1.ClusterRole{}
-	err := k8sClient.Get(context.TODO(), resource, clusterRole)
-	if errors.IsNotFound(err) {
-		return nil
-	} else if err != nil {
-		return err
+	objMeta.SetAnnotations(annotations)
+	labels := make(map[string]string)
+	for key, value := range objMeta.GetLabels() {
+		if !strings.HasPrefix(key, util.LabelOwnerPrefix) {
+			labels[key] = value
+		}
 	}
+	objMeta.SetLabels(labels)
+	objMeta.SetOwnerReferences(nil)
+	objMeta.SetResourceVersion("")
 
-	newOwners := removeOwners(clusterRole.OwnerReferences, owners)
+	hasher := fnv.New32a()
+	hashutil.DeepHashObject(hasher, objCopy)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
 
-	// Do not delete the object if it does not have the owner that was passed;
-	// even if the object has no owner
-	if (len(clusterRole.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(clusterRole.OwnerReferences) > 0 && len(clusterRole.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete ClusterRole %s as it is not owned", name)
-		return nil
+// kindAndName returns the kind of the given object, and its name prefixed
+// with the namespace if the object is namespaced
+func kindAndName(obj runtime.Object) (string, string) {
+	kind := reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return kind, ""
 	}
-
-	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s ClusterRole", name)
-		return k8sClient.Delete(context.TODO(), clusterRole)
+	name := objMeta.GetName()
+	if objMeta.GetNamespace() != "" {
+		name = objMeta.GetNamespace() + "/" + name
 	}
-	clusterRole.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s ClusterRole", name)
-	return k8sClient.Update(context.TODO(), clusterRole)
+	return kind, name
 }
 
-// CreateOrUpdateClusterRoleBinding creates a cluster role binding if not present,
-// else updates it if it has changed
-func CreateOrUpdateClusterRoleBinding(
-	k8sClient client.Client,
-	crb *rbacv1.ClusterRoleBinding,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingCRB := &rbacv1.ClusterRoleBinding{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{Name: crb.Name},
-		existingCRB,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s cluster role binding", crb.Name)
-		return k8sClient.Create(context.TODO(), crb)
-	} else if err != nil {
-		return err
-	}
-
-	modified := !reflect.DeepEqual(crb.Subjects, existingCRB.Subjects) ||
-		!reflect.DeepEqual(crb.RoleRef, existingCRB.RoleRef)
-
-	for _, o := range existingCRB.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			crb.OwnerReferences = append(crb.OwnerReferences, o)
+// mergeOwners returns the current owners along with the given owners that
+// are not already present
+func mergeOwners(current []metav1.OwnerReference, owners ...metav1.OwnerReference) []metav1.OwnerReference {
+	merged := append(make([]metav1.OwnerReference, 0, len(current)), current...)
+	for _, owner := range owners {
+		present := false
+		for _, o := range merged {
+			if o.UID == owner.UID {
+				present = true
+				break
+			}
+		}
+		if !present {
+			merged = append(merged, owner)
 		}
 	}
+	return merged
+}
 
-	if modified || len(crb.OwnerReferences) > len(existingCRB.OwnerReferences) {
-		logrus.Debugf("Updating %s cluster role binding", crb.Name)
-		return k8sClient.Update(context.TODO(), crb)
+// mergeMaps returns a new map with the entries of both maps. The entries of
+// the second map take precedence.
+func mergeMaps(first, second map[string]string) map[string]string {
+	if len(first) == 0 && len(second) == 0 {
+		return second
 	}
-	return nil
+	merged := make(map[string]string)
+	for key, value := range first {
+		merged[key] = value
+	}
+	for key, value := range second {
+		merged[key] = value
+	}
+	return merged
 }
 
-// DeleteClusterRoleBinding deletes a cluster role binding if present and owned
-func DeleteClusterRoleBinding(
+// DeleteServiceAccount deletes a service account if present and owned
+func DeleteServiceAccount(
 	k8sClient client.Client,
-	name string,
+	name, namespace string,
 	owners ...metav1.OwnerReference,
 ) error {
 	resource := types.NamespacedName{
-		Name: name,
+		Name:      name,
+		Namespace: namespace,
 	}
 
-	crb := &rbacv1.ClusterRoleBinding{}
-	err := k8sClient.Get(context.TODO(), resource, crb)
+	serviceAccount := &v1.ServiceAccount{}
+	err := k8sClient.Get(context.TODO(), resource, serviceAccount)
 	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	newOwners := removeOwners(crb.OwnerReferences, owners)
+	newOwners := removeOwners(serviceAccount.OwnerReferences, owners)
 
 	// Do not delete the object if it does not have the owner that was passed;
 	// even if the object has no owner
-	if (len(crb.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(crb.OwnerReferences) > 0 && len(crb.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete ClusterRoleBinding %s as it is not owned", name)
+	if (len(serviceAccount.OwnerReferences) == 0 && len(owners) > 0) ||
+		(len(serviceAccount.OwnerReferences) > 0 && len(serviceAccount.OwnerReferences) == len(newOwners)) {
+		logrus.Debugf("Cannot delete ServiceAccount %s/%s as it is not owned",
+			namespace, name)
 		return nil
 	}
 
 	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s ClusterRoleBinding", name)
-		return k8sClient.Delete(context.TODO(), crb)
-	}
-	crb.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s ClusterRoleBinding", name)
-	return k8sClient.Update(context.TODO(), crb)
-}
-
-// CreateOrUpdateConfigMap creates a config map if not present,
-// else updates it if it has changed
-func CreateOrUpdateConfigMap(
-	k8sClient client.Client,
-	configMap *v1.ConfigMap,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingConfigMap := &v1.ConfigMap{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      configMap.Name,
-			Namespace: configMap.Namespace,
-		},
-		existingConfigMap,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %v config map", configMap.Name)
-		return k8sClient.Create(context.TODO(), configMap)
-	} else if err != nil {
-		return err
-	}
-
-	modified := !reflect.DeepEqual(configMap.Data, existingConfigMap.Data) ||
-		!reflect.DeepEqual(configMap.BinaryData, existingConfigMap.BinaryData)
-
-	for _, o := range existingConfigMap.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			configMap.OwnerReferences = append(configMap.OwnerReferences, o)
-		}
-	}
-
-	if modified || len(configMap.OwnerReferences) > len(existingConfigMap.OwnerReferences) {
-		logrus.Debugf("Updating %v config map", configMap.Name)
-		return k8sClient.Update(context.TODO(), configMap)
+		logrus.Debugf("Deleting %s/%s ServiceAccount", namespace, name)
+		return k8sClient.Delete(context.TODO(), serviceAccount)
 	}
-	return nil
+	serviceAccount.OwnerReferences = newOwners
+	logrus.Debugf("Disowning %s/%s ServiceAccount", namespace, name)
+	return k8sClient.Update(context.TODO(), serviceAccount)
 }
 
-// DeleteConfigMap deletes a config map if present and owned
-func DeleteConfigMap(
+// DeleteRole deletes a role if present and owned
+func DeleteRole(
 	k8sClient client.Client,
 	name, namespace string,
 	owners ...metav1.OwnerReference,
@@ -482,255 +513,186 @@ func DeleteConfigMap(
 		Namespace: namespace,
 	}
 
-	configMap := &v1.ConfigMap{}
-	err := k8sClient.Get(context.TODO(), resource, configMap)
+	role := &rbacv1.Role{}
+	err := k8sClient.Get(context.TODO(), resource, role)
 	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	newOwners := removeOwners(configMap.OwnerReferences, owners)
+	newOwners := removeOwners(role.OwnerReferences, owners)
 
 	// Do not delete the object if it does not have the owner that was passed;
 	// even if the object has no owner
-	if (len(configMap.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(configMap.OwnerReferences) > 0 && len(configMap.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete ConfigMap %s/%s as it is not owned",
+	if (len(role.OwnerReferences) == 0 && len(owners) > 0) ||
+		(len(role.OwnerReferences) > 0 && len(role.OwnerReferences) == len(newOwners)) {
+		logrus.Debugf("Cannot delete Role %s/%s as it is not owned",
 			namespace, name)
 		return nil
 	}
 
 	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s/%s ConfigMap", namespace, name)
-		return k8sClient.Delete(context.TODO(), configMap)
-	}
-	configMap.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s/%s ConfigMap", namespace, name)
-	return k8sClient.Update(context.TODO(), configMap)
-}
-
-// CreateStorageClass creates a storage class only if not present.
-// It will not return error if already present.
-func CreateStorageClass(
-	k8sClient client.Client,
-	sc *storagev1.StorageClass,
-) error {
-	existingSC := &storagev1.StorageClass{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{Name: sc.Name},
-		existingSC,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s StorageClass", sc.Name)
-		return k8sClient.Create(context.TODO(), sc)
+		logrus.Debugf("Deleting %s/%s Role", namespace, name)
+		return k8sClient.Delete(context.TODO(), role)
 	}
-	return err
+	role.OwnerReferences = newOwners
+	logrus.Debugf("Disowning %s/%s Role", namespace, name)
+	return k8sClient.Update(context.TODO(), role)
 }
 
-// DeleteStorageClass deletes a storage class if present and owned
-func DeleteStorageClass(
+// DeleteRoleBinding deletes a role binding if present and owned
+func DeleteRoleBinding(
 	k8sClient client.Client,
-	name string,
+	name, namespace string,
 	owners ...metav1.OwnerReference,
 ) error {
 	resource := types.NamespacedName{
-		Name: name,
+		Name:      name,
+		Namespace: namespace,
 	}
 
-	storageClass := &storagev1.StorageClass{}
-	err := k8sClient.Get(context.TODO(), resource, storageClass)
+	roleBinding := &rbacv1.RoleBinding{}
+	err := k8sClient.Get(context.TODO(), resource, roleBinding)
 	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	newOwners := removeOwners(storageClass.OwnerReferences, owners)
+	newOwners := removeOwners(roleBinding.OwnerReferences, owners)
 
 	// Do not delete the object if it does not have the owner that was passed;
 	// even if the object has no owner
-	if (len(storageClass.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(storageClass.OwnerReferences) > 0 && len(storageClass.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete StorageClass %s as it is not owned", name)
+	if (len(roleBinding.OwnerReferences) == 0 && len(owners) > 0) ||
+		(len(roleBinding.OwnerReferences) > 0 && len(roleBinding.OwnerReferences) == len(newOwners)) {
+		logrus.Debugf("Cannot delete RoleBinding %s/%s as it is not owned",
+			namespace, name)
 		return nil
 	}
 
 	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s StorageClass", name)
-		return k8sClient.Delete(context.TODO(), storageClass)
-	}
-	storageClass.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s StorageClass", name)
-	return k8sClient.Update(context.TODO(), storageClass)
-}
-
-// CreateOrUpdateCSIDriver creates a CSIDriver if not present,
-// else updates it if it has changed
-func CreateOrUpdateCSIDriver(
-	k8sClient client.Client,
-	driver *storagev1beta1.CSIDriver,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingDriver := &storagev1beta1.CSIDriver{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{Name: driver.Name},
-		existingDriver,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s CSIDriver", driver.Name)
-		return k8sClient.Create(context.TODO(), driver)
-	} else if err != nil {
-		return err
-	}
-
-	modified := !reflect.DeepEqual(driver.Spec, existingDriver.Spec)
-
-	for _, o := range existingDriver.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			driver.OwnerReferences = append(driver.OwnerReferences, o)
-		}
+		logrus.Debugf("Deleting %s/%s RoleBinding", namespace, name)
+		return k8sClient.Delete(context.TODO(), roleBinding)
 	}
+	roleBinding.OwnerReferences = newOwners
+	logrus.Debugf("Disowning %s/%s RoleBinding", namespace, name)
+	return k8sClient.Update(context.TODO(), roleBinding)
+}
 
-	if modified || len(driver.OwnerReferences) > len(existingDriver.OwnerReferences) {
-		logrus.Debugf("Updating %s CSIDriver", driver.Name)
-		return k8sClient.Update(context.TODO(), driver)
-	}
-	return nil
+// DeleteClusterRole deletes a cluster role if present and owned
+func DeleteClusterRole(
+	k8sClient client.Client,
+	name string,
+	owners ...metav1.OwnerReference,
+) error {
+	return Delete(k8sClient, &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}, owners...)
 }
 
-// DeleteCSIDriver deletes the CSIDriver object if present and owned
-func DeleteCSIDriver(
+// DeleteClusterRoleBinding deletes a cluster role binding if present and owned
+func DeleteClusterRoleBinding(
 	k8sClient client.Client,
 	name string,
 	owners ...metav1.OwnerReference,
+) error {
+	return Delete(k8sClient, &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name}}, owners...)
+}
+
+// DeleteConfigMap deletes a config map if present and owned
+func DeleteConfigMap(
+	k8sClient client.Client,
+	name, namespace string,
+	owners ...metav1.OwnerReference,
 ) error {
 	resource := types.NamespacedName{
-		Name: name,
+		Name:      name,
+		Namespace: namespace,
 	}
 
-	csiDriver := &storagev1beta1.CSIDriver{}
-	err := k8sClient.Get(context.TODO(), resource, csiDriver)
+	configMap := &v1.ConfigMap{}
+	err := k8sClient.Get(context.TODO(), resource, configMap)
 	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	newOwners := removeOwners(csiDriver.OwnerReferences, owners)
+	newOwners := removeOwners(configMap.OwnerReferences, owners)
 
 	// Do not delete the object if it does not have the owner that was passed;
 	// even if the object has no owner
-	if (len(csiDriver.OwnerReferences) == 0 && len(owners) > 0) ||
-		(len(csiDriver.OwnerReferences) > 0 && len(csiDriver.OwnerReferences) == len(newOwners)) {
-		logrus.Debugf("Cannot delete CSIDriver %s as it is not owned", name)
+	if (len(configMap.OwnerReferences) == 0 && len(owners) > 0) ||
+		(len(configMap.OwnerReferences) > 0 && len(configMap.OwnerReferences) == len(newOwners)) {
+		logrus.Debugf("Cannot delete ConfigMap %s/%s as it is not owned",
+			namespace, name)
 		return nil
 	}
 
 	if len(newOwners) == 0 {
-		logrus.Debugf("Deleting %s CSIDriver", name)
-		return k8sClient.Delete(context.TODO(), csiDriver)
+		logrus.Debugf("Deleting %s/%s ConfigMap", namespace, name)
+		return k8sClient.Delete(context.TODO(), configMap)
 	}
-	csiDriver.OwnerReferences = newOwners
-	logrus.Debugf("Disowning %s CSIDriver", name)
-	return k8sClient.Update(context.TODO(), csiDriver)
+	configMap.OwnerReferences = newOwners
+	logrus.Debugf("Disowning %s/%s ConfigMap", namespace, name)
+	return k8sClient.Update(context.TODO(), configMap)
 }
 
-// CreateOrUpdateService creates a service if not present, else updates it
-func CreateOrUpdateService(
+// CreateStorageClass creates a storage class only if not present.
+// It will not return error if already present. An existing storage class
+// is only updated to add the given owner to it.
+func CreateStorageClass(
 	k8sClient client.Client,
-	service *v1.Service,
+	sc *storagev1.StorageClass,
 	ownerRef *metav1.OwnerReference,
 ) error {
-	existingService := &v1.Service{}
+	if ownerRef != nil {
+		sc.OwnerReferences = mergeOwners(sc.OwnerReferences, *ownerRef)
+	}
+	setOwnerLabels(sc)
+	existingSC := &storagev1.StorageClass{}
 	err := k8sClient.Get(
 		context.TODO(),
-		types.NamespacedName{
-			Name:      service.Name,
-			Namespace: service.Namespace,
-		},
-		existingService,
+		types.NamespacedName{Name: sc.Name},
+		existingSC,
 	)
 	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s service", service.Name)
-		return k8sClient.Create(context.TODO(), service)
+		logrus.Debugf("Creating %s StorageClass", sc.Name)
+		return k8sClient.Create(context.TODO(), sc)
 	} else if err != nil {
 		return err
 	}
 
-	ownerRefs := make([]metav1.OwnerReference, 0)
-	if ownerRef != nil {
-		ownerRefs = append(ownerRefs, *ownerRef)
-		for _, o := range existingService.OwnerReferences {
-			if o.UID != ownerRef.UID {
-				ownerRefs = append(ownerRefs, o)
-			}
+	toUpdate := existingSC.DeepCopy()
+	setOwnerLabels(toUpdate)
+	for key, value := range sc.Labels {
+		if key == util.LabelManaged || strings.HasPrefix(key, util.LabelOwnerPrefix) {
+			toUpdate.Labels = mergeMaps(toUpdate.Labels, map[string]string{key: value})
 		}
 	}
-
-	if service.Spec.Type == "" {
-		service.Spec.Type = v1.ServiceTypeClusterIP
-	}
-
-	modified := existingService.Spec.Type != service.Spec.Type ||
-		!reflect.DeepEqual(existingService.Labels, service.Labels) ||
-		!reflect.DeepEqual(existingService.Spec.Selector, service.Spec.Selector)
-
-	portMapping := make(map[string]v1.ServicePort)
-	for _, port := range service.Spec.Ports {
-		portMapping[port.Name] = port
-	}
-
-	servicePorts := make([]v1.ServicePort, 0)
-	for _, existingPort := range existingService.Spec.Ports {
-		newPort, exists := portMapping[existingPort.Name]
-		if !exists {
-			// The port is no longer present in the new ports list
-			modified = true
-			continue
-		}
-		if existingPort.Protocol == "" {
-			existingPort.Protocol = v1.ProtocolTCP
-		}
-		if newPort.Protocol == "" {
-			newPort.Protocol = v1.ProtocolTCP
-		}
-		if existingPort.Port != newPort.Port ||
-			!reflect.DeepEqual(existingPort.TargetPort, newPort.TargetPort) ||
-			existingPort.Protocol != newPort.Protocol {
-			modified = true
-		}
-		toUpdate := existingPort.DeepCopy()
-		toUpdate.Port = newPort.Port
-		toUpdate.TargetPort = newPort.TargetPort
-		toUpdate.Protocol = newPort.Protocol
-		if service.Spec.Type != v1.ServiceTypeLoadBalancer &&
-			service.Spec.Type != v1.ServiceTypeNodePort {
-			toUpdate.NodePort = int32(0)
-		}
-		servicePorts = append(servicePorts, *toUpdate)
-		delete(portMapping, newPort.Name)
+	if equality.Semantic.DeepEqual(toUpdate.Labels, existingSC.Labels) &&
+		equality.Semantic.DeepEqual(toUpdate.OwnerReferences, existingSC.OwnerReferences) {
+		return nil
 	}
+	logrus.Debugf("Adding owner to %s StorageClass", sc.Name)
+	return k8sClient.Update(context.TODO(), toUpdate)
+}
 
-	// Copy new ports that were not present in the service before
-	for _, port := range portMapping {
-		modified = true
-		toUpdate := port.DeepCopy()
-		servicePorts = append(servicePorts, *toUpdate)
-	}
+// DeleteStorageClass deletes a storage class if present and owned
+func DeleteStorageClass(
+	k8sClient client.Client,
+	name string,
+	owners ...metav1.OwnerReference,
+) error {
+	return Delete(k8sClient, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: name}}, owners...)
+}
 
-	if modified || len(ownerRefs) > len(existingService.OwnerReferences) {
-		existingService.OwnerReferences = ownerRefs
-		existingService.Labels = service.Labels
-		existingService.Spec.Selector = service.Spec.Selector
-		existingService.Spec.Type = service.Spec.Type
-		existingService.Spec.Ports = servicePorts
-		logrus.Debugf("Updating %s service", service.Name)
-		return k8sClient.Update(context.TODO(), existingService)
-	}
-	return nil
+// DeleteCSIDriver deletes the CSIDriver object if present and owned
+func DeleteCSIDriver(
+	k8sClient client.Client,
+	name string,
+	owners ...metav1.OwnerReference,
+) error {
+	return Delete(k8sClient, &storagev1beta1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: name}}, owners...)
 }
 
 // DeleteService deletes a service if present and owned
@@ -772,38 +734,6 @@ func DeleteService(
 	return k8sClient.Update(context.TODO(), service)
 }
 
-// CreateOrUpdateDeployment creates a deployment if not present, else updates it
-func CreateOrUpdateDeployment(
-	k8sClient client.Client,
-	deployment *appsv1.Deployment,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingDeployment := &appsv1.Deployment{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      deployment.Name,
-			Namespace: deployment.Namespace,
-		},
-		existingDeployment,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s deployment", deployment.Name)
-		return k8sClient.Create(context.TODO(), deployment)
-	} else if err != nil {
-		return err
-	}
-
-	for _, o := range existingDeployment.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			deployment.OwnerReferences = append(deployment.OwnerReferences, o)
-		}
-	}
-
-	logrus.Debugf("Updating %s deployment", deployment.Name)
-	return k8sClient.Update(context.TODO(), deployment)
-}
-
 // DeleteDeployment deletes a deployment if present and owned
 func DeleteDeployment(
 	k8sClient client.Client,
@@ -843,38 +773,6 @@ func DeleteDeployment(
 	return k8sClient.Update(context.TODO(), deployment)
 }
 
-// CreateOrUpdateStatefulSet creates a stateful set if not present, else updates it
-func CreateOrUpdateStatefulSet(
-	k8sClient client.Client,
-	ss *appsv1.StatefulSet,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingSS := &appsv1.StatefulSet{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      ss.Name,
-			Namespace: ss.Namespace,
-		},
-		existingSS,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s stateful set", ss.Name)
-		return k8sClient.Create(context.TODO(), ss)
-	} else if err != nil {
-		return err
-	}
-
-	for _, o := range existingSS.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			ss.OwnerReferences = append(ss.OwnerReferences, o)
-		}
-	}
-
-	logrus.Debugf("Updating %s stateful set", ss.Name)
-	return k8sClient.Update(context.TODO(), ss)
-}
-
 // DeleteStatefulSet deletes a stateful set if present and owned
 func DeleteStatefulSet(
 	k8sClient client.Client,
@@ -914,49 +812,6 @@ func DeleteStatefulSet(
 	return k8sClient.Update(context.TODO(), statefulSet)
 }
 
-// CreateOrUpdateDaemonSet creates a daemon set if not present, else updates it
-func CreateOrUpdateDaemonSet(
-	k8sClient client.Client,
-	ds *appsv1.DaemonSet,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingDS := &appsv1.DaemonSet{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      ds.Name,
-			Namespace: ds.Namespace,
-		},
-		existingDS,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating %s daemon set", ds.Name)
-		return k8sClient.Create(context.TODO(), ds)
-	} else if err != nil {
-		return err
-	}
-
-	ownerRefPresent := false
-	for _, o := range existingDS.OwnerReferences {
-		if o.UID == ownerRef.UID {
-			ownerRefPresent = true
-			break
-		}
-	}
-	if !ownerRefPresent {
-		if existingDS.OwnerReferences == nil {
-			existingDS.OwnerReferences = make([]metav1.OwnerReference, 0)
-		}
-		existingDS.OwnerReferences = append(existingDS.OwnerReferences, *ownerRef)
-	}
-
-	existingDS.Labels = ds.Labels
-	existingDS.Spec = ds.Spec
-
-	logrus.Debugf("Updating %s daemon set", ds.Name)
-	return k8sClient.Update(context.TODO(), existingDS)
-}
-
 // UpdateStorageClusterStatus updates the status of given StorageCluster object
 // on the latest copy
 func UpdateStorageClusterStatus(
@@ -979,7 +834,10 @@ func UpdateStorageClusterStatus(
 	return k8sClient.Status().Update(context.TODO(), cluster)
 }
 
-// CreateOrUpdateStorageNode creates a StorageNode if not present, else updates it
+// CreateOrUpdateStorageNode creates a StorageNode if not present, else updates it.
+// The status of the storage pod is retained if the given node does not have one.
+// The maintenance field of the spec, and the conditions of types not present in
+// the given node, are retained as they are not managed by the storage driver.
 func CreateOrUpdateStorageNode(
 	k8sClient client.Client,
 	node *corev1alpha1.StorageNode,
@@ -1011,8 +869,18 @@ func CreateOrUpdateStorageNode(
 		}
 	}
 
-	modified := !reflect.DeepEqual(node.Status, existingNode.Status) ||
-		!reflect.DeepEqual(node.Spec, existingNode.Spec)
+	if node.Status.Pod == nil {
+		node.Status.Pod = existingNode.Status.Pod
+	}
+	node.Spec.Maintenance = existingNode.Spec.Maintenance
+	for _, condition := range existingNode.Status.Conditions {
+		if !hasStorageNodeCondition(node, condition.Type) {
+			node.Status.Conditions = append(node.Status.Conditions, condition)
+		}
+	}
+
+	modified := !equality.Semantic.DeepEqual(node.Status, existingNode.Status) ||
+		!equality.Semantic.DeepEqual(node.Spec, existingNode.Spec)
 
 	if modified || len(ownerRefs) > len(existingNode.OwnerReferences) {
 		existingNode.Spec = node.Spec
@@ -1026,42 +894,16 @@ func CreateOrUpdateStorageNode(
 	return nil
 }
 
-// CreateOrUpdateServiceMonitor creates a ServiceMonitor if not present, else updates it
-func CreateOrUpdateServiceMonitor(
-	k8sClient client.Client,
-	monitor *monitoringv1.ServiceMonitor,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingMonitor := &monitoringv1.ServiceMonitor{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      monitor.Name,
-			Namespace: monitor.Namespace,
-		},
-		existingMonitor,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating ServiceMonitor %s/%s", monitor.Namespace, monitor.Name)
-		return k8sClient.Create(context.TODO(), monitor)
-	} else if err != nil {
-		return err
-	}
-
-	modified := !reflect.DeepEqual(monitor.Spec, existingMonitor.Spec)
-
-	for _, o := range existingMonitor.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			monitor.OwnerReferences = append(monitor.OwnerReferences, o)
+func hasStorageNodeCondition(
+	node *corev1alpha1.StorageNode,
+	conditionType corev1alpha1.NodeConditionType,
+) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType {
+			return true
 		}
 	}
-
-	if modified || len(monitor.OwnerReferences) > len(existingMonitor.OwnerReferences) {
-		monitor.ResourceVersion = existingMonitor.ResourceVersion
-		logrus.Debugf("Updating ServiceMonitor %s/%s", monitor.Namespace, monitor.Name)
-		return k8sClient.Update(context.TODO(), monitor)
-	}
-	return nil
+	return false
 }
 
 // DeleteServiceMonitor deletes a storage class if present and owned
@@ -1101,44 +943,6 @@ func DeleteServiceMonitor(
 	return k8sClient.Update(context.TODO(), monitor)
 }
 
-// CreateOrUpdatePrometheusRule creates a PrometheusRule if not present, else updates it
-func CreateOrUpdatePrometheusRule(
-	k8sClient client.Client,
-	rule *monitoringv1.PrometheusRule,
-	ownerRef *metav1.OwnerReference,
-) error {
-	existingRule := &monitoringv1.PrometheusRule{}
-	err := k8sClient.Get(
-		context.TODO(),
-		types.NamespacedName{
-			Name:      rule.Name,
-			Namespace: rule.Namespace,
-		},
-		existingRule,
-	)
-	if errors.IsNotFound(err) {
-		logrus.Debugf("Creating PrometheusRule %s/%s", rule.Namespace, rule.Name)
-		return k8sClient.Create(context.TODO(), rule)
-	} else if err != nil {
-		return err
-	}
-
-	modified := !reflect.DeepEqual(rule.Spec, existingRule.Spec)
-
-	for _, o := range existingRule.OwnerReferences {
-		if o.UID != ownerRef.UID {
-			rule.OwnerReferences = append(rule.OwnerReferences, o)
-		}
-	}
-
-	if modified || len(rule.OwnerReferences) > len(existingRule.OwnerReferences) {
-		rule.ResourceVersion = existingRule.ResourceVersion
-		logrus.Debugf("Updating PrometheusRule %s/%s", rule.Namespace, rule.Name)
-		return k8sClient.Update(context.TODO(), rule)
-	}
-	return nil
-}
-
 // DeletePrometheusRule deletes a storage class if present and owned
 func DeletePrometheusRule(
 	k8sClient client.Client,
@@ -1177,6 +981,54 @@ func DeletePrometheusRule(
 	return k8sClient.Update(context.TODO(), rule)
 }
 
+// DeletePodDisruptionBudget deletes a PodDisruptionBudget if present and owned
+func DeletePodDisruptionBudget(
+	k8sClient client.Client,
+	name, namespace string,
+	owners ...metav1.OwnerReference,
+) error {
+	resource := types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}
+
+	pdb := &policyv1beta1.PodDisruptionBudget{}
+	err := k8sClient.Get(context.TODO(), resource, pdb)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	newOwners := removeOwners(pdb.OwnerReferences, owners)
+
+	// Do not delete the object if it does not have the owner that was passed;
+	// even if the object has no owner
+	if (len(pdb.OwnerReferences) == 0 && len(owners) > 0) ||
+		(len(pdb.OwnerReferences) > 0 && len(pdb.OwnerReferences) == len(newOwners)) {
+		logrus.Debugf("Cannot delete PodDisruptionBudget %s/%s as it is not owned",
+			namespace, name)
+		return nil
+	}
+
+	if len(newOwners) == 0 {
+		logrus.Debugf("Deleting %s/%s PodDisruptionBudget", namespace, name)
+		return k8sClient.Delete(context.TODO(), pdb)
+	}
+	pdb.OwnerReferences = newOwners
+	logrus.Debugf("Disowning %s/%s PodDisruptionBudget", namespace, name)
+	return k8sClient.Update(context.TODO(), pdb)
+}
+
+// DeletePriorityClass deletes a PriorityClass if present and owned
+func DeletePriorityClass(
+	k8sClient client.Client,
+	name string,
+	owners ...metav1.OwnerReference,
+) error {
+	return Delete(k8sClient, &schedulingv1beta1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: name}}, owners...)
+}
+
 // GetDaemonSetPods returns a list of pods for the given daemon set
 func GetDaemonSetPods(
 	k8sClient client.Client,
@@ -1239,6 +1091,117 @@ func GetValueFromEnv(imageKey string, envs []v1.EnvVar) string {
 	return ""
 }
 
+// DetectDrift checks if the existing copy of an object, that was created
+// earlier by the operator, has been deleted or changed out of band. If so, it
+// records a Drifted event on the cluster with the fields that will be reverted
+// and returns true, so the caller can apply the desired object again. The
+// existing object is expected to be empty if it was not found.
+func DetectDrift(
+	recorder record.EventRecorder,
+	cluster *corev1alpha1.StorageCluster,
+	desired, existing runtime.Object,
+) (bool, error) {
+	existingMeta, err := meta.Accessor(existing)
+	if err != nil {
+		return false, err
+	}
+
+	kind, name := kindAndName(desired)
+
+	var message string
+	if existingMeta.GetName() == "" {
+		message = fmt.Sprintf("%s %s was deleted, recreating it", kind, name)
+	} else {
+		drifted, err := DriftedFields(desired, existing)
+		if err != nil {
+			return false, err
+		}
+		if len(drifted) == 0 {
+			return false, nil
+		}
+		message = fmt.Sprintf("Reverting changes to %s %s: %s", kind, name, strings.Join(drifted, ", "))
+	}
+
+	logrus.Info(message)
+	recorder.Event(cluster, v1.EventTypeWarning, util.DriftedReason, message)
+	return true, nil
+}
+
+// DriftedFields returns the paths of the fields that are set in the desired
+// object, but have a different value in the existing object. Apart from the
+// labels, the metadata and the status of the objects are not compared. Fields
+// that are not set in the desired object are skipped too, as they may have
+// been defaulted by the API server.
+func DriftedFields(desired, existing runtime.Object) ([]string, error) {
+	desiredFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return nil, err
+	}
+	existingFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fields := range []map[string]interface{}{desiredFields, existingFields} {
+		var labels interface{}
+		if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+			labels = metadata["labels"]
+		}
+		delete(fields, "apiVersion")
+		delete(fields, "kind")
+		delete(fields, "status")
+		fields["metadata"] = map[string]interface{}{"labels": labels}
+	}
+
+	var drifted []string
+	driftedFields("", desiredFields, existingFields, &drifted)
+	return drifted, nil
+}
+
+func driftedFields(path string, desired, existing interface{}, drifted *[]string) {
+	switch desiredValue := desired.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		if len(desiredValue) == 0 {
+			return
+		}
+		existingValue, ok := existing.(map[string]interface{})
+		if !ok {
+			*drifted = append(*drifted, path)
+			return
+		}
+		keys := make([]string, 0, len(desiredValue))
+		for key := range desiredValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			driftedFields(fieldPath, desiredValue[key], existingValue[key], drifted)
+		}
+	case []interface{}:
+		if len(desiredValue) == 0 {
+			return
+		}
+		existingValue, ok := existing.([]interface{})
+		if !ok || len(existingValue) != len(desiredValue) {
+			*drifted = append(*drifted, path)
+			return
+		}
+		for i := range desiredValue {
+			driftedFields(fmt.Sprintf("%s[%d]", path, i), desiredValue[i], existingValue[i], drifted)
+		}
+	default:
+		if !reflect.DeepEqual(desiredValue, existing) {
+			*drifted = append(*drifted, path)
+		}
+	}
+}
+
 func removeOwners(current, toBeDeleted []metav1.OwnerReference) []metav1.OwnerReference {
 	toBeDeletedOwnerMap := make(map[types.UID]bool)
 	for _, owner := range toBeDeleted {
@@ -6,21 +6,26 @@ import (
 
 	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	"github.com/libopenstorage/operator/pkg/util"
 	testutil "github.com/libopenstorage/operator/pkg/util/test"
 	"github.com/portworx/sched-ops/k8s"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1beta1 "k8s.io/api/scheduling/v1beta1"
 	storagev1 "k8s.io/api/storage/v1"
 	storagev1beta1 "k8s.io/api/storage/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	kversion "k8s.io/apimachinery/pkg/version"
 	fakediscovery "k8s.io/client-go/discovery/fake"
 	fakek8sclient "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -430,7 +435,7 @@ func TestCreateStorageClass(t *testing.T) {
 		Provisioner: "foo",
 	}
 
-	err := CreateStorageClass(k8sClient, expectedStorageClass)
+	err := CreateStorageClass(k8sClient, expectedStorageClass, nil)
 	require.NoError(t, err)
 
 	actualStorageClass := &storagev1.StorageClass{}
@@ -441,7 +446,7 @@ func TestCreateStorageClass(t *testing.T) {
 	// Trying to create again will not create again and not return an error
 	expectedStorageClass.Provisioner = "bar"
 
-	err = CreateStorageClass(k8sClient, expectedStorageClass)
+	err = CreateStorageClass(k8sClient, expectedStorageClass, nil)
 	require.NoError(t, err)
 
 	actualStorageClass = &storagev1.StorageClass{}
@@ -935,6 +940,350 @@ func TestUpdateStorageClusterStatus(t *testing.T) {
 	require.Equal(t, "200", actualCluster.ResourceVersion)
 }
 
+func TestCreateOrUpdateSkipsUnchangedObject(t *testing.T) {
+	k8sClient := &updateCountingClient{Client: fake.NewFakeClient()}
+	owner := &metav1.OwnerReference{UID: "owner"}
+	newDeployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-ns",
+				Labels:    map[string]string{"key": "value"},
+			},
+			Spec: appsv1.DeploymentSpec{
+				MinReadySeconds: 10,
+				Paused:          true,
+			},
+		}
+	}
+
+	err := CreateOrUpdate(k8sClient, newDeployment(), owner)
+	require.NoError(t, err)
+
+	created := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, created, "test", "test-ns")
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Annotations[util.AnnotationLastAppliedHash])
+	require.Equal(t, []metav1.OwnerReference{*owner}, created.OwnerReferences)
+
+	// The object should not be written if nothing has changed
+	err = CreateOrUpdate(k8sClient, newDeployment(), owner)
+	require.NoError(t, err)
+
+	actual := &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, actual, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, created, actual)
+	require.Zero(t, k8sClient.updates)
+
+	// The object should be written if a field is removed from the desired object
+	desired := newDeployment()
+	desired.Spec.Paused = false
+
+	err = CreateOrUpdate(k8sClient, desired, owner)
+	require.NoError(t, err)
+
+	actual = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, actual, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, 1, k8sClient.updates)
+	require.NotEqual(t, created.Annotations[util.AnnotationLastAppliedHash],
+		actual.Annotations[util.AnnotationLastAppliedHash])
+	require.False(t, actual.Spec.Paused)
+
+	// The object should be written if the owner is missing
+	secondOwner := &metav1.OwnerReference{UID: "second-owner"}
+
+	err = CreateOrUpdate(k8sClient, desired, secondOwner)
+	require.NoError(t, err)
+
+	actual = &appsv1.Deployment{}
+	err = testutil.Get(k8sClient, actual, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, 2, k8sClient.updates)
+	require.ElementsMatch(t, []metav1.OwnerReference{*owner, *secondOwner}, actual.OwnerReferences)
+}
+
+func TestCreateOrUpdateRevertsChangedFields(t *testing.T) {
+	k8sClient := fake.NewFakeClient()
+	newConfigMap := func() *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-ns",
+			},
+			Data: map[string]string{"key": "value"},
+		}
+	}
+
+	err := CreateOrUpdate(k8sClient, newConfigMap(), nil)
+	require.NoError(t, err)
+
+	// Change a field that is set by the operator out of band
+	existing := &v1.ConfigMap{}
+	err = testutil.Get(k8sClient, existing, "test", "test-ns")
+	require.NoError(t, err)
+	existing.Data["key"] = "changed"
+	err = k8sClient.Update(context.TODO(), existing)
+	require.NoError(t, err)
+
+	err = CreateOrUpdate(k8sClient, newConfigMap(), nil)
+	require.NoError(t, err)
+
+	actual := &v1.ConfigMap{}
+	err = testutil.Get(k8sClient, actual, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key": "value"}, actual.Data)
+}
+
+func TestCreateOrUpdateRetainsFieldsSetByOthers(t *testing.T) {
+	k8sClient := &updateCountingClient{Client: fake.NewFakeClient()}
+	owner := &metav1.OwnerReference{UID: "owner"}
+	otherOwner := metav1.OwnerReference{UID: "other-owner"}
+	desired := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test-ns",
+		},
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeLoadBalancer,
+			Ports: []v1.ServicePort{
+				{
+					Name: "p1",
+					Port: int32(1000),
+				},
+			},
+		},
+	}
+
+	err := CreateOrUpdate(k8sClient, desired.DeepCopy(), owner)
+	require.NoError(t, err)
+
+	// Set fields that are not set by the operator
+	existing := &v1.Service{}
+	err = testutil.Get(k8sClient, existing, "test", "test-ns")
+	require.NoError(t, err)
+	existing.Annotations["other"] = "value"
+	existing.OwnerReferences = append(existing.OwnerReferences, otherOwner)
+	existing.Finalizers = []string{"other-finalizer"}
+	existing.Spec.ClusterIP = "10.0.0.1"
+	existing.Spec.Ports[0].NodePort = int32(30000)
+	err = k8sClient.Client.Update(context.TODO(), existing)
+	require.NoError(t, err)
+
+	// Nothing should be written as the operator's fields have not changed
+	err = CreateOrUpdate(k8sClient, desired.DeepCopy(), owner)
+	require.NoError(t, err)
+
+	actual := &v1.Service{}
+	err = testutil.Get(k8sClient, actual, "test", "test-ns")
+	require.NoError(t, err)
+	require.Zero(t, k8sClient.updates)
+
+	// The fields set by others should be retained when the object is updated
+	desired.Spec.Ports[0].Port = int32(2000)
+
+	err = CreateOrUpdate(k8sClient, desired.DeepCopy(), owner)
+	require.NoError(t, err)
+
+	actual = &v1.Service{}
+	err = testutil.Get(k8sClient, actual, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, 1, k8sClient.updates)
+	require.Equal(t, int32(2000), actual.Spec.Ports[0].Port)
+	require.Equal(t, "value", actual.Annotations["other"])
+	require.ElementsMatch(t, []metav1.OwnerReference{*owner, otherOwner}, actual.OwnerReferences)
+	require.Equal(t, []string{"other-finalizer"}, actual.Finalizers)
+	require.Equal(t, "10.0.0.1", actual.Spec.ClusterIP)
+	require.Equal(t, int32(30000), actual.Spec.Ports[0].NodePort)
+}
+
+func TestCreateOrUpdateRetainsServiceAccountSecrets(t *testing.T) {
+	k8sClient := fake.NewFakeClient()
+	desired := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test-ns",
+		},
+	}
+
+	err := CreateOrUpdate(k8sClient, desired.DeepCopy(), nil)
+	require.NoError(t, err)
+
+	existing := &v1.ServiceAccount{}
+	err = testutil.Get(k8sClient, existing, "test", "test-ns")
+	require.NoError(t, err)
+	existing.Secrets = []v1.ObjectReference{{Name: "token"}}
+	err = k8sClient.Update(context.TODO(), existing)
+	require.NoError(t, err)
+
+	desired.Labels = map[string]string{"key": "value"}
+	err = CreateOrUpdate(k8sClient, desired.DeepCopy(), nil)
+	require.NoError(t, err)
+
+	actual := &v1.ServiceAccount{}
+	err = testutil.Get(k8sClient, actual, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, desired.Labels, actual.Labels)
+	require.Equal(t, existing.Secrets, actual.Secrets)
+}
+
+func TestCreateOrUpdateAddsManagedLabel(t *testing.T) {
+	k8sClient := fake.NewFakeClient()
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-cluster",
+			Namespace: "kube-test",
+			UID:       "cluster-uid",
+		},
+	}
+	ownerRef := metav1.NewControllerRef(cluster, corev1alpha1.SchemeGroupVersion.WithKind("StorageCluster"))
+
+	// Cluster scoped objects owned by a StorageCluster should be labelled
+	err := CreateOrUpdate(k8sClient, &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}, ownerRef)
+	require.NoError(t, err)
+
+	clusterRole := &rbacv1.ClusterRole{}
+	err = testutil.Get(k8sClient, clusterRole, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, "true", clusterRole.Labels[util.LabelManaged])
+	require.Equal(t, "true", clusterRole.Labels[util.LabelOwnerPrefix+"cluster-uid"])
+	require.Empty(t, clusterRole.OwnerReferences)
+	require.Equal(t, []types.UID{"cluster-uid"}, StorageClusterOwners(clusterRole))
+
+	// Namespaced objects should not be labelled
+	err = CreateOrUpdate(k8sClient, &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "kube-test"},
+	}, ownerRef)
+	require.NoError(t, err)
+
+	role := &rbacv1.Role{}
+	err = testutil.Get(k8sClient, role, "test", "kube-test")
+	require.NoError(t, err)
+	require.Empty(t, role.Labels)
+
+	// Cluster scoped objects without a StorageCluster owner should not be labelled
+	err = CreateOrUpdate(k8sClient, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}, nil)
+	require.NoError(t, err)
+
+	binding := &rbacv1.ClusterRoleBinding{}
+	err = testutil.Get(k8sClient, binding, "test", "")
+	require.NoError(t, err)
+	require.Empty(t, binding.Labels)
+}
+
+func TestClusterScopedObjectOwnerLabels(t *testing.T) {
+	k8sClient := fake.NewFakeClient()
+	clusterKind := corev1alpha1.SchemeGroupVersion.WithKind("StorageCluster")
+	cluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "px-cluster", Namespace: "kube-test", UID: "cluster-uid"},
+	}
+	otherCluster := &corev1alpha1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "px-cluster", Namespace: "other-ns", UID: "other-uid"},
+	}
+	ownerRef := metav1.NewControllerRef(cluster, clusterKind)
+	otherOwnerRef := metav1.NewControllerRef(otherCluster, clusterKind)
+
+	// Owner references to StorageClusters set by earlier versions should be
+	// replaced by owner labels
+	err := k8sClient.Create(context.TODO(), &storagev1beta1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+	})
+	require.NoError(t, err)
+
+	err = CreateOrUpdate(k8sClient, &storagev1beta1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}, ownerRef)
+	require.NoError(t, err)
+
+	csiDriver := &storagev1beta1.CSIDriver{}
+	err = testutil.Get(k8sClient, csiDriver, "test", "")
+	require.NoError(t, err)
+	require.Empty(t, csiDriver.OwnerReferences)
+	require.Equal(t, []types.UID{"cluster-uid"}, StorageClusterOwners(csiDriver))
+
+	// A shared object should record all the clusters as owners
+	err = CreateOrUpdate(k8sClient, &storagev1beta1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}, otherOwnerRef)
+	require.NoError(t, err)
+
+	csiDriver = &storagev1beta1.CSIDriver{}
+	err = testutil.Get(k8sClient, csiDriver, "test", "")
+	require.NoError(t, err)
+	require.Empty(t, csiDriver.OwnerReferences)
+	require.Equal(t, []types.UID{"cluster-uid", "other-uid"}, StorageClusterOwners(csiDriver))
+
+	// Deleting for one cluster should only remove it from the owners
+	err = DeleteCSIDriver(k8sClient, "test", *ownerRef)
+	require.NoError(t, err)
+
+	csiDriver = &storagev1beta1.CSIDriver{}
+	err = testutil.Get(k8sClient, csiDriver, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, []types.UID{"other-uid"}, StorageClusterOwners(csiDriver))
+	require.Equal(t, "true", csiDriver.Labels[util.LabelManaged])
+
+	// Deleting for a cluster that is not an owner should not delete the object
+	err = DeleteCSIDriver(k8sClient, "test", *ownerRef)
+	require.NoError(t, err)
+
+	csiDriver = &storagev1beta1.CSIDriver{}
+	err = testutil.Get(k8sClient, csiDriver, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, []types.UID{"other-uid"}, StorageClusterOwners(csiDriver))
+
+	// Deleting for the last owner should delete the object
+	err = DeleteCSIDriver(k8sClient, "test", *otherOwnerRef)
+	require.NoError(t, err)
+
+	err = testutil.Get(k8sClient, &storagev1beta1.CSIDriver{}, "test", "")
+	require.True(t, errors.IsNotFound(err))
+
+	// An existing storage class should only get the owner labels added
+	err = k8sClient.Create(context.TODO(), &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "test"},
+		Provisioner: "foo",
+	})
+	require.NoError(t, err)
+
+	err = CreateStorageClass(k8sClient, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "test"},
+		Provisioner: "bar",
+	}, ownerRef)
+	require.NoError(t, err)
+
+	storageClass := &storagev1.StorageClass{}
+	err = testutil.Get(k8sClient, storageClass, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, "foo", storageClass.Provisioner)
+	require.Empty(t, storageClass.OwnerReferences)
+	require.Equal(t, "true", storageClass.Labels[util.LabelManaged])
+	require.Equal(t, []types.UID{"cluster-uid"}, StorageClusterOwners(storageClass))
+}
+
+// updateCountingClient counts the updates made through the client
+type updateCountingClient struct {
+	client.Client
+	updates int
+}
+
+func (c *updateCountingClient) Update(
+	ctx context.Context,
+	obj runtime.Object,
+	opts ...client.UpdateOption,
+) error {
+	c.updates++
+	return c.Client.Update(ctx, obj, opts...)
+}
+
 func TestServiceMonitorChangeSpec(t *testing.T) {
 	k8sClient := testutil.FakeK8sClient()
 	expectedMonitor := &monitoringv1.ServiceMonitor{
@@ -949,7 +1298,7 @@ func TestServiceMonitorChangeSpec(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateServiceMonitor(k8sClient, expectedMonitor, nil)
+	err := CreateOrUpdate(k8sClient, expectedMonitor, nil)
 	require.NoError(t, err)
 
 	actualMonitor := &monitoringv1.ServiceMonitor{}
@@ -960,7 +1309,7 @@ func TestServiceMonitorChangeSpec(t *testing.T) {
 	// Change spec
 	expectedMonitor.Spec.NamespaceSelector.Any = false
 
-	err = CreateOrUpdateServiceMonitor(k8sClient, expectedMonitor, nil)
+	err = CreateOrUpdate(k8sClient, expectedMonitor, nil)
 	require.NoError(t, err)
 
 	actualMonitor = &monitoringv1.ServiceMonitor{}
@@ -981,7 +1330,7 @@ func TestServiceMonitorWithOwnerReferences(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateServiceMonitor(k8sClient, expectedMonitor, nil)
+	err := CreateOrUpdate(k8sClient, expectedMonitor, nil)
 	require.NoError(t, err)
 
 	actualMonitor := &monitoringv1.ServiceMonitor{}
@@ -990,7 +1339,7 @@ func TestServiceMonitorWithOwnerReferences(t *testing.T) {
 	require.ElementsMatch(t, []metav1.OwnerReference{firstOwner}, actualMonitor.OwnerReferences)
 
 	// Update with the same owner. Nothing should change as owner hasn't changed.
-	err = CreateOrUpdateServiceMonitor(k8sClient, expectedMonitor, &firstOwner)
+	err = CreateOrUpdate(k8sClient, expectedMonitor, &firstOwner)
 	require.NoError(t, err)
 
 	actualMonitor = &monitoringv1.ServiceMonitor{}
@@ -1002,7 +1351,7 @@ func TestServiceMonitorWithOwnerReferences(t *testing.T) {
 	secondOwner := metav1.OwnerReference{UID: "second-owner"}
 	expectedMonitor.OwnerReferences = []metav1.OwnerReference{secondOwner}
 
-	err = CreateOrUpdateServiceMonitor(k8sClient, expectedMonitor, &secondOwner)
+	err = CreateOrUpdate(k8sClient, expectedMonitor, &secondOwner)
 	require.NoError(t, err)
 
 	actualMonitor = &monitoringv1.ServiceMonitor{}
@@ -1103,7 +1452,7 @@ func TestPrometheusRuleChangeSpec(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdatePrometheusRule(k8sClient, expectedRule, nil)
+	err := CreateOrUpdate(k8sClient, expectedRule, nil)
 	require.NoError(t, err)
 
 	actualRule := &monitoringv1.PrometheusRule{}
@@ -1114,7 +1463,7 @@ func TestPrometheusRuleChangeSpec(t *testing.T) {
 	// Change spec
 	expectedRule.Spec.Groups[0].Name = "group-2"
 
-	err = CreateOrUpdatePrometheusRule(k8sClient, expectedRule, nil)
+	err = CreateOrUpdate(k8sClient, expectedRule, nil)
 	require.NoError(t, err)
 
 	actualRule = &monitoringv1.PrometheusRule{}
@@ -1135,7 +1484,7 @@ func TestPrometheusRuleWithOwnerReferences(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdatePrometheusRule(k8sClient, expectedRule, nil)
+	err := CreateOrUpdate(k8sClient, expectedRule, nil)
 	require.NoError(t, err)
 
 	actualRule := &monitoringv1.PrometheusRule{}
@@ -1144,7 +1493,7 @@ func TestPrometheusRuleWithOwnerReferences(t *testing.T) {
 	require.ElementsMatch(t, []metav1.OwnerReference{firstOwner}, actualRule.OwnerReferences)
 
 	// Update with the same owner. Nothing should change as owner hasn't changed.
-	err = CreateOrUpdatePrometheusRule(k8sClient, expectedRule, &firstOwner)
+	err = CreateOrUpdate(k8sClient, expectedRule, &firstOwner)
 	require.NoError(t, err)
 
 	actualRule = &monitoringv1.PrometheusRule{}
@@ -1156,7 +1505,7 @@ func TestPrometheusRuleWithOwnerReferences(t *testing.T) {
 	secondOwner := metav1.OwnerReference{UID: "second-owner"}
 	expectedRule.OwnerReferences = []metav1.OwnerReference{secondOwner}
 
-	err = CreateOrUpdatePrometheusRule(k8sClient, expectedRule, &secondOwner)
+	err = CreateOrUpdate(k8sClient, expectedRule, &secondOwner)
 	require.NoError(t, err)
 
 	actualRule = &monitoringv1.PrometheusRule{}
@@ -1241,6 +1590,242 @@ func TestDeletePrometheusRule(t *testing.T) {
 	require.True(t, errors.IsNotFound(err))
 }
 
+func TestPodDisruptionBudgetChangeSpec(t *testing.T) {
+	k8sClient := testutil.FakeK8sClient()
+	minAvailable := intstr.FromInt(2)
+	expectedPDB := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test-ns",
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+		},
+	}
+
+	err := CreateOrUpdate(k8sClient, expectedPDB.DeepCopy(), nil)
+	require.NoError(t, err)
+
+	actualPDB := &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, actualPDB, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, 2, actualPDB.Spec.MinAvailable.IntValue())
+
+	// Change spec. The budget should be recreated with the new spec.
+	minAvailable = intstr.FromInt(3)
+	expectedPDB.Spec.MinAvailable = &minAvailable
+
+	err = CreateOrUpdate(k8sClient, expectedPDB.DeepCopy(), nil)
+	require.NoError(t, err)
+
+	actualPDB = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, actualPDB, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, 3, actualPDB.Spec.MinAvailable.IntValue())
+}
+
+func TestPodDisruptionBudgetWithOwnerReferences(t *testing.T) {
+	k8sClient := testutil.FakeK8sClient()
+	firstOwner := metav1.OwnerReference{UID: "first-owner"}
+	expectedPDB := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Namespace:       "test-ns",
+			OwnerReferences: []metav1.OwnerReference{firstOwner},
+		},
+	}
+
+	err := CreateOrUpdate(k8sClient, expectedPDB, &firstOwner)
+	require.NoError(t, err)
+
+	actualPDB := &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, actualPDB, "test", "test-ns")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []metav1.OwnerReference{firstOwner}, actualPDB.OwnerReferences)
+
+	// Update with the same owner. Nothing should change as owner hasn't changed.
+	err = CreateOrUpdate(k8sClient, expectedPDB, &firstOwner)
+	require.NoError(t, err)
+
+	actualPDB = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, actualPDB, "test", "test-ns")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []metav1.OwnerReference{firstOwner}, actualPDB.OwnerReferences)
+
+	// Update with a new owner.
+	secondOwner := metav1.OwnerReference{UID: "second-owner"}
+	expectedPDB.OwnerReferences = []metav1.OwnerReference{secondOwner}
+
+	err = CreateOrUpdate(k8sClient, expectedPDB, &secondOwner)
+	require.NoError(t, err)
+
+	actualPDB = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, actualPDB, "test", "test-ns")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []metav1.OwnerReference{secondOwner, firstOwner}, actualPDB.OwnerReferences)
+}
+
+func TestDeletePodDisruptionBudget(t *testing.T) {
+	name := "test"
+	namespace := "test-ns"
+	expected := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	k8sClient := testutil.FakeK8sClient(expected)
+
+	// Don't delete or throw error if the budget is not present
+	err := DeletePodDisruptionBudget(k8sClient, "not-present-pdb", namespace)
+	require.NoError(t, err)
+
+	pdb := &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, name, namespace)
+	require.NoError(t, err)
+	require.Equal(t, expected, pdb)
+
+	// Don't delete when there is no owner in the budget
+	// but trying to delete for specific owners
+	err = DeletePodDisruptionBudget(k8sClient, name, namespace, metav1.OwnerReference{UID: "foo"})
+	require.NoError(t, err)
+
+	pdb = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, name, namespace)
+	require.NoError(t, err)
+	require.Equal(t, expected, pdb)
+
+	// Delete when there is no owner in the budget
+	err = DeletePodDisruptionBudget(k8sClient, name, namespace)
+	require.NoError(t, err)
+
+	pdb = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, name, namespace)
+	require.True(t, errors.IsNotFound(err))
+
+	// Don't delete when the budget is owned by objects
+	// more than what are passed on delete call
+	expected.OwnerReferences = []metav1.OwnerReference{{UID: "alpha"}, {UID: "beta"}}
+	k8sClient.Create(context.TODO(), expected)
+
+	err = DeletePodDisruptionBudget(k8sClient, name, namespace, metav1.OwnerReference{UID: "beta"})
+	require.NoError(t, err)
+
+	pdb = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, name, namespace)
+	require.NoError(t, err)
+	require.Len(t, pdb.OwnerReferences, 1)
+	require.Equal(t, types.UID("alpha"), pdb.OwnerReferences[0].UID)
+
+	// Delete when delete call passes all owners of the budget
+	err = DeletePodDisruptionBudget(k8sClient, name, namespace, metav1.OwnerReference{UID: "alpha"})
+	require.NoError(t, err)
+
+	pdb = &policyv1beta1.PodDisruptionBudget{}
+	err = testutil.Get(k8sClient, pdb, name, namespace)
+	require.True(t, errors.IsNotFound(err))
+}
+
+func TestPriorityClassChangeValue(t *testing.T) {
+	k8sClient := testutil.FakeK8sClient()
+	expectedClass := &schedulingv1beta1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Value: 1000,
+	}
+
+	err := CreateOrUpdate(k8sClient, expectedClass.DeepCopy(), nil)
+	require.NoError(t, err)
+
+	actualClass := &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, actualClass, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, int32(1000), actualClass.Value)
+
+	// Change description
+	expectedClass.Description = "test class"
+
+	err = CreateOrUpdate(k8sClient, expectedClass.DeepCopy(), nil)
+	require.NoError(t, err)
+
+	actualClass = &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, actualClass, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, "test class", actualClass.Description)
+
+	// Change value. The class should be recreated with the new value.
+	expectedClass.Value = 2000
+
+	err = CreateOrUpdate(k8sClient, expectedClass.DeepCopy(), nil)
+	require.NoError(t, err)
+
+	actualClass = &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, actualClass, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, int32(2000), actualClass.Value)
+	require.Equal(t, "test class", actualClass.Description)
+}
+
+func TestDeletePriorityClass(t *testing.T) {
+	name := "test"
+	expected := &schedulingv1beta1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	k8sClient := testutil.FakeK8sClient(expected)
+
+	// Don't delete or throw error if the class is not present
+	err := DeletePriorityClass(k8sClient, "not-present-class")
+	require.NoError(t, err)
+
+	priorityClass := &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, priorityClass, name, "")
+	require.NoError(t, err)
+	require.Equal(t, expected, priorityClass)
+
+	// Don't delete when there is no owner in the class
+	// but trying to delete for specific owners
+	err = DeletePriorityClass(k8sClient, name, metav1.OwnerReference{UID: "foo"})
+	require.NoError(t, err)
+
+	priorityClass = &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, priorityClass, name, "")
+	require.NoError(t, err)
+	require.Equal(t, expected, priorityClass)
+
+	// Delete when there is no owner in the class
+	err = DeletePriorityClass(k8sClient, name)
+	require.NoError(t, err)
+
+	priorityClass = &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, priorityClass, name, "")
+	require.True(t, errors.IsNotFound(err))
+
+	// Don't delete when the class is owned by objects
+	// more than what are passed on delete call
+	expected.OwnerReferences = []metav1.OwnerReference{{UID: "alpha"}, {UID: "beta"}}
+	k8sClient.Create(context.TODO(), expected)
+
+	err = DeletePriorityClass(k8sClient, name, metav1.OwnerReference{UID: "beta"})
+	require.NoError(t, err)
+
+	priorityClass = &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, priorityClass, name, "")
+	require.NoError(t, err)
+	require.Len(t, priorityClass.OwnerReferences, 1)
+	require.Equal(t, types.UID("alpha"), priorityClass.OwnerReferences[0].UID)
+
+	// Delete when delete call passes all owners of the class
+	err = DeletePriorityClass(k8sClient, name, metav1.OwnerReference{UID: "alpha"})
+	require.NoError(t, err)
+
+	priorityClass = &schedulingv1beta1.PriorityClass{}
+	err = testutil.Get(k8sClient, priorityClass, name, "")
+	require.True(t, errors.IsNotFound(err))
+}
+
 func TestCSIDriverChangeSpec(t *testing.T) {
 	k8sClient := fake.NewFakeClient()
 
@@ -1254,7 +1839,7 @@ func TestCSIDriverChangeSpec(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateCSIDriver(k8sClient, expectedDriver, nil)
+	err := CreateOrUpdate(k8sClient, expectedDriver, nil)
 	require.NoError(t, err)
 
 	actualDriver := &storagev1beta1.CSIDriver{}
@@ -1265,7 +1850,7 @@ func TestCSIDriverChangeSpec(t *testing.T) {
 	// Change spec
 	attachRequired = false
 
-	err = CreateOrUpdateCSIDriver(k8sClient, expectedDriver, nil)
+	err = CreateOrUpdate(k8sClient, expectedDriver, nil)
 	require.NoError(t, err)
 
 	actualDriver = &storagev1beta1.CSIDriver{}
@@ -1285,7 +1870,7 @@ func TestCSIDriverWithOwnerReferences(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateCSIDriver(k8sClient, expectedDriver, nil)
+	err := CreateOrUpdate(k8sClient, expectedDriver, nil)
 	require.NoError(t, err)
 
 	actualDriver := &storagev1beta1.CSIDriver{}
@@ -1294,7 +1879,7 @@ func TestCSIDriverWithOwnerReferences(t *testing.T) {
 	require.ElementsMatch(t, []metav1.OwnerReference{firstOwner}, actualDriver.OwnerReferences)
 
 	// Update with the same owner. Nothing should change as owner hasn't changed.
-	err = CreateOrUpdateCSIDriver(k8sClient, expectedDriver, &firstOwner)
+	err = CreateOrUpdate(k8sClient, expectedDriver, &firstOwner)
 	require.NoError(t, err)
 
 	actualDriver = &storagev1beta1.CSIDriver{}
@@ -1306,7 +1891,7 @@ func TestCSIDriverWithOwnerReferences(t *testing.T) {
 	secondOwner := metav1.OwnerReference{UID: "second-owner"}
 	expectedDriver.OwnerReferences = []metav1.OwnerReference{secondOwner}
 
-	err = CreateOrUpdateCSIDriver(k8sClient, expectedDriver, &secondOwner)
+	err = CreateOrUpdate(k8sClient, expectedDriver, &secondOwner)
 	require.NoError(t, err)
 
 	actualDriver = &storagev1beta1.CSIDriver{}
@@ -1334,7 +1919,7 @@ func TestServicePortAddition(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1348,7 +1933,7 @@ func TestServicePortAddition(t *testing.T) {
 		v1.ServicePort{Name: "p2", Port: int32(2000), Protocol: v1.ProtocolTCP},
 	)
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1381,7 +1966,7 @@ func TestServicePortRemoval(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1392,7 +1977,7 @@ func TestServicePortRemoval(t *testing.T) {
 	// Remove port from the target service spec
 	expectedService.Spec.Ports = append([]v1.ServicePort{}, expectedService.Spec.Ports[1:]...)
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1421,7 +2006,7 @@ func TestServiceTargetPortChange(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1432,7 +2017,7 @@ func TestServiceTargetPortChange(t *testing.T) {
 	// Change the target port number of an existing port
 	expectedService.Spec.Ports[0].TargetPort = intstr.FromInt(2000)
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1460,7 +2045,7 @@ func TestServicePortNumberChange(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1471,7 +2056,7 @@ func TestServicePortNumberChange(t *testing.T) {
 	// Change the port number of an existing port
 	expectedService.Spec.Ports[0].Port = int32(2000)
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1501,7 +2086,7 @@ func TestServiceRemoveNodePortsForClusterIP(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1512,7 +2097,7 @@ func TestServiceRemoveNodePortsForClusterIP(t *testing.T) {
 	// Changing to ClusterIP type should remove the node ports
 	expectedService.Spec.Type = v1.ServiceTypeClusterIP
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1542,7 +2127,7 @@ func TestServiceRemoveNodePortsForExternalNameType(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1553,7 +2138,7 @@ func TestServiceRemoveNodePortsForExternalNameType(t *testing.T) {
 	// Changing to ClusterIP type should remove the node ports
 	expectedService.Spec.Type = v1.ServiceTypeExternalName
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1581,7 +2166,7 @@ func TestServicePortProtocolChange(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1592,7 +2177,7 @@ func TestServicePortProtocolChange(t *testing.T) {
 	// Change the protocol of an existing port
 	expectedService.Spec.Ports[0].Protocol = v1.ProtocolUDP
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1620,7 +2205,7 @@ func TestServicePortEmptyExistingProtocol(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1631,7 +2216,7 @@ func TestServicePortEmptyExistingProtocol(t *testing.T) {
 	// Set the default TCP protocol and nothing should change
 	expectedService.Spec.Ports[0].Protocol = v1.ProtocolTCP
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1660,7 +2245,7 @@ func TestServicePortEmptyNewProtocol(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1671,7 +2256,7 @@ func TestServicePortEmptyNewProtocol(t *testing.T) {
 	// Set the protocol to empty and nothing should change as default is TCP
 	expectedService.Spec.Ports[0].Protocol = ""
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1694,7 +2279,7 @@ func TestServiceChangeServiceType(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1705,7 +2290,7 @@ func TestServiceChangeServiceType(t *testing.T) {
 	// Change service type
 	expectedService.Spec.Type = v1.ServiceTypeNodePort
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1724,7 +2309,7 @@ func TestServiceChangeLabels(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1735,7 +2320,7 @@ func TestServiceChangeLabels(t *testing.T) {
 	// Add new labels
 	expectedService.Labels = map[string]string{"key": "value"}
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1746,7 +2331,7 @@ func TestServiceChangeLabels(t *testing.T) {
 	// Change labels
 	expectedService.Labels = map[string]string{"key": "newvalue"}
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1757,13 +2342,45 @@ func TestServiceChangeLabels(t *testing.T) {
 	// Remove labels
 	expectedService.Labels = nil
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
 	err = testutil.Get(k8sClient, actualService, "test", "test-ns")
 	require.NoError(t, err)
 	require.Empty(t, actualService.Labels)
+
+	// Labels added by others should be retained, like annotations
+	expectedService.Labels = map[string]string{"key": "value"}
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
+	require.NoError(t, err)
+
+	actualService = &v1.Service{}
+	err = testutil.Get(k8sClient, actualService, "test", "test-ns")
+	require.NoError(t, err)
+	actualService.Labels["other"] = "value"
+	err = k8sClient.Update(context.TODO(), actualService)
+	require.NoError(t, err)
+	expectedService.Labels = map[string]string{"key": "latestvalue"}
+
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
+	require.NoError(t, err)
+
+	actualService = &v1.Service{}
+	err = testutil.Get(k8sClient, actualService, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key": "latestvalue", "other": "value"}, actualService.Labels)
+
+	// Remove labels again. Only the labels applied by the operator should be removed.
+	expectedService.Labels = nil
+
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
+	require.NoError(t, err)
+
+	actualService = &v1.Service{}
+	err = testutil.Get(k8sClient, actualService, "test", "test-ns")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"other": "value"}, actualService.Labels)
 }
 
 func TestServiceWithOwnerReferences(t *testing.T) {
@@ -1778,7 +2395,7 @@ func TestServiceWithOwnerReferences(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1787,7 +2404,7 @@ func TestServiceWithOwnerReferences(t *testing.T) {
 	require.ElementsMatch(t, []metav1.OwnerReference{firstOwner}, actualService.OwnerReferences)
 
 	// Update with the same owner. Nothing should change as owner hasn't changed.
-	err = CreateOrUpdateService(k8sClient, expectedService, &firstOwner)
+	err = CreateOrUpdate(k8sClient, expectedService, &firstOwner)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1798,7 +2415,7 @@ func TestServiceWithOwnerReferences(t *testing.T) {
 	// Update with a new owner.
 	secondOwner := metav1.OwnerReference{UID: "second-owner"}
 
-	err = CreateOrUpdateService(k8sClient, expectedService, &secondOwner)
+	err = CreateOrUpdate(k8sClient, expectedService, &secondOwner)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1817,7 +2434,7 @@ func TestServiceChangeSelector(t *testing.T) {
 		},
 	}
 
-	err := CreateOrUpdateService(k8sClient, expectedService, nil)
+	err := CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService := &v1.Service{}
@@ -1828,7 +2445,7 @@ func TestServiceChangeSelector(t *testing.T) {
 	// Add new selectors
 	expectedService.Spec.Selector = map[string]string{"key": "value"}
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1839,7 +2456,7 @@ func TestServiceChangeSelector(t *testing.T) {
 	// Change selectors
 	expectedService.Spec.Selector = map[string]string{"key": "newvalue"}
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1850,7 +2467,7 @@ func TestServiceChangeSelector(t *testing.T) {
 	// Remove selectors
 	expectedService.Spec.Selector = nil
 
-	err = CreateOrUpdateService(k8sClient, expectedService, nil)
+	err = CreateOrUpdate(k8sClient, expectedService, nil)
 	require.NoError(t, err)
 
 	actualService = &v1.Service{}
@@ -1858,3 +2475,69 @@ func TestServiceChangeSelector(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, actualService.Spec.Selector)
 }
+
+func TestDriftedFields(t *testing.T) {
+	replicas := int32(1)
+	desired := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test-ns",
+			Labels:    map[string]string{"key": "value"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name:  "test",
+							Image: "test/image:v1",
+							Env:   []v1.EnvVar{},
+							Ports: []v1.ContainerPort{{ContainerPort: 9001}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Fields defaulted by the API server and the metadata managed by
+	// Kubernetes should not be reported as drifted
+	existing := desired.DeepCopy()
+	existing.ResourceVersion = "100"
+	existing.Annotations = map[string]string{"deployment.kubernetes.io/revision": "2"}
+	existing.Spec.RevisionHistoryLimit = &replicas
+	existing.Spec.Template.Spec.Containers[0].Env = nil
+	existing.Spec.Template.Spec.Containers[0].Ports[0].Protocol = v1.ProtocolTCP
+	existing.Spec.Template.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	existing.Status.Replicas = 1
+
+	drifted, err := DriftedFields(desired, existing)
+	require.NoError(t, err)
+	require.Empty(t, drifted)
+
+	// Changes to the fields set in the desired object should be reported
+	newReplicas := int32(0)
+	existing.Labels["key"] = "newvalue"
+	existing.Spec.Replicas = &newReplicas
+	existing.Spec.Template.Spec.Containers[0].Image = "test/image:v2"
+	existing.Spec.Template.Spec.Containers[0].Ports = nil
+
+	drifted, err = DriftedFields(desired, existing)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"metadata.labels.key",
+		"spec.replicas",
+		"spec.template.spec.containers[0].image",
+		"spec.template.spec.containers[0].ports",
+	}, drifted)
+
+	// Additional list items should be reported
+	existing = desired.DeepCopy()
+	existing.Spec.Template.Spec.Containers = append(existing.Spec.Template.Spec.Containers,
+		v1.Container{Name: "sidecar"})
+
+	drifted, err = DriftedFields(desired, existing)
+	require.NoError(t, err)
+	require.Equal(t, []string{"spec.template.spec.containers"}, drifted)
+}
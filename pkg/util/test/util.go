@@ -40,6 +40,7 @@ func FakeK8sClient(initObjects ...runtime.Object) client.Client {
 	s := scheme.Scheme
 	corev1alpha1.AddToScheme(s)
 	monitoringv1.AddToScheme(s)
+	apiextensionsv1beta1.AddToScheme(s)
 	return fake.NewFakeClientWithScheme(s, initObjects...)
 }
 
@@ -2,7 +2,47 @@ package util
 
 import (
 	"path"
+	"reflect"
 	"strings"
+	"sync"
+
+	corev1alpha1 "github.com/libopenstorage/operator/pkg/apis/core/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+)
+
+const (
+	// AnnotationResourceSuffix is the annotation on a StorageCluster whose value is
+	// appended to the names of all the cluster scoped objects of that cluster. The
+	// operator sets it when another StorageCluster already exists in the Kubernetes
+	// cluster, so the cluster scoped objects of different StorageClusters do not collide.
+	AnnotationResourceSuffix = "operator.libopenstorage.org/resource-suffix"
+	// AnnotationMigrateDaemonSet is the annotation on a StorageCluster with the name
+	// of a DaemonSet, in the namespace of the cluster, whose pods are replaced by the
+	// storage pods of the cluster one node at a time. The DaemonSet is deleted once
+	// all its pods have been replaced.
+	AnnotationMigrateDaemonSet = "operator.libopenstorage.org/migrate-daemonset"
+	// AnnotationLastAppliedHash is the annotation on the objects created by the
+	// operator with the hash of the object as last applied by the operator. It is
+	// used to skip updating objects whose desired state has not changed.
+	AnnotationLastAppliedHash = "operator.libopenstorage.org/last-applied-hash"
+	// AnnotationLastAppliedLabels is the annotation on the objects created by the
+	// operator with the keys of the labels last applied by the operator. It is used
+	// to remove the labels that are no longer desired, while retaining the labels
+	// added by others.
+	AnnotationLastAppliedLabels = "operator.libopenstorage.org/last-applied-labels"
+	// LabelManaged is the label on the cluster scoped objects created by the operator
+	// for StorageClusters. Such objects are not garbage collected when the clusters
+	// owning them are deleted, so the operator uses the label to find and delete them.
+	LabelManaged = "operator.libopenstorage.org/managed"
+	// LabelOwnerPrefix is the prefix of the labels that record the StorageClusters
+	// owning a cluster scoped object, with the UID of the owner after the prefix.
+	// Kubernetes does not support owner references from cluster scoped objects to
+	// namespaced ones, so the owners of such objects are recorded in labels.
+	LabelOwnerPrefix = "owner.operator.libopenstorage.org/"
 )
 
 // Reasons for controller events
@@ -17,6 +57,17 @@ const (
 	FailedValidationReason = "FailedValidation"
 	// FailedComponentReason is added to an event when setting up or removing a component fails.
 	FailedComponentReason = "FailedComponent"
+	// DriftedReason is added to an event when an object owned by a cluster was
+	// deleted or changed out of band, and the operator is reverting it.
+	DriftedReason = "Drifted"
+	// MaintenanceReason is added to an event when a storage node is moved in or out of maintenance.
+	MaintenanceReason = "Maintenance"
+	// FailedMaintenanceReason is added to an event when a storage node could not be moved
+	// in or out of maintenance.
+	FailedMaintenanceReason = "FailedMaintenance"
+	// DeprecatedAnnotationReason is added to an event when a cluster uses an annotation
+	// that has been replaced by a field in the spec.
+	DeprecatedAnnotationReason = "DeprecatedAnnotation"
 )
 
 var (
@@ -57,3 +108,149 @@ func GetImageURN(registryAndRepo, image string) string {
 	}
 	return registryAndRepo + "/" + path.Join(imgParts...)
 }
+
+// ClusterScopedName returns the name of a cluster scoped object, like a ClusterRole
+// or a StorageClass, that belongs to the given StorageCluster. The name is suffixed
+// if the cluster has a resource suffix, else the given name is returned as is.
+func ClusterScopedName(cluster *corev1alpha1.StorageCluster, name string) string {
+	suffix := strings.TrimSpace(cluster.Annotations[AnnotationResourceSuffix])
+	if suffix == "" {
+		return name
+	}
+	return name + "-" + suffix
+}
+
+// ApplyPlacement sets the node affinity and adds the tolerations from the given
+// placement to the pod spec. Pod affinities already in the pod spec are kept,
+// and tolerations already in the pod spec are updated instead of repeated.
+func ApplyPlacement(podSpec *v1.PodSpec, placement *corev1alpha1.PlacementSpec) {
+	if placement == nil {
+		return
+	}
+	if placement.NodeAffinity != nil {
+		if podSpec.Affinity == nil {
+			podSpec.Affinity = &v1.Affinity{}
+		}
+		podSpec.Affinity.NodeAffinity = placement.NodeAffinity.DeepCopy()
+	}
+	for i := range placement.Tolerations {
+		v1helper.AddOrUpdateTolerationInPodSpec(podSpec, placement.Tolerations[i].DeepCopy())
+	}
+}
+
+// PlacementChanged returns true if the affinity or the tolerations of the
+// existing pod spec are different from the ones of the target pod spec
+func PlacementChanged(existing, target *v1.PodSpec) bool {
+	return !reflect.DeepEqual(existing.Affinity, target.Affinity) ||
+		!reflect.DeepEqual(existing.Tolerations, target.Tolerations)
+}
+
+// ResourceRequirements returns a copy of the given resource requirements from
+// the spec if set. Otherwise, only the CPU from the given annotation of the
+// cluster is requested, or the default CPU if the annotation is not present.
+// Nothing is requested if there is no default either.
+func ResourceRequirements(
+	cluster *corev1alpha1.StorageCluster,
+	resources *v1.ResourceRequirements,
+	cpuAnnotation, defaultCPU string,
+) (v1.ResourceRequirements, error) {
+	if resources != nil {
+		return *resources.DeepCopy(), nil
+	}
+
+	cpu := defaultCPU
+	if cpuStr, ok := cluster.Annotations[cpuAnnotation]; ok && cpuAnnotation != "" {
+		cpu = cpuStr
+	}
+	if cpu == "" {
+		return v1.ResourceRequirements{}, nil
+	}
+	cpuQuantity, err := resource.ParseQuantity(cpu)
+	if err != nil {
+		return v1.ResourceRequirements{}, err
+	}
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU: cpuQuantity,
+		},
+	}, nil
+}
+
+// SetContainerResources sets the given resource requirements on all the
+// containers, including the init containers, of the pod spec. The pod spec
+// is not changed if there are no resource requirements.
+func SetContainerResources(podSpec *v1.PodSpec, resources *v1.ResourceRequirements) {
+	if resources == nil {
+		return
+	}
+	for i := range podSpec.InitContainers {
+		podSpec.InitContainers[i].Resources = *resources.DeepCopy()
+	}
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].Resources = *resources.DeepCopy()
+	}
+}
+
+// ContainerResourcesChanged returns true if the resources of any container of
+// the target pod spec are different from the ones of the same container in the
+// existing pod spec
+func ContainerResourcesChanged(existing, target *v1.PodSpec) bool {
+	existingResources := make(map[string]v1.ResourceRequirements)
+	for _, containers := range [][]v1.Container{existing.InitContainers, existing.Containers} {
+		for _, c := range containers {
+			existingResources[c.Name] = c.Resources
+		}
+	}
+	for _, containers := range [][]v1.Container{target.InitContainers, target.Containers} {
+		for _, c := range containers {
+			if resources, ok := existingResources[c.Name]; !ok ||
+				!equality.Semantic.DeepEqual(resources, c.Resources) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ClusterSet is a set of StorageClusters that is safe for concurrent use. The
+// operator manages multiple StorageClusters, so any state it keeps about what
+// it has done for a cluster has to be kept per cluster.
+type ClusterSet struct {
+	lock     sync.Mutex
+	clusters map[types.NamespacedName]bool
+}
+
+// Add adds the given cluster to the set
+func (s *ClusterSet) Add(cluster *corev1alpha1.StorageCluster) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.clusters == nil {
+		s.clusters = make(map[types.NamespacedName]bool)
+	}
+	s.clusters[clusterKey(cluster)] = true
+}
+
+// Remove removes the given cluster from the set
+func (s *ClusterSet) Remove(cluster *corev1alpha1.StorageCluster) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.clusters, clusterKey(cluster))
+}
+
+// Contains returns true if the given cluster is in the set
+func (s *ClusterSet) Contains(cluster *corev1alpha1.StorageCluster) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.clusters[clusterKey(cluster)]
+}
+
+// Clear removes all the clusters from the set
+func (s *ClusterSet) Clear() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clusters = nil
+}
+
+func clusterKey(cluster *corev1alpha1.StorageCluster) types.NamespacedName {
+	return types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}
+}